@@ -0,0 +1,69 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 51: gather abbreviation definitions at the end
+// ----------------------------------------------------------------
+
+type AbbreviationsRule struct{}
+
+// NewAbbreviationsRule constructs a rule that moves Markdown Extra
+// abbreviation definitions (*[HTML]: HyperText Markup Language) to the end
+// of the document, after the link and footnote definitions, keeping the
+// first definition of each abbreviation.
+func NewAbbreviationsRule() Rule { return AbbreviationsRule{} }
+
+func (AbbreviationsRule) Name() string {
+	return "Abbreviations"
+}
+
+func (AbbreviationsRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var defs []string
+	seen := map[string]bool{}
+	isDef := make([]bool, len(lines))
+	for i, line := range lines {
+		if b.lines[i].kind != lineAbbreviation {
+			continue
+		}
+		isDef[i] = true
+		m := abbreviationDefinition.FindStringSubmatch(line)
+		if seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		defs = append(defs, "*["+m[1]+"]: "+strings.TrimSpace(line[len(m[0]):]))
+	}
+	if len(defs) == 0 {
+		return content, nil
+	}
+
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+	out := make([]string, 0, len(lines))
+	removed := false
+	for i, l := range lines {
+		if isDef[i] {
+			removed = true
+			continue
+		}
+		if removed && blank(l) && (len(out) == 0 || blank(out[len(out)-1])) {
+			continue
+		}
+		removed = false
+		out = append(out, l)
+	}
+	trailingNewline := len(lines) > 1 && lines[len(lines)-1] == ""
+	for len(out) > 0 && blank(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+	if len(out) > 0 {
+		out = append(out, "")
+	}
+	out = append(out, defs...)
+	if trailingNewline {
+		out = append(out, "")
+	}
+	return strings.Join(out, "\n"), nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestAbbreviationsRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "moved to the end",
+			input:    "The HTML spec.\n\n*[HTML]: HyperText Markup Language\n\nBy the W3C.\n\n[w3c]: https://w3.org\n",
+			expected: "The HTML spec.\n\nBy the W3C.\n\n[w3c]: https://w3.org\n\n*[HTML]: HyperText Markup Language\n",
+		},
+		{
+			name:     "duplicates and spacing",
+			input:    "Text.\n\n*[HTML]:   HyperText Markup Language\n*[W3C]: World Wide Web Consortium\n*[HTML]: Something else",
+			expected: "Text.\n\n*[HTML]: HyperText Markup Language\n*[W3C]: World Wide Web Consortium",
+		},
+		{
+			name:     "only definitions",
+			input:    "*[HTML]: HyperText Markup Language\n",
+			expected: "*[HTML]: HyperText Markup Language\n",
+		},
+		{
+			name:     "code and lists are not definitions",
+			input:    "```\n*[HTML]: x\n```\n\n- *[HTML]: item\n",
+			expected: "```\n*[HTML]: x\n```\n\n- *[HTML]: item\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewAbbreviationsRule()
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestAbbreviationsSurviveFormatting(t *testing.T) {
+	input := "The HTML spec by the W3C.\n\n*[HTML]: HyperText Markup Language\n*[W3C]: World Wide Web Consortium\n"
+	got, _, err := formatDocument(input, "", Options{}, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != input {
+		t.Errorf("expected:\n%q\ngot:\n%q", input, got)
+	}
+}
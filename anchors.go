@@ -0,0 +1,152 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/404Simon/mdfmt/slug"
+)
+
+// ----------------------------------------------------------------
+// Rule 62: give headings explicit HTML anchors
+// ----------------------------------------------------------------
+
+// headingAnchorLine matches the anchor lines HeadingAnchorsRule writes and
+// captures their id.
+var headingAnchorLine = regexp.MustCompile(`^<a id="([^"<>]*)"></a>[ \t]*$`)
+
+type HeadingAnchorsRule struct {
+	// after puts anchors on the line after their heading instead of the
+	// line before.
+	after bool
+	slug  func(string) string
+}
+
+// NewHeadingAnchorsRule constructs a rule that puts an <a id="..."></a>
+// line next to each heading, before it or with after under it, for
+// renderers that do not give headings anchors themselves. Ids are those
+// slug makes of the heading text, deduplicated across the document, or
+// the heading's own {#id}. Anchor lines already next to a heading are
+// replaced, so stale ids are updated rather than stacked.
+func NewHeadingAnchorsRule(after bool, slug func(string) string) Rule {
+	return HeadingAnchorsRule{after: after, slug: slug}
+}
+
+func newHeadingAnchorsRuleFromOptions(o Options) (Rule, error) {
+	pos, err := o.Choice("heading-anchors", "before", "before", "after")
+	if err != nil {
+		return nil, err
+	}
+	style, err := o.Choice("slug-style", "github", "github", "gitlab")
+	if err != nil {
+		return nil, err
+	}
+	f, err := slug.ByName(style)
+	if err != nil {
+		return nil, err
+	}
+	return NewHeadingAnchorsRule(pos == "after", f), nil
+}
+
+func (HeadingAnchorsRule) Name() string {
+	return "HeadingAnchors"
+}
+
+// isHeadingAnchor reports whether line is an anchor line as written by
+// HeadingAnchorsRule.
+func isHeadingAnchor(line string) bool {
+	return headingAnchorLine.MatchString(line)
+}
+
+// headingID returns the identifier of a {#id} attribute block ending the
+// heading line, or "".
+func headingID(line string) string {
+	text := strings.TrimRight(strings.TrimSpace(line), "#")
+	text = strings.TrimSpace(text)
+	k := strings.LastIndexByte(text, '{')
+	if k < 0 {
+		return ""
+	}
+	if end, ok := attributeBlock(text, k); !ok || end != len(text) {
+		return ""
+	}
+	for _, f := range strings.Fields(text[k+1 : len(text)-1]) {
+		if strings.HasPrefix(f, "#") {
+			return f[1:]
+		}
+	}
+	return ""
+}
+
+func (r HeadingAnchorsRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	headings := documentHeadings(lines, b)
+	if len(headings) == 0 {
+		return content, nil
+	}
+
+	// explicit ids are taken before any slug is handed out
+	ids := make([]string, len(headings))
+	d := slug.NewDeduplicator(r.slug)
+	for n, h := range headings {
+		if ids[n] = headingID(lines[h.line]); ids[n] != "" {
+			d.Reserve(ids[n])
+		}
+	}
+	// the first and last line of each heading, by its first line
+	first := map[int]int{}
+	last := map[int]int{}
+	for n, h := range headings {
+		if ids[n] == "" {
+			ids[n] = d.Slug(plainText(h.text))
+		}
+		// an anchor line would end the list item a heading is in
+		if b.lines[h.line].container > 0 {
+			continue
+		}
+		end := h.line
+		if h.line+1 < len(lines) && b.lines[h.line+1].kind == lineSetextUnderline {
+			end++
+		}
+		first[h.line] = n
+		last[end] = n
+	}
+
+	// anchor lines right next to a heading are the rule's own, to be
+	// rewritten; they are dropped and written anew
+	drop := map[int]bool{}
+	for i, line := range lines {
+		if b.lines[i].kind != lineText || !isHeadingAnchor(line) {
+			continue
+		}
+		if _, ok := first[i+1]; ok {
+			drop[i] = true
+		}
+		if _, ok := last[i-1]; ok {
+			drop[i] = true
+		}
+	}
+
+	anchor := func(n int) string {
+		return `<a id="` + ids[n] + `"></a>`
+	}
+	var out []string
+	for i, line := range lines {
+		if drop[i] {
+			continue
+		}
+		if n, ok := first[i]; ok && !r.after {
+			// a blank line keeps the anchor out of the paragraph above
+			if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+				out = append(out, "")
+			}
+			out = append(out, anchor(n))
+		}
+		out = append(out, line)
+		if n, ok := last[i]; ok && r.after {
+			out = append(out, anchor(n))
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
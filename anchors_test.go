@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/404Simon/mdfmt/slug"
+)
+
+func TestHeadingAnchorsRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		after    bool
+		gitlab   bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "before",
+			input:    "# Intro\n\nText\n## Usage\n\n## Usage\n",
+			expected: "<a id=\"intro\"></a>\n# Intro\n\nText\n\n<a id=\"usage\"></a>\n## Usage\n\n<a id=\"usage-1\"></a>\n## Usage\n",
+		},
+		{
+			name:     "after",
+			after:    true,
+			input:    "# Intro\n\nText\n\nSetext\n------\n\nMore\n",
+			expected: "# Intro\n<a id=\"intro\"></a>\n\nText\n\nSetext\n------\n<a id=\"setext\"></a>\n\nMore\n",
+		},
+		{
+			name:     "stale anchors are refreshed",
+			input:    "<a id=\"old\"></a>\n# New Name\n<a id=\"older\"></a>\n\nText\n",
+			expected: "<a id=\"new-name\"></a>\n# New Name\n\nText\n",
+		},
+		{
+			name:     "explicit ids",
+			input:    "# FAQ\n\n## Questions {#faq}\n",
+			expected: "<a id=\"faq-1\"></a>\n# FAQ\n\n<a id=\"faq\"></a>\n## Questions {#faq}\n",
+		},
+		{
+			name:     "slug style",
+			gitlab:   true,
+			input:    "# A & B\n\n# 2024\n",
+			expected: "<a id=\"a-b\"></a>\n# A & B\n\n<a id=\"anchor-2024\"></a>\n# 2024\n",
+		},
+		{
+			name:     "headings in code, quotes and lists",
+			input:    "```\n# code\n```\n\n> # quoted\n\n- item\n\n  ## In item\n",
+			expected: "```\n# code\n```\n\n> # quoted\n\n- item\n\n  ## In item\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := slug.GitHub
+			if tt.gitlab {
+				style = slug.GitLab
+			}
+			rule := NewHeadingAnchorsRule(tt.after, style)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestHeadingAnchorsInPipeline(t *testing.T) {
+	input := "# Intro\nText\n## Usage\nMore <b>bold</b>\n"
+	for pos, want := range map[string]string{
+		"before": "<a id=\"intro\"></a>\n# Intro\n\nText\n\n<a id=\"usage\"></a>\n## Usage\n\nMore <b>bold</b>\n",
+		"after":  "# Intro\n<a id=\"intro\"></a>\n\nText\n## Usage\n<a id=\"usage\"></a>\n\nMore <b>bold</b>\n",
+	} {
+		opts := Options{"heading-anchors": pos}
+		enable := []string{"HeadingAnchors"}
+		got, diags, err := formatDocument(input, "", opts, enable, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: expected:\n%q\ngot:\n%q", pos, want, got)
+		}
+		// the anchors are not reported as HTML, unlike <b>
+		if len(diags) != 1 || diags[0].Key != "b" {
+			t.Errorf("%s: got %v", pos, diags)
+		}
+		again, _, err := formatDocument(got, "", opts, enable, nil, false)
+		if err != nil || again != got {
+			t.Errorf("%s: not idempotent: %q", pos, again)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 45: write apostrophes as ASCII or typographic apostrophes
+// ----------------------------------------------------------------
+
+// elisions are the words commonly written with a leading apostrophe.
+var elisions = []string{"tis", "twas", "em", "cause", "til", "n", "bout", "round"}
+
+type ApostropheRule struct {
+	// style is "ascii", "typographic", or "" to leave apostrophes alone.
+	style string
+}
+
+// NewApostropheRule constructs a rule that writes the apostrophes of
+// contractions, possessives and elisions such as ’90s as ' with style
+// "ascii" and as ’ with style "typographic". Single quotation marks and
+// feet, as in 5'9", are left alone.
+func NewApostropheRule(style string) Rule {
+	return ApostropheRule{style: style}
+}
+
+func newApostropheRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("apostrophe", "", "", "ascii", "typographic")
+	if err != nil {
+		return nil, err
+	}
+	return NewApostropheRule(style), nil
+}
+
+func (ApostropheRule) Name() string {
+	return "Apostrophe"
+}
+
+func (r ApostropheRule) Apply(content string) (string, error) {
+	if r.style == "" {
+		return content, nil
+	}
+	from, to := "’", "'"
+	if r.style == "typographic" {
+		from, to = to, from
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		spans := mergeSpans(append(nonProseSpans(line), mathSpans(line)...))
+		lines[i] = mapOutside(line, spans, func(s string) string {
+			var out strings.Builder
+			quoted := false // a single quotation mark is open
+			for k, c := range s {
+				if c == '‘' {
+					quoted = true
+				}
+				if c != '\'' && c != '’' {
+					out.WriteRune(c)
+					continue
+				}
+				apostrophe := false
+				prev, _ := utf8.DecodeLastRuneInString(s[:k])
+				next, _ := utf8.DecodeRuneInString(s[k+utf8.RuneLen(c):])
+				word := unicode.IsLetter(prev) || unicode.IsDigit(prev)
+				switch {
+				case k > 0 && word && unicode.IsLetter(next):
+					// don't, it's, 1990's
+					apostrophe = true
+				case k > 0 && unicode.IsLetter(prev):
+					// students' or the end of a quotation
+					apostrophe = !quoted
+					quoted = false
+				case k == 0 || !word && !unicode.IsPunct(prev) || strings.ContainsRune("([", prev):
+					rest := s[k+utf8.RuneLen(c):]
+					if unicode.IsDigit(next) && !unicode.IsDigit(prev) || elided(rest) {
+						// ’90s, ’tis
+						apostrophe = true
+					} else if unicode.IsLetter(next) {
+						quoted = true
+					}
+				}
+				if apostrophe && string(c) == from {
+					out.WriteString(to)
+				} else {
+					out.WriteRune(c)
+				}
+			}
+			return out.String()
+		})
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// elided reports whether s starts with a word that is written with a
+// leading apostrophe.
+func elided(s string) bool {
+	end := strings.IndexFunc(s, func(c rune) bool { return !unicode.IsLetter(c) })
+	if end < 0 {
+		end = len(s)
+	}
+	for _, w := range elisions {
+		if strings.EqualFold(s[:end], w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestApostropheRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "contractions and possessives",
+			style:    "typographic",
+			input:    "Don't say it's the students' fault, or James's, in the 1990's.",
+			expected: "Don’t say it’s the students’ fault, or James’s, in the 1990’s.",
+		},
+		{
+			name:     "leading apostrophes",
+			style:    "typographic",
+			input:    "Back in the '90s, 'tis said, rock 'n' roll ruled ('80s too).",
+			expected: "Back in the ’90s, ’tis said, rock ’n’ roll ruled (’80s too).",
+		},
+		{
+			name:     "quotation marks",
+			style:    "typographic",
+			input:    "He said 'fine' and 'it's done'.",
+			expected: "He said 'fine' and 'it’s done'.",
+		},
+		{
+			name:     "feet",
+			style:    "typographic",
+			input:    "She is 5'9\" tall, the board 6' long.",
+			expected: "She is 5'9\" tall, the board 6' long.",
+		},
+		{
+			name:     "code and front matter",
+			style:    "typographic",
+			input:    "---\ntitle: Don't\n---\nUse `don't` and\n\n```\ndon't\n```\n\nsee [it's](http://x/it's).",
+			expected: "---\ntitle: Don't\n---\nUse `don't` and\n\n```\ndon't\n```\n\nsee [it’s](http://x/it's).",
+		},
+		{
+			name:     "ascii",
+			style:    "ascii",
+			input:    "Don’t say ‘it’s fine’ in the ’90s, the students’ way.",
+			expected: "Don't say ‘it's fine’ in the '90s, the students' way.",
+		},
+		{
+			name:     "off",
+			input:    "Don't won’t",
+			expected: "Don't won’t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewApostropheRule(tt.style)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
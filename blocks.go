@@ -0,0 +1,698 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Block classifier shared by the region-aware rules
+// ----------------------------------------------------------------
+
+// lineKind is the block-level role of a single line.
+type lineKind int
+
+const (
+	lineText lineKind = iota // paragraph text and anything unclassified
+	lineBlank
+	lineFenceOpen
+	lineFenceClose
+	lineCode // content of a fenced code block
+	lineIndentedCode
+	lineThematicBreak
+	lineSetextUnderline
+	lineFrontMatter    // front matter, delimiters included
+	lineTable          // table header, delimiter and body rows
+	lineDefinitionTerm // term of a Pandoc definition list
+	lineHTML           // raw HTML block; only the goldmark backend finds these
+	lineDivFence       // opening or closing fence of a Pandoc div
+	lineAbbreviation   // Markdown Extra abbreviation definition, *[HTML]: ...
+)
+
+// lineInfo is what the classifier knows about one line of the document.
+type lineInfo struct {
+	kind lineKind
+	// quote is the blockquote nesting depth.
+	quote int
+	// bodyStart is the byte offset just past the blockquote markers.
+	bodyStart int
+	// indent is the column of the first non-blank character of the body.
+	indent int
+	// container is the column at which the enclosing list item's content
+	// starts, or 0 outside of lists.
+	container int
+	// item is set when the line opens a list item.
+	item bool
+	// footnote is set when the line opens a footnote definition.
+	footnote bool
+	// definition is set when the line opens a Pandoc definition.
+	definition bool
+	// lazy is set when the line continues a quoted paragraph without
+	// repeating all of its ">" markers.
+	lazy bool
+	// fence indexes blocks.fences for fence and code lines, -1 otherwise.
+	fence int
+}
+
+// fence is a fenced code block. Line numbers are 0-based indexes.
+type fence struct {
+	open  int
+	close int // -1 when the block runs to the end of its container
+	char  byte
+	size  int
+	// markerEnd is the byte offset in the opening line just past the
+	// fence characters, i.e. where the info string begins.
+	markerEnd int
+	info      string
+	quote     int
+	container int
+}
+
+// blocks is the result of classifying a document line by line.
+type blocks struct {
+	lines  []lineInfo
+	fences []fence
+}
+
+// isCode reports whether line i belongs to a code block, fences included.
+func (b *blocks) isCode(i int) bool {
+	switch b.lines[i].kind {
+	case lineFenceOpen, lineFenceClose, lineCode, lineIndentedCode:
+		return true
+	}
+	return false
+}
+
+// blockParser selects how classifyBlocks finds the block structure:
+// "internal" uses the line heuristics of classifyLines alone, "goldmark"
+// corrects them with goldmark's parse tree.
+var blockParser = "internal"
+
+// blockParsers are the values blockParser can take.
+var blockParsers = []string{"internal", "goldmark"}
+
+// classifyBlocks records the block structure of a document with the
+// selected blockParser.
+func classifyBlocks(lines []string) *blocks {
+	if blockParser == "goldmark" {
+		return classifyWithGoldmark(lines)
+	}
+	return classifyLines(lines)
+}
+
+// classifyLines walks the lines of a document and records the block
+// structure that rules need to stay out of code and respect containers.
+// It is a line-oriented approximation of CommonMark, not a full parser.
+func classifyLines(lines []string) *blocks {
+	b := &blocks{lines: make([]lineInfo, len(lines))}
+	open := -1      // fence currently being read
+	var items []int // content columns of the open list items
+	quote := 0      // blockquote depth of the current block
+	defCol := 0     // content column of the last definition, 0 if none
+	divs := 0       // Pandoc divs open
+	prev := lineBlank
+
+	fm := metadataEnd(lines)
+	for i := 0; i <= fm; i++ {
+		b.lines[i] = lineInfo{kind: lineFrontMatter, fence: -1}
+	}
+
+	for i, line := range lines {
+		if i <= fm {
+			continue
+		}
+		info := &b.lines[i]
+		info.fence = -1
+
+		if open >= 0 {
+			f := &b.fences[open]
+			depth, off := stripQuote(line, f.quote)
+			body := line[off:]
+			col, _ := leadingColumns(body)
+			blank := strings.TrimSpace(body) == ""
+			if depth == f.quote && (blank || col >= f.container) {
+				info.quote, info.bodyStart, info.indent = depth, off, col
+				info.container, info.fence = f.container, open
+				info.kind = lineCode
+				if !blank && col-f.container < 4 && isClosingFence(body, f.char, f.size) {
+					info.kind = lineFenceClose
+					f.close = i
+					open = -1
+				}
+				prev = info.kind
+				continue
+			}
+			// the container of the fence ended before its closer
+			open = -1
+		}
+
+		depth, off := stripQuote(line, -1)
+		body := line[off:]
+		col, _ := leadingColumns(body)
+		info.quote, info.bodyStart, info.indent = depth, off, col
+
+		if strings.TrimSpace(body) == "" {
+			info.kind = lineBlank
+			if len(items) > 0 {
+				info.container = items[len(items)-1]
+			}
+			prev = lineBlank
+			continue
+		}
+
+		lazy := prev == lineText
+		sameBlock := depth == quote
+		if depth != quote {
+			if lazy && depth < quote && !startsBlock(body) {
+				// lazy continuation of a quoted paragraph
+				info.kind = lineText
+				info.lazy = true
+				continue
+			}
+			items = nil
+			quote = depth
+		}
+
+		_, markerEnd, contentCol, isItem := listMarker(body)
+		for len(items) > 0 && col < items[len(items)-1] {
+			if lazy && !isItem && !startsBlock(body) {
+				break
+			}
+			items = items[:len(items)-1]
+			sameBlock = false
+		}
+		container := 0
+		if len(items) > 0 {
+			container = items[len(items)-1]
+		}
+		info.container = container
+
+		if col-container >= 4 && col >= container {
+			if lazy {
+				info.kind = lineText
+			} else {
+				info.kind = lineIndentedCode
+			}
+			prev = info.kind
+			continue
+		}
+
+		// a div fence with attributes opens a Pandoc div, a bare one
+		// closes the innermost; a bare fence outside of divs is text
+		if attrs, ok := divFence(body); ok && (attrs != "" || divs > 0) {
+			if attrs != "" {
+				divs++
+			} else {
+				divs--
+			}
+			info.kind = lineDivFence
+			prev = info.kind
+			continue
+		}
+
+		if depth == 0 && container == 0 && abbreviationDefinition.MatchString(body) {
+			info.kind = lineAbbreviation
+			prev = info.kind
+			continue
+		}
+
+		// a delimiter row turns the paragraph line above it into the
+		// header of a table when their cell counts agree
+		if lazy && sameBlock && isTableSeparator(body) && strings.Contains(lines[i-1], "|") &&
+			tableCells(lines[i-1][b.lines[i-1].bodyStart:]) == tableCells(body) {
+			b.lines[i-1].kind = lineTable
+			info.kind = lineTable
+			prev = info.kind
+			continue
+		}
+		if prev == lineTable && sameBlock && !startsBlock(body) {
+			info.kind = lineTable
+			prev = info.kind
+			continue
+		}
+
+		// a paragraph's underline takes precedence over a thematic break,
+		// which in turn takes precedence over a list item
+		if lazy && sameBlock && isSetextUnderline(body) {
+			info.kind = lineSetextUnderline
+			prev = info.kind
+			continue
+		}
+		if isThematicBreak(body) {
+			info.kind = lineThematicBreak
+			prev = info.kind
+			continue
+		}
+
+		if isItem {
+			info.item = true
+			items = append(items, contentCol)
+			container = contentCol
+			rest := body[markerEnd:]
+			if strings.TrimSpace(rest) == "" {
+				info.kind = lineText
+				prev = info.kind
+				continue
+			}
+			body = strings.Repeat(" ", contentCol) + strings.TrimLeft(rest, " \t")
+			off += markerEnd + len(rest) - len(strings.TrimLeft(rest, " \t")) - contentCol
+		} else if footnoteDefinition.MatchString(body) {
+			// the body of a footnote continues on lines indented by four
+			info.footnote = true
+			info.kind = lineText
+			items = append(items, col+4)
+			prev = info.kind
+			continue
+		} else if definitionMarker.MatchString(body) && col-container < 3 {
+			if term, ok := definitionTerm(lines, b, i, container, defCol); ok {
+				// like a footnote, a definition continues on lines
+				// indented by four
+				if term >= 0 {
+					b.lines[term].kind = lineDefinitionTerm
+				}
+				info.definition = true
+				info.kind = lineText
+				defCol = container + 4
+				items = append(items, defCol)
+				prev = info.kind
+				continue
+			}
+		}
+
+		if ch, n, end, ok := openingFence(body); ok {
+			info.kind = lineFenceOpen
+			info.fence = len(b.fences)
+			b.fences = append(b.fences, fence{
+				open:      i,
+				close:     -1,
+				char:      ch,
+				size:      n,
+				markerEnd: off + end,
+				info:      strings.TrimSpace(body[end:]),
+				quote:     depth,
+				container: container,
+			})
+			info.container = container
+			open = info.fence
+			prev = info.kind
+			continue
+		}
+
+		info.kind = lineText
+		prev = info.kind
+	}
+	return b
+}
+
+// stripQuote removes up to max blockquote markers (all of them when max is
+// negative) from the start of line. It returns how many were removed and
+// the byte offset of the remaining text.
+func stripQuote(line string, max int) (depth, offset int) {
+	for max < 0 || depth < max {
+		i, spaces := offset, 0
+		for i < len(line) && line[i] == ' ' && spaces < 3 {
+			i++
+			spaces++
+		}
+		if i >= len(line) || line[i] != '>' {
+			break
+		}
+		i++
+		if i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		depth++
+		offset = i
+	}
+	return depth, offset
+}
+
+// leadingColumns returns the visual width of the leading whitespace of s,
+// expanding tabs to the next multiple of four, and its length in bytes.
+func leadingColumns(s string) (cols, n int) {
+	for n < len(s) {
+		switch s[n] {
+		case ' ':
+			cols++
+		case '\t':
+			cols += 4 - cols%4
+		default:
+			return cols, n
+		}
+		n++
+	}
+	return cols, n
+}
+
+// listMarker recognizes a bullet (-, +, *) or ordered (1. or 1)) list item
+// marker at the start of s. It returns the marker text, the byte offset
+// just past it, and the column at which the item's content begins.
+func listMarker(s string) (marker string, end, contentCol int, ok bool) {
+	col, i := leadingColumns(s)
+	start := i
+	switch {
+	case i < len(s) && (s[i] == '-' || s[i] == '+' || s[i] == '*'):
+		i++
+	default:
+		j := i
+		for j < len(s) && j-i < 9 && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == i || j >= len(s) || (s[j] != '.' && s[j] != ')') {
+			return "", 0, 0, false
+		}
+		i = j + 1
+	}
+	markerCol := col + (i - start)
+	if i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		return "", 0, 0, false
+	}
+	c := markerCol
+	for j := i; j < len(s) && (s[j] == ' ' || s[j] == '\t'); j++ {
+		if s[j] == '\t' {
+			c += 4 - c%4
+		} else {
+			c++
+		}
+	}
+	spaces := c - markerCol
+	switch {
+	case strings.TrimSpace(s[i:]) == "":
+		contentCol = markerCol + 1
+	case spaces > 4:
+		contentCol = markerCol + 1
+	default:
+		contentCol = markerCol + spaces
+	}
+	return s[start:i], i, contentCol, true
+}
+
+// startsBlock reports whether s begins a block that cannot be a lazy
+// paragraph continuation.
+func startsBlock(s string) bool {
+	t := strings.TrimLeft(s, " \t")
+	if _, _, _, ok := openingFence(s); ok {
+		return true
+	}
+	// only non-empty bullets and ordered items starting at 1 can
+	// interrupt a paragraph
+	if marker, end, _, ok := listMarker(s); ok && strings.TrimSpace(s[end:]) != "" {
+		if c := marker[0]; c == '-' || c == '+' || c == '*' ||
+			strings.TrimLeft(marker[:len(marker)-1], "0") == "1" {
+			return true
+		}
+	}
+	_, div := divFence(s)
+	return strings.HasPrefix(t, ">") || isATXHeading(s) || isThematicBreak(s) ||
+		footnoteDefinition.MatchString(s) || definitionMarker.MatchString(s) || div ||
+		abbreviationDefinition.MatchString(s)
+}
+
+// divFenceLine matches a Pandoc div fence: three or more colons, then the
+// attributes of an opening fence, optionally followed by more colons.
+var divFenceLine = regexp.MustCompile(`^ {0,3}:{3,}[ \t]*(.*?)[ \t]*$`)
+
+// divFence recognizes a Pandoc div fence and returns its attributes, which
+// are empty for a closing fence.
+func divFence(s string) (attrs string, ok bool) {
+	m := divFenceLine.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimRight(m[1], ":")), true
+}
+
+// definitionMarker matches the ":" or "~" that opens a Pandoc definition,
+// followed by the start of its text.
+var definitionMarker = regexp.MustCompile(`^[ \t]*[:~][ \t]+\S`)
+
+// definitionTerm reports whether the definition marker on line i belongs to
+// a definition list: it must follow a single-line term, at most one blank
+// line above it, or an earlier definition of the same term. It returns the
+// index of the term, or -1 for a further definition. defCol is the content
+// column of the last definition seen.
+func definitionTerm(lines []string, b *blocks, i, container, defCol int) (int, bool) {
+	t := i - 1
+	if t >= 0 && b.lines[t].kind == lineBlank {
+		t--
+	}
+	if t < 0 {
+		return -1, false
+	}
+	info := b.lines[t]
+	if info.definition || (defCol > 0 && info.container >= defCol && info.kind != lineBlank) {
+		return -1, true
+	}
+	if info.kind != lineText || info.item || info.footnote || info.lazy ||
+		info.quote != b.lines[i].quote || info.container != container ||
+		isATXHeading(lines[t][info.bodyStart:]) {
+		return -1, false
+	}
+	if t > 0 && b.lines[t-1].kind == lineText && !isATXHeading(lines[t-1][b.lines[t-1].bodyStart:]) {
+		return -1, false // the paragraph above has more than one line
+	}
+	return t, true
+}
+
+// footnoteDefinition matches the "[^label]:" that opens a footnote
+// definition.
+var footnoteDefinition = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:`)
+
+// abbreviationDefinition matches the start of a Markdown Extra
+// abbreviation definition, "*[HTML]: HyperText Markup Language".
+var abbreviationDefinition = regexp.MustCompile(`^ {0,3}\*\[([^\]]+)\]:`)
+
+// isThematicBreak reports whether s, ignoring its indentation, is a
+// thematic break: three or more of the same -, * or _ character, optionally
+// separated by spaces or tabs.
+func isThematicBreak(s string) bool {
+	var ch byte
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+		case ch == 0 && (c == '-' || c == '*' || c == '_'):
+			ch = c
+			n++
+		case c == ch:
+			n++
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// tableCells counts the cells of a table row, ignoring escaped pipes and
+// the optional pipes at either end.
+func tableCells(row string) int {
+	t := strings.TrimSpace(row)
+	t = strings.TrimPrefix(t, "|")
+	if strings.HasSuffix(t, "|") && !strings.HasSuffix(t, `\|`) {
+		t = t[:len(t)-1]
+	}
+	n := 1
+	for i := 0; i < len(t); i++ {
+		switch t[i] {
+		case '\\':
+			i++
+		case '|':
+			n++
+		}
+	}
+	return n
+}
+
+// isSetextUnderline reports whether s, ignoring its indentation, is a run
+// of = or - that turns a preceding paragraph into a heading.
+func isSetextUnderline(s string) bool {
+	t := strings.TrimSpace(s)
+	if t == "" || (t[0] != '=' && t[0] != '-') {
+		return false
+	}
+	return strings.Trim(t, t[:1]) == ""
+}
+
+// frontMatterEnd returns the index of the line closing a YAML (---) or
+// TOML (+++) front matter block at the very top of the document, or -1.
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 {
+		return -1
+	}
+	open := strings.TrimRight(lines[0], " \t")
+	if open != "---" && open != "+++" {
+		return -1
+	}
+	for i := 1; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], " \t")
+		if l == open || (open == "---" && l == "...") {
+			return i
+		}
+	}
+	return -1
+}
+
+// titleBlockEnd returns the index of the last line of a Pandoc title block
+// ("% title", "% author", "% date", with indented continuation lines) at
+// the very top of the document, or -1.
+func titleBlockEnd(lines []string) int {
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "%") {
+		return -1
+	}
+	end, fields := 0, 1
+	for i := 1; i < len(lines); i++ {
+		l := lines[i]
+		switch {
+		case strings.HasPrefix(l, "%"):
+			fields++
+		case strings.TrimSpace(l) != "" && (l[0] == ' ' || l[0] == '\t'):
+		default:
+			return end
+		}
+		if fields > 3 {
+			return -1
+		}
+		end = i
+	}
+	return end
+}
+
+// metadataEnd returns the index of the last line of the front matter or
+// title block that opens the document, or -1.
+func metadataEnd(lines []string) int {
+	if end := frontMatterEnd(lines); end >= 0 {
+		return end
+	}
+	return titleBlockEnd(lines)
+}
+
+// splitMetadata splits content just after the front matter or title block
+// that opens it, for rules that rewrite raw text and must leave it alone.
+func splitMetadata(content string) (head, body string) {
+	lines := strings.Split(content, "\n")
+	n := 0
+	for i := 0; i <= metadataEnd(lines); i++ {
+		n += len(lines[i]) + 1
+	}
+	if n > len(content) {
+		n = len(content)
+	}
+	return content[:n], content[n:]
+}
+
+// openingFence recognizes an opening code fence of three or more backticks
+// or tildes. It returns the fence character, its run length, and the byte
+// offset just past the run.
+func openingFence(s string) (ch byte, n, end int, ok bool) {
+	_, i := leadingColumns(s)
+	if i >= len(s) || (s[i] != '`' && s[i] != '~') {
+		return 0, 0, 0, false
+	}
+	ch = s[i]
+	j := i
+	for j < len(s) && s[j] == ch {
+		j++
+	}
+	if j-i < 3 {
+		return 0, 0, 0, false
+	}
+	// backtick fences cannot have backticks in their info string
+	if ch == '`' && strings.IndexByte(s[j:], '`') >= 0 {
+		return 0, 0, 0, false
+	}
+	return ch, j - i, j, true
+}
+
+// isClosingFence reports whether s closes a fence opened with n ch's.
+func isClosingFence(s string, ch byte, n int) bool {
+	t := strings.TrimLeft(s, " \t")
+	run := 0
+	for run < len(t) && t[run] == ch {
+		run++
+	}
+	return run >= n && strings.TrimSpace(t[run:]) == ""
+}
+
+// firstInContainer reports whether line i opens the blockquote or list item
+// it lives in, given that p is the previous non-blank line.
+func firstInContainer(b *blocks, p, i int) bool {
+	return b.lines[p].quote < b.lines[i].quote
+}
+
+// lastInContainer reports whether line i is the last line of its
+// blockquote or list item, given that n is the next non-blank line.
+func lastInContainer(b *blocks, i, n int) bool {
+	cur, next := b.lines[i], b.lines[n]
+	if next.quote < cur.quote {
+		return true
+	}
+	return cur.container > 0 && next.indent < cur.container
+}
+
+// blankFor returns the blank line that separates blocks inside the
+// containers of line: a bare ">" per blockquote level, or nothing.
+func blankFor(line string, info lineInfo) string {
+	if info.quote == 0 {
+		return ""
+	}
+	return strings.TrimRight(strings.Repeat("> ", info.quote), " ")
+}
+
+// gapEditor collects blank-line insertions and deletions between lines and
+// applies them in one pass, so several rules of a pass can agree on a gap.
+type gapEditor struct {
+	lines  []string
+	b      *blocks
+	insert map[int]string // blank line to insert before the index
+	drop   map[int]bool
+}
+
+func newGapEditor(lines []string, b *blocks) *gapEditor {
+	return &gapEditor{lines: lines, b: b, insert: map[int]string{}, drop: map[int]bool{}}
+}
+
+func (g *gapEditor) prevNonBlank(i int) int {
+	return prevNonBlankLine(g.b, i)
+}
+
+// prevNonBlankLine returns the index of the last non-blank line before i,
+// or -1.
+func prevNonBlankLine(b *blocks, i int) int {
+	for i--; i >= 0 && b.lines[i].kind == lineBlank; i-- {
+	}
+	return i
+}
+
+func (g *gapEditor) nextNonBlank(i int) int {
+	for i++; i < len(g.lines) && g.b.lines[i].kind == lineBlank; i++ {
+	}
+	if i == len(g.lines) {
+		return -1
+	}
+	return i
+}
+
+// ensureOne leaves exactly one blank line between the non-blank lines p and
+// n, inserting blank when there is none.
+func (g *gapEditor) ensureOne(p, n int, blank string) {
+	if n-p == 1 {
+		g.insert[n] = blank
+		return
+	}
+	for i := p + 1; i < n-1; i++ {
+		g.drop[i] = true
+	}
+}
+
+func (g *gapEditor) apply() []string {
+	out := make([]string, 0, len(g.lines)+len(g.insert))
+	for i, l := range g.lines {
+		if blank, ok := g.insert[i]; ok {
+			out = append(out, blank)
+		}
+		if !g.drop[i] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
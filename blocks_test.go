@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyBlocks(t *testing.T) {
+	const (
+		T = lineText
+		B = lineBlank
+		O = lineFenceOpen
+		C = lineCode
+		E = lineFenceClose
+		I = lineIndentedCode
+		H = lineThematicBreak
+		S = lineSetextUnderline
+		F = lineFrontMatter
+		X = lineTable
+		D = lineDefinitionTerm
+		V = lineDivFence
+		A = lineAbbreviation
+	)
+	tests := []struct {
+		name  string
+		input string
+		want  []lineKind
+	}{
+		{
+			name:  "fence",
+			input: "text\n```go\ncode\n```\nafter",
+			want:  []lineKind{T, O, C, E, T},
+		},
+		{
+			name:  "longer closing fence needed",
+			input: "````\n```\n````",
+			want:  []lineKind{O, C, E},
+		},
+		{
+			name:  "unterminated fence",
+			input: "~~~\ncode\n```",
+			want:  []lineKind{O, C, C},
+		},
+		{
+			name:  "pandoc divs nest",
+			input: "::::: {#a .sidebar}\ntext\n::: warning\n> quote\n:::\n:::::\n:::",
+			want:  []lineKind{V, T, V, T, V, V, T},
+		},
+		{
+			name:  "abbreviation definitions",
+			input: "The HTML spec.\n*[HTML]: HyperText Markup Language\n\n- *[W3C]: item\n> *[W3C]: quote",
+			want:  []lineKind{T, A, B, T, T},
+		},
+		{
+			name:  "indented code",
+			input: "para\n\n    code\n\npara",
+			want:  []lineKind{T, B, I, B, T},
+		},
+		{
+			name:  "indented line continues paragraph",
+			input: "para\n    more",
+			want:  []lineKind{T, T},
+		},
+		{
+			name:  "list continuation is not code",
+			input: "- item\n\n    continued\n\n      code",
+			want:  []lineKind{T, B, T, B, I},
+		},
+		{
+			name:  "fence in blockquote ends with the quote",
+			input: "> ```\n> code\nafter",
+			want:  []lineKind{O, C, T},
+		},
+		{
+			name:  "front matter, setext and breaks",
+			input: "---\na: 1\n---\nTitle\n---\n\n* * *",
+			want:  []lineKind{F, F, F, T, S, B, H},
+		},
+		{
+			name:  "table",
+			input: "Para\n| a | b |\n|---|---|\n| 1 | 2 |\n\nafter",
+			want:  []lineKind{T, X, X, X, B, T},
+		},
+		{
+			name:  "cell count mismatch is not a table",
+			input: "a | b\n---",
+			want:  []lineKind{T, S},
+		},
+		{
+			name:  "fence in list item",
+			input: "- ```\n  code\n  ```\n- next",
+			want:  []lineKind{O, C, E, T},
+		},
+		{
+			name:  "pandoc title block",
+			input: "% Title\n% Ann;\n  Bob\n% 2024\n\n% not a title",
+			want:  []lineKind{F, F, F, F, B, T},
+		},
+		{
+			name:  "percent line after the top is prose",
+			input: "Text\n% 50 off",
+			want:  []lineKind{T, T},
+		},
+		{
+			name:  "too many percent lines for a title block",
+			input: "% a\n% b\n% c\n% d",
+			want:  []lineKind{T, T, T, T},
+		},
+		{
+			name:  "definition list",
+			input: "Term\n:   def\n\n    more\n\nTerm 2\n\n~   def\n:   another",
+			want:  []lineKind{D, T, B, T, B, D, B, T, T},
+		},
+		{
+			name:  "multi-line paragraph is not a term",
+			input: "one\ntwo\n: three",
+			want:  []lineKind{T, T, T},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := classifyBlocks(strings.Split(tt.input, "\n"))
+			var got []lineKind
+			for _, l := range b.lines {
+				got = append(got, l.kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 17: normalize thematic breaks to a single canonical form
+// ----------------------------------------------------------------
+
+type ThematicBreakStyleRule struct {
+	// style is the canonical thematic break, e.g. "---".
+	style string
+}
+
+// NewThematicBreakStyleRule constructs a rule that rewrites every thematic
+// break as style.
+func NewThematicBreakStyleRule(style string) Rule {
+	return ThematicBreakStyleRule{style: style}
+}
+
+func newThematicBreakStyleRuleFromOptions(o Options) (Rule, error) {
+	style := o.Get("thematic-break", "---")
+	if !isThematicBreak(style) || strings.TrimSpace(style) != style {
+		return nil, fmt.Errorf("option thematic-break: %q is not a thematic break", style)
+	}
+	return NewThematicBreakStyleRule(style), nil
+}
+
+func (ThematicBreakStyleRule) Name() string {
+	return "ThematicBreakStyle"
+}
+
+func (r ThematicBreakStyleRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineThematicBreak {
+			continue
+		}
+		lines[i] = line[:info.bodyStart] + strings.Repeat(" ", info.container) + r.style
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 18: exactly one blank line around thematic breaks
+// ----------------------------------------------------------------
+
+type BlankLinesAroundThematicBreaksRule struct{}
+
+func NewBlankLinesAroundThematicBreaksRule() Rule {
+	return BlankLinesAroundThematicBreaksRule{}
+}
+
+func (BlankLinesAroundThematicBreaksRule) Name() string {
+	return "BlankLinesAroundThematicBreaks"
+}
+
+func (BlankLinesAroundThematicBreaksRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	gaps := newGapEditor(lines, b)
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineThematicBreak {
+			continue
+		}
+		if p := gaps.prevNonBlank(i); p >= 0 && !firstInContainer(b, p, i) {
+			gaps.ensureOne(p, i, blankFor(line, info))
+		}
+		if n := gaps.nextNonBlank(i); n >= 0 && !lastInContainer(b, i, n) {
+			gaps.ensureOne(i, n, blankFor(line, info))
+		}
+	}
+	return strings.Join(gaps.apply(), "\n"), nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import "testing"
+
+func TestThematicBreakStyleRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "stars",
+			style:    "---",
+			input:    "***",
+			expected: "---",
+		},
+		{
+			name:     "underscores with spaces and indentation",
+			style:    "---",
+			input:    "   _ _ _",
+			expected: "---",
+		},
+		{
+			name:     "spaced dashes",
+			style:    "---",
+			input:    "Para\n\n- - -\n\nMore",
+			expected: "Para\n\n---\n\nMore",
+		},
+		{
+			name:     "long run",
+			style:    "---",
+			input:    "*****",
+			expected: "---",
+		},
+		{
+			name:     "configured style",
+			style:    "***",
+			input:    "---\n\n___",
+			expected: "***\n\n***",
+		},
+		{
+			name:     "setext underline untouched",
+			style:    "***",
+			input:    "Heading\n---\n\nText",
+			expected: "Heading\n---\n\nText",
+		},
+		{
+			name:     "stars after paragraph are a break",
+			style:    "---",
+			input:    "Para\n***",
+			expected: "Para\n---",
+		},
+		{
+			name:     "front matter untouched",
+			style:    "***",
+			input:    "---\ntitle: x\n---\n\n___",
+			expected: "---\ntitle: x\n---\n\n***",
+		},
+		{
+			name:     "four spaces is code",
+			style:    "---",
+			input:    "    ***",
+			expected: "    ***",
+		},
+		{
+			name:     "fenced code untouched",
+			style:    "---",
+			input:    "```\n***\n```",
+			expected: "```\n***\n```",
+		},
+		{
+			name:     "quoted break",
+			style:    "---",
+			input:    "> * * *",
+			expected: "> ---",
+		},
+		{
+			name:     "not a break",
+			style:    "---",
+			input:    "-- -x\n**bold**",
+			expected: "-- -x\n**bold**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewThematicBreakStyleRule(tt.style).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBlankLinesAroundThematicBreaksRule(t *testing.T) {
+	rule := NewBlankLinesAroundThematicBreaksRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "inserts blanks",
+			input:    "Para\n\n***\nMore",
+			expected: "Para\n\n***\n\nMore",
+		},
+		{
+			name:     "collapses blanks",
+			input:    "Para\n\n\n***\n\n\nMore",
+			expected: "Para\n\n***\n\nMore",
+		},
+		{
+			name:     "setext underline kept",
+			input:    "Head\n---\n",
+			expected: "Head\n---\n",
+		},
+		{
+			name:     "long setext underline kept",
+			input:    "Heading\n-------\nText",
+			expected: "Heading\n-------\nText",
+		},
+		{
+			name:     "front matter untouched",
+			input:    "---\ntitle: x\n---\nText",
+			expected: "---\ntitle: x\n---\nText",
+		},
+		{
+			name:     "table separator untouched",
+			input:    "| A |\n|---|\n| 1 |",
+			expected: "| A |\n|---|\n| 1 |",
+		},
+		{
+			name:     "pipeless table separator untouched",
+			input:    "a | b\n--- | ---\n1 | 2",
+			expected: "a | b\n--- | ---\n1 | 2",
+		},
+		{
+			name:     "start and end of document",
+			input:    "***\nText\n\n___",
+			expected: "***\n\nText\n\n___",
+		},
+		{
+			name:     "quoted break",
+			input:    "> a\n> ***\n> b",
+			expected: "> a\n>\n> ***\n>\n> b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
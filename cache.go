@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// version is the mdfmt release, set at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
+// cacheMaxAge is how long a cache entry is kept after it was last used.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// cachePruneInterval is how long a run that pruned the cache spares later
+// runs the scan of its directory.
+const cachePruneInterval = 24 * time.Hour
+
+// fileOptions name files that rules read; the cache fingerprint covers
+// their content, not just their name.
+var fileOptions = []string{"front-matter-template", "terminology-file"}
+
+// formatCache remembers documents that came out of the formatter
+// unchanged and without findings, so that later runs with the same
+// configuration can skip them. An entry is an empty file named after the
+// hash of the document and the configuration.
+type formatCache struct {
+	dir         string
+	fingerprint string
+}
+
+// defaultCacheDir is where the cache lives unless -cache-dir says
+// otherwise, or "" when the user has no cache directory.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mdfmt")
+}
+
+// newFormatCache opens the cache in dir for runs configured by
+// fingerprint.
+func newFormatCache(dir, fingerprint string) *formatCache {
+	return &formatCache{dir: dir, fingerprint: fingerprint}
+}
+
+// configFingerprint describes everything besides the document that
+// decides what formatting does: the mdfmt build, the rule options,
+// including the content of the files they name, the enabled and disabled
+// rules and the remaining settings in extra.
+func configFingerprint(opts Options, enable, disable []string, extra ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "version %s\n", version)
+	if exe, err := os.Executable(); err == nil {
+		if fi, err := os.Stat(exe); err == nil {
+			// catch rebuilds of development versions
+			fmt.Fprintf(h, "binary %d %d\n", fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "set %q=%q\n", k, opts[k])
+		if containsString(fileOptions, k) {
+			if f, err := os.Open(opts[k]); err == nil {
+				io.Copy(h, f)
+				f.Close()
+			}
+		}
+	}
+	fmt.Fprintf(h, "enable %q\ndisable %q\n", enable, disable)
+	fmt.Fprintf(h, "extra %q\n", extra)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// settingsFingerprint is the configFingerprint of the settings that
+// format the document at path: those pathSettings resolves with opts,
+// enable and disable, the block parser, and the remaining settings in
+// extra.
+func settingsFingerprint(path string, opts Options, enable, disable []string, extra ...string) string {
+	s, _ := pathSettings(path, opts, enable, disable)
+	return configFingerprint(s.options, s.enable, s.disable,
+		append([]string{"parser=" + blockParser, fmt.Sprintf("rules=%q", s.rules)}, extra...)...)
+}
+
+func (c *formatCache) entry(path, content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%q\n", c.fingerprint, path)
+	io.WriteString(h, content)
+	return filepath.Join(c.dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// clean reports whether content, read from path, is known to be formatted.
+// A hit counts as a use of the entry, which prune then spares.
+func (c *formatCache) clean(path, content string) bool {
+	entry := c.entry(path, content)
+	if _, err := os.Stat(entry); err != nil {
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+	return true
+}
+
+// markClean records content, read from path, as formatted. The entry is
+// written under a temporary name and renamed into place, so concurrent
+// runs never see a partial entry.
+func (c *formatCache) markClean(path, content string) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.entry(path, content)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return c.prune(time.Now())
+}
+
+// prune removes the entries, and temporary files left behind, that were
+// last used more than cacheMaxAge before now, so that the cache does not
+// grow without bound as documents change. The scan happens at most once
+// every cachePruneInterval, as the modification time of a .pruned file
+// in the directory records.
+func (c *formatCache) prune(now time.Time) error {
+	marker := filepath.Join(c.dir, ".pruned")
+	if fi, err := os.Stat(marker); err == nil && now.Sub(fi.ModTime()) < cachePruneInterval {
+		return nil
+	}
+	// claimed before the scan, so that concurrent runs skip it
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return err
+	}
+	if err := os.Chtimes(marker, now, now); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == ".pruned" || !e.Type().IsRegular() {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil || now.Sub(fi.ModTime()) <= cacheMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanOutput reports whether formatting in produced out with no findings,
+// so that in can be cached as formatted.
+func cleanOutput(in, out string, diags []Diagnostic) bool {
+	return in == out && len(diags) == 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormatCache(t *testing.T) {
+	dir := t.TempDir()
+	fp := configFingerprint(Options{"fence": "tilde"}, nil, nil)
+	c := newFormatCache(dir, fp)
+	if c.clean("a.md", "# A\n") {
+		t.Fatal("empty cache reports a clean document")
+	}
+	if err := c.markClean("a.md", "# A\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.clean("a.md", "# A\n") {
+		t.Error("marked document is not clean")
+	}
+	if c.clean("a.md", "# B\n") || c.clean("b.md", "# A\n") {
+		t.Error("entry matches other content or another path")
+	}
+	other := newFormatCache(dir, configFingerprint(Options{"fence": "backtick"}, nil, nil))
+	if other.clean("a.md", "# A\n") {
+		t.Error("entry survives a change of options")
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".tmp-*")); len(tmps) > 0 {
+		t.Errorf("temporary files left behind: %v", tmps)
+	}
+}
+
+func TestFormatCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	c := newFormatCache(dir, configFingerprint(nil, nil, nil))
+	for _, doc := range []string{"# Old\n", "# Used\n", "# New\n"} {
+		if err := c.markClean("a.md", doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	age := func(doc string, d time.Duration) {
+		t.Helper()
+		if err := os.Chtimes(c.entry("a.md", doc), now.Add(-d), now.Add(-d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age("# Old\n", cacheMaxAge+time.Hour)
+	age("# Used\n", cacheMaxAge+time.Hour)
+	if !c.clean("a.md", "# Used\n") {
+		t.Fatal("entry missing before pruning")
+	}
+
+	// markClean pruned just now
+	if err := c.prune(now); err != nil {
+		t.Fatal(err)
+	}
+	if !c.clean("a.md", "# Old\n") {
+		t.Error("entry pruned within the prune interval")
+	}
+	age("# Old\n", cacheMaxAge+time.Hour)
+
+	later := now.Add(cachePruneInterval + time.Minute)
+	if err := c.prune(later); err != nil {
+		t.Fatal(err)
+	}
+	if c.clean("a.md", "# Old\n") {
+		t.Error("old entry survives pruning")
+	}
+	if !c.clean("a.md", "# Used\n") || !c.clean("a.md", "# New\n") {
+		t.Error("recently used entry pruned")
+	}
+}
+
+func TestConfigFingerprint(t *testing.T) {
+	base := configFingerprint(Options{"a": "1", "b": "2"}, []string{"X"}, nil, "parser=internal")
+	if got := configFingerprint(Options{"b": "2", "a": "1"}, []string{"X"}, nil, "parser=internal"); got != base {
+		t.Error("fingerprint depends on map order")
+	}
+	for name, fp := range map[string]string{
+		"options": configFingerprint(Options{"a": "1"}, []string{"X"}, nil, "parser=internal"),
+		"enable":  configFingerprint(Options{"a": "1", "b": "2"}, nil, nil, "parser=internal"),
+		"disable": configFingerprint(Options{"a": "1", "b": "2"}, []string{"X"}, []string{"Y"}, "parser=internal"),
+		"extra":   configFingerprint(Options{"a": "1", "b": "2"}, []string{"X"}, nil, "parser=goldmark"),
+	} {
+		if fp == base {
+			t.Errorf("%s: fingerprint unchanged", name)
+		}
+	}
+
+	words := filepath.Join(t.TempDir(), "words")
+	os.WriteFile(words, []byte("a => b\n"), 0o644)
+	before := configFingerprint(Options{"terminology-file": words}, nil, nil)
+	os.WriteFile(words, []byte("a => c\n"), 0o644)
+	if configFingerprint(Options{"terminology-file": words}, nil, nil) == before {
+		t.Error("fingerprint ignores the content of terminology-file")
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	if !cleanOutput("a\n", "a\n", nil) {
+		t.Error("unchanged output is not clean")
+	}
+	if cleanOutput("a", "a\n", nil) {
+		t.Error("an added trailing newline is a change")
+	}
+	if cleanOutput("a\n", "a\n", []Diagnostic{{Line: 1}}) {
+		t.Error("output with findings is clean")
+	}
+}
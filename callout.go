@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 22: normalize GitHub/Obsidian callout markers
+// ----------------------------------------------------------------
+
+// calloutMarker matches the "[!TYPE]" that turns a blockquote into a
+// callout, with Obsidian's optional fold indicator and title.
+var calloutMarker = regexp.MustCompile(`^\[!([^\]\s]+)\]([+-]?)[ \t]*(.*)$`)
+
+// defaultCalloutTypes are the alert types GitHub renders.
+var defaultCalloutTypes = []string{"note", "tip", "important", "warning", "caution"}
+
+type CalloutRule struct {
+	upper bool
+	types map[string]bool // lowercased
+}
+
+// NewCalloutRule constructs a rule that writes callout types in upper or
+// lower case and reports types missing from types.
+func NewCalloutRule(upper bool, types []string) Rule {
+	known := make(map[string]bool, len(types))
+	for _, t := range types {
+		known[strings.ToLower(t)] = true
+	}
+	return CalloutRule{upper: upper, types: known}
+}
+
+func newCalloutRuleFromOptions(o Options) (Rule, error) {
+	c, err := o.Choice("callout-case", "upper", "upper", "lower")
+	if err != nil {
+		return nil, err
+	}
+	return NewCalloutRule(c == "upper", o.List("callout-types", defaultCalloutTypes)), nil
+}
+
+func (CalloutRule) Name() string {
+	return "Callout"
+}
+
+func (r CalloutRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i := range lines {
+		m, ok := callout(lines, b, i)
+		if !ok {
+			continue
+		}
+		typ := strings.ToLower(m[1])
+		if r.upper {
+			typ = strings.ToUpper(typ)
+		}
+		line := strings.Repeat("> ", b.lines[i].quote) + "[!" + typ + "]" + m[2]
+		if title := strings.TrimSpace(m[3]); title != "" {
+			line += " " + title
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r CalloutRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i := range lines {
+		m, ok := callout(lines, b, i)
+		if !ok || r.types[strings.ToLower(m[1])] {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Line:    i + 1,
+			Rule:    r.Name(),
+			Message: fmt.Sprintf("unknown callout type %q", m[1]),
+		})
+	}
+	return diags
+}
+
+// callout matches the callout marker on line i, which must be the first
+// line of a blockquote.
+func callout(lines []string, b *blocks, i int) ([]string, bool) {
+	info := b.lines[i]
+	if info.quote == 0 || info.lazy || info.kind != lineText || info.container != 0 ||
+		(i > 0 && b.lines[i-1].quote >= info.quote) {
+		return nil, false
+	}
+	m := calloutMarker.FindStringSubmatch(strings.TrimSpace(lines[i][info.bodyStart:]))
+	return m, m != nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalloutRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		upper    bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "github casing and spacing",
+			upper:    true,
+			input:    ">[!Note]\n> body",
+			expected: "> [!NOTE]\n> body",
+		},
+		{
+			name:     "obsidian fold and title",
+			input:    ">  [!WARNING]-   Careful  now\n> body",
+			expected: "> [!warning]- Careful  now\n> body",
+		},
+		{
+			name:     "nested callout",
+			upper:    true,
+			input:    "> text\n>\n> > [!tip]+",
+			expected: "> text\n>\n> > [!TIP]+",
+		},
+		{
+			name:     "only the first line of a quote",
+			upper:    true,
+			input:    "> text\n> [!note]",
+			expected: "> text\n> [!note]",
+		},
+		{
+			name:     "not in code",
+			upper:    true,
+			input:    "```\n> [!note]\n```",
+			expected: "```\n> [!note]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewCalloutRule(tt.upper, defaultCalloutTypes)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCalloutRuleLint(t *testing.T) {
+	rule := NewCalloutRule(true, []string{"note", "Bug"}).(CalloutRule)
+	input := "> [!NOTE]\n\n> [!bug]\n\n> [!todo] Later\n\n> [!NOTE]\n> [!nope]"
+	want := []Diagnostic{
+		{Line: 5, Rule: "Callout", Message: `unknown callout type "todo"`},
+	}
+	if got := rule.Lint(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
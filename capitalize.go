@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 50: capitalize list items and table headers
+// ----------------------------------------------------------------
+
+// defaultLowercaseWords are names written in lower case, or with a lower
+// case first letter, on purpose.
+var defaultLowercaseWords = []string{"iOS", "npm", "gRPC", "macOS", "iPhone", "iPad", "pnpm", "git"}
+
+type CapitalizeRule struct {
+	lowercase map[string]bool
+}
+
+// NewCapitalizeRule constructs a rule that uppercases the first letter of
+// list items and table header cells. Text starting with code or a link,
+// with a word from lowercase, or with something that reads as an
+// identifier, such as camelCase or file.go, is left alone.
+func NewCapitalizeRule(lowercase ...string) Rule {
+	r := CapitalizeRule{lowercase: map[string]bool{}}
+	for _, w := range lowercase {
+		r.lowercase[w] = true
+	}
+	return r
+}
+
+func newCapitalizeRuleFromOptions(o Options) (Rule, error) {
+	return NewCapitalizeRule(o.List("capitalize-exempt", defaultLowercaseWords)...), nil
+}
+
+func (CapitalizeRule) Name() string {
+	return "Capitalize"
+}
+
+func (r CapitalizeRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		info := b.lines[i]
+		if literalLine(b, i) {
+			continue
+		}
+		body := line[info.bodyStart:]
+		switch {
+		case info.item && info.kind == lineText:
+			_, end, _, _ := listMarker(body)
+			at := info.bodyStart + end
+			if m := taskBox.FindString(strings.TrimLeft(line[at:], " \t")); m != "" {
+				at = strings.Index(line, m) + len(m)
+			}
+			lines[i] = line[:at] + r.capitalize(line[at:])
+		case info.kind == lineTable && i+1 < len(lines) && b.lines[i+1].kind == lineTable &&
+			(i == 0 || b.lines[i-1].kind != lineTable):
+			// the header row
+			lines[i] = line[:info.bodyStart] + mapCells(body, r.capitalize)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// capitalize uppercases the first letter of s, past spaces and emphasis
+// markers, unless it begins something that should stay as it is.
+func (r CapitalizeRule) capitalize(s string) string {
+	at := len(s) - len(strings.TrimLeft(s, " \t*_~"))
+	c, size := utf8.DecodeRuneInString(s[at:])
+	if !unicode.IsLower(c) {
+		return s
+	}
+	end := strings.IndexAny(s[at:], " \t*_~")
+	if end < 0 {
+		end = len(s) - at
+	}
+	word := strings.TrimRight(s[at:at+end], ",;:!?)")
+	if r.lowercase[word] || strings.IndexFunc(word, func(c rune) bool {
+		return unicode.IsUpper(c) || unicode.IsDigit(c) || strings.ContainsRune("./@#=+", c)
+	}) >= 0 {
+		return s
+	}
+	return s[:at] + string(unicode.ToUpper(c)) + s[at+size:]
+}
+
+// mapCells applies fn to the text of each cell of a table row, leaving the
+// pipes, escaped pipes and code spans alone.
+func mapCells(row string, fn func(string) string) string {
+	var out strings.Builder
+	code := codeSpans(row)
+	start := 0
+	flush := func(end int) {
+		out.WriteString(fn(row[start:end]))
+	}
+	for k := 0; k < len(row); k++ {
+		switch {
+		case row[k] == '\\':
+			k++
+		case row[k] == '|' && !inSpans(code, k):
+			flush(k)
+			out.WriteByte('|')
+			start = k + 1
+		}
+	}
+	if start <= len(row) {
+		flush(len(row))
+	}
+	return out.String()
+}
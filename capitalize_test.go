@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestCapitalizeRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "list items",
+			input:    "- fast\n1. safe and sound\n> * quoted\n- **bold** start\n- [ ] write docs\n- élan",
+			expected: "- Fast\n1. Safe and sound\n> * Quoted\n- **Bold** start\n- [ ] Write docs\n- Élan",
+		},
+		{
+			name:     "only the first letter",
+			input:    "- fAST, or not\n- already Capitalized",
+			expected: "- fAST, or not\n- Already Capitalized",
+		},
+		{
+			name:     "code, links and identifiers",
+			input:    "- `make` builds\n- [mdfmt](https://x.org) formats\n- <https://x.org>\n- main.go, camelCase\n- v2 ships\n- --flag",
+			expected: "- `make` builds\n- [mdfmt](https://x.org) formats\n- <https://x.org>\n- main.go, camelCase\n- v2 ships\n- --flag",
+		},
+		{
+			name:     "lower case names",
+			input:    "- iOS apps\n- npm, then\n- gRPC calls",
+			expected: "- iOS apps\n- npm, then\n- gRPC calls",
+		},
+		{
+			name:     "table headers",
+			input:    "| name | `type` | default \\| none |\n| --- | --- | --- |\n| value | x | y |",
+			expected: "| Name | `type` | Default \\| none |\n| --- | --- | --- |\n| value | x | y |",
+		},
+		{
+			name:     "code blocks and paragraphs",
+			input:    "plain text\n\n```\n- item\n```",
+			expected: "plain text\n\n```\n- item\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewCapitalizeRule(defaultLowercaseWords...)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------
+// Rule 61: keep a changelog in the Keep a Changelog format
+// ----------------------------------------------------------------
+
+// changelogSections are the subsections Keep a Changelog allows under a
+// version, in their canonical spelling.
+var changelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+var (
+	// versionHeading matches the text of a version heading such as
+	// "[1.2.3] - 2024-05-01", "[1.2.3](url) - ..." or "[Unreleased]",
+	// capturing the version, the inline link and what follows them.
+	versionHeading = regexp.MustCompile(`^\[([^\]]+)\](\([^)]*\))?(.*)$`)
+	// versionDate matches what follows the version: the date and an
+	// optional yanked marker.
+	versionDate   = regexp.MustCompile(`^ - (.+?)( \[YANKED\])?$`)
+	semverPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+)
+
+// changelogDateLayouts are the date formats a version's date is rewritten
+// from. Day-first and month-first numeric dates are left out: "01/05/2024"
+// could be either.
+var changelogDateLayouts = []string{
+	"2006/01/02",
+	"2006.01.02",
+	"2006-1-2",
+	"2006/1/2",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+type KeepAChangelogRule struct{}
+
+// NewKeepAChangelogRule constructs a rule that holds a changelog to the
+// structure of keepachangelog.com. It rewrites version dates given in
+// another common format as YYYY-MM-DD and fixes the case of subsection
+// names, and reports what it cannot fix: a missing title, malformed
+// version headings, versions out of order, unknown subsections and
+// versions without a link definition at the bottom.
+func NewKeepAChangelogRule() Rule {
+	return KeepAChangelogRule{}
+}
+
+func (KeepAChangelogRule) Name() string {
+	return "KeepAChangelog"
+}
+
+// semver is a semantic version; build metadata does not order versions
+// and is dropped.
+type semver struct {
+	major, minor, patch int
+	pre                 []string
+}
+
+func parseSemver(s string) (semver, bool) {
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return semver{}, false
+	}
+	var v semver
+	v.major, _ = strconv.Atoi(m[1])
+	v.minor, _ = strconv.Atoi(m[2])
+	v.patch, _ = strconv.Atoi(m[3])
+	if m[4] != "" {
+		v.pre = strings.Split(m[4], ".")
+	}
+	return v, true
+}
+
+// compare returns -1, 0 or 1 as v orders before, with or after w under
+// semver precedence: a pre-release comes before its release, and its
+// identifiers compare numerically when both are numbers.
+func (v semver) compare(w semver) int {
+	for _, d := range []int{v.major - w.major, v.minor - w.minor, v.patch - w.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case len(v.pre) == 0 && len(w.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(w.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(w.pre); i++ {
+		a, errA := strconv.Atoi(v.pre[i])
+		b, errB := strconv.Atoi(w.pre[i])
+		switch {
+		case errA == nil && errB == nil:
+			if a != b {
+				return sign(a - b)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(v.pre[i], w.pre[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(v.pre) - len(w.pre))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// changelogIssue is a departure from the format found by
+// KeepAChangelogRule. Those with fix set are fixed by replacing bytes
+// start to end of the line with text.
+type changelogIssue struct {
+	line       int
+	start, end int
+	fix        bool
+	text       string
+	key        string
+	message    string
+}
+
+func (KeepAChangelogRule) issues(lines []string) []changelogIssue {
+	b := classifyBlocks(lines)
+	headings := documentHeadings(lines, b)
+	var found []changelogIssue
+	report := func(line int, key, format string, args ...any) {
+		found = append(found, changelogIssue{line: line, key: key, message: fmt.Sprintf(format, args...)})
+	}
+	// replace reports old on line as fixable, when it is there
+	replace := func(line int, old, text, key, message string) {
+		if k := strings.Index(lines[line], old); k >= 0 {
+			found = append(found, changelogIssue{line: line, start: k, end: k + len(old),
+				fix: true, text: text, key: key, message: message})
+		}
+	}
+
+	if len(headings) == 0 || headings[0].level != 1 {
+		report(min(metadataEnd(lines)+1, len(lines)-1), "title", "a changelog starts with a level 1 title, such as \"# Changelog\"")
+	}
+
+	// versions are the labels of the version headings in order, which
+	// need a link definition
+	type version struct {
+		line  int
+		label string
+	}
+	var versions []version
+	var last *semver
+	lastLabel := ""
+	inVersion := false
+	for n, h := range headings {
+		switch h.level {
+		case 2:
+			inVersion = true
+			m := versionHeading.FindStringSubmatch(h.text)
+			if m == nil {
+				report(h.line, "version", "version heading %q is not of the form \"[1.2.3] - YYYY-MM-DD\" or \"[Unreleased]\"", h.text)
+				continue
+			}
+			label, link, rest := m[1], m[2], m[3]
+			if link == "" {
+				versions = append(versions, version{h.line, label})
+			}
+			if strings.EqualFold(label, "Unreleased") {
+				if label != "Unreleased" {
+					replace(h.line, "["+label+"]", "[Unreleased]", "version",
+						fmt.Sprintf("write %q as \"[Unreleased]\"", label))
+				}
+				if rest != "" {
+					report(h.line, "version", "the Unreleased heading has no date")
+				}
+				if last != nil {
+					report(h.line, "order", "the Unreleased section comes before all versions")
+				}
+				continue
+			}
+			v, ok := parseSemver(label)
+			if !ok {
+				report(h.line, "version", "%q is not a semantic version such as 1.2.3", label)
+				continue
+			}
+			if last != nil && v.compare(*last) >= 0 {
+				report(h.line, "order", "version %s is listed after %s; versions go newest first", label, lastLabel)
+			}
+			last, lastLabel = &v, label
+			d := versionDate.FindStringSubmatch(rest)
+			if d == nil {
+				report(h.line, "date", "version %s has no date; write \"[%s] - YYYY-MM-DD\"", label, label)
+				continue
+			}
+			if t, err := time.Parse("2006-01-02", d[1]); err == nil && t.Format("2006-01-02") == d[1] {
+				continue
+			}
+			fixed := ""
+			for _, layout := range changelogDateLayouts {
+				if t, err := time.Parse(layout, d[1]); err == nil {
+					fixed = t.Format("2006-01-02")
+					break
+				}
+			}
+			if fixed == "" {
+				report(h.line, "date", "date %q of version %s is not YYYY-MM-DD", d[1], label)
+				continue
+			}
+			// the date is the last thing on the line but a yanked marker
+			k := strings.LastIndex(lines[h.line], " - "+d[1]) + len(" - ")
+			found = append(found, changelogIssue{line: h.line, start: k, end: k + len(d[1]), fix: true,
+				text: fixed, key: "date", message: fmt.Sprintf("write the date %q as %q", d[1], fixed)})
+		case 3:
+			if !inVersion {
+				continue
+			}
+			name := ""
+			for _, s := range changelogSections {
+				if strings.EqualFold(h.text, s) {
+					name = s
+				}
+			}
+			switch {
+			case name == "":
+				report(h.line, "section", "%q is not one of the subsections %s", h.text, strings.Join(changelogSections, ", "))
+			case name != h.text:
+				replace(h.line, h.text, name, "section", fmt.Sprintf("write %q as %q", h.text, name))
+			}
+		case 1:
+			if n > 0 {
+				report(h.line, "title", "a changelog has one level 1 title")
+			}
+		}
+	}
+
+	// version links are reference definitions after the last heading
+	defs := map[string]int{}
+	for i, label := range linkDefinitions(lines, b) {
+		defs[label] = i
+	}
+	bottom := 0
+	if len(headings) > 0 {
+		bottom = headings[len(headings)-1].line
+	}
+	for _, v := range versions {
+		i, ok := defs[referenceLabel(v.label)]
+		switch {
+		case !ok:
+			report(v.line, "link", "no link definition for [%s] at the bottom of the changelog", v.label)
+		case i < bottom:
+			report(i, "link", "the link definition for [%s] belongs at the bottom of the changelog", v.label)
+		}
+	}
+	return found
+}
+
+func (r KeepAChangelogRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	// right to left, so earlier offsets on a line stay good
+	issues := r.issues(lines)
+	for k := len(issues) - 1; k >= 0; k-- {
+		if is := issues[k]; is.fix {
+			lines[is.line] = lines[is.line][:is.start] + is.text + lines[is.line][is.end:]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r KeepAChangelogRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	var diags []Diagnostic
+	for _, is := range r.issues(lines) {
+		d := Diagnostic{Line: is.line + 1, Rule: r.Name(), Key: is.key, Message: is.message}
+		if is.fix {
+			d.Column = is.start + 1
+			d.Fix = lineFix(lines[is.line], is.start, is.end, is.text)
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
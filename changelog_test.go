@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeepAChangelogRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "dates",
+			input:    "## [1.2.0] - 2024/05/01\n\n## [1.1.0] - May 3, 2023 [YANKED]\n\n## [1.0.0] - 2 Jan 2023\n",
+			expected: "## [1.2.0] - 2024-05-01\n\n## [1.1.0] - 2023-05-03 [YANKED]\n\n## [1.0.0] - 2023-01-02\n",
+		},
+		{
+			name:     "subsection and Unreleased casing",
+			input:    "## [unreleased]\n\n### added\n\n## [1.0.0] - 2024-01-01\n\n### SECURITY\n",
+			expected: "## [Unreleased]\n\n### Added\n\n## [1.0.0] - 2024-01-01\n\n### Security\n",
+		},
+		{
+			name:     "left alone",
+			input:    "# Changelog\n\n### added\n\n## [1.0.0] - 01/02/2024\n\n### Misc\n\n```\n## [2.0.0] - 2024/01/01\n```\n",
+			expected: "# Changelog\n\n### added\n\n## [1.0.0] - 01/02/2024\n\n### Misc\n\n```\n## [2.0.0] - 2024/01/01\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewKeepAChangelogRule()
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestKeepAChangelogRuleLint(t *testing.T) {
+	input := `## [Unreleased]
+
+### Misc
+
+## [1.2.0] - 2024/05/01
+
+## [1.3.0] - 2024-05-03
+
+## [1.1.0] - 2024-13-01
+
+## 1.0.0
+
+[1.2.0]: https://example.com/1.2.0
+
+## [0.9.0] - 2023-01-02 [YANKED]
+
+[unreleased]: https://example.com/compare
+[1.3.0]: https://example.com/1.3.0
+`
+	var got []string
+	for _, d := range NewKeepAChangelogRule().(KeepAChangelogRule).Lint(input) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`1: warning: KeepAChangelog: a changelog starts with a level 1 title, such as "# Changelog"`,
+		`3: warning: KeepAChangelog: "Misc" is not one of the subsections Added, Changed, Deprecated, Removed, Fixed, Security`,
+		`5:14: warning: KeepAChangelog: write the date "2024/05/01" as "2024-05-01"`,
+		`7: warning: KeepAChangelog: version 1.3.0 is listed after 1.2.0; versions go newest first`,
+		`9: warning: KeepAChangelog: date "2024-13-01" of version 1.1.0 is not YYYY-MM-DD`,
+		`11: warning: KeepAChangelog: version heading "1.0.0" is not of the form "[1.2.3] - YYYY-MM-DD" or "[Unreleased]"`,
+		`13: warning: KeepAChangelog: the link definition for [1.2.0] belongs at the bottom of the changelog`,
+		`9: warning: KeepAChangelog: no link definition for [1.1.0] at the bottom of the changelog`,
+		`15: warning: KeepAChangelog: no link definition for [0.9.0] at the bottom of the changelog`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	valid := "# Changelog\n\n## [Unreleased]\n\n## [1.0.0] - 2024-01-01\n\n### Added\n\n- All.\n\n## [1.0.0-rc.2] - 2023-12-01\n\n[Unreleased]: https://x/compare\n[1.0.0]: https://x/1.0.0\n[1.0.0-rc.2]: https://x/rc2\n"
+	if diags := NewKeepAChangelogRule().(KeepAChangelogRule).Lint(valid); len(diags) != 0 {
+		t.Errorf("valid changelog: got %v", diags)
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// in increasing precedence, as in the semver specification
+	order := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1+build.5", "1.2.0", "10.0.0"}
+	for i := range order {
+		for j := range order {
+			v, ok1 := parseSemver(order[i])
+			w, ok2 := parseSemver(order[j])
+			if !ok1 || !ok2 {
+				t.Fatalf("cannot parse %s or %s", order[i], order[j])
+			}
+			if got, want := v.compare(w), sign(i-j); got != want {
+				t.Errorf("compare(%s, %s) = %d, want %d", order[i], order[j], got, want)
+			}
+		}
+	}
+	if _, ok := parseSemver("v1.0"); ok {
+		t.Error("parsed v1.0")
+	}
+}
+
+func TestKeepAChangelogOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		configFileName: "[override.\"**/CHANGELOG.md\"]\nenable = [\"KeepAChangelog\"]\n",
+	})
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *config) { projectConfig = c }(projectConfig)
+	projectConfig = cfg
+
+	input := "# Changelog\n\n## [1.0.0] - 2024/01/01\n"
+	for path, want := range map[string]string{
+		"CHANGELOG.md":      "# Changelog\n\n## [1.0.0] - 2024-01-01\n",
+		"sub/CHANGELOG.md":  "# Changelog\n\n## [1.0.0] - 2024-01-01\n",
+		"docs/release.md":   input,
+		"sub/CHANGELOG.txt": input,
+	} {
+		got, _, err := formatDocument(input, filepath.Join(dir, filepath.FromSlash(path)), Options{}, nil, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: expected:\n%q\ngot:\n%q", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ----------------------------------------------------------------
+// Rule 40: space CJK text apart from Latin letters and digits
+// ----------------------------------------------------------------
+
+type CJKSpacingRule struct {
+	// remove takes the spaces out instead of putting them in.
+	remove bool
+}
+
+// NewCJKSpacingRule constructs a rule that puts a space between CJK
+// characters and adjacent Latin letters or digits in prose, as in
+// "使用 Go 编写", or with remove takes such spaces out.
+func NewCJKSpacingRule(remove bool) Rule { return CJKSpacingRule{remove: remove} }
+
+func newCJKSpacingRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("cjk-spacing", "add", "add", "remove")
+	if err != nil {
+		return nil, err
+	}
+	return NewCJKSpacingRule(mode == "remove"), nil
+}
+
+func (CJKSpacingRule) Name() string {
+	return "CJKSpacing"
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character.
+// CJK punctuation and full-width forms are not.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// isLatinAlnum reports whether r is a Latin letter or a digit, leaving out
+// full-width forms.
+func isLatinAlnum(r rune) bool {
+	return r < 0x250 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func (r CJKSpacingRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutside(line, nonProseSpans(line), r.space)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// space adds or removes the spaces in one stretch of prose.
+func (r CJKSpacingRule) space(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for k := 0; k < len(runes); k++ {
+		c := runes[k]
+		sb.WriteRune(c)
+		if k+1 == len(runes) {
+			break
+		}
+		if r.remove {
+			// a run of spaces between the two kinds of text
+			n := k + 1
+			for n < len(runes) && runes[n] == ' ' {
+				n++
+			}
+			if n > k+1 && n < len(runes) && boundary(c, runes[n]) {
+				k = n - 1
+			}
+			continue
+		}
+		if boundary(c, runes[k+1]) {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+// boundary reports whether a and b are CJK and Latin, in either order.
+func boundary(a, b rune) bool {
+	return isCJK(a) && isLatinAlnum(b) || isLatinAlnum(a) && isCJK(b)
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestCJKSpacingRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		remove   bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "spaces added",
+			input:    "# 使用Go编写\n\n版本2发布了，支持macOS和Linux。\n\nひらがなとKatakanaとカタカナ、한국어Korean",
+			expected: "# 使用 Go 编写\n\n版本 2 发布了，支持 macOS 和 Linux。\n\nひらがなと Katakana とカタカナ、한국어 Korean",
+		},
+		{
+			name:     "full-width punctuation and existing spaces",
+			input:    "（Go）是语言：Go 很好「Rust」",
+			expected: "（Go）是语言：Go 很好「Rust」",
+		},
+		{
+			name:     "code, links and URLs untouched",
+			input:    "用`go中文`和[文档](https://例子.com/a中b)\n见https://x.org/中文路径\n\n```\n使用Go\n```",
+			expected: "用`go中文`和[文档](https://例子.com/a中b)\n见https://x.org/中文路径\n\n```\n使用Go\n```",
+		},
+		{
+			name:     "spaces removed",
+			remove:   true,
+			input:    "使用 Go 编写，版本  2 发布。English stays as is",
+			expected: "使用Go编写，版本2发布。English stays as is",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewCJKSpacingRule(tt.remove)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 23: strip HTML comments from prose
+// ----------------------------------------------------------------
+
+// defaultKeptComments match tool markers that must survive the rule: table
+// of contents markers and linter/formatter directives.
+var defaultKeptComments = []string{
+	`(?i)^/?toc`,
+	`(?i)doctoc`,
+	`^prettier-ignore`,
+	`^markdownlint-`,
+}
+
+type StripHTMLCommentsRule struct {
+	keep []*regexp.Regexp
+}
+
+// NewStripHTMLCommentsRule constructs a rule that removes HTML comments
+// except mdfmt directives and comments whose trimmed text matches one of
+// keep.
+func NewStripHTMLCommentsRule(keep []*regexp.Regexp) Rule {
+	return StripHTMLCommentsRule{keep: keep}
+}
+
+func newStripHTMLCommentsRuleFromOptions(o Options) (Rule, error) {
+	var keep []*regexp.Regexp
+	for _, p := range o.List("html-comment-keep", defaultKeptComments) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("option html-comment-keep: %w", err)
+		}
+		keep = append(keep, re)
+	}
+	return NewStripHTMLCommentsRule(keep), nil
+}
+
+func (StripHTMLCommentsRule) Name() string {
+	return "StripHTMLComments"
+}
+
+func (StripHTMLCommentsRule) Trigger() []string {
+	return []string{"<!--"}
+}
+
+func (r StripHTMLCommentsRule) kept(comment string) bool {
+	text := strings.TrimSpace(comment)
+	if strings.HasPrefix(text, "mdfmt-") || strings.HasPrefix(text, "mdfmt:") {
+		return true
+	}
+	for _, re := range r.keep {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r StripHTMLCommentsRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	prose := func(i int) bool {
+		return !b.isCode(i) && b.lines[i].kind != lineFrontMatter
+	}
+	emptied := make([]bool, len(lines))
+
+	for i, from := 0, 0; i < len(lines); i, from = i+1, 0 {
+		if !prose(i) {
+			continue
+		}
+		if from < b.lines[i].bodyStart {
+			from = b.lines[i].bodyStart
+		}
+		for {
+			start := commentStart(lines[i], from)
+			if start < 0 {
+				break
+			}
+			// find the closing "-->", which may be on a later prose line
+			j, end := i, -1
+			if k := strings.Index(lines[i][start+4:], "-->"); k >= 0 {
+				end = start + 4 + k + 3
+			}
+			for end < 0 && j+1 < len(lines) && prose(j+1) {
+				j++
+				if k := strings.Index(lines[j], "-->"); k >= 0 {
+					end = k + 3
+				}
+			}
+			if end < 0 {
+				break // unterminated: leave it alone
+			}
+			var text string
+			if j == i {
+				text = lines[i][start+4 : end-3]
+			} else {
+				text = lines[i][start+4:] + "\n" + strings.Join(lines[i+1:j], "\n") + "\n" + lines[j][:end-3]
+			}
+			if r.kept(text) {
+				i, from = j, end
+				continue
+			}
+
+			before, after := lines[i][:start], lines[j][end:]
+			if j > i {
+				lines[i] = strings.TrimRight(before, " \t")
+				emptied[i] = isBlankLine(lines[i])
+				for k := i + 1; k < j; k++ {
+					lines[k], emptied[k] = "", true
+				}
+				before = lines[j][:b.lines[j].bodyStart]
+			}
+			// don't leave a doubled, leading or trailing space where the
+			// comment was
+			if strings.TrimSpace(before[b.lines[j].bodyStart:]) == "" || strings.HasSuffix(before, " ") {
+				after = strings.TrimLeft(after, " \t")
+			}
+			if after == "" {
+				before = strings.TrimRight(before, " \t")
+			}
+			lines[j] = before + after
+			emptied[j] = isBlankLine(lines[j])
+			i, from = j, len(before)
+		}
+	}
+
+	// drop emptied lines, and the blank line that would double up with
+	// the one before them
+	out := make([]string, 0, len(lines))
+	dropped := false
+	for i, l := range lines {
+		if emptied[i] {
+			dropped = true
+			continue
+		}
+		if dropped && b.lines[i].kind == lineBlank && (len(out) == 0 || isBlankLine(out[len(out)-1])) {
+			continue
+		}
+		dropped = false
+		out = append(out, l)
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// commentStart returns the byte offset of the first "<!--" at or after from
+// that is not inside an inline code span, or -1.
+func commentStart(line string, from int) int {
+	spans := codeSpans(line)
+	for {
+		k := strings.Index(line[from:], "<!--")
+		if k < 0 {
+			return -1
+		}
+		at := from + k
+		inCode := false
+		for _, sp := range spans {
+			if at >= sp[0] && at < sp[1] {
+				inCode, from = true, sp[1]
+				break
+			}
+		}
+		if !inCode {
+			return at
+		}
+	}
+}
+
+// isBlankLine reports whether line holds nothing but blockquote markers and
+// whitespace.
+func isBlankLine(line string) bool {
+	_, off := stripQuote(line, -1)
+	return strings.TrimSpace(line[off:]) == ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestStripHTMLCommentsRule(t *testing.T) {
+	rule, err := newStripHTMLCommentsRuleFromOptions(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "inline comment",
+			input:    "Ship it <!-- internal: ask legal --> today.",
+			expected: "Ship it today.",
+		},
+		{
+			name:     "comment at end of line",
+			input:    "Ship it <!-- later -->\nnext",
+			expected: "Ship it\nnext",
+		},
+		{
+			name:     "comment line between blank lines",
+			input:    "a\n\n<!-- note -->\n\nb",
+			expected: "a\n\nb",
+		},
+		{
+			name:     "multi-line comment",
+			input:    "a\n\n<!--\n  internal\n  notes\n-->\n\nb\n",
+			expected: "a\n\nb\n",
+		},
+		{
+			name:     "multi-line comment inside a paragraph",
+			input:    "one <!-- a\nb --> two",
+			expected: "one\ntwo",
+		},
+		{
+			name:     "comment at start of document",
+			input:    "<!-- draft -->\n\n# Title",
+			expected: "# Title",
+		},
+		{
+			name:     "quoted comment",
+			input:    "> a\n>\n> <!-- x -->\n>\n> b",
+			expected: "> a\n>\n> b",
+		},
+		{
+			name:     "directives and markers kept",
+			input:    "<!-- mdfmt-ignore -->\n<!-- toc -->\n<!-- prettier-ignore-start -->\n<!-- x -->",
+			expected: "<!-- mdfmt-ignore -->\n<!-- toc -->\n<!-- prettier-ignore-start -->",
+		},
+		{
+			name:     "code untouched",
+			input:    "```html\n<!-- x -->\n```\n\n`<!-- y -->` and <!-- z -->",
+			expected: "```html\n<!-- x -->\n```\n\n`<!-- y -->` and",
+		},
+		{
+			name:     "unterminated comment",
+			input:    "a <!-- open\n\nb",
+			expected: "a <!-- open\n\nb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStripHTMLCommentsRuleKeepList(t *testing.T) {
+	rule := NewStripHTMLCommentsRule([]*regexp.Regexp{regexp.MustCompile(`^vale `)})
+	input := "<!-- vale off -->\n<!-- toc -->\n<!-- mdfmt-off -->"
+	expected := "<!-- vale off -->\n<!-- mdfmt-off -->"
+	got, _ := rule.Apply(input)
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------------------------------------------
+// Configuration file
+// ----------------------------------------------------------------
+
+// configFileName is the configuration file mdfmt reads from the current
+// directory, e.g.
+//
+//	disable = ["SmartQuotesToAscii"]
+//	max-line-length = 120
+//
+//	[override."blog/**"]
+//	enable = ["Capitalize"]
+//	dashes = "smart"
+//
+// "enable" and "disable" list rules, "rules" the rules to run in their
+// order, "override" holds the settings for the files matching a glob,
+// relative to the file's directory, and every other key sets an option.
+// A table of strings sets an option of from:to pairs.
+const configFileName = ".mdfmt.toml"
+
+// yamlConfigFileName is the same file in YAML, used when there is no TOML
+// one; ".yml" works as well.
+const yamlConfigFileName = ".mdfmt.yaml"
+
+// projectConfig is the configuration file in use, if any.
+var projectConfig *config
+
+// config is a parsed configuration file.
+type config struct {
+	dir       string // slash path of the directory it applies to
+	base      configSettings
+	overrides []configOverride // in the order of the file
+}
+
+// configSettings are the settings of the base configuration or of one
+// override.
+type configSettings struct {
+	options Options
+	enable  []string
+	disable []string
+	// rules are the rules to run in their order instead of the default
+	// pipeline, or nil for it.
+	rules []string
+}
+
+type configOverride struct {
+	glob     string
+	settings configSettings
+}
+
+// configDirFor returns the directory of the configuration file for the
+// file at path: the nearest of the directories above it that holds one,
+// or the current directory when none does.
+func configDirFor(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "."
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		for _, name := range []string{configFileName, yamlConfigFileName, ".mdfmt.yml"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return "."
+		}
+	}
+}
+
+// loadConfig reads the configuration file in dir. It returns nil, and no
+// error, when there is none.
+func loadConfig(dir string) (*config, error) {
+	var found []string
+	for _, name := range []string{configFileName, yamlConfigFileName, ".mdfmt.yml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("%s: more than one configuration file: %s", dir, strings.Join(found, ", "))
+	}
+	path := filepath.Join(dir, found[0])
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	parse := parseConfig
+	if found[0] != configFileName {
+		parse = parseYAMLConfig
+	}
+	cfg, err := parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.dir = filepath.ToSlash(abs)
+	return cfg, nil
+}
+
+// parseConfig parses the TOML of a configuration file, rejecting unknown
+// rules and options.
+func parseConfig(data string) (*config, error) {
+	var raw map[string]any
+	md, err := toml.Decode(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	// the overrides in the order of the file, which the decoded map
+	// forgets
+	var order []string
+	seen := map[string]bool{}
+	for _, k := range md.Keys() {
+		if len(k) < 2 || k[0] != "override" || seen[k[1]] {
+			continue
+		}
+		seen[k[1]] = true
+		order = append(order, k[1])
+	}
+	return buildConfig(raw, order)
+}
+
+// parseYAMLConfig parses a configuration file written in YAML, with the
+// same keys as in TOML.
+func parseYAMLConfig(data string) (*config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return buildConfig(map[string]any{}, nil)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of settings", root.Line)
+	}
+	raw, err := yamlValue(root)
+	if err != nil {
+		return nil, err
+	}
+	var order []string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if v := root.Content[i+1]; root.Content[i].Value == "override" && v.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				order = append(order, v.Content[j].Value)
+			}
+		}
+	}
+	return buildConfig(raw.(map[string]any), order)
+}
+
+// yamlValue converts a YAML node to the values TOML decodes to: strings,
+// int64s, bools, lists and tables.
+func yamlValue(n *yaml.Node) (any, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.MappingNode:
+		m := map[string]any{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := yamlValue(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[n.Content[i].Value] = v
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		list := []any{}
+		for _, c := range n.Content {
+			v, err := yamlValue(c)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	if i, ok := v.(int); ok {
+		return int64(i), nil
+	}
+	return v, nil
+}
+
+// buildConfig builds a configuration from the decoded keys of its file
+// and the globs of its overrides in the order of the file.
+func buildConfig(raw map[string]any, order []string) (*config, error) {
+	cfg := &config{}
+	var err error
+	if cfg.base, err = parseConfigSettings(raw, ""); err != nil {
+		return nil, err
+	}
+	overrides, _ := raw["override"].(map[string]any)
+	if _, ok := raw["override"]; ok && overrides == nil {
+		return nil, errors.New("override: expected tables of settings by glob")
+	}
+	for _, glob := range order {
+		table, ok := overrides[glob].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("override.%q: expected a table of settings", glob)
+		}
+		s, err := parseConfigSettings(table, fmt.Sprintf("override.%q: ", glob))
+		if err != nil {
+			return nil, err
+		}
+		cfg.overrides = append(cfg.overrides, configOverride{glob: glob, settings: s})
+	}
+	return cfg, nil
+}
+
+// parseConfigSettings reads the settings of one table of a configuration
+// file, prefixing errors with where.
+func parseConfigSettings(table map[string]any, where string) (configSettings, error) {
+	s := configSettings{options: Options{}}
+	for k, v := range table {
+		switch k {
+		case "override":
+			if where != "" {
+				return s, fmt.Errorf("%soverrides cannot be nested", where)
+			}
+		case "enable", "disable", "rules":
+			list, ok := v.([]any)
+			if !ok {
+				return s, fmt.Errorf("%s%s: expected a list of rule names", where, k)
+			}
+			names := []string{}
+			for _, item := range list {
+				name, ok := item.(string)
+				if !ok {
+					return s, fmt.Errorf("%s%s: expected rule names", where, k)
+				}
+				resolved, err := resolveRuleNames([]string{name})
+				if err != nil {
+					return s, fmt.Errorf("%s%s: %w (rules are %s)", where, k, err, strings.Join(builtinRuleNames(), ", "))
+				}
+				names = append(names, resolved...)
+			}
+			switch k {
+			case "enable":
+				s.enable = append(s.enable, names...)
+			case "disable":
+				s.disable = append(s.disable, names...)
+			default:
+				s.rules = names
+			}
+		default:
+			if !isKnownOption(k) {
+				return s, fmt.Errorf("%sunknown option %q", where, k)
+			}
+			switch v := v.(type) {
+			case string:
+				s.options[k] = v
+			case int64:
+				s.options[k] = strconv.FormatInt(v, 10)
+			case bool:
+				s.options[k] = strconv.FormatBool(v)
+			case map[string]any:
+				pairs, err := configPairs(v)
+				if err != nil {
+					return s, fmt.Errorf("%soption %q: %w", where, k, err)
+				}
+				s.options[k] = pairs
+			default:
+				return s, fmt.Errorf("%soption %q: expected a single value", where, k)
+			}
+		}
+	}
+	sort.Strings(s.enable)
+	sort.Strings(s.disable)
+	return s, nil
+}
+
+// configPairs writes a table of strings as the from:to pairs of an
+// option such as code-language-aliases.
+func configPairs(table map[string]any) (string, error) {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		v, ok := table[k].(string)
+		if !ok {
+			return "", fmt.Errorf("%q: expected a string", k)
+		}
+		if strings.ContainsAny(k+v, ",:") || strings.TrimSpace(k) == "" {
+			return "", fmt.Errorf("%q = %q: a pair cannot hold \",\" or \":\"", k, v)
+		}
+		pairs[i] = k + ":" + v
+	}
+	return strings.Join(pairs, ","), nil
+}
+
+// settingsFor returns the settings of the configuration for the file at
+// path: the base settings with those of each matching override merged
+// over them in turn. Rules enabled or disabled later undo the opposite.
+func (c *config) settingsFor(path string) configSettings {
+	s := configSettings{options: Options{}}
+	if c == nil {
+		return s
+	}
+	settings := []configSettings{c.base}
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			abs = filepath.ToSlash(abs)
+			for _, o := range c.overrides {
+				if rel, ok := strings.CutPrefix(abs, c.dir+"/"); ok && globRegexp(o.glob).MatchString(rel) {
+					settings = append(settings, o.settings)
+				}
+			}
+		}
+	}
+	for _, t := range settings {
+		s = s.merge(t)
+	}
+	return s
+}
+
+// merge returns s with the settings of t over them: t's options win, and
+// its enable and disable lists add to s's, moving rules from one list to
+// the other.
+func (s configSettings) merge(t configSettings) configSettings {
+	out := configSettings{options: Options{}}
+	for k, v := range s.options {
+		out.options[k] = v
+	}
+	for k, v := range t.options {
+		out.options[k] = v
+	}
+	enabled := map[string]bool{}
+	for _, name := range s.enable {
+		enabled[name] = true
+	}
+	for _, name := range s.disable {
+		enabled[name] = false
+	}
+	for _, name := range t.enable {
+		enabled[name] = true
+	}
+	for _, name := range t.disable {
+		enabled[name] = false
+	}
+	for name, on := range enabled {
+		if on {
+			out.enable = append(out.enable, name)
+		} else {
+			out.disable = append(out.disable, name)
+		}
+	}
+	sort.Strings(out.enable)
+	sort.Strings(out.disable)
+	out.rules = s.rules
+	if t.rules != nil {
+		out.rules = t.rules
+	}
+	return out
+}
+
+// String formats the settings as a configuration file would give them.
+func (s configSettings) String() string {
+	var sb strings.Builder
+	list := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, n := range names {
+			quoted[i] = strconv.Quote(n)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	fmt.Fprintf(&sb, "enable = %s\n", list(s.enable))
+	fmt.Fprintf(&sb, "disable = %s\n", list(s.disable))
+	if s.rules != nil {
+		fmt.Fprintf(&sb, "rules = %s\n", list(s.rules))
+	}
+	keys := make([]string, 0, len(s.options))
+	for k := range s.options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s = %s\n", k, strconv.Quote(s.options[k]))
+	}
+	return sb.String()
+}
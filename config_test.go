@@ -0,0 +1,299 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `disable = ["SmartQuotesToAscii"]
+max-line-length = 100
+
+[override."blog/**"]
+enable = ["Capitalize", "SmartQuotesToAscii"]
+dashes = "smart"
+
+[override."CHANGELOG.md"]
+disable = ["BlankLineAfterHeading", "MD040"]
+
+[override."blog/drafts/**"]
+dashes = "ascii"
+disable = ["Capitalize"]
+`
+
+func TestConfigSettingsFor(t *testing.T) {
+	cfg, err := parseConfig(sampleConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.dir = filepath.ToSlash(t.TempDir())
+
+	tests := []struct {
+		path string
+		want configSettings
+	}{
+		{"docs/a.md", configSettings{
+			options: Options{"max-line-length": "100"},
+			disable: []string{"SmartQuotesToAscii"},
+		}},
+		{"blog/post.md", configSettings{
+			options: Options{"max-line-length": "100", "dashes": "smart"},
+			enable:  []string{"Capitalize", "SmartQuotesToAscii"},
+		}},
+		{"blog/drafts/post.md", configSettings{
+			options: Options{"max-line-length": "100", "dashes": "ascii"},
+			enable:  []string{"SmartQuotesToAscii"},
+			disable: []string{"Capitalize"},
+		}},
+		{"CHANGELOG.md", configSettings{
+			options: Options{"max-line-length": "100"},
+			disable: []string{"BlankLineAfterHeading", "FencedCodeLanguage", "SmartQuotesToAscii"},
+		}},
+		{"docs/CHANGELOG.md", configSettings{
+			options: Options{"max-line-length": "100"},
+			disable: []string{"SmartQuotesToAscii"},
+		}},
+	}
+	for _, tt := range tests {
+		got := cfg.settingsFor(filepath.Join(filepath.FromSlash(cfg.dir), filepath.FromSlash(tt.path)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+
+	var none *config
+	if got := none.settingsFor("a.md"); len(got.options) != 0 || got.enable != nil || got.disable != nil {
+		t.Errorf("no configuration: got %+v", got)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`enable = ["Bogus"]`, `enable: unknown rule "Bogus"`},
+		{`disable = "SortList"`, "disable: expected a list of rule names"},
+		{`bogus = 1`, `unknown option "bogus"`},
+		{`quotes = ["en"]`, `option "quotes": expected a single value`},
+		{"[override.\"a/**\"]\nbogus = 1", `override."a/**": unknown option "bogus"`},
+		{"[override.\"a/**\".override.\"b\"]\nfence = \"tilde\"", `override."a/**": overrides cannot be nested`},
+		{`override = 1`, "override: expected tables of settings by glob"},
+		{`fence = `, "line 1"},
+		{`rules = ["Bogus"]`, `rules: unknown rule "Bogus" (rules are UnterminatedFence, ExpandTabs, `},
+		{"[code-language-aliases]\n\"a,b\" = \"c\"", `option "code-language-aliases": "a,b" = "c": a pair cannot hold`},
+	}
+	for _, tt := range tests {
+		_, err := parseConfig(tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got error %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := loadConfig(dir); cfg != nil || err != nil {
+		t.Errorf("without a file: got %v, %v", cfg, err)
+	}
+	writeFiles(t, dir, map[string]string{configFileName: sampleConfig})
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.overrides) != 3 || cfg.overrides[2].glob != "blog/drafts/**" {
+		t.Errorf("overrides out of order: %+v", cfg.overrides)
+	}
+}
+
+func TestPathSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".editorconfig": "root = true\n[*]\nend_of_line = crlf\nindent_style = space\n",
+		configFileName:  "end-of-line = \"lf\"\n[override.\"blog/**\"]\ndisable = [\"SortList\"]\n",
+	})
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *config) { projectConfig = c }(projectConfig)
+	projectConfig = cfg
+
+	got, err := pathSettings(filepath.Join(dir, "blog", "a.md"), Options{"fence": "tilde"}, []string{"SortList"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "enable = [\"SortList\"]\ndisable = []\nend-of-line = \"lf\"\nfence = \"tilde\"\ntabs = \"expand\"\n"
+	if got.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+const sampleYAMLConfig = `disable: [SmartQuotesToAscii]
+max-line-length: 100
+override:
+  blog/**:
+    enable: [Capitalize, SmartQuotesToAscii]
+    dashes: smart
+  CHANGELOG.md:
+    disable: [BlankLineAfterHeading, MD040]
+  blog/drafts/**:
+    dashes: ascii
+    disable: [Capitalize]
+`
+
+func TestParseYAMLConfig(t *testing.T) {
+	want, err := parseConfig(sampleConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseYAMLConfig(sampleYAMLConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for config, want := range map[string]string{
+		"- a\n":              "expected a mapping of settings",
+		"enable: [Bogus]\n":  `enable: unknown rule "Bogus"`,
+		"fence: [tilde]\n":   `option "fence": expected a single value`,
+		"override: {a: 1}\n": `override."a": expected a table of settings`,
+	} {
+		if _, err := parseYAMLConfig(config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", config, err, want)
+		}
+	}
+	if cfg, err := parseYAMLConfig(""); err != nil || cfg.base.rules != nil || len(cfg.base.options) != 0 {
+		t.Errorf("empty file: got %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadYAMLConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".mdfmt.yml": sampleYAMLConfig})
+	cfg, err := loadConfig(dir)
+	if err != nil || len(cfg.overrides) != 3 {
+		t.Fatalf("got %+v, %v", cfg, err)
+	}
+	writeFiles(t, dir, map[string]string{configFileName: sampleConfig})
+	if _, err := loadConfig(dir); err == nil || !strings.Contains(err.Error(), "more than one configuration file") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestConfigRules(t *testing.T) {
+	ruleNames := func(f *Formatter) []string {
+		var names []string
+		for _, r := range f.Rules() {
+			names = append(names, r.Name())
+		}
+		return names
+	}
+	tests := []struct {
+		name     string
+		config   string
+		settings configSettings // on top of the file's
+		want     []string
+	}{
+		{
+			name:   "in the order given",
+			config: "rules = [\"FinalNewline\", \"Capitalize\", \"MD013\"]\n",
+			want:   []string{"UnterminatedFence", "FinalNewline", "Capitalize", "LineLength"},
+		},
+		{
+			name:     "enabled and disabled besides",
+			config:   "rules:\n  - TrailingWhitespace\n  - FenceStyle\n  - FinalNewline\n",
+			settings: configSettings{enable: []string{"TodoTask", "ExpandTabs"}, disable: []string{"FenceStyle"}},
+			want:     []string{"UnterminatedFence", "TrailingWhitespace", "FinalNewline", "ExpandTabs", "TodoTask"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := parseConfig
+			if strings.HasPrefix(tt.config, "rules:") {
+				parse = parseYAMLConfig
+			}
+			cfg, err := parse(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := cfg.settingsFor("").merge(tt.settings)
+			f, err := newFormatterFromSettings(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ruleNames(f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// without a list the default pipeline runs
+	f, err := newFormatterFromSettings(configSettings{options: Options{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := newFormatterFromOptions(Options{}, nil, nil)
+	if !reflect.DeepEqual(ruleNames(f), ruleNames(def)) {
+		t.Errorf("got %q, want %q", ruleNames(f), ruleNames(def))
+	}
+	// rules are called by their names in the registry
+	all, err := newFormatterFromOptions(Options{}, builtinRuleNames(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ruleNames(all); !reflect.DeepEqual(got, builtinRuleNames()) {
+		t.Errorf("rule names differ from the registry:\n%q\n%q", got, builtinRuleNames())
+	}
+}
+
+func TestConfigReplacements(t *testing.T) {
+	cfg, err := parseConfig("[replacements]\n\"\u2019\" = \"'\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.settingsFor("")
+	if want := (Options{"replacements": "\u2019:'"}); !reflect.DeepEqual(s.options, want) {
+		t.Errorf("got %v, want %v", s.options, want)
+	}
+	f, err := newFormatterFromSettings(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Format("It\u2019s \u201cquoted\u201d.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "It's \"quoted\u201d.\n"; got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestConfigDirFor(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"proj/.mdfmt.yaml":      "fence: tilde\n",
+		"proj/docs/guide/a.md":  "",
+		"other/.mdfmt.toml":     "",
+		"other/sub/.mdfmt.toml": "",
+		"other/sub/deeper/b.md": "",
+		"plain/c.md":            "",
+	})
+	for path, want := range map[string]string{
+		"proj/docs/guide/a.md":  filepath.Join(dir, "proj"),
+		"proj/new.md":           filepath.Join(dir, "proj"),
+		"other/sub/deeper/b.md": filepath.Join(dir, "other", "sub"),
+		"other/b.md":            filepath.Join(dir, "other"),
+	} {
+		if got := configDirFor(filepath.Join(dir, filepath.FromSlash(path))); got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+	// a file in no project finds nothing above it, in a temporary directory
+	if got := configDirFor(filepath.Join(dir, "plain", "c.md")); got != "." {
+		t.Skipf("a configuration file above %s: %s", dir, got)
+	}
+}
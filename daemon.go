@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// daemonProtocol is the version of the daemon protocol. Requests and
+// responses carry it, and either side rejects a version it does not speak.
+const daemonProtocol = 2
+
+// maxDaemonMessage bounds the size of one message.
+const maxDaemonMessage = 64 << 20
+
+// daemonRequest asks the daemon to format one document. Options, Enable,
+// Disable and Verify are applied on top of the daemon's own settings.
+type daemonRequest struct {
+	Version int `json:"version"`
+	// Path is the absolute path of the document, by which the daemon finds
+	// the configuration and .editorconfig files that apply to it. Name is
+	// the path as the client was given it, which rules see.
+	Path string `json:"path,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Config is the settingsFingerprint of the configuration files for
+	// Path on the client; the daemon refuses to format with other ones.
+	Config  string   `json:"config"`
+	Content string   `json:"content"`
+	Options Options  `json:"options,omitempty"`
+	Enable  []string `json:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty"`
+	Verify  bool     `json:"verify,omitempty"`
+}
+
+type daemonResponse struct {
+	Version     int          `json:"version"`
+	Content     string       `json:"content,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	// Refused is set when the daemon will not format the document: the
+	// configuration files differ from the client's, or a rule that overran
+	// rule-timeout still runs.
+	Refused bool `json:"refused,omitempty"`
+}
+
+// daemonConfig is what the daemon was started with.
+type daemonConfig struct {
+	opts    Options
+	enable  []string
+	disable []string
+	verify  bool
+}
+
+// defaultSocket is where the daemon listens unless -socket says otherwise.
+// Unix sockets work on Windows 10 and later as well.
+func defaultSocket() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("mdfmt-%d.sock", os.Getuid()))
+}
+
+// writeMessage writes v as JSON preceded by its length as a 4-byte big
+// endian integer.
+func writeMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxDaemonMessage {
+		return fmt.Errorf("message of %d bytes is too large", len(data))
+	}
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	if _, err := w.Write(n[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readMessage reads a message written by writeMessage into v. It returns
+// io.EOF when the connection ends between messages.
+func readMessage(r io.Reader, v any) error {
+	var n [4]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size > maxDaemonMessage {
+		return fmt.Errorf("message of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// listenDaemon listens on socket, replacing a stale socket file that no
+// daemon answers on.
+func listenDaemon(socket string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", socket)
+	}
+	os.Remove(socket)
+	return net.Listen("unix", socket)
+}
+
+// serveDaemon answers requests on l until it is closed.
+func serveDaemon(l net.Listener, cfg daemonConfig) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var req daemonRequest
+				if err := readMessage(conn, &req); err != nil {
+					if !errors.Is(err, io.EOF) {
+						writeMessage(conn, daemonResponse{Version: daemonProtocol, Error: err.Error()})
+					}
+					return
+				}
+				if err := writeMessage(conn, cfg.handle(req)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (cfg daemonConfig) handle(req daemonRequest) daemonResponse {
+	if req.Version != daemonProtocol {
+		return daemonResponse{
+			Version: daemonProtocol,
+			Error:   fmt.Sprintf("protocol version %d is not supported; the daemon speaks version %d", req.Version, daemonProtocol),
+		}
+	}
+	if settingsFingerprint(req.Path, nil, nil, nil) != req.Config {
+		return daemonResponse{
+			Version: daemonProtocol,
+			Error:   fmt.Sprintf("the configuration for %s differs from the client's", req.Path),
+			Refused: true,
+		}
+	}
+	if err := checkOverrunning(); err != nil {
+		return daemonResponse{Version: daemonProtocol, Error: err.Error(), Refused: true}
+	}
+	opts := Options{}
+	for k, v := range cfg.opts {
+		opts[k] = v
+	}
+	for k, v := range req.Options {
+		opts[k] = v
+	}
+	enable := append(append([]string(nil), cfg.enable...), req.Enable...)
+	disable := append(append([]string(nil), cfg.disable...), req.Disable...)
+	fmter, err := documentFormatter(req.Content, req.Path, opts, enable, disable)
+	if err != nil {
+		return daemonResponse{Version: daemonProtocol, Error: err.Error()}
+	}
+	out, diags, err := formatWith(fmter.ForPath(req.Name), req.Content, cfg.verify || req.Verify, nil)
+	if err != nil {
+		return daemonResponse{Version: daemonProtocol, Error: err.Error()}
+	}
+	return daemonResponse{Version: daemonProtocol, Content: out, Diagnostics: diags}
+}
+
+// errNoDaemon is returned by callDaemon when no daemon is listening.
+var errNoDaemon = errors.New("no daemon is running")
+
+// errDaemonRefused is returned by callDaemon when the daemon refuses a
+// document: its configuration files differ from the client's, as when it
+// was started in another project, or a rule that overran rule-timeout
+// still runs in it.
+var errDaemonRefused = errors.New("the daemon refused the document")
+
+// callDaemon sends req, with its Path as the client names it, to the
+// daemon on socket and returns its answer.
+func callDaemon(socket string, req daemonRequest) (string, []Diagnostic, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return "", nil, errNoDaemon
+	}
+	defer conn.Close()
+	req.Version = daemonProtocol
+	if req.Path != "" {
+		// the daemon runs in a directory of its own
+		abs, err := filepath.Abs(req.Path)
+		if err != nil {
+			return "", nil, err
+		}
+		req.Name, req.Path = req.Path, abs
+	}
+	req.Config = settingsFingerprint(req.Path, nil, nil, nil)
+	if err := writeMessage(conn, req); err != nil {
+		return "", nil, fmt.Errorf("daemon: %w", err)
+	}
+	var resp daemonResponse
+	if err := readMessage(conn, &resp); err != nil {
+		return "", nil, fmt.Errorf("daemon: %w", err)
+	}
+	if resp.Version != daemonProtocol {
+		return "", nil, fmt.Errorf("daemon speaks protocol version %d, this client version %d", resp.Version, daemonProtocol)
+	}
+	if resp.Refused {
+		return "", nil, errDaemonRefused
+	}
+	if resp.Error != "" {
+		return "", nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return resp.Content, resp.Diagnostics, nil
+}
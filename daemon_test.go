@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func startDaemon(t *testing.T, cfg daemonConfig) string {
+	t.Helper()
+	// socket paths are limited to about 100 bytes, too few for t.TempDir
+	dir, err := os.MkdirTemp("", "mdfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "d.sock")
+	l, err := listenDaemon(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go serveDaemon(l, cfg)
+	return socket
+}
+
+func TestDaemon(t *testing.T) {
+	socket := startDaemon(t, daemonConfig{opts: Options{"fence": "tilde"}})
+
+	out, diags, err := callDaemon(socket, daemonRequest{Content: "```\nx\n```\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "~~~\nx\n~~~\n"; out != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, out)
+	}
+	want := []Diagnostic{{Line: 1, Rule: "FencedCodeLanguage", Code: "MD040", Message: "fenced code block has no language"}}
+	if !reflect.DeepEqual(diags, want) {
+		t.Errorf("got %v, want %v", diags, want)
+	}
+
+	// request options override the daemon's
+	out, _, err = callDaemon(socket, daemonRequest{Content: "```go\nx\n```\n", Options: Options{"fence": "backtick"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "```go\nx\n```\n"; out != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, out)
+	}
+
+	if _, _, err := callDaemon(socket, daemonRequest{Content: "x", Options: Options{"bogus": "1"}}); err == nil ||
+		!strings.Contains(err.Error(), "unknown option") {
+		t.Errorf("got error %v, want the formatting error", err)
+	}
+
+	if _, err := listenDaemon(socket); err == nil {
+		t.Error("second daemon on the same socket")
+	}
+}
+
+func TestDaemonProtocolVersion(t *testing.T) {
+	socket := startDaemon(t, daemonConfig{})
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := writeMessage(conn, daemonRequest{Version: daemonProtocol + 1, Content: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	var resp daemonResponse
+	if err := readMessage(conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Error, fmt.Sprintf("protocol version %d is not supported", daemonProtocol+1)) {
+		t.Errorf("got %+v", resp)
+	}
+}
+
+func TestDaemonRefusesOtherConfig(t *testing.T) {
+	socket := startDaemon(t, daemonConfig{})
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := writeMessage(conn, daemonRequest{Version: daemonProtocol, Config: "elsewhere", Content: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	var resp daemonResponse
+	if err := readMessage(conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Refused || resp.Content != "" {
+		t.Errorf("got %+v, want a refusal", resp)
+	}
+}
+
+func TestDaemonRefusesWhileRuleOverruns(t *testing.T) {
+	overrunning.Add(1)
+	defer overrunning.Add(-1)
+	resp := daemonConfig{}.handle(daemonRequest{Version: daemonProtocol, Config: settingsFingerprint("", nil, nil, nil), Content: "x"})
+	if !resp.Refused || !strings.Contains(resp.Error, "rule-timeout") {
+		t.Errorf("got %+v, want a refusal", resp)
+	}
+}
+
+func TestCallDaemonPath(t *testing.T) {
+	// a daemon that records the request and refuses it
+	dir, err := os.MkdirTemp("", "mdfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "d.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	reqs := make(chan daemonRequest, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var req daemonRequest
+		readMessage(conn, &req)
+		reqs <- req
+		writeMessage(conn, daemonResponse{Version: daemonProtocol, Refused: true})
+	}()
+
+	if _, _, err := callDaemon(socket, daemonRequest{Path: "docs/a.md", Content: "x"}); err != errDaemonRefused {
+		t.Errorf("got %v, want errDaemonRefused", err)
+	}
+	req := <-reqs
+	abs, _ := filepath.Abs("docs/a.md")
+	if req.Path != abs || req.Name != "docs/a.md" {
+		t.Errorf("got path %q, name %q, want %q, docs/a.md", req.Path, req.Name, abs)
+	}
+	if req.Config != settingsFingerprint(abs, nil, nil, nil) {
+		t.Errorf("got config %q", req.Config)
+	}
+}
+
+func TestCallDaemonWithoutDaemon(t *testing.T) {
+	if _, _, err := callDaemon(filepath.Join(t.TempDir(), "none.sock"), daemonRequest{}); err != errNoDaemon {
+		t.Errorf("got %v, want errNoDaemon", err)
+	}
+}
+
+func TestDaemonMessages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, daemonRequest{Version: 1, Content: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	var req daemonRequest
+	if err := readMessage(&buf, &req); err != nil || req.Content != "a" {
+		t.Errorf("got %+v, %v", req, err)
+	}
+	huge := []byte{0xff, 0xff, 0xff, 0xff}
+	if err := readMessage(bytes.NewReader(huge), &req); err == nil {
+		t.Error("expected an error for an oversized message")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 43: write dashes as typographic dashes or as hyphens
+// ----------------------------------------------------------------
+
+var (
+	// hyphenRun matches a run of hyphens.
+	hyphenRun = regexp.MustCompile(`-+`)
+	// numberRange matches a range of numbers such as 3-5 or 3–5.
+	numberRange = regexp.MustCompile(`\d+(-|–)\d+`)
+	// inlineComment matches an HTML comment that ends on its line.
+	inlineComment = regexp.MustCompile(`<!--.*?-->`)
+)
+
+type DashStyleRule struct {
+	// style is "smart", "ascii", or "" to leave dashes alone.
+	style string
+	// ranges also rewrites the hyphen in number ranges.
+	ranges bool
+}
+
+// NewDashStyleRule constructs a rule that, with style "smart", turns "--"
+// between words into an en dash and "---" into an em dash, and with style
+// "ascii" turns them back. Command line flags such as --verbose, tables,
+// comments and thematic breaks are left alone. With ranges it also writes
+// number ranges as 3–5, or as 3-5 with style "ascii".
+func NewDashStyleRule(style string, ranges bool) Rule {
+	return DashStyleRule{style: style, ranges: ranges}
+}
+
+func newDashStyleRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("dashes", "", "", "smart", "ascii")
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := o.Bool("dash-ranges", false)
+	if err != nil {
+		return nil, err
+	}
+	return NewDashStyleRule(style, ranges), nil
+}
+
+func (DashStyleRule) Name() string {
+	return "DashStyle"
+}
+
+func (r DashStyleRule) Apply(content string) (string, error) {
+	if r.style == "" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	inComment := false
+	for i, line := range lines {
+		spans := nonProseSpans(line)
+		// comments that span lines
+		from := 0
+		if inComment {
+			end := strings.Index(line, "-->")
+			if end < 0 {
+				continue
+			}
+			from = end + 3
+			spans = append(spans, [2]int{0, from})
+			inComment = false
+		}
+		for _, m := range inlineComment.FindAllStringIndex(line[from:], -1) {
+			spans = append(spans, [2]int{from + m[0], from + m[1]})
+			from += m[1]
+		}
+		if k := strings.Index(line[from:], "<!--"); k >= 0 {
+			spans = append(spans, [2]int{from + k, len(line)})
+			inComment = true
+		}
+		switch b.lines[i].kind {
+		case lineTable, lineThematicBreak, lineSetextUnderline:
+			continue
+		}
+		if literalLine(b, i) {
+			continue
+		}
+		spans = mergeSpans(append(spans, mathSpans(line)...))
+		lines[i] = mapOutside(line, spans, r.rewrite)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r DashStyleRule) rewrite(s string) string {
+	if r.style == "ascii" {
+		if r.ranges {
+			s = numberRange.ReplaceAllStringFunc(s, func(m string) string {
+				return strings.Replace(m, "–", "-", 1)
+			})
+		}
+		out := strings.NewReplacer("–", "--", "—", "---").Replace(s)
+		if strings.Trim(out, "- ") == "" {
+			// that would be a thematic break
+			return s
+		}
+		return out
+	}
+	var out strings.Builder
+	last := 0
+	for _, m := range hyphenRun.FindAllStringIndex(s, -1) {
+		n := m[1] - m[0]
+		if n != 2 && n != 3 || !betweenWords(s, m[0], m[1]) {
+			continue
+		}
+		out.WriteString(s[last:m[0]])
+		out.WriteString(map[int]string{2: "–", 3: "—"}[n])
+		last = m[1]
+	}
+	out.WriteString(s[last:])
+	s = out.String()
+	if r.ranges {
+		s = replaceRanges(s)
+	}
+	return s
+}
+
+// betweenWords reports whether the dash s[start:end] joins two words,
+// directly or with spaces around it. A dash after a space and right
+// before a word is a command line flag.
+func betweenWords(s string, start, end int) bool {
+	word := func(c rune) bool {
+		return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune(`.,;:!?)]*_"'”’`, c)
+	}
+	opening := func(c rune) bool {
+		return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune(`([*_"'“‘`, c)
+	}
+	before, _ := utf8.DecodeLastRuneInString(s[:start])
+	after, _ := utf8.DecodeRuneInString(s[end:])
+	if start == 0 || end == len(s) {
+		return false
+	}
+	if word(before) && opening(after) {
+		return true
+	}
+	if before != ' ' || after != ' ' {
+		return false
+	}
+	// after another flag, "--" ends the options of a command
+	fields := strings.Fields(s[:start])
+	if len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], "-") {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:start-1])
+	next, _ := utf8.DecodeRuneInString(s[end+1:])
+	return start > 1 && end+1 < len(s) && word(prev) && opening(next)
+}
+
+// replaceRanges writes the hyphen of number ranges as an en dash, leaving
+// dates, versions and phone numbers, where more numbers are attached, alone.
+func replaceRanges(s string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range numberRange.FindAllStringIndex(s, -1) {
+		before, _ := utf8.DecodeLastRuneInString(s[:m[0]])
+		after, _ := utf8.DecodeRuneInString(s[m[1]:])
+		attached := func(c rune) bool {
+			return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("-–./:_", c)
+		}
+		if m[0] > 0 && attached(before) || m[1] < len(s) && attached(after) {
+			continue
+		}
+		out.WriteString(s[last:m[0]])
+		out.WriteString(strings.Replace(s[m[0]:m[1]], "-", "–", 1))
+		last = m[1]
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
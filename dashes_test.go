@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDashStyleRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		ranges   bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "en and em dashes",
+			style:    "smart",
+			input:    "Fast -- and safe. Words---joined. A--B.",
+			expected: "Fast – and safe. Words—joined. A–B.",
+		},
+		{
+			name:     "flags",
+			style:    "smart",
+			input:    "Run with --verbose or --dry-run, then -- oops -- use git log --oneline -- path.",
+			expected: "Run with --verbose or --dry-run, then – oops – use git log --oneline -- path.",
+		},
+		{
+			name:     "longer runs and lone dashes",
+			style:    "smart",
+			input:    "a ---- b\n-- at the start\nat the end --",
+			expected: "a ---- b\n-- at the start\nat the end --",
+		},
+		{
+			name:     "code, math and comments",
+			style:    "smart",
+			input:    "`a -- b` $x -- y$ <!-- a -- b --> [a -- b](http://x/a--b)\n<!-- open\na -- b\n-->c -- d",
+			expected: "`a -- b` $x -- y$ <!-- a -- b --> [a – b](http://x/a--b)\n<!-- open\na -- b\n-->c – d",
+		},
+		{
+			name:     "tables and breaks",
+			style:    "smart",
+			input:    "| a -- b |\n| --- |\n| --x |\n\n---\n\n***",
+			expected: "| a -- b |\n| --- |\n| --x |\n\n---\n\n***",
+		},
+		{
+			name:     "ranges",
+			style:    "smart",
+			ranges:   true,
+			input:    "Pages 3-5 and 10-12, not 2024-01-15, 555-123-4567, v1.2-3 or a3-5.",
+			expected: "Pages 3–5 and 10–12, not 2024-01-15, 555-123-4567, v1.2-3 or a3-5.",
+		},
+		{
+			name:     "ranges off",
+			style:    "smart",
+			input:    "Pages 3-5.",
+			expected: "Pages 3-5.",
+		},
+		{
+			name:     "ascii",
+			style:    "ascii",
+			ranges:   true,
+			input:    "Fast – and safe. Words—joined, pages 3–5.\n\n—\n\n`a—b`",
+			expected: "Fast -- and safe. Words---joined, pages 3-5.\n\n—\n\n`a—b`",
+		},
+		{
+			name:     "off",
+			input:    "Fast -- and safe.",
+			expected: "Fast -- and safe.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewDashStyleRule(tt.style, tt.ranges)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestDashStyleCLIDocument(t *testing.T) {
+	data, err := os.ReadFile("testdata/cli.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := string(data)
+	opts := Options{"dashes": "smart", "dash-ranges": "true"}
+	got, _, err := formatDocument(input, "", opts, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every flag survives; only the dashes of the prose change
+	expected := strings.NewReplacer(
+		"patterns -- quickly", "patterns – quickly",
+		"match---the", "match—the",
+		"1-100", "1–100",
+		"0-2", "0–2",
+	).Replace(input)
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+
+	// and the ASCII style takes them back out
+	opts["dashes"] = "ascii"
+	back, _, err := formatDocument(got, "", opts, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back != input {
+		t.Errorf("expected:\n%q\ngot:\n%q", input, back)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 21: normalize Pandoc definition lists
+// ----------------------------------------------------------------
+
+type DefinitionListRule struct{}
+
+func NewDefinitionListRule() Rule { return DefinitionListRule{} }
+
+func (DefinitionListRule) Name() string {
+	return "DefinitionList"
+}
+
+// Apply writes every definition marker as ":" (or "~") followed by three
+// spaces, so the text lines up with the four-column indentation Pandoc
+// expects of the rest of the definition, and separates each term from the
+// definitions before it with exactly one blank line. Whether a term is
+// followed by a blank line is left alone: it decides between tight and
+// loose definitions.
+func (DefinitionListRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	gaps := newGapEditor(lines, b)
+	for i, line := range lines {
+		info := b.lines[i]
+		switch {
+		case info.kind == lineDefinitionTerm:
+			if p := gaps.prevNonBlank(i); p >= 0 {
+				gaps.ensureOne(p, i, blankFor(line, info))
+			}
+		case info.definition:
+			body := strings.TrimLeft(line[info.bodyStart:], " \t")
+			text := strings.TrimLeft(body[1:], " \t")
+			lines[i] = line[:info.bodyStart] + strings.Repeat(" ", info.container) +
+				body[:1] + "   " + text
+		}
+	}
+	return strings.Join(gaps.apply(), "\n"), nil
+}
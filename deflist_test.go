@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestDefinitionListRule(t *testing.T) {
+	rule := NewDefinitionListRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "marker spacing",
+			input:    "Term\n: def\n  ~  other",
+			expected: "Term\n:   def\n~   other",
+		},
+		{
+			name:     "loose definition keeps its blank line",
+			input:    "Term\n\n:   def",
+			expected: "Term\n\n:   def",
+		},
+		{
+			name:     "blank line between pairs",
+			input:    "Apple\n:   red\n\n\n\nBanana\n:   yellow",
+			expected: "Apple\n:   red\n\nBanana\n:   yellow",
+		},
+		{
+			name:     "term after heading",
+			input:    "# Fruit\nApple\n:   red",
+			expected: "# Fruit\n\nApple\n:   red",
+		},
+		{
+			name:     "definition paragraphs",
+			input:    "Term\n:   first\n\n    second\n\nOther\n:   def",
+			expected: "Term\n:   first\n\n    second\n\nOther\n:   def",
+		},
+		{
+			name:     "definition in blockquote",
+			input:    "> Term\n> :  def",
+			expected: "> Term\n> :   def",
+		},
+		{
+			name:     "colon in a paragraph",
+			input:    "one\ntwo\n: three",
+			expected: "one\ntwo\n: three",
+		},
+		{
+			name:     "colon in code",
+			input:    "```\nTerm\n: def\n```",
+			expected: "```\nTerm\n: def\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hunk is a run of lines that differs between two versions of a document:
+// a[aStart:aEnd] was replaced by b[bStart:bEnd]. One of the ranges may be
+// empty.
+type hunk struct {
+	aStart, aEnd int
+	bStart, bEnd int
+}
+
+// lineDiff returns the hunks that turn a into b, in order, using Myers'
+// algorithm for a shortest edit script in its linear space form: the middle
+// snake of each part splits it in two until only insertions or deletions
+// are left.
+func lineDiff(a, b []string) []hunk {
+	del, ins := make([]bool, len(a)), make([]bool, len(b))
+	diffLines(a, b, 0, len(a), 0, len(b), del, ins)
+	// of equal lines, keep the first and change the later ones
+	slideDown(a, del)
+	slideDown(b, ins)
+
+	// lines marked in neither are the common ones, paired in order
+	var hunks []hunk
+	for x, y := 0, 0; x < len(a) || y < len(b); {
+		if x < len(a) && y < len(b) && !del[x] && !ins[y] {
+			x++
+			y++
+			continue
+		}
+		h := hunk{aStart: x, bStart: y}
+		for x < len(a) && del[x] {
+			x++
+		}
+		for y < len(b) && ins[y] {
+			y++
+		}
+		h.aEnd, h.bEnd = x, y
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// diffLines marks in del the lines of a[aLo:aHi] and in ins the lines of
+// b[bLo:bHi] that a shortest edit script between them deletes and inserts.
+func diffLines(a, b []string, aLo, aHi, bLo, bHi int, del, ins []bool) {
+	// trim the common ends, which is most of a document formatting touches
+	for aLo < aHi && bLo < bHi && a[aLo] == b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && a[aHi-1] == b[bHi-1] {
+		aHi--
+		bHi--
+	}
+	if aLo == aHi || bLo == bHi {
+		for x := aLo; x < aHi; x++ {
+			del[x] = true
+		}
+		for y := bLo; y < bHi; y++ {
+			ins[y] = true
+		}
+		return
+	}
+	x, y, ok := middleSnake(a[aLo:aHi], b[bLo:bHi])
+	if !ok {
+		for x := aLo; x < aHi; x++ {
+			del[x] = true
+		}
+		for y := bLo; y < bHi; y++ {
+			ins[y] = true
+		}
+		return
+	}
+	diffLines(a, b, aLo, aLo+x, bLo, bLo+y, del, ins)
+	diffLines(a, b, aLo+x, aHi, bLo+y, bHi, del, ins)
+}
+
+// slideDown moves each run of lines marked in changed past the unmarked
+// lines that follow it as long as they equal its first line, which leaves
+// the same lines of s unmarked, one copy later.
+func slideDown(s []string, changed []bool) {
+	for i := 0; i < len(s); i++ {
+		if !changed[i] {
+			continue
+		}
+		j := i
+		for j < len(s) && changed[j] {
+			j++
+		}
+		for j < len(s) && s[i] == s[j] {
+			changed[i], changed[j] = false, true
+			for i++; j < len(s) && changed[j]; j++ {
+			}
+		}
+		i = j
+	}
+}
+
+// middleSnake searches from both ends of a and b at once and returns the
+// point where the two searches meet, which lies on a shortest edit script.
+// It reports false when they never meet, which only happens when a and b
+// have no line in common.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	dMax := (n + m + 1) / 2
+	off := dMax
+	// vf[off+k] is the furthest x reached on diagonal k from the start,
+	// vb[off+k] the furthest reached on diagonal k from the end
+	vf, vb := make([]int, 2*dMax+2), make([]int, 2*dMax+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+	delta := n - m
+	// with an odd delta the forward search is the first to overlap
+	front := delta%2 != 0
+	// diagonals that ran off the edges are skipped from then on
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d < dMax; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			i := off + k
+			var x1 int
+			if k == -d || (k != d && vf[i-1] < vf[i+1]) {
+				x1 = vf[i+1]
+			} else {
+				x1 = vf[i-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			vf[i] = x1
+			switch {
+			case x1 > n:
+				fEnd += 2
+			case y1 > m:
+				fStart += 2
+			case front:
+				if j := off + delta - k; j >= 0 && j < len(vb) && vb[j] != -1 && x1 >= n-vb[j] {
+					return x1, y1, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			i := off + k
+			var x2 int
+			if k == -d || (k != d && vb[i-1] < vb[i+1]) {
+				x2 = vb[i+1]
+			} else {
+				x2 = vb[i-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			vb[i] = x2
+			switch {
+			case x2 > n:
+				bEnd += 2
+			case y2 > m:
+				bStart += 2
+			case !front:
+				if j := off + delta - k; j >= 0 && j < len(vf) && vf[j] != -1 && vf[j] >= n-x2 {
+					x1 := vf[j]
+					return x1, x1 - (j - off), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// diffContext is the number of unchanged lines shown around the changes
+// of a unified diff.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff that turns old, named oldName, into
+// new, named newName, or "" when they are equal. A last line without a
+// newline is marked "\ No newline at end of file", as diff(1) does.
+func unifiedDiff(oldName, newName, old, new string) string {
+	if old == new {
+		return ""
+	}
+	// lines keep their newlines, so a missing final one is a change
+	split := func(s string) []string {
+		lines := strings.SplitAfter(s, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return lines
+	}
+	a, b := split(old), split(new)
+	hunks := lineDiff(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	line := func(prefix, l string) {
+		sb.WriteString(prefix + l)
+		if !strings.HasSuffix(l, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	for i := 0; i < len(hunks); {
+		// hunks closer than twice the context share one
+		j := i
+		for j+1 < len(hunks) && hunks[j+1].aStart-hunks[j].aEnd <= 2*diffContext {
+			j++
+		}
+		aStart := max(hunks[i].aStart-diffContext, 0)
+		aEnd := min(hunks[j].aEnd+diffContext, len(a))
+		bStart := hunks[i].bStart - (hunks[i].aStart - aStart)
+		bEnd := hunks[j].bEnd + (aEnd - hunks[j].aEnd)
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aEnd), hunkRange(bStart, bEnd))
+		at := aStart
+		for _, h := range hunks[i : j+1] {
+			for ; at < h.aStart; at++ {
+				line(" ", a[at])
+			}
+			for _, l := range a[h.aStart:h.aEnd] {
+				line("-", l)
+			}
+			for _, l := range b[h.bStart:h.bEnd] {
+				line("+", l)
+			}
+			at = h.aEnd
+		}
+		for ; at < aEnd; at++ {
+			line(" ", a[at])
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// hunkRange formats the lines start to end, counted from 0, for a hunk
+// header: the first line counted from 1 and the number of lines, left out
+// when it is 1. An empty range names the line before it.
+func hunkRange(start, end int) string {
+	switch end - start {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end-start)
+}
+
+// Edit replaces the lines StartLine up to EndLine, exclusive and counted
+// from 0, by NewLines. Lines are the document split at "\n", so one that
+// ends in a newline ends with an empty line, and an empty line range is
+// an insertion before StartLine.
+type Edit struct {
+	StartLine, EndLine int
+	NewLines           []string
+}
+
+// lineEdits returns the edits that turn old into new, sorted and without
+// overlaps, one per changed run of lines; none when the two are equal.
+func lineEdits(old, new string) []Edit {
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+	var edits []Edit
+	for _, h := range lineDiff(a, b) {
+		edits = append(edits, Edit{
+			StartLine: h.aStart,
+			EndLine:   h.aEnd,
+			NewLines:  append([]string{}, b[h.bStart:h.bEnd]...),
+		})
+	}
+	return edits
+}
+
+// LineRange is the lines Start to End, counted from 1 and inclusive.
+type LineRange struct {
+	Start, End int
+}
+
+// keepLines returns content with only those of the changes that turned
+// it into out that touch one of ranges, widened by expandRanges: that
+// replace one of its lines, or insert lines inside it or right before or
+// after it. The other lines stay as they are in content, trailing newline
+// included.
+func keepLines(content, out string, ranges []LineRange) string {
+	ranges = expandRanges(content, ranges)
+	var kept []Edit
+	for _, e := range lineEdits(content, out) {
+		for _, r := range ranges {
+			start, end := r.Start-1, r.End
+			if e.StartLine == e.EndLine && e.StartLine >= start && e.StartLine <= end ||
+				e.StartLine < end && e.EndLine > start {
+				kept = append(kept, e)
+				break
+			}
+		}
+	}
+	return applyEdits(content, kept)
+}
+
+// expandRanges widens ranges to the constructs of content they cut
+// through, so that none takes in half of one: fenced code and front
+// matter run from fence to fence, a table's header goes with its
+// delimiter row, a setext heading's text with its underline, and a
+// paragraph or list item with the lines that continue it. Headings and
+// other blocks that merely sit next to each other are not joined.
+func expandRanges(content string, ranges []LineRange) []LineRange {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	// joined reports whether lines i and i+1, counted from 0, belong to
+	// one construct
+	joined := func(i int) bool {
+		this, next := b.lines[i], b.lines[i+1]
+		switch {
+		case this.fence >= 0 && this.fence == next.fence:
+			return true
+		case this.kind == lineFrontMatter && next.kind == lineFrontMatter:
+			return true
+		case this.kind == lineTable && next.kind == lineTable:
+			return isTableSeparator(lines[i+1][next.bodyStart:])
+		case next.kind == lineSetextUnderline:
+			return this.kind == lineText
+		case this.kind == lineText && next.kind == lineText:
+			return (next.quote == this.quote || next.lazy) && !next.item && !next.footnote && !next.definition &&
+				!isHeadingLine(lines[i], this) && !isHeadingLine(lines[i+1], next)
+		}
+		return false
+	}
+	wide := make([]LineRange, 0, len(ranges))
+	for _, r := range ranges {
+		r.End = min(r.End, len(lines))
+		if r.Start > r.End {
+			// past the end of the document
+			continue
+		}
+		for r.Start > 1 && joined(r.Start-2) {
+			r.Start--
+		}
+		for r.End < len(lines) && joined(r.End-1) {
+			r.End++
+		}
+		wide = append(wide, r)
+	}
+	return wide
+}
+
+// applyEdits applies edits, sorted and with line numbers counted in
+// content, to content.
+func applyEdits(content string, edits []Edit) string {
+	lines := strings.Split(content, "\n")
+	var out []string
+	last := 0
+	for _, e := range edits {
+		out = append(out, lines[last:e.StartLine]...)
+		out = append(out, e.NewLines...)
+		last = e.EndLine
+	}
+	out = append(out, lines[last:]...)
+	return strings.Join(out, "\n")
+}
+
+// Deleted marks the lines of a LineMap that have no counterpart after
+// the edits.
+const Deleted = -1
+
+// LineMap maps each line of a document, counted from 0, to its index
+// after a set of edits, or to Deleted. Lines an edit replaces map to the
+// new lines in the same position, as far as there are any; lines that
+// edits insert have nothing mapping to them.
+type LineMap []int
+
+// NewLineMap returns the LineMap of edits, as returned by FormatEdits, on
+// a document of n lines.
+func NewLineMap(n int, edits []Edit) LineMap {
+	m := make(LineMap, n)
+	shift, last := 0, 0
+	for _, e := range edits {
+		for i := last; i < e.StartLine; i++ {
+			m[i] = i + shift
+		}
+		for i := e.StartLine; i < e.EndLine; i++ {
+			m[i] = Deleted
+			if k := i - e.StartLine; k < len(e.NewLines) {
+				m[i] = e.StartLine + shift + k
+			}
+		}
+		shift += len(e.NewLines) - (e.EndLine - e.StartLine)
+		last = e.EndLine
+	}
+	for i := last; i < n; i++ {
+		m[i] = i + shift
+	}
+	return m
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []hunk
+	}{
+		{"equal", "a\nb", "a\nb", nil},
+		{"insert", "a\nc", "a\nb\nc", []hunk{{1, 1, 1, 2}}},
+		{"delete", "a\nb\nc", "a\nc", []hunk{{1, 2, 1, 1}}},
+		{"replace", "a\nb\nc", "a\nB\nc", []hunk{{1, 2, 1, 2}}},
+		{"two hunks", "a\nb\nc\nd\ne", "a\nB\nc\nd\ne\nf", []hunk{{1, 2, 1, 2}, {5, 5, 5, 6}}},
+		{"from empty", "", "a\nb", []hunk{{0, 1, 0, 2}}},
+		{"moved line", "x\na\nb\nc", "a\nb\nc\nx", []hunk{{0, 1, 0, 0}, {4, 4, 3, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n")
+			got := lineDiff(a, b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			// applying the hunks must turn a into b
+			var out []string
+			last := 0
+			for _, h := range got {
+				out = append(out, a[last:h.aStart]...)
+				out = append(out, b[h.bStart:h.bEnd]...)
+				last = h.aEnd
+			}
+			out = append(out, a[last:]...)
+			if !reflect.DeepEqual(out, b) {
+				t.Errorf("applied: got %q, want %q", out, b)
+			}
+		})
+	}
+}
+
+func TestLineDiffShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		l := make([]string, r.Intn(30))
+		for i := range l {
+			l[i] = string(rune('a' + r.Intn(4)))
+		}
+		return l
+	}
+	for range 500 {
+		a, b := lines(), lines()
+		// the longest common subsequence, by dynamic programming
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		var out []string
+		last, edits := 0, 0
+		for _, h := range lineDiff(a, b) {
+			out = append(out, a[last:h.aStart]...)
+			out = append(out, b[h.bStart:h.bEnd]...)
+			last = h.aEnd
+			edits += h.aEnd - h.aStart + h.bEnd - h.bStart
+		}
+		out = append(out, a[last:]...)
+		if !reflect.DeepEqual(out, b) && len(out)+len(b) > 0 {
+			t.Fatalf("%q to %q: applied hunks give %q", a, b, out)
+		}
+		if want := len(a) + len(b) - 2*lcs[0][0]; edits != want {
+			t.Fatalf("%q to %q: %d lines changed, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestFormatEdits(t *testing.T) {
+	fmter, err := newFormatterFromOptions(Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  []Edit
+	}{
+		{
+			name:  "formatted",
+			input: "# Title\n\n- item\n",
+		},
+		{
+			name:  "blank line after a heading",
+			input: "# Title\ntext\n",
+			want:  []Edit{{1, 1, []string{""}}},
+		},
+		{
+			name:  "changes apart",
+			input: "# Title\n\n* one\n* two\n\nkeep\n\n*   three",
+			want: []Edit{
+				{2, 4, []string{"- one", "- two"}},
+				{7, 8, []string{"- three", ""}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fmter.FormatEdits(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].StartLine < got[i-1].EndLine {
+					t.Errorf("edits %d and %d overlap or are out of order", i-1, i)
+				}
+			}
+			want, _ := fmter.Format(tt.input)
+			if out := applyEdits(tt.input, got); out != want {
+				t.Errorf("applied:\n%q\nwant:\n%q", out, want)
+			}
+		})
+	}
+}
+
+func TestFormatLineMap(t *testing.T) {
+	fmter, err := newFormatterFromOptions(Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("text\n", 50)
+	tests := []struct {
+		name  string
+		input string
+		// want maps some of the input lines
+		want map[int]int
+	}{
+		{
+			name:  "unchanged",
+			input: "# Title\n\n" + body,
+			want:  map[int]int{0: 0, 2: 2, 51: 51, 52: 52},
+		},
+		{
+			name:  "blank line inserted at the top",
+			input: "# Title\n" + body,
+			want:  map[int]int{0: 0, 1: 2, 50: 51, 51: 52},
+		},
+		{
+			name:  "blank lines deleted at the top",
+			input: "# Title\n\n\n\n\n" + body,
+			want:  map[int]int{0: 0, 1: 1, 2: Deleted, 4: Deleted, 5: 2, 54: 51},
+		},
+		{
+			name:  "insertions and deletions",
+			input: "# A\n" + body + "# B\n\n\n" + body + "* item",
+			want:  map[int]int{1: 2, 50: 51, 51: 52, 52: 53, 53: Deleted, 54: 54, 103: 103, 104: 104},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, m, err := fmter.FormatLineMap(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in, lines := strings.Split(tt.input, "\n"), strings.Split(out, "\n")
+			if len(m) != len(in) {
+				t.Fatalf("got %d entries for %d lines", len(m), len(in))
+			}
+			for old, want := range tt.want {
+				if m[old] != want {
+					t.Errorf("line %d: got %d, want %d", old, m[old], want)
+				}
+			}
+			// kept lines stay in order
+			last := -1
+			for old, to := range m {
+				if to == Deleted {
+					continue
+				}
+				if to <= last || to >= len(lines) {
+					t.Errorf("line %d maps to %d after %d", old, to, last)
+				}
+				last = to
+			}
+			edits, _ := fmter.FormatEdits(tt.input)
+			if !reflect.DeepEqual(m, NewLineMap(len(in), edits)) {
+				t.Error("mapping differs from the one of the edit list")
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	mustApply := func(r Rule, in string) string {
+		out, err := r.Apply(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	headings := "# A\ntext\n## B\n1\n2\n3\n4\n5\n6\n7\n8\n9\n## C\nend\n"
+	list := "Intro\n\n-   one\n-  two\n- three\n"
+
+	tests := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name: "insertions",
+			old:  headings,
+			new:  mustApply(NewBlankLineAfterHeadingRule(), headings),
+			expected: "--- a.md.orig\n+++ a.md\n" +
+				"@@ -1,6 +1,8 @@\n # A\n+\n text\n ## B\n+\n 1\n 2\n 3\n" +
+				"@@ -11,4 +13,5 @@\n 8\n 9\n ## C\n+\n end\n",
+		},
+		{
+			name: "rewrites",
+			old:  list,
+			new:  mustApply(NewSingleSpaceAfterListItemRule(), list),
+			expected: "--- a.md.orig\n+++ a.md\n" +
+				"@@ -1,5 +1,5 @@\n Intro\n \n--   one\n--  two\n+- one\n+- two\n - three\n",
+		},
+		{
+			name: "final newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			expected: "--- a.md.orig\n+++ a.md\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "from nothing",
+			old:      "",
+			new:      "\n",
+			expected: "--- a.md.orig\n+++ a.md\n@@ -0,0 +1 @@\n+\n",
+		},
+		{
+			name: "unchanged",
+			old:  headings,
+			new:  headings,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("a.md.orig", "a.md", tt.old, tt.new)
+			if got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatLines(t *testing.T) {
+	fmter, err := newFormatterFromOptions(Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unformatted throughout, without a final newline
+	input := "# A\ntext\n\nSee\n| a | b |\n|---|---|\n| 1 | 2 |\n\n# B\nmore\n\n*   item"
+	tests := []struct {
+		name   string
+		input  string
+		ranges []LineRange
+		want   string
+	}{
+		{
+			name:   "heading",
+			ranges: []LineRange{{1, 1}},
+			want:   "# A\n\ntext\n\nSee\n| a | b |\n|---|---|\n| 1 | 2 |\n\n# B\nmore\n\n*   item",
+		},
+		{
+			name:   "table delimiter row",
+			ranges: []LineRange{{6, 6}},
+			want:   "# A\ntext\n\nSee\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n# B\nmore\n\n*   item",
+		},
+		{
+			name:   "blank line before the second heading",
+			ranges: []LineRange{{8, 8}},
+			want:   input,
+		},
+		{
+			name:   "two ranges",
+			ranges: []LineRange{{2, 2}, {10, 10}},
+			want:   "# A\n\ntext\n\nSee\n| a | b |\n|---|---|\n| 1 | 2 |\n\n# B\n\nmore\n\n*   item",
+		},
+		{
+			name:   "last line",
+			ranges: []LineRange{{12, 40}},
+			want:   "# A\ntext\n\nSee\n| a | b |\n|---|---|\n| 1 | 2 |\n\n# B\nmore\n\n- item\n",
+		},
+		{
+			name:   "past the end",
+			ranges: []LineRange{{50, 60}},
+			want:   input,
+		},
+		{
+			name:   "adjacent headings",
+			input:  "# A\nx\n# B\ny\n# C\nz\n",
+			ranges: []LineRange{{3, 4}},
+			want:   "# A\nx\n# B\n\ny\n# C\nz\n",
+		},
+		{
+			name:   "list item continuation",
+			input:  "* a\n  b\n* c\n",
+			ranges: []LineRange{{2, 2}},
+			want:   "- a\n  b\n* c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.input == "" {
+				tt.input = input
+			}
+			got, err := fmter.FormatLines(tt.input, tt.ranges)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.want, got)
+			}
+		})
+	}
+
+	all, _ := fmter.Format(input)
+	if got, _ := fmter.FormatLines(input, []LineRange{{1, 12}}); got != all {
+		t.Errorf("all lines: expected:\n%q\ngot:\n%q", all, got)
+	}
+}
+
+func TestLineRangesFlag(t *testing.T) {
+	var l lineRanges
+	for _, s := range []string{"40:80", "1:10, 12", "5:5"} {
+		if err := l.Set(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+	want := lineRanges{{40, 80}, {1, 10}, {12, 12}, {5, 5}}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("got %v, want %v", l, want)
+	}
+	if got := l.String(); got != "40:80,1:10,12:12,5:5" {
+		t.Errorf("got %q", got)
+	}
+	for _, s := range []string{"", "0:3", "8:4", "a:b", "3:", "1:2:3"} {
+		if err := new(lineRanges).Set(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 39: separate Pandoc fenced divs from the text around them
+// ----------------------------------------------------------------
+
+type BlankLinesAroundDivsRule struct{}
+
+// NewBlankLinesAroundDivsRule constructs a rule that puts one blank line
+// before the opening fence of each Pandoc div and after its closing fence.
+// Nested fences may follow each other directly; the content of a div is
+// left to the other rules.
+func NewBlankLinesAroundDivsRule() Rule { return BlankLinesAroundDivsRule{} }
+
+func (BlankLinesAroundDivsRule) Name() string {
+	return "BlankLinesAroundDivs"
+}
+
+func (BlankLinesAroundDivsRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	// opening reports whether line i opens a div
+	opening := func(i int) bool {
+		attrs, _ := divFence(lines[i][b.lines[i].bodyStart:])
+		return attrs != ""
+	}
+	isDiv := func(i int) bool { return b.lines[i].kind == lineDivFence }
+	gaps := newGapEditor(lines, b)
+	for i := range lines {
+		if !isDiv(i) {
+			continue
+		}
+		if opening(i) {
+			if p := gaps.prevNonBlank(i); p >= 0 && !(isDiv(p) && opening(p)) && !firstInContainer(b, p, i) {
+				gaps.ensureOne(p, i, blankFor(lines[i], b.lines[i]))
+			}
+			continue
+		}
+		if n := gaps.nextNonBlank(i); n >= 0 && !(isDiv(n) && !opening(n)) && !lastInContainer(b, i, n) {
+			gaps.ensureOne(i, n, blankFor(lines[i], b.lines[i]))
+		}
+	}
+	return strings.Join(gaps.apply(), "\n"), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestBlankLinesAroundDivsRule(t *testing.T) {
+	rule := NewBlankLinesAroundDivsRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "separated from paragraphs",
+			input:    "Before.\n::: {.warning}\nCareful.\n:::\nAfter.",
+			expected: "Before.\n\n::: {.warning}\nCareful.\n:::\n\nAfter.",
+		},
+		{
+			name:     "extra blank lines collapse",
+			input:    "Before.\n\n\n::: note\nx\n:::\n\n\nAfter.",
+			expected: "Before.\n\n::: note\nx\n:::\n\nAfter.",
+		},
+		{
+			name:     "nested fences stay together",
+			input:    "::::: outer\n::: inner\nx\n:::\n:::::\n::: next\ny\n:::",
+			expected: "::::: outer\n::: inner\nx\n:::\n:::::\n\n::: next\ny\n:::",
+		},
+		{
+			name:     "a bare fence outside of divs is text",
+			input:    "Text\n:::\nmore",
+			expected: "Text\n:::\nmore",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDivAttributesAreProtected(t *testing.T) {
+	rule := NewReplacementRule("SmartQuotesToAscii", map[string]string{"“": `"`, "”": `"`})
+	input := "::: {title=“Note”}\n“Inside”\n:::"
+	expected := "::: {title=“Note”}\n\"Inside\"\n:::"
+	got, err := rule.Apply(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// EditorConfig
+// ----------------------------------------------------------------
+
+// useEditorConfig selects whether documentFormatter takes the settings of
+// the .editorconfig files above a document into account.
+var useEditorConfig = true
+
+// editorConfigSection is a [glob] section of an .editorconfig file.
+type editorConfigSection struct {
+	glob  *regexp.Regexp
+	props map[string]string
+}
+
+// editorConfigProperties returns the EditorConfig properties that apply
+// to the file at path, read from the .editorconfig files in its directory
+// and the ones above it, up to the first that says root = true. Nearer
+// files and later sections win; a property set to "unset" is removed.
+func editorConfigProperties(path string) (map[string]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	abs = filepath.ToSlash(abs)
+	var files [][]editorConfigSection // nearest first
+	for dir := filepath.Dir(filepath.FromSlash(abs)); ; dir = filepath.Dir(dir) {
+		sections, root, err := readEditorConfig(filepath.Join(dir, ".editorconfig"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sections)
+		if root || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	props := map[string]string{}
+	for k := len(files) - 1; k >= 0; k-- {
+		for _, s := range files[k] {
+			if !s.glob.MatchString(abs) {
+				continue
+			}
+			for name, v := range s.props {
+				if v == "unset" {
+					delete(props, name)
+				} else {
+					props[name] = v
+				}
+			}
+		}
+	}
+	return props, nil
+}
+
+// readEditorConfig parses the .editorconfig file at path, which need not
+// exist. Lines that are not sections, properties or comments are
+// ignored, as editors do.
+func readEditorConfig(path string) (sections []editorConfigSection, root bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	dir := filepath.ToSlash(filepath.Dir(path))
+	var cur *editorConfigSection
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			sections = append(sections, editorConfigSection{
+				glob:  editorConfigGlob(dir, line[1:len(line)-1]),
+				props: map[string]string{},
+			})
+			cur = &sections[len(sections)-1]
+		default:
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			k = strings.ToLower(strings.TrimSpace(k))
+			v = strings.ToLower(strings.TrimSpace(v))
+			switch {
+			case cur != nil:
+				cur.props[k] = v
+			case k == "root":
+				root = v == "true"
+			}
+		}
+	}
+	return sections, root, sc.Err()
+}
+
+// editorConfigGlob translates the glob of a section of the .editorconfig
+// file in dir into a regular expression matching absolute slash paths.
+// A glob without a slash matches a file name in any directory below dir.
+// Besides "*", "**" and "?" it knows [chars], [!chars], {a,b} and
+// {n1..n2}.
+func editorConfigGlob(dir, glob string) *regexp.Regexp {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	glob = strings.TrimPrefix(glob, "/")
+	var sb strings.Builder
+	sb.WriteString("^" + regexp.QuoteMeta(strings.TrimSuffix(dir, "/")) + "/")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				break
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end
+		case c == '{':
+			end := strings.IndexByte(glob[i:], '}')
+			if end < 0 {
+				sb.WriteString(`\{`)
+				break
+			}
+			if alt, ok := numericRange(glob[i+1 : i+end]); ok {
+				sb.WriteString(alt)
+				i += end
+				break
+			}
+			if !strings.Contains(glob[i:i+end], ",") {
+				sb.WriteString(regexp.QuoteMeta(glob[i : i+end+1]))
+				i += end
+				break
+			}
+			sb.WriteString("(?:")
+			braces++
+		case c == ',' && braces > 0:
+			sb.WriteString("|")
+		case c == '}' && braces > 0:
+			sb.WriteString(")")
+			braces--
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		// a malformed glob matches nothing
+		return regexp.MustCompile(`[^\s\S]`)
+	}
+	return re
+}
+
+// numericRange translates "n1..n2" into an alternation of the integers
+// from n1 to n2.
+func numericRange(s string) (string, bool) {
+	lo, hi, ok := strings.Cut(s, "..")
+	if !ok {
+		return "", false
+	}
+	a, err1 := strconv.Atoi(lo)
+	b, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || b < a || b-a > 1000 {
+		return "", false
+	}
+	alts := make([]string, 0, b-a+1)
+	for n := a; n <= b; n++ {
+		alts = append(alts, strconv.Itoa(n))
+	}
+	return "(?:" + strings.Join(alts, "|") + ")", true
+}
+
+// editorConfigOptions returns the mdfmt options the EditorConfig
+// properties for the file at path map to.
+func editorConfigOptions(path string) (Options, error) {
+	props, err := editorConfigProperties(path)
+	if err != nil {
+		return nil, err
+	}
+	opts := Options{}
+	if props["indent_style"] == "space" {
+		opts["tabs"] = "expand"
+	}
+	for _, k := range []string{"indent_size", "tab_width"} {
+		if n, err := strconv.Atoi(props[k]); err == nil && n > 0 {
+			opts["tab-width"] = props[k]
+		}
+	}
+	switch props["end_of_line"] {
+	case "lf", "crlf":
+		opts["end-of-line"] = props["end_of_line"]
+	}
+	switch props["trim_trailing_whitespace"] {
+	case "true":
+		opts["trailing-whitespace"] = "trim"
+	case "false":
+		opts["trailing-whitespace"] = "keep"
+	}
+	switch props["insert_final_newline"] {
+	case "true", "false":
+		opts["final-newline"] = props["insert_final_newline"]
+	}
+	return opts, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEditorConfigOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		// above the root: never read
+		".editorconfig": "[*]\nend_of_line = cr\n",
+		"repo/.editorconfig": `root = true
+
+; defaults
+[*]
+end_of_line = crlf
+insert_final_newline = true
+indent_style = tab
+
+[*.{md,markdown}]
+indent_style = space
+indent_size = 2
+trim_trailing_whitespace = true
+
+[/docs/chapter{1..3}.md]
+tab_width = 8
+`,
+		"repo/docs/.editorconfig": "[*]\nend_of_line = LF\n\n[notes/**]\ntrim_trailing_whitespace = unset\n",
+	})
+
+	tests := []struct {
+		path string
+		want Options
+	}{
+		{"repo/x.txt", Options{"end-of-line": "crlf", "final-newline": "true"}},
+		{"repo/a/b.md", Options{
+			"end-of-line": "crlf", "final-newline": "true", "tabs": "expand",
+			"tab-width": "2", "trailing-whitespace": "trim",
+		}},
+		{"repo/docs/chapter2.md", Options{
+			"end-of-line": "lf", "final-newline": "true", "tabs": "expand",
+			"tab-width": "8", "trailing-whitespace": "trim",
+		}},
+		{"repo/docs/chapter4.md", Options{
+			"end-of-line": "lf", "final-newline": "true", "tabs": "expand",
+			"tab-width": "2", "trailing-whitespace": "trim",
+		}},
+		{"repo/docs/notes/n.markdown", Options{
+			"end-of-line": "lf", "final-newline": "true", "tabs": "expand", "tab-width": "2",
+		}},
+		// only the .editorconfig above the root, whose cr maps to nothing
+		{"elsewhere.md", Options{}},
+	}
+	for _, tt := range tests {
+		got, err := editorConfigOptions(filepath.Join(dir, filepath.FromSlash(tt.path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEditorConfigGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*", "/r/a.md", true},
+		{"*", "/r/d/a.md", true},
+		{"*.md", "/r/d/a.md", true},
+		{"/*.md", "/r/d/a.md", false},
+		{"d/*.md", "/r/d/a.md", true},
+		{"d/*.md", "/r/d/e/a.md", false},
+		{"d/**.md", "/r/d/e/a.md", true},
+		{"a?.md", "/r/ab.md", true},
+		{"[abc].md", "/r/b.md", true},
+		{"[!abc].md", "/r/b.md", false},
+		{"*.{md,txt}", "/r/a.txt", true},
+		{"*.{md,txt}", "/r/a.go", false},
+		{"{single}.md", "/r/{single}.md", true},
+		{"ch{1..3}.md", "/r/ch3.md", true},
+		{"ch{1..3}.md", "/r/ch10.md", false},
+		{`\*.md`, "/r/*.md", true},
+		{`\*.md`, "/r/a.md", false},
+	}
+	for _, tt := range tests {
+		if got := editorConfigGlob("/r", tt.glob).MatchString(tt.path); got != tt.match {
+			t.Errorf("%q on %q: got %v, want %v", tt.glob, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestEditorConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".editorconfig": "root = true\n[*.md]\nend_of_line = crlf\ntrim_trailing_whitespace = true\n",
+	})
+	path := filepath.Join(dir, "a.md")
+	format := func(opts Options) string {
+		t.Helper()
+		out, _, err := formatDocument("text \n\nmore", path, opts, nil, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if got, want := format(Options{}), "text\r\n\r\nmore\r\n"; got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+	if got, want := format(Options{"end-of-line": "lf"}), "text\n\nmore\n"; got != want {
+		t.Errorf("options override .editorconfig; expected:\n%q\ngot:\n%q", want, got)
+	}
+
+	defer func(use bool) { useEditorConfig = use }(useEditorConfig)
+	useEditorConfig = false
+	if got, want := format(Options{}), "text \n\nmore\n"; got != want {
+		t.Errorf("without EditorConfig; expected:\n%q\ngot:\n%q", want, got)
+	}
+}
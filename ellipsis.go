@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 44: write ellipses as one character or as three dots
+// ----------------------------------------------------------------
+
+// dotRun matches a run of dots and ellipsis characters.
+var dotRun = regexp.MustCompile(`[.…]+`)
+
+type EllipsisRule struct {
+	// style is "unicode", "ascii", or "" to leave ellipses alone.
+	style string
+}
+
+// NewEllipsisRule constructs a rule that writes "..." in prose as "…" with
+// style "unicode", and "…" as "..." with style "ascii". Longer runs of
+// dots and code are left alone; so is the space, if any, before the
+// ellipsis.
+func NewEllipsisRule(style string) Rule {
+	return EllipsisRule{style: style}
+}
+
+func newEllipsisRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("ellipsis", "", "", "unicode", "ascii")
+	if err != nil {
+		return nil, err
+	}
+	return NewEllipsisRule(style), nil
+}
+
+func (EllipsisRule) Name() string {
+	return "Ellipsis"
+}
+
+func (r EllipsisRule) Trigger() []string {
+	switch r.style {
+	case "unicode":
+		return []string{"..."}
+	case "ascii":
+		return []string{"…"}
+	}
+	return nil
+}
+
+func (r EllipsisRule) Apply(content string) (string, error) {
+	if r.style == "" {
+		return content, nil
+	}
+	from, to := "...", "…"
+	if r.style == "ascii" {
+		from, to = to, from
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		spans := mergeSpans(append(nonProseSpans(line), mathSpans(line)...))
+		lines[i] = mapOutside(line, spans, func(s string) string {
+			return dotRun.ReplaceAllStringFunc(s, func(m string) string {
+				if m == from {
+					return to
+				}
+				return m
+			})
+		})
+	}
+	return strings.Join(lines, "\n"), nil
+}
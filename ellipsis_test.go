@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestEllipsisRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "unicode",
+			style:    "unicode",
+			input:    "Wait... what? And then ... nothing.",
+			expected: "Wait… what? And then … nothing.",
+		},
+		{
+			name:     "longer runs",
+			style:    "unicode",
+			input:    "Hmm.... and so on..., then ..…",
+			expected: "Hmm.... and so on…, then ..…",
+		},
+		{
+			name:     "code",
+			style:    "unicode",
+			input:    "Call `f(args...)` or\n\n```go\nfunc f(xs ...int) {}\n```\n\n    g(xs...)\n\nspread [...a] too...",
+			expected: "Call `f(args...)` or\n\n```go\nfunc f(xs ...int) {}\n```\n\n    g(xs...)\n\nspread […a] too…",
+		},
+		{
+			name:     "links and front matter",
+			style:    "unicode",
+			input:    "---\ntitle: So...\n---\nSee [more...](http://x/a...b).",
+			expected: "---\ntitle: So...\n---\nSee [more…](http://x/a...b).",
+		},
+		{
+			name:     "ascii",
+			style:    "ascii",
+			input:    "Wait… what? And then … nothing. `a…` .…",
+			expected: "Wait... what? And then ... nothing. `a…` .…",
+		},
+		{
+			name:     "off",
+			input:    "Wait... what…",
+			expected: "Wait... what…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewEllipsisRule(tt.style)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 47: write emoji as GitHub shortcodes or as Unicode
+// ----------------------------------------------------------------
+
+//go:embed emoji.txt
+var emojiTable string
+
+var (
+	// emojiByCode maps every shortcode to its emoji, and
+	// emojiShortcode maps every emoji, and every alias, to the preferred
+	// shortcode.
+	emojiByCode    = map[string]string{}
+	emojiShortcode = map[string]string{}
+	// emojiCodes are the known shortcodes, sorted.
+	emojiCodes []string
+	// emojiChar matches a known emoji, with or without its emoji
+	// presentation selector.
+	emojiChar *regexp.Regexp
+	// shortcode matches something written like a shortcode, such as :tada:.
+	shortcode = regexp.MustCompile(`:[a-z0-9_+-]+:`)
+)
+
+func init() {
+	var chars []string
+	for _, line := range strings.Split(emojiTable, "\n") {
+		codes, char, ok := strings.Cut(line, "\t")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names := strings.Fields(codes)
+		for _, c := range names {
+			emojiByCode[c] = char
+			emojiShortcode[c] = names[0]
+			emojiCodes = append(emojiCodes, c)
+		}
+		char = strings.TrimSuffix(char, "\ufe0f")
+		emojiShortcode[char] = names[0]
+		chars = append(chars, regexp.QuoteMeta(char))
+	}
+	sort.Strings(emojiCodes)
+	// longest first, so that a sequence wins over its first character
+	sort.Slice(chars, func(i, j int) bool { return len(chars[i]) > len(chars[j]) })
+	emojiChar = regexp.MustCompile(`(?:` + strings.Join(chars, "|") + `)\x{fe0f}?`)
+}
+
+type EmojiRule struct {
+	// style is "shortcode", "unicode", or "" to leave emoji alone.
+	style string
+}
+
+// NewEmojiRule constructs a rule that writes emoji in prose as their
+// preferred GitHub shortcode with style "shortcode", as in :tada:, and as
+// Unicode with style "unicode". It reports shortcodes GitHub does not
+// know whatever the style.
+func NewEmojiRule(style string) Rule {
+	return EmojiRule{style: style}
+}
+
+func newEmojiRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("emoji", "", "", "shortcode", "unicode")
+	if err != nil {
+		return nil, err
+	}
+	return NewEmojiRule(style), nil
+}
+
+func (EmojiRule) Name() string {
+	return "Emoji"
+}
+
+func (r EmojiRule) Apply(content string) (string, error) {
+	if r.style == "" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutside(line, nonProseSpans(line), r.rewrite)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r EmojiRule) rewrite(s string) string {
+	if r.style == "unicode" {
+		return replaceShortcodes(s, func(code string) string {
+			if char, ok := emojiByCode[code]; ok {
+				return char
+			}
+			return ":" + code + ":"
+		})
+	}
+	s = replaceShortcodes(s, func(code string) string {
+		if pref, ok := emojiShortcode[code]; ok {
+			return ":" + pref + ":"
+		}
+		return ":" + code + ":"
+	})
+	var out strings.Builder
+	last := 0
+	for _, m := range emojiChar.FindAllStringIndex(s, -1) {
+		// skin tones and joined sequences have no shortcode
+		next, _ := utf8.DecodeRuneInString(s[m[1]:])
+		prev, _ := utf8.DecodeLastRuneInString(s[:m[0]])
+		if next == '\u200d' || next >= 0x1f3fb && next <= 0x1f3ff || prev == '\u200d' {
+			continue
+		}
+		char := strings.TrimSuffix(s[m[0]:m[1]], "\ufe0f")
+		out.WriteString(s[last:m[0]])
+		out.WriteString(":" + emojiShortcode[char] + ":")
+		last = m[1]
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// shortcodes returns the byte ranges of the shortcodes in s: those not
+// glued to a word or to other colons, as in 10:30:00.
+func shortcodes(s string) [][2]int {
+	var spans [][2]int
+	for _, m := range shortcode.FindAllStringIndex(s, -1) {
+		prev, _ := utf8.DecodeLastRuneInString(s[:m[0]])
+		next, _ := utf8.DecodeRuneInString(s[m[1]:])
+		if m[0] > 0 && (isWordRune(prev) || prev == ':') || m[1] < len(s) && (isWordRune(next) || next == ':') {
+			continue
+		}
+		spans = append(spans, [2]int{m[0], m[1]})
+	}
+	return spans
+}
+
+func isWordRune(c rune) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func replaceShortcodes(s string, fn func(code string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, sp := range shortcodes(s) {
+		out.WriteString(s[last:sp[0]])
+		out.WriteString(fn(s[sp[0]+1 : sp[1]-1]))
+		last = sp[1]
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+func (r EmojiRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		covered := nonProseSpans(line)
+		for _, sp := range shortcodes(line) {
+			code := line[sp[0]+1 : sp[1]-1]
+			if _, ok := emojiByCode[code]; ok || inSpans(covered, sp[0]) || !strings.ContainsAny(code, "abcdefghijklmnopqrstuvwxyz") {
+				continue
+			}
+			d := Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:sp[0]]) + 1,
+				Rule:    r.Name(),
+				Key:     code,
+				Message: fmt.Sprintf("unknown emoji shortcode :%s:", code),
+			}
+			if guess := closestShortcode(code); guess != "" {
+				d.Message += fmt.Sprintf("; did you mean :%s:?", guess)
+				d.Fix = lineFix(line, sp[0], sp[1], ":"+guess+":")
+			}
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+func inSpans(spans [][2]int, k int) bool {
+	for _, sp := range spans {
+		if k >= sp[0] && k < sp[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// closestShortcode returns the known shortcode nearest to code, ignoring
+// underscores, if it is close enough to be a typo.
+func closestShortcode(code string) string {
+	bare := strings.ReplaceAll(code, "_", "")
+	best, dist := "", len(bare)/3+1
+	for _, c := range emojiCodes {
+		if d := editDistance(bare, strings.ReplaceAll(c, "_", "")); d < dist {
+			best, dist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
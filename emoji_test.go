@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmojiRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "shortcode",
+			style:    "shortcode",
+			input:    "Ship it 🎉, 👍 and ❤️ or ❤, :thumbsup: too",
+			expected: "Ship it :tada:, :+1: and :heart: or :heart:, :+1: too",
+		},
+		{
+			name:     "skin tones and sequences",
+			style:    "shortcode",
+			input:    "👍🏽 and 👩‍💻",
+			expected: "👍🏽 and 👩‍💻",
+		},
+		{
+			name:     "unicode",
+			style:    "unicode",
+			input:    "Ship it :tada:, :+1: and :heart: :unknown:",
+			expected: "Ship it 🎉, 👍 and ❤️ :unknown:",
+		},
+		{
+			name:     "colons that are not shortcodes",
+			style:    "unicode",
+			input:    "At 10:30:00, key: value, a:tada:b, ::tada:: and http://x/:tada:/",
+			expected: "At 10:30:00, key: value, a:tada:b, ::tada:: and http://x/:tada:/",
+		},
+		{
+			name:     "code and front matter",
+			style:    "unicode",
+			input:    "---\nicon: :tada:\n---\n`:tada:`\n\n```\n:tada:\n```",
+			expected: "---\nicon: :tada:\n---\n`:tada:`\n\n```\n:tada:\n```",
+		},
+		{
+			name:     "off",
+			input:    ":tada: 🎉",
+			expected: ":tada: 🎉",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewEmojiRule(tt.style)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestEmojiLint(t *testing.T) {
+	input := "Nice :thumbs_up: and :tadaa:, not :xyzzyq: or :30: or `:nope:`\n"
+	var got []string
+	for _, d := range NewEmojiRule("").(Linter).Lint(input) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"1:6: warning: Emoji: unknown emoji shortcode :thumbs_up:; did you mean :thumbsup:?",
+		"1:22: warning: Emoji: unknown emoji shortcode :tadaa:; did you mean :tada:?",
+		"1:35: warning: Emoji: unknown emoji shortcode :xyzzyq:",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 31: report emphasized paragraphs posing as headings
+// ----------------------------------------------------------------
+
+// emphasized returns the text of s if s is wholly strong or emphasized
+// text.
+func emphasized(s string) (string, bool) {
+	for _, d := range []string{"**", "__", "*", "_"} {
+		if len(s) <= 2*len(d) || !strings.HasPrefix(s, d) || !strings.HasSuffix(s, d) {
+			continue
+		}
+		inner := s[len(d) : len(s)-len(d)]
+		if strings.Contains(inner, d) || inner != strings.TrimSpace(inner) {
+			return "", false
+		}
+		return inner, true
+	}
+	return "", false
+}
+
+type EmphasisAsHeadingRule struct {
+	fix bool
+	// level is the heading level used by the fix, or 0 to go one level
+	// below the heading before.
+	level     int
+	maxLength int
+}
+
+// NewEmphasisAsHeadingRule constructs a rule that reports, or with fix
+// converts, single-line paragraphs of emphasized text up to maxLength
+// characters long.
+func NewEmphasisAsHeadingRule(fix bool, level, maxLength int) Rule {
+	return EmphasisAsHeadingRule{fix: fix, level: level, maxLength: maxLength}
+}
+
+func newEmphasisAsHeadingRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("emphasis-heading", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	lvl, err := o.Choice("emphasis-heading-level", "auto", "auto", "1", "2", "3", "4", "5", "6")
+	if err != nil {
+		return nil, err
+	}
+	level, _ := strconv.Atoi(lvl) // 0 for auto
+	max, err := o.Int("emphasis-heading-max-length", 60)
+	if err != nil {
+		return nil, err
+	}
+	return NewEmphasisAsHeadingRule(mode == "fix", level, max), nil
+}
+
+func (EmphasisAsHeadingRule) Name() string {
+	return "EmphasisAsHeading"
+}
+
+// fakeHeading is a paragraph found by EmphasisAsHeadingRule.
+type fakeHeading struct {
+	line  int
+	text  string // the text between the emphasis markers
+	level int    // suggested heading level
+}
+
+func (r EmphasisAsHeadingRule) find(lines []string) []fakeHeading {
+	b := classifyBlocks(lines)
+	var found []fakeHeading
+	last := 0 // level of the last real heading
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind == lineSetextUnderline {
+			last = 2
+			if strings.HasPrefix(strings.TrimSpace(line), "=") {
+				last = 1
+			}
+		}
+		if info.kind != lineText {
+			continue
+		}
+		if isATXHeading(line) {
+			last = len(strings.TrimSpace(line)) - len(strings.TrimLeft(strings.TrimSpace(line), "#"))
+			continue
+		}
+		if info.quote > 0 || info.container > 0 || info.item || info.indent > 3 {
+			continue
+		}
+		if !paragraphOfItsOwn(lines, b, i) {
+			continue
+		}
+		inner, ok := emphasized(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		text := plainText(inner)
+		if text == "" || utf8.RuneCountInString(text) > r.maxLength ||
+			strings.ContainsAny(text[len(text)-1:], ".,;:!?") || strings.Contains(inner, "![") {
+			continue
+		}
+		// a caption under an image
+		if p := prevNonBlankLine(b, i); p >= 0 && inlineImage.MatchString(lines[p]) {
+			continue
+		}
+		level := r.level
+		if level == 0 {
+			level = min(last+1, 6)
+		}
+		found = append(found, fakeHeading{line: i, text: inner, level: level})
+	}
+	return found
+}
+
+// paragraphOfItsOwn reports whether the text line i is a whole
+// paragraph: one that no paragraph line comes right before or after. A
+// heading, fence or thematic break ends the paragraph before it as well as
+// a blank line does, so the line may be made a heading before the blank
+// line rules run, and they put blank lines around it.
+func paragraphOfItsOwn(lines []string, b *blocks, i int) bool {
+	if i > 0 {
+		switch prev := b.lines[i-1]; prev.kind {
+		case lineBlank, lineFrontMatter, lineFenceClose, lineThematicBreak, lineSetextUnderline, lineDivFence:
+		case lineText:
+			if prev.quote > 0 || !isATXHeading(lines[i-1]) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if i+1 < len(lines) {
+		switch next := b.lines[i+1]; next.kind {
+		case lineBlank, lineFenceOpen, lineThematicBreak, lineTable, lineDivFence:
+		case lineText:
+			if next.quote > 0 || !isATXHeading(lines[i+1]) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (r EmphasisAsHeadingRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	for _, h := range r.find(lines) {
+		lines[h.line] = strings.Repeat("#", h.level) + " " + h.text
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r EmphasisAsHeadingRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	var diags []Diagnostic
+	for _, h := range r.find(lines) {
+		heading := strings.Repeat("#", h.level) + " " + h.text
+		diags = append(diags, Diagnostic{
+			Line:    h.line + 1,
+			Rule:    r.Name(),
+			Message: fmt.Sprintf("emphasis used instead of a heading; write %q", heading),
+			Fix:     lineFix(lines[h.line], 0, len(lines[h.line]), heading),
+		})
+	}
+	return diags
+}
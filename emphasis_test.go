@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmphasisAsHeadingRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    int
+		input    string
+		expected string
+	}{
+		{
+			name:     "strong paragraph below a heading",
+			input:    "# Guide\n\n**Setup**\n\nText",
+			expected: "# Guide\n\n## Setup\n\nText",
+		},
+		{
+			name:     "siblings share a level",
+			input:    "## Part\n\n*One*\n\nText\n\n__Two__\n",
+			expected: "## Part\n\n### One\n\nText\n\n### Two\n",
+		},
+		{
+			name:     "configured level",
+			level:    4,
+			input:    "**Setup**",
+			expected: "#### Setup",
+		},
+		{
+			name:     "ended by a heading, fence or table",
+			input:    "# Guide\n**Setup**\n```sh\nmake\n```\n**Notes**\n| a |\n| --- |\n",
+			expected: "# Guide\n## Setup\n```sh\nmake\n```\n## Notes\n| a |\n| --- |\n",
+		},
+		{
+			name:     "not flagged",
+			input:    "**Note:**\n\n**Done.**\n\n**a** and **b**\n\n**part of**\na paragraph\n\n- **item**\n\n| **cell** |\n| --- |\n\n![img](a.png)\n\n*Figure 1*\n\n```\n**code**\n```",
+			expected: "**Note:**\n\n**Done.**\n\n**a** and **b**\n\n**part of**\na paragraph\n\n- **item**\n\n| **cell** |\n| --- |\n\n![img](a.png)\n\n*Figure 1*\n\n```\n**code**\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewEmphasisAsHeadingRule(true, tt.level, 60)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEmphasisAsHeadingRuleLint(t *testing.T) {
+	rule := NewEmphasisAsHeadingRule(false, 0, 10).(EmphasisAsHeadingRule)
+	input := "**Installation**\n\n**Short**"
+	if got, _ := rule.Apply(input); got != input {
+		t.Errorf("lint mode changed the document: %q", got)
+	}
+	want := []Diagnostic{{
+		Line:    3,
+		Rule:    "EmphasisAsHeading",
+		Message: `emphasis used instead of a heading; write "# Short"`,
+		Fix:     &Fix{Start: 1, End: 10, Text: "# Short"},
+	}}
+	if got := rule.Lint(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEmphasisAsHeadingSpacedInOneRun(t *testing.T) {
+	input := "# Guide\n**Setup**\n```go\nmake\n```\n"
+	want := "# Guide\n\n## Setup\n\n```go\nmake\n```\n"
+	got, _, err := formatDocument(input, "", Options{"emphasis-heading": "fix"}, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+	again, _, err := formatDocument(got, "", Options{"emphasis-heading": "fix"}, nil, nil, false)
+	if err != nil || again != got {
+		t.Errorf("not idempotent: %q", again)
+	}
+}
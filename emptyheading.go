@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 53: report ATX headings without text
+// ----------------------------------------------------------------
+
+// emptyATXHeading matches an ATX heading marker with nothing but
+// whitespace and a closing sequence after it, such as "##" or "### #".
+var emptyATXHeading = regexp.MustCompile(`^#{1,6}(?:[ \t]+#*)?[ \t]*$`)
+
+type EmptyHeadingRule struct {
+	// fix deletes the empty headings instead of only reporting them.
+	fix bool
+}
+
+// NewEmptyHeadingRule constructs a rule that reports ATX headings with
+// no text. With fix it deletes them, except those on the line of a list
+// marker, whose item would go with them.
+func NewEmptyHeadingRule(fix bool) Rule {
+	return EmptyHeadingRule{fix: fix}
+}
+
+func newEmptyHeadingRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("empty-heading", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	return NewEmptyHeadingRule(mode == "fix"), nil
+}
+
+func (EmptyHeadingRule) Name() string {
+	return "EmptyHeading"
+}
+
+// emptyHeading is an empty heading found by EmptyHeadingRule.
+type emptyHeading struct {
+	line  int
+	start int // byte offset of the first #
+	item  bool
+}
+
+// emptyHeadings finds the empty headings of a document. Code, front
+// matter and HTML are not searched, and "#######" is no heading at all.
+func emptyHeadings(lines []string) []emptyHeading {
+	b := classifyBlocks(lines)
+	var found []emptyHeading
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineText || literalLine(b, i) {
+			continue
+		}
+		body := line[info.bodyStart:]
+		if info.item {
+			if _, end, _, ok := listMarker(body); ok {
+				body = strings.TrimLeft(body[end:], " \t")
+			}
+		} else {
+			body = containerBody(body, info.container)
+		}
+		cols, n := leadingColumns(body)
+		if cols > 3 || !emptyATXHeading.MatchString(body[n:]) {
+			continue
+		}
+		found = append(found, emptyHeading{line: i, start: len(line) - len(body) + n, item: info.item})
+	}
+	return found
+}
+
+func (r EmptyHeadingRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	drop := map[int]int{} // line → start of the heading
+	for _, h := range emptyHeadings(lines) {
+		if !h.item {
+			drop[h.line] = h.start
+		}
+	}
+	if len(drop) == 0 {
+		return content, nil
+	}
+	blank := func(s string) bool { return strings.TrimSpace(strings.TrimLeft(s, "> \t")) == "" }
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		start, ok := drop[i]
+		if !ok {
+			out = append(out, lines[i])
+			continue
+		}
+		prevBlank := len(out) == 0 || blank(out[len(out)-1])
+		nextBlank := i+1 == len(lines) || blank(lines[i+1])
+		switch {
+		case !prevBlank && !nextBlank:
+			// the heading ended a paragraph: keep them apart
+			out = append(out, strings.TrimRight(lines[i][:start], " \t"))
+		case prevBlank && nextBlank && i+1 < len(lines)-1:
+			// the blank lines on either side of it become one
+			i++
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func (r EmptyHeadingRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	var diags []Diagnostic
+	for _, h := range emptyHeadings(lines) {
+		line := lines[h.line]
+		d := Diagnostic{
+			Line:    h.line + 1,
+			Rule:    r.Name(),
+			Message: fmt.Sprintf("heading %q has no text", strings.TrimSpace(line[h.start:])),
+		}
+		if !h.item {
+			d.Fix = lineFix(line, h.start, len(line), "")
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
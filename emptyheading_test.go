@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmptyHeadingLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "marker only",
+			input: "# Title\n\n##\n\ntext\n   ### \n#### ##\n",
+			want: []string{
+				`3: warning: EmptyHeading: heading "##" has no text`,
+				`6: warning: EmptyHeading: heading "###" has no text`,
+				`7: warning: EmptyHeading: heading "#### ##" has no text`,
+			},
+		},
+		{
+			name:  "in containers",
+			input: "> ##\n\n- #\n\n  ##\n",
+			want: []string{
+				`1: warning: EmptyHeading: heading "##" has no text`,
+				`3: warning: EmptyHeading: heading "#" has no text`,
+				`5: warning: EmptyHeading: heading "##" has no text`,
+			},
+		},
+		{
+			name:  "not headings",
+			input: "#######\n#hashtag\n\n---\n\n***\n\n    ##\n\n```\n##\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range NewEmptyHeadingRule(false).(Linter).Lint(tt.input) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyHeadingFix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "between blank lines",
+			input:    "# Title\n\n##\n\ntext\n",
+			expected: "# Title\n\ntext\n",
+		},
+		{
+			name:     "between paragraphs",
+			input:    "one\n##\ntwo\n> three\n> ## #\n> four\n",
+			expected: "one\n\ntwo\n> three\n>\n> four\n",
+		},
+		{
+			name:     "at the end",
+			input:    "text\n\n###\n",
+			expected: "text\n\n",
+		},
+		{
+			name:     "on a list marker",
+			input:    "- ##\n- item\n",
+			expected: "- ##\n- item\n",
+		},
+	}
+
+	rule := NewEmptyHeadingRule(true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
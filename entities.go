@@ -0,0 +1,124 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 46: decode HTML entities in prose
+// ----------------------------------------------------------------
+
+// entity matches a named, decimal or hexadecimal character reference.
+var entity = regexp.MustCompile(`&(?:[a-zA-Z][a-zA-Z0-9]*|#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6});`)
+
+// namedEntities are the named references the rule decodes: spaces, dashes,
+// quotes and the symbols common in imported text.
+var namedEntities = map[string]bool{
+	"nbsp": true, "amp": true, "lt": true, "gt": true, "quot": true, "apos": true,
+	"ndash": true, "mdash": true, "hellip": true, "ldquo": true, "rdquo": true,
+	"lsquo": true, "rsquo": true, "bdquo": true, "sbquo": true, "laquo": true,
+	"raquo": true, "copy": true, "reg": true, "trade": true, "deg": true,
+	"times": true, "divide": true, "middot": true, "bull": true, "euro": true,
+	"pound": true, "yen": true, "cent": true, "sect": true, "para": true,
+	"frac12": true, "frac14": true, "frac34": true, "plusmn": true, "rarr": true,
+	"larr": true, "harr": true, "thinsp": true, "ensp": true, "emsp": true,
+}
+
+// htmlBlockStart matches the first line of a raw HTML block that runs to
+// the next blank line: a block-level tag, or any tag alone on its line.
+var htmlBlockStart = regexp.MustCompile(`^ {0,3}(?:</?(?i:address|article|aside|blockquote|body|center|details|dialog|dd|div|dl|dt|fieldset|figcaption|figure|footer|form|h[1-6]|head|header|hr|html|iframe|li|main|menu|nav|ol|p|section|summary|table|tbody|td|tfoot|th|thead|tr|ul)(?:[\s/>]|$)|</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>\s*$)`)
+
+// defaultKeptEntities stay encoded: decoding them can create HTML.
+var defaultKeptEntities = []string{"lt", "gt"}
+
+type DecodeEntitiesRule struct {
+	keep map[string]bool
+}
+
+// NewDecodeEntitiesRule constructs a rule that replaces common named and
+// numeric HTML entities in prose with the characters they stand for,
+// except the named entities in keep. References that would decode to
+// Markdown syntax, or an &amp; that would start another entity, stay.
+func NewDecodeEntitiesRule(keep ...string) Rule {
+	r := DecodeEntitiesRule{keep: map[string]bool{}}
+	for _, k := range keep {
+		r.keep[k] = true
+	}
+	return r
+}
+
+func newDecodeEntitiesRuleFromOptions(o Options) (Rule, error) {
+	return NewDecodeEntitiesRule(o.List("entity-keep", defaultKeptEntities)...), nil
+}
+
+func (DecodeEntitiesRule) Name() string {
+	return "DecodeEntities"
+}
+
+func (DecodeEntitiesRule) Trigger() []string {
+	return []string{"&"}
+}
+
+func (r DecodeEntitiesRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	inHTML := htmlBlockLines(lines, b)
+	for i, line := range lines {
+		if inHTML[i] || literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutside(line, nonProseSpans(line), func(s string) string {
+			return r.decode(s)
+		})
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// htmlBlockLines reports which lines belong to a raw HTML block, which
+// the internal parser does not find.
+func htmlBlockLines(lines []string, b *blocks) []bool {
+	in := make([]bool, len(lines))
+	inHTML := false
+	for i, line := range lines {
+		switch {
+		case b.lines[i].kind == lineBlank:
+			inHTML = false
+		case !inHTML && (i == 0 || b.lines[i-1].kind == lineBlank):
+			inHTML = htmlBlockStart.MatchString(line[b.lines[i].bodyStart:])
+		}
+		in[i] = inHTML
+	}
+	return in
+}
+
+func (r DecodeEntitiesRule) decode(s string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range entity.FindAllStringIndex(s, -1) {
+		ref := s[m[0]:m[1]]
+		name := ref[1 : len(ref)-1]
+		if !strings.HasPrefix(name, "#") && (!namedEntities[name] || r.keep[name]) {
+			continue
+		}
+		text := html.UnescapeString(ref)
+		switch {
+		case text == ref:
+			continue
+		case text == "&":
+			// &amp;lt; is the text "&lt;"
+			if loc := entity.FindStringIndex("&" + s[m[1]:]); loc != nil && loc[0] == 0 {
+				continue
+			}
+		case name[0] == '#' && len(text) == 1 && (strings.Contains("\\`*_{}[]()#+-.!|<>~$", text) || text[0] < ' '):
+			// numeric references to Markdown syntax escape it
+			continue
+		}
+		out.WriteString(s[last:m[0]])
+		out.WriteString(text)
+		last = m[1]
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestDecodeEntitiesRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		keep     []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "named",
+			keep:     defaultKeptEntities,
+			input:    "Fish&nbsp;&amp;&nbsp;chips &mdash; &ldquo;great&rdquo;&hellip; &copy;",
+			expected: "Fish\u00a0&\u00a0chips — “great”… ©",
+		},
+		{
+			name:     "numeric",
+			keep:     defaultKeptEntities,
+			input:    "&#8212; &#x2014; &#42;not bold&#42; &#35; &#x5B;x]",
+			expected: "— — &#42;not bold&#42; &#35; &#x5B;x]",
+		},
+		{
+			name:     "kept and unknown",
+			keep:     defaultKeptEntities,
+			input:    "&lt;div&gt; &amp;lt; &amp;#42; &bogus; &Alpha; a & b",
+			expected: "&lt;div&gt; &amp;lt; &amp;#42; &bogus; &Alpha; a & b",
+		},
+		{
+			name:     "nothing kept",
+			input:    "1 &lt; 2",
+			expected: "1 < 2",
+		},
+		{
+			name:     "code and html",
+			keep:     defaultKeptEntities,
+			input:    "`&amp;` <abbr title=\"a&amp;b\">x&amp;y</abbr>\n\n```\n&mdash;\n```\n\n<div>\n&mdash;\n</div>\n\n[&amp;](http://x/?a=1&amp;b=2)",
+			expected: "`&amp;` <abbr title=\"a&amp;b\">x&y</abbr>\n\n```\n&mdash;\n```\n\n<div>\n&mdash;\n</div>\n\n[&](http://x/?a=1&amp;b=2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDecodeEntitiesRule(tt.keep...).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeEntitiesRunsFirst(t *testing.T) {
+	// decoded characters go through the punctuation rules of the same run
+	opts := Options{"ellipsis": "ascii", "dashes": "ascii", "apostrophe": "ascii", "lang": "fr"}
+	enable := []string{"DecodeEntities", "FrenchSpacing"}
+	input := "Wait&hellip; it&rsquo;s 3&ndash;5 pages&nbsp;: non !\n"
+	expected := "Wait... it's 3--5 pages\u00a0: non\u00a0!\n"
+	got, _, err := formatDocument(input, "", opts, enable, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 7: require a language on fenced code blocks (MD040)
+// ----------------------------------------------------------------
+
+type FencedCodeLanguageRule struct {
+	// fix inserts a language instead of only reporting its absence.
+	fix bool
+	// defaultLang is inserted when fixing and no better guess exists.
+	defaultLang string
+	// guess enables the content heuristics in guessLanguage.
+	guess bool
+}
+
+// NewFencedCodeLanguageRule constructs a rule that reports fences without
+// an info string, or fills one in when fix is set.
+func NewFencedCodeLanguageRule(fix bool, defaultLang string, guess bool) Rule {
+	return FencedCodeLanguageRule{fix: fix, defaultLang: defaultLang, guess: guess}
+}
+
+func newFencedCodeLanguageRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("code-language", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	guess, err := o.Bool("code-language-guess", false)
+	if err != nil {
+		return nil, err
+	}
+	return NewFencedCodeLanguageRule(
+		mode == "fix", o.Get("code-language-default", "text"), guess,
+	), nil
+}
+
+func (FencedCodeLanguageRule) Name() string {
+	return "FencedCodeLanguage"
+}
+
+func (r FencedCodeLanguageRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for _, f := range b.fences {
+		if f.info != "" {
+			continue
+		}
+		lang := r.defaultLang
+		if r.guess {
+			if g := guessLanguage(fenceContent(lines, b, f)); g != "" {
+				lang = g
+			}
+		}
+		lines[f.open] = lines[f.open][:f.markerEnd] + lang
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r FencedCodeLanguageRule) Lint(content string) []Diagnostic {
+	var diags []Diagnostic
+	b := classifyBlocks(strings.Split(content, "\n"))
+	for _, f := range b.fences {
+		if f.info == "" {
+			diags = append(diags, Diagnostic{
+				Line:    f.open + 1,
+				Rule:    r.Name(),
+				Message: "fenced code block has no language",
+			})
+		}
+	}
+	return diags
+}
+
+// fenceContent returns the lines between the fences of f with their
+// container prefixes removed.
+func fenceContent(lines []string, b *blocks, f fence) []string {
+	end := f.close
+	if end < 0 {
+		end = f.open + 1
+		for end < len(lines) && b.lines[end].fence == b.lines[f.open].fence {
+			end++
+		}
+	}
+	var body []string
+	for i := f.open + 1; i < end; i++ {
+		body = append(body, lines[i][b.lines[i].bodyStart:])
+	}
+	return body
+}
+
+var shebangInterpreters = map[string]string{
+	"bash":    "bash",
+	"sh":      "bash",
+	"zsh":     "bash",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+}
+
+var goPackageClause = regexp.MustCompile(`^package [A-Za-z_][A-Za-z0-9_]*$`)
+
+// guessLanguage applies a few cheap heuristics to the content of a code
+// block and returns a language name, or "" when nothing matches.
+func guessLanguage(body []string) string {
+	first := ""
+	for _, l := range body {
+		if t := strings.TrimSpace(l); t != "" {
+			first = t
+			break
+		}
+	}
+	switch {
+	case first == "":
+		return ""
+	case strings.HasPrefix(first, "#!"):
+		fields := strings.Fields(first[2:])
+		if len(fields) == 0 {
+			return ""
+		}
+		interp := path.Base(fields[0])
+		if interp == "env" && len(fields) > 1 {
+			interp = fields[1]
+		}
+		return shebangInterpreters[interp]
+	case strings.HasPrefix(first, "{"):
+		return "json"
+	}
+	for _, l := range body {
+		t := strings.TrimSpace(l)
+		if goPackageClause.MatchString(t) {
+			return "go"
+		}
+	}
+	for _, l := range body {
+		if strings.HasPrefix(l, "def ") || strings.HasPrefix(l, "import ") {
+			return "python"
+		}
+	}
+	return ""
+}
+
+// ----------------------------------------------------------------
+// Rule 8: convert indented code blocks to fenced code blocks
+// ----------------------------------------------------------------
+
+type IndentedCodeToFencedRule struct {
+	// info is the info string written on the new opening fence.
+	info string
+}
+
+// NewIndentedCodeToFencedRule constructs a rule that rewrites indented code
+// blocks as backtick fences carrying the given info string.
+func NewIndentedCodeToFencedRule(info string) Rule {
+	return IndentedCodeToFencedRule{info: info}
+}
+
+func (IndentedCodeToFencedRule) Name() string {
+	return "IndentedCodeToFenced"
+}
+
+func (r IndentedCodeToFencedRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		if b.lines[i].kind != lineIndentedCode {
+			out = append(out, lines[i])
+			continue
+		}
+		// the block runs to its last code line; blank lines in between
+		// belong to it, trailing ones do not
+		start, end := i, i
+		for j := i + 1; j < len(lines); j++ {
+			l := b.lines[j]
+			if l.quote != b.lines[start].quote {
+				break
+			}
+			if l.kind == lineIndentedCode {
+				end = j
+			} else if l.kind != lineBlank {
+				break
+			}
+		}
+
+		first := b.lines[start]
+		prefix := lines[start][:first.bodyStart]
+		indent := strings.Repeat(" ", first.container)
+		code := make([]string, 0, end-start+1)
+		for j := start; j <= end; j++ {
+			code = append(code, trimColumns(lines[j][b.lines[j].bodyStart:], first.container+4))
+		}
+		marker := strings.Repeat("`", fenceLength(code, '`'))
+		out = append(out, prefix+indent+marker+r.info)
+		for j := start; j <= end; j++ {
+			if b.lines[j].kind == lineBlank {
+				out = append(out, lines[j])
+			} else {
+				out = append(out, prefix+indent+code[j-start])
+			}
+		}
+		out = append(out, prefix+indent+marker)
+		i = end
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// fenceLength returns the shortest fence of ch that no line of body can
+// close: three, or one more than the longest run of ch starting a line.
+func fenceLength(body []string, ch byte) int {
+	longest := 0
+	for _, l := range body {
+		t := strings.TrimLeft(l, " \t")
+		run := 0
+		for run < len(t) && t[run] == ch {
+			run++
+		}
+		longest = max(longest, run)
+	}
+	return max(3, longest+1)
+}
+
+// trimColumns removes n columns of leading whitespace from s, splitting a
+// tab into spaces when it straddles the cut.
+func trimColumns(s string, n int) string {
+	col := 0
+	for i := 0; i < len(s); i++ {
+		if col >= n {
+			return s[i:]
+		}
+		switch s[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return s[i:]
+		}
+		if col > n {
+			return strings.Repeat(" ", col-n) + s[i+1:]
+		}
+	}
+	return ""
+}
+
+// ----------------------------------------------------------------
+// Rule 9: normalize fence character and length
+// ----------------------------------------------------------------
+
+type FenceStyleRule struct {
+	// char is the preferred fence character, '`' or '~'.
+	char byte
+}
+
+// NewFenceStyleRule constructs a rule that rewrites code fences to use char
+// with the shortest length the content allows.
+func NewFenceStyleRule(char byte) Rule {
+	return FenceStyleRule{char: char}
+}
+
+func newFenceStyleRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("fence", "backtick", "backtick", "tilde")
+	if err != nil {
+		return nil, err
+	}
+	if style == "tilde" {
+		return NewFenceStyleRule('~'), nil
+	}
+	return NewFenceStyleRule('`'), nil
+}
+
+func (FenceStyleRule) Name() string {
+	return "FenceStyle"
+}
+
+func (r FenceStyleRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for _, f := range b.fences {
+		// an unterminated fence is left for the user to close
+		if f.close < 0 {
+			continue
+		}
+		ch := r.char
+		// a backtick fence cannot carry backticks in its info string
+		if ch == '`' && strings.IndexByte(lines[f.open][f.markerEnd:], '`') >= 0 {
+			ch = f.char
+		}
+		marker := strings.Repeat(string(ch), fenceLength(fenceContent(lines, b, f), ch))
+
+		open := lines[f.open]
+		lines[f.open] = open[:f.markerEnd-f.size] + marker + open[f.markerEnd:]
+
+		closing := lines[f.close]
+		start := b.lines[f.close].bodyStart
+		_, ws := leadingColumns(closing[start:])
+		lines[f.close] = closing[:start+ws] + marker
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 10: exactly one blank line around fenced code blocks (MD031)
+// ----------------------------------------------------------------
+
+type BlankLinesAroundFencesRule struct{}
+
+func NewBlankLinesAroundFencesRule() Rule { return BlankLinesAroundFencesRule{} }
+
+func (BlankLinesAroundFencesRule) Name() string {
+	return "BlankLinesAroundFences"
+}
+
+func (BlankLinesAroundFencesRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	gaps := newGapEditor(lines, b)
+	for _, f := range b.fences {
+		open := b.lines[f.open]
+		if !open.item {
+			if p := gaps.prevNonBlank(f.open); p >= 0 && !firstInContainer(b, p, f.open) {
+				gaps.ensureOne(p, f.open, blankFor(lines[f.open], open))
+			}
+		}
+		if f.close < 0 {
+			continue
+		}
+		if n := gaps.nextNonBlank(f.close); n >= 0 && !lastInContainer(b, f.close, n) {
+			gaps.ensureOne(f.close, n, blankFor(lines[f.close], b.lines[f.close]))
+		}
+	}
+	return strings.Join(gaps.apply(), "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 11: normalize the language of code fence info strings
+// ----------------------------------------------------------------
+
+// defaultLanguageAliases maps common language shorthands to the names our
+// highlighter recognizes.
+var defaultLanguageAliases = map[string]string{
+	"js":     "javascript",
+	"ts":     "typescript",
+	"sh":     "bash",
+	"shell":  "bash",
+	"yml":    "yaml",
+	"golang": "go",
+	"py":     "python",
+}
+
+type FenceInfoStringRule struct {
+	// aliases maps lowercased language tokens to their canonical name.
+	aliases map[string]string
+}
+
+// NewFenceInfoStringRule constructs a rule that trims info strings,
+// lowercases their language token and resolves it through aliases.
+func NewFenceInfoStringRule(aliases map[string]string) Rule {
+	lower := make(map[string]string, len(aliases))
+	for k, v := range aliases {
+		lower[strings.ToLower(k)] = v
+	}
+	return FenceInfoStringRule{aliases: lower}
+}
+
+func newFenceInfoStringRuleFromOptions(o Options) (Rule, error) {
+	aliases, err := o.Map("code-language-aliases", defaultLanguageAliases)
+	if err != nil {
+		return nil, err
+	}
+	return NewFenceInfoStringRule(aliases), nil
+}
+
+func (FenceInfoStringRule) Name() string {
+	return "FenceInfoString"
+}
+
+func (r FenceInfoStringRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for _, f := range b.fences {
+		// empty info strings are FencedCodeLanguage's business
+		if f.info == "" {
+			continue
+		}
+		// the token ends at whitespace or an attribute block
+		end := strings.IndexAny(f.info, " \t{")
+		if end < 0 {
+			end = len(f.info)
+		}
+		if end == 0 {
+			continue
+		}
+		lang := strings.ToLower(f.info[:end])
+		if alias, ok := r.aliases[lang]; ok {
+			lang = alias
+		}
+		lines[f.open] = lines[f.open][:f.markerEnd] + lang + f.info[end:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 12: strip blank lines at the edges of fenced code blocks
+// ----------------------------------------------------------------
+
+type TrimFencedCodeRule struct {
+	// leading also removes blank lines right after the opening fence.
+	leading bool
+}
+
+// NewTrimFencedCodeRule constructs a rule that removes blank lines before
+// the closing fence, and after the opening fence when leading is set.
+func NewTrimFencedCodeRule(leading bool) Rule {
+	return TrimFencedCodeRule{leading: leading}
+}
+
+func newTrimFencedCodeRuleFromOptions(o Options) (Rule, error) {
+	leading, err := o.Bool("code-trim-leading", false)
+	if err != nil {
+		return nil, err
+	}
+	return NewTrimFencedCodeRule(leading), nil
+}
+
+func (TrimFencedCodeRule) Name() string {
+	return "TrimFencedCode"
+}
+
+func (r TrimFencedCodeRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	blank := func(i int) bool {
+		return strings.TrimSpace(lines[i][b.lines[i].bodyStart:]) == ""
+	}
+	drop := map[int]bool{}
+	for _, f := range b.fences {
+		// without a closer there is no telling where the code ends
+		if f.close < 0 {
+			continue
+		}
+		for i := f.close - 1; i > f.open && blank(i); i-- {
+			drop[i] = true
+		}
+		if r.leading {
+			for i := f.open + 1; i < f.close && blank(i); i++ {
+				drop[i] = true
+			}
+		}
+	}
+	out := make([]string, 0, len(lines)-len(drop))
+	for i, l := range lines {
+		if !drop[i] {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 13: report (and optionally close) unterminated code fences
+// ----------------------------------------------------------------
+
+type UnterminatedFenceRule struct {
+	// fix appends a closing fence at the end of the fence's container.
+	fix bool
+}
+
+// NewUnterminatedFenceRule constructs a rule that reports code fences
+// without a closer, closing them when fix is set.
+func NewUnterminatedFenceRule(fix bool) Rule {
+	return UnterminatedFenceRule{fix: fix}
+}
+
+func newUnterminatedFenceRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("unterminated-fence", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	return NewUnterminatedFenceRule(mode == "fix"), nil
+}
+
+func (UnterminatedFenceRule) Name() string {
+	return "UnterminatedFence"
+}
+
+func (r UnterminatedFenceRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	closers := map[int]string{} // closing fence to insert after the index
+	for idx, f := range b.fences {
+		if f.close >= 0 {
+			continue
+		}
+		last := f.open
+		for i := f.open + 1; i < len(lines) && b.lines[i].fence == idx; i++ {
+			last = i
+		}
+		// trailing blank lines stay outside the block
+		for last > f.open && strings.TrimSpace(lines[last][b.lines[last].bodyStart:]) == "" {
+			last--
+		}
+		open := lines[f.open]
+		closers[last] = open[:b.lines[f.open].bodyStart] +
+			strings.Repeat(" ", f.container) +
+			strings.Repeat(string(f.char), f.size)
+	}
+	out := make([]string, 0, len(lines)+len(closers))
+	for i, l := range lines {
+		out = append(out, l)
+		if c, ok := closers[i]; ok {
+			out = append(out, c)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func (r UnterminatedFenceRule) Lint(content string) []Diagnostic {
+	var diags []Diagnostic
+	b := classifyBlocks(strings.Split(content, "\n"))
+	for _, f := range b.fences {
+		if f.close < 0 {
+			diags = append(diags, Diagnostic{
+				Line:     f.open + 1,
+				Rule:     r.Name(),
+				Severity: SeverityError,
+				Message:  "code fence is never closed",
+			})
+		}
+	}
+	return diags
+}
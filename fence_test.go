@@ -0,0 +1,528 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFencedCodeLanguageRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		input    string
+		expected string
+	}{
+		{
+			name:     "lint mode leaves content alone",
+			rule:     NewFencedCodeLanguageRule(false, "text", false),
+			input:    "```\ncode\n```",
+			expected: "```\ncode\n```",
+		},
+		{
+			name:     "fix inserts default",
+			rule:     NewFencedCodeLanguageRule(true, "text", false),
+			input:    "```\ncode\n```",
+			expected: "```text\ncode\n```",
+		},
+		{
+			name:     "existing language kept",
+			rule:     NewFencedCodeLanguageRule(true, "text", false),
+			input:    "```go\ncode\n```\n\n```\nmore\n```",
+			expected: "```go\ncode\n```\n\n```text\nmore\n```",
+		},
+		{
+			name:     "tilde fence",
+			rule:     NewFencedCodeLanguageRule(true, "text", false),
+			input:    "~~~  \ncode\n~~~",
+			expected: "~~~text\ncode\n~~~",
+		},
+		{
+			name:     "closing fence is not an opener",
+			rule:     NewFencedCodeLanguageRule(true, "text", false),
+			input:    "```sh\nls\n```\ntext\n````\n```\n````",
+			expected: "```sh\nls\n```\ntext\n````text\n```\n````",
+		},
+		{
+			name:     "fence inside blockquote",
+			rule:     NewFencedCodeLanguageRule(true, "text", false),
+			input:    "> ```\n> code\n> ```",
+			expected: "> ```text\n> code\n> ```",
+		},
+		{
+			name:     "guess shebang",
+			rule:     NewFencedCodeLanguageRule(true, "text", true),
+			input:    "```\n#!/usr/bin/env python3\nprint(1)\n```",
+			expected: "```python\n#!/usr/bin/env python3\nprint(1)\n```",
+		},
+		{
+			name:     "guess go",
+			rule:     NewFencedCodeLanguageRule(true, "text", true),
+			input:    "```\npackage main\n\nfunc main() {}\n```",
+			expected: "```go\npackage main\n\nfunc main() {}\n```",
+		},
+		{
+			name:     "guess python",
+			rule:     NewFencedCodeLanguageRule(true, "text", true),
+			input:    "~~~\nimport os\n~~~",
+			expected: "~~~python\nimport os\n~~~",
+		},
+		{
+			name:     "guess json",
+			rule:     NewFencedCodeLanguageRule(true, "text", true),
+			input:    "```\n{\"a\": 1}\n```",
+			expected: "```json\n{\"a\": 1}\n```",
+		},
+		{
+			name:     "guess falls back to default",
+			rule:     NewFencedCodeLanguageRule(true, "plaintext", true),
+			input:    "```\nhello\n```",
+			expected: "```plaintext\nhello\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFencedCodeLanguageRule_Lint(t *testing.T) {
+	rule := NewFencedCodeLanguageRule(false, "text", false).(Linter)
+	input := "# Title\n\n```\ncode\n```\n\n```go\nx\n```\n\n~~~\ny\n~~~\n"
+	var lines []int
+	for _, d := range rule.Lint(input) {
+		lines = append(lines, d.Line)
+	}
+	if want := []int{3, 11}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got diagnostics on lines %v, want %v", lines, want)
+	}
+}
+
+func TestIndentedCodeToFencedRule(t *testing.T) {
+	rule := NewIndentedCodeToFencedRule("text")
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "simple block",
+			input:    "Para\n\n    code\n    more\n\nEnd",
+			expected: "Para\n\n```text\ncode\nmore\n```\n\nEnd",
+		},
+		{
+			name:     "deeper indentation and interior blanks preserved",
+			input:    "    if x {\n        y()\n\n    }\n\n",
+			expected: "```text\nif x {\n    y()\n\n}\n```\n\n",
+		},
+		{
+			name:     "indented paragraph continuation is not code",
+			input:    "Para\n    still para",
+			expected: "Para\n    still para",
+		},
+		{
+			name:     "list continuation is not code",
+			input:    "- item\n\n    continued paragraph",
+			expected: "- item\n\n    continued paragraph",
+		},
+		{
+			name:     "definition continuation is not code",
+			input:    "Term\n:   def\n\n    continued definition",
+			expected: "Term\n:   def\n\n    continued definition",
+		},
+		{
+			name:     "code nested in list item",
+			input:    "- item\n\n      code\n",
+			expected: "- item\n\n  ```text\n  code\n  ```\n",
+		},
+		{
+			name:     "content with fences gets a longer fence",
+			input:    "    ```go\n    x\n    ```",
+			expected: "````text\n```go\nx\n```\n````",
+		},
+		{
+			name:     "blockquote",
+			input:    ">     code\n> text",
+			expected: "> ```text\n> code\n> ```\n> text",
+		},
+		{
+			name:     "tab indentation",
+			input:    "\tcode\n\t\tnested",
+			expected: "```text\ncode\n\tnested\n```",
+		},
+		{
+			name:     "fenced code untouched",
+			input:    "```\n    not indented code\n```",
+			expected: "```\n    not indented code\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFenceStyleRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		char     byte
+		input    string
+		expected string
+	}{
+		{
+			name:     "tilde to backtick",
+			char:     '`',
+			input:    "~~~go\nx\n~~~",
+			expected: "```go\nx\n```",
+		},
+		{
+			name:     "backtick to tilde",
+			char:     '~',
+			input:    "```go\nx\n```",
+			expected: "~~~go\nx\n~~~",
+		},
+		{
+			name:     "overlong fence shortened and closer matched",
+			char:     '`',
+			input:    "`````\nx\n```````",
+			expected: "```\nx\n```",
+		},
+		{
+			name:     "info string preserved verbatim",
+			char:     '`',
+			input:    "~~~~ python title=\"x.py\"\nx\n~~~~",
+			expected: "``` python title=\"x.py\"\nx\n```",
+		},
+		{
+			name:     "nested fence example keeps a longer outer fence",
+			char:     '`',
+			input:    "``````markdown\n```go\nx\n```\n``````",
+			expected: "````markdown\n```go\nx\n```\n````",
+		},
+		{
+			name:     "nested fence example with tilde needs no extra length",
+			char:     '~',
+			input:    "````markdown\n```go\nx\n```\n````",
+			expected: "~~~markdown\n```go\nx\n```\n~~~",
+		},
+		{
+			name:     "inner tilde run lengthens tilde fence",
+			char:     '~',
+			input:    "```\n~~~~\n```",
+			expected: "~~~~~\n~~~~\n~~~~~",
+		},
+		{
+			name:     "backticks in info string keep tilde fence",
+			char:     '`',
+			input:    "~~~ `odd`\nx\n~~~",
+			expected: "~~~ `odd`\nx\n~~~",
+		},
+		{
+			name:     "indented fence in list item",
+			char:     '`',
+			input:    "- ~~~sh\n  ls\n  ~~~",
+			expected: "- ```sh\n  ls\n  ```",
+		},
+		{
+			name:     "quoted fence",
+			char:     '~',
+			input:    "> ````\n> x\n> ````",
+			expected: "> ~~~\n> x\n> ~~~",
+		},
+		{
+			name:     "unterminated fence untouched",
+			char:     '`',
+			input:    "~~~~\nx",
+			expected: "~~~~\nx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFenceStyleRule(tt.char).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBlankLinesAroundFencesRule(t *testing.T) {
+	rule := NewBlankLinesAroundFencesRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "inserts blanks",
+			input:    "Text\n```\ncode\n```\nMore",
+			expected: "Text\n\n```\ncode\n```\n\nMore",
+		},
+		{
+			name:     "collapses extra blanks",
+			input:    "Text\n\n\n```\ncode\n```\n\n\n\nMore",
+			expected: "Text\n\n```\ncode\n```\n\nMore",
+		},
+		{
+			name:     "start and end of document",
+			input:    "```\ncode\n```",
+			expected: "```\ncode\n```",
+		},
+		{
+			name:     "fence-looking text inside another fence",
+			input:    "````\ntext\n```\ninner\n```\n````",
+			expected: "````\ntext\n```\ninner\n```\n````",
+		},
+		{
+			name:     "blockquote uses quoted blank",
+			input:    "> Text\n> ```\n> code\n> ```\n> More",
+			expected: "> Text\n>\n> ```\n> code\n> ```\n>\n> More",
+		},
+		{
+			name:     "first and last thing in blockquote",
+			input:    "Para\n\n> ```\n> code\n> ```\n\nAfter",
+			expected: "Para\n\n> ```\n> code\n> ```\n\nAfter",
+		},
+		{
+			name:     "fence on list item marker line",
+			input:    "- ```\n  code\n  ```\n- next",
+			expected: "- ```\n  code\n  ```\n- next",
+		},
+		{
+			name:     "fence after list item text",
+			input:    "- item\n  ```\n  code\n  ```\n- next",
+			expected: "- item\n\n  ```\n  code\n  ```\n- next",
+		},
+		{
+			name:     "adjacent fences",
+			input:    "```\na\n```\n~~~\nb\n~~~",
+			expected: "```\na\n```\n\n~~~\nb\n~~~",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestFenceInfoStringRule(t *testing.T) {
+	rule := NewFenceInfoStringRule(defaultLanguageAliases)
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "lowercase",
+			input:    "```Javascript\nx\n```",
+			expected: "```javascript\nx\n```",
+		},
+		{
+			name:     "trim and alias",
+			input:    "```  js  \nx\n```",
+			expected: "```javascript\nx\n```",
+		},
+		{
+			name:     "uppercase alias",
+			input:    "~~~JS\nx\n~~~",
+			expected: "~~~javascript\nx\n~~~",
+		},
+		{
+			name:     "hugo attributes preserved",
+			input:    "```golang {hl_lines=[2]}\nx\n```",
+			expected: "```go {hl_lines=[2]}\nx\n```",
+		},
+		{
+			name:     "attribute directly after token",
+			input:    "```yml{linenos=true}\nx\n```",
+			expected: "```yaml{linenos=true}\nx\n```",
+		},
+		{
+			name:     "title attribute preserved",
+			input:    "```Python title=\"X.py\"\nx\n```",
+			expected: "```python title=\"X.py\"\nx\n```",
+		},
+		{
+			name:     "pandoc attribute block without language",
+			input:    "``` {.Python}\nx\n```",
+			expected: "``` {.Python}\nx\n```",
+		},
+		{
+			name:     "empty info string left alone",
+			input:    "```\nx\n```",
+			expected: "```\nx\n```",
+		},
+		{
+			name:     "closing fence untouched",
+			input:    "```sh\n```JS\n```",
+			expected: "```bash\n```JS\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrimFencedCodeRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		leading  bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "trailing blanks removed",
+			input:    "```\ncode\n\n\n```",
+			expected: "```\ncode\n```",
+		},
+		{
+			name:     "interior blanks kept",
+			input:    "```\na\n\nb\n  \n```",
+			expected: "```\na\n\nb\n```",
+		},
+		{
+			name:     "leading blanks kept by default",
+			input:    "```\n\ncode\n```",
+			expected: "```\n\ncode\n```",
+		},
+		{
+			name:     "leading blanks removed when enabled",
+			leading:  true,
+			input:    "```\n\n\ncode\n\n```",
+			expected: "```\ncode\n```",
+		},
+		{
+			name:     "only blank content",
+			leading:  true,
+			input:    "```\n\n\n```",
+			expected: "```\n```",
+		},
+		{
+			name:     "unterminated fence untouched",
+			input:    "```\ncode\n\n",
+			expected: "```\ncode\n\n",
+		},
+		{
+			name:     "shorter inner fence does not close",
+			input:    "````\n```\n\n````",
+			expected: "````\n```\n````",
+		},
+		{
+			name:     "quoted fence",
+			input:    "> ```\n> code\n>\n> ```",
+			expected: "> ```\n> code\n> ```",
+		},
+		{
+			name:     "indented code untouched",
+			input:    "    code\n\n\nText",
+			expected: "    code\n\n\nText",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTrimFencedCodeRule(tt.leading).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnterminatedFenceRule(t *testing.T) {
+	rule := NewUnterminatedFenceRule(true)
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "closed at end of document",
+			input:    "Text\n\n```go\ncode\n",
+			expected: "Text\n\n```go\ncode\n```\n",
+		},
+		{
+			name:     "closer matches fence length and character",
+			input:    "~~~~\ncode\n~~~\n\n",
+			expected: "~~~~\ncode\n~~~\n~~~~\n\n",
+		},
+		{
+			name:     "closed at end of blockquote",
+			input:    "> ```\n> code\n\nAfter",
+			expected: "> ```\n> code\n> ```\n\nAfter",
+		},
+		{
+			name:     "closed at end of list item",
+			input:    "- ```\n  code\nAfter",
+			expected: "- ```\n  code\n  ```\nAfter",
+		},
+		{
+			name:     "terminated fences untouched",
+			input:    "```\ncode\n```\n",
+			expected: "```\ncode\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnterminatedFenceRule_Lint(t *testing.T) {
+	rule := NewUnterminatedFenceRule(false).(Linter)
+	diags := rule.Lint("```\nok\n```\n\nText\n\n~~~\nnever closed\n")
+	if len(diags) != 1 || diags[0].Line != 7 {
+		t.Fatalf("got %v, want one diagnostic on line 7", diags)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------------------------------------------
+// Per-file settings in YAML front matter
+// ----------------------------------------------------------------
+
+// fileConfigKey is the front matter key whose sub-keys override the
+// settings for that document only, e.g.
+//
+//	mdfmt: {disable: [InlineMathToDollar], fence: tilde}
+//
+// "enable" and "disable" list rules; every other sub-key sets an option.
+const fileConfigKey = "mdfmt"
+
+// fileConfig holds the settings a document overrides.
+type fileConfig struct {
+	options Options
+	enable  []string
+	disable []string
+}
+
+// parseFileConfig reads the mdfmt key from the YAML front matter of
+// content. Entries naming unknown rules or options are reported and left
+// out. Front matter without the key, or that does not parse, yields an
+// empty config; the FrontMatter rule reports parse errors.
+func parseFileConfig(content string) (fileConfig, []Diagnostic) {
+	cfg := fileConfig{options: Options{}}
+	lines := strings.Split(content, "\n")
+	end := yamlFrontMatterEnd(lines)
+	if end < 0 {
+		return cfg, nil
+	}
+	var doc yaml.Node
+	if yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")+"\n"), &doc) != nil ||
+		len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return cfg, nil
+	}
+	var node *yaml.Node
+	m := doc.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == fileConfigKey {
+			node = m.Content[i+1]
+		}
+	}
+	if node == nil {
+		return cfg, nil
+	}
+
+	var diags []Diagnostic
+	report := func(n *yaml.Node, format string, args ...any) {
+		diags = append(diags, Diagnostic{
+			// the parser counts lines from the one after the opening "---"
+			Line:     n.Line + 1,
+			Rule:     "FrontMatter",
+			Severity: SeverityError,
+			Message:  fileConfigKey + ": " + fmt.Sprintf(format, args...),
+		})
+	}
+	if node.Kind != yaml.MappingNode {
+		report(node, "expected a mapping of settings")
+		return cfg, diags
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		switch k.Value {
+		case "enable", "disable":
+			names := []*yaml.Node{v}
+			if v.Kind == yaml.SequenceNode {
+				names = v.Content
+			}
+			for _, n := range names {
+				switch {
+				case n.Kind != yaml.ScalarNode:
+					report(n, "%s: expected rule names", k.Value)
+				case !isBuiltinRule(n.Value):
+					report(n, "%s: unknown rule %q", k.Value, n.Value)
+				case k.Value == "disable" && isRequiredRule(n.Value):
+					report(n, "disable: rule %q cannot be disabled", n.Value)
+				case k.Value == "enable":
+					cfg.enable = append(cfg.enable, n.Value)
+				default:
+					cfg.disable = append(cfg.disable, n.Value)
+				}
+			}
+		default:
+			switch {
+			case !isKnownOption(k.Value):
+				report(k, "unknown option %q", k.Value)
+			case v.Kind != yaml.ScalarNode:
+				report(v, "option %q: expected a single value", k.Value)
+			default:
+				cfg.options[k.Value] = v.Value
+			}
+		}
+	}
+	return cfg, diags
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFileConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  fileConfig
+		diags []Diagnostic
+	}{
+		{
+			name:  "no front matter",
+			input: "# Title\n",
+			want:  fileConfig{options: Options{}},
+		},
+		{
+			name:  "no mdfmt key",
+			input: "---\ntitle: x\n---\n",
+			want:  fileConfig{options: Options{}},
+		},
+		{
+			name:  "flow mapping",
+			input: "---\ntitle: x\nmdfmt: {disable: [InlineMathToDollar], fence: tilde}\n---\n",
+			want: fileConfig{
+				options: Options{"fence": "tilde"},
+				disable: []string{"InlineMathToDollar"},
+			},
+		},
+		{
+			name:  "block mapping with a single rule",
+			input: "---\nmdfmt:\n  enable: DefinitionList\n  code-language-guess: true\n---\n",
+			want: fileConfig{
+				options: Options{"code-language-guess": "true"},
+				enable:  []string{"DefinitionList"},
+			},
+		},
+		{
+			name:  "unknown names are reported",
+			input: "---\nmdfmt:\n  wrap: 0\n  disable: [Nope, UnterminatedFence, FenceStyle]\n---\n",
+			want: fileConfig{
+				options: Options{},
+				disable: []string{"FenceStyle"},
+			},
+			diags: []Diagnostic{
+				{Line: 3, Rule: "FrontMatter", Severity: SeverityError, Message: `mdfmt: unknown option "wrap"`},
+				{Line: 4, Rule: "FrontMatter", Severity: SeverityError, Message: `mdfmt: disable: unknown rule "Nope"`},
+				{Line: 4, Rule: "FrontMatter", Severity: SeverityError, Message: `mdfmt: disable: rule "UnterminatedFence" cannot be disabled`},
+			},
+		},
+		{
+			name:  "not a mapping",
+			input: "---\nmdfmt: off\n---\n",
+			want:  fileConfig{options: Options{}},
+			diags: []Diagnostic{
+				{Line: 2, Rule: "FrontMatter", Severity: SeverityError, Message: "mdfmt: expected a mapping of settings"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := parseFileConfig(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("config: got %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(diags, tt.diags) {
+				t.Errorf("diagnostics: got %v, want %v", diags, tt.diags)
+			}
+		})
+	}
+}
+
+func TestFormatDocumentFileConfig(t *testing.T) {
+	input := "---\nmdfmt:\n  disable:\n    -   InlineMathToDollar\n  bogus: 1\n---\n\nMath: \\( x \\)\n"
+	got, diags, err := formatDocument(input, "", Options{}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != input {
+		t.Errorf("expected:\n%q\ngot:\n%q", input, got)
+	}
+	want := []Diagnostic{
+		{Line: 5, Rule: "FrontMatter", Severity: SeverityError, Message: `mdfmt: unknown option "bogus"`},
+	}
+	if !reflect.DeepEqual(diags, want) {
+		t.Errorf("diagnostics: got %v, want %v", diags, want)
+	}
+
+	if _, _, err := formatDocument("---\nmdfmt: {fence: wavy}\n---\n", "", Options{}, nil, nil, false); err == nil {
+		t.Error("expected an error for an invalid option value")
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ----------------------------------------------------------------
+// Formatting documents from the command line
+// ----------------------------------------------------------------
+
+// runOptions are the command-line settings that decide how main formats
+// and reports on documents.
+type runOptions struct {
+	opts      Options
+	enable    []string
+	disable   []string
+	verify    bool
+	format    string
+	useDaemon bool
+	socket    string
+	// cacheDir is the directory of the cache, or empty without one.
+	cacheDir string
+	// write puts formatted files in place of the originals instead of
+	// printing them. A backup suffix keeps each original in the path plus
+	// the suffix, and refuseSymlinks leaves files that are symbolic links
+	// alone rather than writing their targets.
+	write          bool
+	backup         string
+	refuseSymlinks bool
+	// check only reports the documents formatting would change.
+	check bool
+	// diff prints the changes formatting makes as a unified diff in place
+	// of the formatted document.
+	diff bool
+	// jobs is how many files are formatted at once.
+	jobs int
+	// verbose reports on stderr which rules changed each document, with
+	// 2 how.
+	verbose int
+	// lines, if any, restricts formatting to the changes that touch them.
+	lines []LineRange
+}
+
+// formatAndReport formats content, the document at path, which is empty
+// for stdin without -stdin-filepath, and reports whether formatting
+// changed it. It prints the formatted document to stdout, unless a report
+// in the run's format takes its place there, and the report to stderr
+// otherwise.
+func (r runOptions) formatAndReport(stdout, stderr io.Writer, path, content string) (bool, error) {
+	if reportsResults(r.format) {
+		return r.formatResult(stdout, stderr, path, content)
+	}
+	// -check with -format github annotates the rules that would change
+	// the document
+	annotate := r.check && r.format == "github"
+	var changes []ruleChange
+	var cache *formatCache
+	// attributing changes takes formatting the document here and now,
+	// unless the cache knows it to be clean
+	if r.cacheDir != "" && r.verbose == 0 {
+		// the settings of the configuration and .editorconfig files count
+		// as well
+		cache = newFormatCache(r.cacheDir, settingsFingerprint(path, r.opts, r.enable, r.disable,
+			fmt.Sprintf("verify=%t", r.verify), fmt.Sprintf("lines=%v", r.lines)))
+	}
+	out, diags := content, []Diagnostic(nil)
+	if cache == nil || !cache.clean(path, content) {
+		err := errNoDaemon
+		if r.useDaemon && r.verbose == 0 && !annotate && len(r.lines) == 0 {
+			out, diags, err = callDaemon(r.socket, daemonRequest{
+				Path: path, Content: content, Options: r.opts, Enable: r.enable, Disable: r.disable, Verify: r.verify,
+			})
+		}
+		if err == errNoDaemon || err == errDaemonRefused {
+			var record func(rule, before, after string)
+			if annotate {
+				record = traceChanges(content, &changes)
+			}
+			trace := joinTraces(record, r.verboseTrace(stderr, path))
+			out, diags, err = formatDocumentTraced(content, path, r.opts, r.enable, r.disable, r.verify, r.lines, trace)
+		}
+		if err != nil {
+			return false, err
+		}
+		if cache != nil && cleanOutput(content, out, diags) {
+			if err := cache.markClean(path, content); err != nil {
+				fmt.Fprintln(stderr, "warning: cache:", err)
+			}
+		}
+	}
+
+	changed := out != content
+	name := path
+	if name == "" {
+		name = "<stdin>"
+	}
+	// a report on stdout replaces the document, as does writing it back
+	// or checking it
+	report := stderr
+	switch {
+	case reportOnStdout(r.format):
+		report = stdout
+	case r.diff:
+		if _, err := io.WriteString(stdout, unifiedDiff(name+".orig", name, content, out)); err != nil {
+			return changed, err
+		}
+	case !r.write && !r.check:
+		if _, err := io.WriteString(stdout, out); err != nil {
+			return changed, err
+		}
+	}
+	if r.write && changed {
+		if err := writeFileAtomic(path, content, out, r.backup, r.refuseSymlinks); err != nil {
+			return changed, err
+		}
+	}
+	if r.check && changed {
+		fmt.Fprintf(stderr, "%s is not formatted\n", name)
+		if annotate {
+			if err := writeGitHubChanges(stderr, path, changes); err != nil {
+				return changed, err
+			}
+		}
+	}
+	// the findings are on the file as written back
+	if r.write {
+		content = out
+	}
+	return changed, writeReport(report, r.format, path, content, out, diags)
+}
+
+// formatResult is formatAndReport for -format json, ndjson and sarif: it
+// prints the FileResult, or the SARIF results, for the document to stdout,
+// for a document that fails to format too. The rules that change it are
+// only known by formatting it here and now, not from the cache or the
+// daemon.
+func (r runOptions) formatResult(stdout, stderr io.Writer, path, content string) (bool, error) {
+	var changes []ruleChange
+	trace := joinTraces(traceChanges(content, &changes), r.verboseTrace(stderr, path))
+	out, diags, err := formatDocumentTraced(content, path, r.opts, r.enable, r.disable, r.verify, r.lines, trace)
+	if err != nil {
+		// SARIF has no place for a document that failed; stderr tells
+		if r.format != "sarif" {
+			if werr := writeFileResult(stdout, r.format, newFileResult(path, content, out, nil, nil, err)); werr != nil {
+				return false, werr
+			}
+		}
+		return false, err
+	}
+	changed := out != content
+	if r.write && changed {
+		if err := writeFileAtomic(path, content, out, r.backup, r.refuseSymlinks); err != nil {
+			return changed, err
+		}
+	}
+	// the findings are on the file as written back
+	in := content
+	if r.write {
+		in = out
+	}
+	// with -check, the results say what "is not formatted" would
+	if r.format == "sarif" {
+		return changed, writeSARIFResults(stdout, sarifResults(path, in, out, changes, diags))
+	}
+	return changed, writeFileResult(stdout, r.format, newFileResult(path, content, out, changedRules(changes), inputDiagnostics(in, out, diags), nil))
+}
+
+// verboseTrace returns the trace that reports the rules that change the
+// document at path with -v and -vv, nil without.
+func (r runOptions) verboseTrace(stderr io.Writer, path string) func(rule, before, after string) {
+	if r.verbose == 0 {
+		return nil
+	}
+	return ruleChanges(stderr, path, r.verbose > 1)
+}
+
+// limitAnnotations returns stderr for a run that keeps within GitHub's
+// limit on annotations with -format github, and a function that ends it.
+func (r runOptions) limitAnnotations(stderr io.Writer) (io.Writer, func()) {
+	if r.format != "github" {
+		return stderr, func() {}
+	}
+	limit := newAnnotationLimit(stderr)
+	return limit, func() { limit.close() }
+}
+
+// formatStdin formats content read from stdin, as formatAndReport does,
+// naming path, the -stdin-filepath, if any, in the error it fails with.
+func (r runOptions) formatStdin(stdout, stderr io.Writer, path, content string) (bool, error) {
+	stderr, done := r.limitAnnotations(stderr)
+	defer done()
+	arr, err := reportArray(stdout, r.format)
+	if err != nil {
+		return false, err
+	}
+	out := stdout
+	var elems bytes.Buffer
+	if arr != nil {
+		// an array of one, as for files
+		out = &elems
+	}
+	changed, err := r.formatAndReport(out, stderr, path, content)
+	if err != nil && path != "" {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	if arr != nil {
+		if werr := arr.add(elems.Bytes()); werr != nil {
+			return changed, werr
+		}
+		if werr := arr.close(); werr != nil {
+			return changed, werr
+		}
+	}
+	return changed, err
+}
+
+// formatFiles formats the files at paths, as formatAndReport does stdin,
+// and returns how many of them formatting changed. r.jobs workers format
+// files side by side, each into buffers of its own, and what they print is
+// passed on in the order of paths as soon as the files before are done. A
+// file that cannot be read or formatted is reported on stderr and the rest
+// are formatted all the same; formatFiles fails at the end when any of
+// them did. With -format json and sarif the results of the files make up
+// one array.
+func (r runOptions) formatFiles(stdout, stderr io.Writer, paths []string) (int, error) {
+	stderr, done := r.limitAnnotations(stderr)
+	defer done()
+	// -format json and sarif print one array for all the files
+	arr, err := reportArray(stdout, r.format)
+	if err != nil {
+		return 0, err
+	}
+	type result struct {
+		stdout, stderr  bytes.Buffer
+		changed, failed bool
+		done            chan struct{}
+	}
+	results := make([]result, len(paths))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	queue := make(chan int)
+	go func() {
+		for i := range paths {
+			queue <- i
+		}
+		close(queue)
+	}()
+	for range max(1, min(r.jobs, len(paths))) {
+		go func() {
+			for i := range queue {
+				res := &results[i]
+				res.changed, res.failed = r.formatFile(&res.stdout, &res.stderr, paths[i])
+				close(res.done)
+			}
+		}()
+	}
+
+	failed, changed := 0, 0
+	var werr error
+	for i := range results {
+		res := &results[i]
+		<-res.done
+		if res.changed {
+			changed++
+		}
+		if res.failed {
+			failed++
+		}
+		if arr != nil {
+			if err := arr.add(res.stdout.Bytes()); err != nil && werr == nil {
+				werr = err
+			}
+		} else if _, err := res.stdout.WriteTo(stdout); err != nil && werr == nil {
+			werr = err
+		}
+		res.stderr.WriteTo(stderr)
+	}
+	if arr != nil {
+		if err := arr.close(); err != nil && werr == nil {
+			werr = err
+		}
+	}
+	if werr != nil {
+		return changed, werr
+	}
+	if failed > 0 {
+		return changed, fmt.Errorf("%d of %d files failed", failed, len(paths))
+	}
+	return changed, nil
+}
+
+// formatFile formats the file at path for formatFiles, reporting whether
+// formatting changed it and whether it failed, which it reports on stderr,
+// and in the FileResult for -format json and ndjson.
+func (r runOptions) formatFile(stdout, stderr io.Writer, path string) (changed, failed bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// the error names the file
+		fmt.Fprintln(stderr, err)
+		if reportsResults(r.format) {
+			writeFileResult(stdout, r.format, newFileResult(path, "", "", nil, nil, err))
+		}
+		return false, true
+	}
+	changed, err = r.formatAndReport(stdout, stderr, path, string(data))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return changed, true
+	}
+	return changed, false
+}
+
+// summary describes a run over files documents, of which formatting
+// changed the given number.
+func (r runOptions) summary(files, changed int) string {
+	what := "changed"
+	if r.check {
+		what = "not formatted"
+	}
+	return fmt.Sprintf("%d file(s) visited, %d %s", files, changed, what)
+}
+
+// exitStatus is the exit status of a run that changed the given number of
+// documents and ended with err. Failures exit with 1, or with -check 2, as
+// -check exits with 1 when some document is not formatted.
+func (r runOptions) exitStatus(changed int, err error) int {
+	switch {
+	case err != nil && r.check:
+		return 2
+	case err != nil:
+		return 1
+	case r.check && changed > 0:
+		return 1
+	}
+	return 0
+}
+
+// writeTemp writes data to the temporary file that replaceFile renames
+// over the original; tests make it fail as a full disk would.
+var writeTemp = func(f *os.File, data string) error {
+	_, err := f.WriteString(data)
+	return err
+}
+
+// writeFileAtomic replaces the content old of the file at path with data.
+// A symbolic link is followed and its target written, unless
+// refuseSymlinks, when it is left alone and writeFileAtomic fails. With a
+// backupSuffix the old content is kept in path plus the suffix, such as
+// file.md.bak, before the file is replaced.
+func writeFileAtomic(path, old, data, backupSuffix string, refuseSymlinks bool) error {
+	link, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	target := path
+	if link.Mode()&os.ModeSymlink != 0 {
+		if refuseSymlinks {
+			return errors.New("is a symbolic link, left as is with -no-symlinks")
+		}
+		if target, err = filepath.EvalSymlinks(path); err != nil {
+			return err
+		}
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if backupSuffix != "" {
+		if err := replaceFile(path+backupSuffix, old, info); err != nil {
+			return err
+		}
+	}
+	return replaceFile(target, data, info)
+}
+
+// replaceFile puts data in the file at path, giving it the permissions
+// and, when mdfmt runs as root, the owner of info. The data goes to a
+// temporary file in the same directory first, which is synced to disk and
+// then renamed over path: a crash or a full disk leaves either the old
+// file or the new one, never a truncated one.
+func replaceFile(path, data string, info os.FileInfo) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	err = writeTemp(tmp, data)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = chownLike(tmp, info)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, empty, missing := filepath.Join(dir, "a.md"), filepath.Join(dir, "empty.md"), filepath.Join(dir, "missing.md")
+	b := filepath.Join(dir, "b.md")
+	writeFiles(t, dir, map[string]string{
+		"a.md":     "# A\ntext",
+		"empty.md": "",
+		"b.md":     "# B\n\nSee https://b.example\n",
+	})
+	run := runOptions{format: "text"}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		stdout  string
+		stderr  []string
+		failure string
+	}{
+		{
+			name:   "several files",
+			paths:  []string{a, b},
+			stdout: "# A\n\ntext\n# B\n\nSee https://b.example\n",
+			stderr: []string{b + ":3:5: warning: MD034/BareURL"},
+		},
+		{
+			name:   "empty file",
+			paths:  []string{empty},
+			stdout: "\n",
+		},
+		{
+			name:    "missing file",
+			paths:   []string{missing, a},
+			stdout:  "# A\n\ntext\n",
+			stderr:  []string{missing + ": no such file or directory"},
+			failure: "1 of 2 files failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			_, err := run.formatFiles(&stdout, &stderr, tt.paths)
+			if tt.failure == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.failure != "" && (err == nil || err.Error() != tt.failure) {
+				t.Errorf("got error %v, want %q", err, tt.failure)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.stdout, stdout.String())
+			}
+			for _, want := range tt.stderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr %q lacks %q", stderr.String(), want)
+				}
+			}
+		})
+	}
+
+	// the files are left as they are
+	if data, _ := os.ReadFile(a); string(data) != "# A\ntext" {
+		t.Errorf("a.md changed: %q", data)
+	}
+}
+
+func TestFormatFilesWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"messy.md": "# A\ntext",
+		"clean.md": "# A\n\ntext\n",
+	})
+	messy, clean := filepath.Join(dir, "messy.md"), filepath.Join(dir, "clean.md")
+	if err := os.Chmod(messy, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(clean, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	run := runOptions{format: "text", write: true, backup: ".bak"}
+	var stdout, stderr strings.Builder
+	if _, err := run.formatFiles(&stdout, &stderr, []string{messy, clean}); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("output in -w mode: %q, %q", stdout.String(), stderr.String())
+	}
+
+	if data, _ := os.ReadFile(messy); string(data) != "# A\n\ntext\n" {
+		t.Errorf("messy.md: got %q", data)
+	}
+	if info, err := os.Stat(messy); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("messy.md: permissions not kept: %v, %v", info.Mode(), err)
+	}
+	if data, _ := os.ReadFile(messy + ".bak"); string(data) != "# A\ntext" {
+		t.Errorf("messy.md.bak: got %q", data)
+	}
+
+	// formatted files are not touched at all
+	if info, err := os.Stat(clean); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("clean.md: modification time changed to %v", info.ModTime())
+	}
+	if _, err := os.Stat(clean + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("clean.md got a backup: %v", err)
+	}
+	// and no temporary files stay behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("got %d files in the directory, want 3", len(entries))
+	}
+}
+
+func TestFormatFilesCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.md":  "# A\n\ntext\n",
+		"messy.md":  "# A\ntext\n",
+		"no-eol.md": "# A\n\ntext",
+		"bad-fm.md": "---\nmdfmt: {fence: nonsense}\n---\n",
+		"clean2.md": "Text.\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	run := runOptions{format: "text", check: true}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		changed int
+		status  int
+		stderr  string
+	}{
+		{
+			name:   "clean",
+			paths:  []string{path("clean.md"), path("clean2.md")},
+			status: 0,
+		},
+		{
+			name:    "some not formatted",
+			paths:   []string{path("clean.md"), path("messy.md"), path("no-eol.md"), path("clean2.md")},
+			changed: 2,
+			status:  1,
+			stderr:  path("messy.md") + " is not formatted\n" + path("no-eol.md") + " is not formatted\n",
+		},
+		{
+			name:   "bad settings",
+			paths:  []string{path("clean.md"), path("bad-fm.md")},
+			status: 2,
+		},
+		{
+			name:   "bad settings",
+			paths:  []string{path("clean.md"), path("bad-fm.md")},
+			status: 2,
+		},
+		{
+			name:    "missing file",
+			paths:   []string{path("messy.md"), path("missing.md")},
+			changed: 1,
+			status:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			changed, err := run.formatFiles(&stdout, &stderr, tt.paths)
+			if changed != tt.changed {
+				t.Errorf("got %d changed, want %d", changed, tt.changed)
+			}
+			if got := run.exitStatus(changed, err); got != tt.status {
+				t.Errorf("got exit status %d, want %d (error %v)", got, tt.status, err)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("output in -check mode: %q", stdout.String())
+			}
+			if tt.stderr != "" && stderr.String() != tt.stderr {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.stderr, stderr.String())
+			}
+		})
+	}
+	if data, _ := os.ReadFile(path("messy.md")); string(data) != "# A\ntext\n" {
+		t.Errorf("-check changed messy.md: %q", data)
+	}
+}
+
+func TestFormatFilesDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.md": "# A\n\ntext\n",
+		"messy.md": "# A\ntext\n",
+	})
+	clean, messy := filepath.Join(dir, "clean.md"), filepath.Join(dir, "messy.md")
+	run := runOptions{format: "text", diff: true}
+
+	var stdout, stderr strings.Builder
+	changed, err := run.formatFiles(&stdout, &stderr, []string{clean, messy})
+	if err != nil || run.exitStatus(changed, err) != 0 {
+		t.Fatalf("got %d changed, error %v", changed, err)
+	}
+	want := "--- " + messy + ".orig\n+++ " + messy + "\n@@ -1,2 +1,3 @@\n # A\n+\n text\n"
+	if stdout.String() != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, stdout.String())
+	}
+}
+
+func TestFormatFilesJobs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{}
+	var paths []string
+	for i := range 40 {
+		name := fmt.Sprintf("doc%02d.md", i)
+		files[name] = fmt.Sprintf("# Doc %d\ntext\n", i)
+		if i%3 == 0 {
+			// formatted already
+			files[name] = fmt.Sprintf("# Doc %d\n\nSee https://%d.example\n", i, i)
+		}
+		paths = append(paths, filepath.Join(dir, name))
+		if i%10 == 5 {
+			paths = append(paths, filepath.Join(dir, fmt.Sprintf("missing%d.md", i)))
+		}
+	}
+	writeFiles(t, dir, files)
+
+	run := func(jobs int) (string, string, int, error) {
+		var stdout, stderr strings.Builder
+		r := runOptions{format: "text", jobs: jobs}
+		changed, err := r.formatFiles(&stdout, &stderr, paths)
+		return stdout.String(), stderr.String(), changed, err
+	}
+	stdout, stderr, changed, err := run(1)
+	if changed != 26 || err == nil || err.Error() != "4 of 44 files failed" {
+		t.Fatalf("got %d changed, error %v", changed, err)
+	}
+	for _, jobs := range []int{2, 8, 100} {
+		out, errOut, c, err2 := run(jobs)
+		if out != stdout || errOut != stderr || c != changed || err2 == nil || err2.Error() != err.Error() {
+			t.Errorf("-jobs %d: output differs from -jobs 1:\n%s\n%s", jobs, errOut, stderr)
+		}
+	}
+}
+
+func TestFormatStdin(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    runOptions
+		path   string
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "diff",
+			run:    runOptions{format: "text", diff: true},
+			stdout: "--- <stdin>.orig\n+++ <stdin>\n@@ -1,2 +1,3 @@\n # A\n+\n text\n",
+		},
+		{
+			name:   "diff with path",
+			run:    runOptions{format: "text", diff: true},
+			path:   "docs/a.md",
+			stdout: "--- docs/a.md.orig\n+++ docs/a.md\n@@ -1,2 +1,3 @@\n # A\n+\n text\n",
+		},
+		{
+			name:   "check",
+			run:    runOptions{format: "text", check: true},
+			stderr: "<stdin> is not formatted\n",
+		},
+		{
+			name:   "check with path",
+			run:    runOptions{format: "text", check: true},
+			path:   "docs/a.md",
+			stderr: "docs/a.md is not formatted\n",
+		},
+		{
+			name: "error",
+			run:  runOptions{format: "text", opts: Options{"nope": "1"}},
+			err:  "unknown option(s): nope",
+		},
+		{
+			name: "error with path",
+			run:  runOptions{format: "text", opts: Options{"nope": "1"}},
+			path: "docs/a.md",
+			err:  "docs/a.md: unknown option(s): nope",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			_, err := tt.run.formatStdin(&stdout, &stderr, tt.path, "# A\ntext\n")
+			if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.stdout, stdout.String())
+			}
+			if stderr.String() != tt.stderr {
+				t.Errorf("expected stderr:\n%q\ngot:\n%q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestFormatFilesLines(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "# A\ntext\n\n# B\nmore"})
+	a := filepath.Join(dir, "a.md")
+
+	run := runOptions{format: "text", write: true, cacheDir: t.TempDir(), lines: []LineRange{{4, 5}}}
+	var stdout, stderr strings.Builder
+	if _, err := run.formatFiles(&stdout, &stderr, []string{a}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "# A\ntext\n\n# B\n\nmore\n" {
+		t.Errorf("with -lines 4:5: got %q", data)
+	}
+
+	// nothing to change on the blank line, which does not make the
+	// document clean for the cache
+	run.lines = []LineRange{{3, 3}}
+	if changed, err := run.formatFiles(&stdout, &stderr, []string{a}); err != nil || changed != 0 {
+		t.Fatalf("with -lines 3: %d changed, %v", changed, err)
+	}
+	run.lines = nil
+	if _, err := run.formatFiles(&stdout, &stderr, []string{a}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "# A\n\ntext\n\n# B\n\nmore\n" {
+		t.Errorf("without -lines: got %q", data)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	const old, formatted = "# A\ntext", "# A\n\ntext\n"
+
+	t.Run("failing write", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"a.md": old})
+		path := filepath.Join(dir, "a.md")
+		defer func(w func(*os.File, string) error) { writeTemp = w }(writeTemp)
+		// the disk fills up halfway through the formatted document
+		writeTemp = func(f *os.File, data string) error {
+			if data != formatted {
+				_, err := f.WriteString(data)
+				return err
+			}
+			f.WriteString(data[:len(data)/2])
+			return syscall.ENOSPC
+		}
+		if err := writeFileAtomic(path, old, formatted, ".orig", false); !errors.Is(err, syscall.ENOSPC) {
+			t.Fatalf("expected ENOSPC, got %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != old {
+			t.Errorf("original changed to %q", data)
+		}
+		if data, _ := os.ReadFile(path + ".orig"); string(data) != old {
+			t.Errorf("backup: got %q", data)
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 2 {
+			t.Errorf("got %d files in the directory, want the original and its backup", len(entries))
+		}
+	})
+
+	t.Run("backup suffix", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"a.md": old})
+		path := filepath.Join(dir, "a.md")
+		if err := os.Chmod(path, 0o604); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFileAtomic(path, old, formatted, ".orig", false); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != formatted {
+			t.Errorf("got %q", data)
+		}
+		for _, p := range []string{path, path + ".orig"} {
+			if info, err := os.Stat(p); err != nil || info.Mode().Perm() != 0o604 {
+				t.Errorf("%s: permissions not kept: %v, %v", filepath.Base(p), info.Mode(), err)
+			}
+		}
+		if data, _ := os.ReadFile(path + ".orig"); string(data) != old {
+			t.Errorf("backup: got %q", data)
+		}
+	})
+
+	t.Run("symbolic link", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"docs/a.md": old})
+		target, link := filepath.Join(dir, "docs", "a.md"), filepath.Join(dir, "a.md")
+		if err := os.Symlink(filepath.Join("docs", "a.md"), link); err != nil {
+			t.Skip("no symbolic links:", err)
+		}
+
+		if err := writeFileAtomic(link, old, formatted, "", true); err == nil {
+			t.Error("expected -no-symlinks to refuse the link")
+		}
+		if data, _ := os.ReadFile(target); string(data) != old {
+			t.Errorf("refused, but the target changed to %q", data)
+		}
+
+		if err := writeFileAtomic(link, old, formatted, "", false); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("the link was replaced: %v, %v", info.Mode(), err)
+		}
+		if data, _ := os.ReadFile(target); string(data) != formatted {
+			t.Errorf("target: got %q", data)
+		}
+	})
+}
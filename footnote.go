@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 19: renumber footnotes and gather their definitions at the end
+// ----------------------------------------------------------------
+
+// footnoteReference matches a footnote reference such as [^1] or [^note].
+var footnoteReference = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+
+type FootnoteNumberingRule struct{}
+
+func NewFootnoteNumberingRule() Rule { return FootnoteNumberingRule{} }
+
+func (FootnoteNumberingRule) Name() string {
+	return "FootnoteNumbering"
+}
+
+// footnoteBlock is a top-level footnote definition spanning lines
+// start..end inclusive.
+type footnoteBlock struct {
+	key        string // lowercased label
+	start, end int
+}
+
+func (FootnoteNumberingRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	defs := footnoteBlocks(lines, b)
+	inDef := make([]bool, len(lines))
+	byKey := map[string][]footnoteBlock{}
+	for _, d := range defs {
+		for i := d.start; i <= d.end; i++ {
+			inDef[i] = true
+		}
+		byKey[d.key] = append(byKey[d.key], d)
+	}
+
+	// number labels by first reference in the body, then by references
+	// inside the definitions in the order those are emitted, so that a
+	// second run sees the same order
+	var order []string
+	seen := map[string]bool{}
+	add := func(i int) {
+		for _, label := range footnoteRefs(lines[i], b.lines[i]) {
+			if k := strings.ToLower(label); !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+		}
+	}
+	for i := range lines {
+		if !inDef[i] && !literalLine(b, i) {
+			add(i)
+		}
+	}
+	next := 0
+	for qi := 0; ; qi++ {
+		for qi == len(order) && next < len(defs) {
+			if k := defs[next].key; !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+			next++
+		}
+		if qi == len(order) {
+			break
+		}
+		for _, d := range byKey[order[qi]] {
+			for i := d.start; i <= d.end; i++ {
+				if !literalLine(b, i) {
+					add(i)
+				}
+			}
+		}
+	}
+	if len(order) == 0 {
+		return content, nil
+	}
+
+	renamed := map[string]string{}
+	n := 0
+	for _, k := range order {
+		if isNumericLabel(k) {
+			n++
+			renamed[k] = strconv.Itoa(n)
+		}
+	}
+	for i := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutsideCode(lines[i], func(s string) string {
+			return footnoteReference.ReplaceAllStringFunc(s, func(m string) string {
+				if to, ok := renamed[strings.ToLower(m[2:len(m)-1])]; ok {
+					return "[^" + to + "]"
+				}
+				return m
+			})
+		})
+	}
+	if len(defs) == 0 {
+		return strings.Join(lines, "\n"), nil
+	}
+
+	// the body, without definitions and the extra blank lines they
+	// leave behind
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+	out := make([]string, 0, len(lines))
+	removed := false
+	for i, l := range lines {
+		if inDef[i] {
+			removed = true
+			continue
+		}
+		if removed && blank(l) && (len(out) == 0 || blank(out[len(out)-1])) {
+			continue
+		}
+		removed = false
+		out = append(out, l)
+	}
+	trailingNewline := len(lines) > 1 && lines[len(lines)-1] == ""
+	for len(out) > 0 && blank(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+
+	prevMulti := false
+	for i, k := range order {
+		for j, d := range byKey[k] {
+			multi := d.end > d.start
+			switch {
+			case len(out) == 0:
+			case i == 0 && j == 0, multi, prevMulti:
+				out = append(out, "")
+			}
+			out = append(out, lines[d.start:d.end+1]...)
+			prevMulti = multi
+		}
+	}
+	if trailingNewline {
+		out = append(out, "")
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// footnoteBlocks finds the top-level footnote definitions together with
+// their indented continuation lines.
+func footnoteBlocks(lines []string, b *blocks) []footnoteBlock {
+	var defs []footnoteBlock
+	for i := 0; i < len(lines); i++ {
+		info := b.lines[i]
+		if !info.footnote || info.quote != 0 || info.container != 0 {
+			continue
+		}
+		m := footnoteDefinition.FindStringSubmatch(lines[i])
+		d := footnoteBlock{key: strings.ToLower(m[1]), start: i, end: i}
+		for j := i + 1; j < len(lines); j++ {
+			if b.lines[j].kind == lineBlank {
+				continue
+			}
+			if b.lines[j].container < info.indent+4 {
+				break
+			}
+			d.end = j
+		}
+		defs = append(defs, d)
+		i = d.end
+	}
+	return defs
+}
+
+// footnoteRefs returns the labels referenced on a line, in order, leaving
+// out the label a definition line defines and anything in code spans.
+func footnoteRefs(line string, info lineInfo) []string {
+	body := line[info.bodyStart:]
+	if info.footnote {
+		body = body[len(footnoteDefinition.FindString(body)):]
+	}
+	var labels []string
+	mapOutsideCode(body, func(s string) string {
+		for _, m := range footnoteReference.FindAllStringSubmatch(s, -1) {
+			labels = append(labels, m[1])
+		}
+		return s
+	})
+	return labels
+}
+
+// literalLine reports whether markup on line i is literal text: code,
+// front matter, an HTML block, the fence of a Pandoc div, whose
+// attributes are not prose, or an abbreviation definition.
+func literalLine(b *blocks, i int) bool {
+	switch b.lines[i].kind {
+	case lineFrontMatter, lineHTML, lineDivFence, lineAbbreviation:
+		return true
+	}
+	return b.isCode(i)
+}
+
+func isNumericLabel(label string) bool {
+	for _, r := range label {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return label != ""
+}
+
+// ----------------------------------------------------------------
+// Rule 20: report footnote reference/definition mismatches
+// ----------------------------------------------------------------
+
+type FootnoteReferencesRule struct{}
+
+func NewFootnoteReferencesRule() Rule { return FootnoteReferencesRule{} }
+
+func (FootnoteReferencesRule) Name() string {
+	return "FootnoteReferences"
+}
+
+func (FootnoteReferencesRule) Apply(content string) (string, error) {
+	return content, nil
+}
+
+func (r FootnoteReferencesRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+
+	type ref struct {
+		label string
+		line  int
+	}
+	var refs []ref
+	defined := map[string]int{} // key → line of the first definition
+	referenced := map[string]bool{}
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		if b.lines[i].footnote {
+			label := footnoteDefinition.FindStringSubmatch(line[b.lines[i].bodyStart:])[1]
+			k := strings.ToLower(label)
+			if first, ok := defined[k]; ok {
+				diags = append(diags, Diagnostic{
+					Line:     i + 1,
+					Rule:     r.Name(),
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("duplicate definition of footnote [^%s] (first defined on line %d)", label, first),
+				})
+			} else {
+				defined[k] = i + 1
+			}
+		}
+		for _, label := range footnoteRefs(line, b.lines[i]) {
+			refs = append(refs, ref{label, i + 1})
+			referenced[strings.ToLower(label)] = true
+		}
+	}
+
+	for _, rf := range refs {
+		if _, ok := defined[strings.ToLower(rf.label)]; !ok {
+			diags = append(diags, Diagnostic{
+				Line:     rf.line,
+				Rule:     r.Name(),
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("footnote [^%s] has no definition", rf.label),
+			})
+		}
+	}
+	for i, line := range lines {
+		if !b.lines[i].footnote || literalLine(b, i) {
+			continue
+		}
+		label := footnoteDefinition.FindStringSubmatch(line[b.lines[i].bodyStart:])[1]
+		if k := strings.ToLower(label); !referenced[k] && defined[k] == i+1 {
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Rule:    r.Name(),
+				Message: fmt.Sprintf("footnote [^%s] is defined but never referenced", label),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,108 @@
+package main
+
+import "testing"
+
+func TestFootnoteNumberingRule(t *testing.T) {
+	rule := NewFootnoteNumberingRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "renumbers in order of first reference",
+			input:    "A[^3] and B[^1].\n\n[^1]: one\n[^3]: three\n",
+			expected: "A[^1] and B[^2].\n\n[^1]: three\n[^2]: one\n",
+		},
+		{
+			name:     "moves definitions to the end",
+			input:    "Intro[^1].\n\n[^1]: note\n\nMore text.\n",
+			expected: "Intro[^1].\n\nMore text.\n\n[^1]: note\n",
+		},
+		{
+			name:     "named labels keep their names",
+			input:    "X[^caveat] Y[^7]\n\n[^7]: seven\n[^caveat]: careful\n",
+			expected: "X[^caveat] Y[^1]\n\n[^caveat]: careful\n[^1]: seven\n",
+		},
+		{
+			name:     "multi-paragraph definitions move whole",
+			input:    "A[^2]\n\n[^2]: first para\n\n    second para\n\nB\n",
+			expected: "A[^1]\n\nB\n\n[^1]: first para\n\n    second para\n",
+		},
+		{
+			name:     "references in code are literal",
+			input:    "A[^5] `[^5]`\n\n```\n[^5]\n```\n\n[^5]: five\n",
+			expected: "A[^1] `[^5]`\n\n```\n[^5]\n```\n\n[^1]: five\n",
+		},
+		{
+			name:     "references inside definitions come after the body",
+			input:    "A[^1]\n\n[^1]: see [^9]\n\nB[^4]\n\n[^9]: nine\n[^4]: four\n",
+			expected: "A[^1]\n\nB[^2]\n\n[^1]: see [^3]\n[^2]: four\n[^3]: nine\n",
+		},
+		{
+			name:     "unreferenced definitions go last",
+			input:    "[^8]: orphan\n\nA[^2]\n\n[^2]: two\n",
+			expected: "A[^1]\n\n[^1]: two\n[^2]: orphan\n",
+		},
+		{
+			name:     "case-insensitive labels",
+			input:    "A[^Note]\n\n[^note]: n\n",
+			expected: "A[^Note]\n\n[^note]: n\n",
+		},
+		{
+			name:     "no footnotes",
+			input:    "Just text.\n\n\n",
+			expected: "Just text.\n\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestFootnoteReferencesRule_Lint(t *testing.T) {
+	rule := NewFootnoteReferencesRule().(Linter)
+	input := "A[^1] B[^missing] `[^code]`\n" +
+		"\n" +
+		"```\n[^fenced]\n```\n" +
+		"\n" +
+		"[^1]: one\n" +
+		"[^orphan]: nobody points here\n" +
+		"[^ORPHAN]: same label in other case\n" +
+		"[^1]: duplicate\n"
+
+	type want struct {
+		line     int
+		severity Severity
+		message  string
+	}
+	wants := []want{
+		{9, SeverityError, "duplicate definition of footnote [^ORPHAN] (first defined on line 8)"},
+		{10, SeverityError, "duplicate definition of footnote [^1] (first defined on line 7)"},
+		{1, SeverityError, "footnote [^missing] has no definition"},
+		{8, SeverityWarning, "footnote [^orphan] is defined but never referenced"},
+	}
+	got := rule.Lint(input)
+	if len(got) != len(wants) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(got), len(wants), got)
+	}
+	for i, w := range wants {
+		d := got[i]
+		if d.Line != w.line || d.Severity != w.severity || d.Message != w.message {
+			t.Errorf("diagnostic %d = %v, want %d %v %q", i, d, w.line, w.severity, w.message)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ----------------------------------------------------------------
+// Rule 41: French no-break spaces before tall punctuation
+// ----------------------------------------------------------------
+
+type FrenchSpacingRule struct {
+	// lang is the language of documents that do not declare one in their
+	// front matter.
+	lang string
+	// space is the no-break space written.
+	space rune
+}
+
+// NewFrenchSpacingRule constructs a rule that, in French documents, puts
+// the no-break space before ":", ";", "!" and "?" and inside guillemets.
+// A document is French when its front matter lang, or else lang, is "fr"
+// or "fr-...".
+func NewFrenchSpacingRule(lang string, space rune) Rule {
+	return FrenchSpacingRule{lang: lang, space: space}
+}
+
+func newFrenchSpacingRuleFromOptions(o Options) (Rule, error) {
+	space, err := o.Choice("french-space", "nbsp", "nbsp", "narrow")
+	if err != nil {
+		return nil, err
+	}
+	r := '\u00a0'
+	if space == "narrow" {
+		r = '\u202f'
+	}
+	return NewFrenchSpacingRule(o.Get("lang", ""), r), nil
+}
+
+func (FrenchSpacingRule) Name() string {
+	return "FrenchSpacing"
+}
+
+// isFrench reports whether the language tag lang is French.
+func isFrench(lang string) bool {
+	lang = strings.ToLower(lang)
+	return lang == "fr" || strings.HasPrefix(lang, "fr-") || strings.HasPrefix(lang, "fr_")
+}
+
+// documentLang returns the lang of a document's front matter, or def.
+func documentLang(lines []string, def string) string {
+	if v, ok := frontMatterValues(lines)["lang"]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return def
+}
+
+func (r FrenchSpacingRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	if !isFrench(documentLang(lines, r.lang)) {
+		return content, nil
+	}
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutside(line, nonProseSpans(line), r.spaceOut)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// isSpace reports whether c is a space the rule may replace.
+func isSpace(c rune) bool {
+	return c == ' ' || c == '\u00a0' || c == '\u202f'
+}
+
+// spaceOut spaces the punctuation in one stretch of prose.
+func (r FrenchSpacingRule) spaceOut(s string) string {
+	runes := []rune(s)
+	var out []rune
+	for k := 0; k < len(runes); k++ {
+		c := runes[k]
+		switch {
+		case strings.ContainsRune(":;!?", c) && r.tall(runes, k):
+			// replace the space before the mark, or add one
+			n := len(out)
+			if n > 0 && isSpace(out[n-1]) {
+				out[n-1] = r.space
+			} else {
+				out = append(out, r.space)
+			}
+			out = append(out, c)
+		case c == '«':
+			out = append(out, c)
+			for k+1 < len(runes) && isSpace(runes[k+1]) {
+				k++
+			}
+			if k+1 < len(runes) && runes[k+1] != '»' {
+				out = append(out, r.space)
+			}
+		case c == '»':
+			for len(out) > 0 && isSpace(out[len(out)-1]) {
+				out = out[:len(out)-1]
+			}
+			if len(out) > 0 && out[len(out)-1] != '«' {
+				out = append(out, r.space)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+// tall reports whether the mark at runes[k] ends a word or a sentence: it
+// follows a word, directly or after one space, and is followed by a space,
+// the end of the text, emphasis or quotes, or another mark, which then
+// gets no space of its own. That leaves out times (10:30), schemes
+// (http://), emoticons (:-) and the like.
+func (r FrenchSpacingRule) tall(runes []rune, k int) bool {
+	p := k - 1
+	if p >= 0 && isSpace(runes[p]) {
+		p--
+	}
+	if p < 0 {
+		return false
+	}
+	if prev := runes[p]; !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && !strings.ContainsRune("»)*_\"”", prev) {
+		return false
+	}
+	if k+1 == len(runes) {
+		return true
+	}
+	next := runes[k+1]
+	return unicode.IsSpace(next) || strings.ContainsRune("!?*_\"”»", next)
+}
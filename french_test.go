@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrenchSpacingRule(t *testing.T) {
+	// "~" stands for the no-break space in the expectations
+	tests := []struct {
+		name     string
+		lang     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "tall punctuation",
+			lang:     "fr",
+			input:    "Attention: danger ! Vraiment?! Oui ; non. **Note** :",
+			expected: "Attention~: danger~! Vraiment~?! Oui~; non. **Note**~:",
+		},
+		{
+			name:     "guillemets",
+			lang:     "fr-CA",
+			input:    "Il dit «bonjour» puis «  au revoir ».",
+			expected: "Il dit «~bonjour~» puis «~au revoir~».",
+		},
+		{
+			name:     "left alone",
+			lang:     "fr",
+			input:    "À 10:30, voir https://exemple.fr/a?b=1 ou `a: b` :) ;-) :D\n\n[^1]: note\n\n```\nx: y !\n```",
+			expected: "À 10:30, voir https://exemple.fr/a?b=1 ou `a: b` :) ;-) :D\n\n[^1]: note\n\n```\nx: y !\n```",
+		},
+		{
+			name:     "other languages",
+			lang:     "en",
+			input:    "Attention: danger!",
+			expected: "Attention: danger!",
+		},
+		{
+			name:     "front matter lang wins",
+			lang:     "en",
+			input:    "---\nlang: fr\n---\nAttention: danger!",
+			expected: "---\nlang: fr\n---\nAttention~: danger~!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewFrenchSpacingRule(tt.lang, '\u00a0')
+			expected := strings.ReplaceAll(tt.expected, "~", "\u00a0")
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+			// a different space replaces the one there
+			narrow := NewFrenchSpacingRule(tt.lang, '\u202f')
+			if n, _ := narrow.Apply(got); n != strings.ReplaceAll(expected, "\u00a0", "\u202f") {
+				t.Errorf("narrow:\n%q", n)
+			}
+		})
+	}
+}
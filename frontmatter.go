@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------------------------------------------
+// Rule 24: sort and re-emit YAML front matter keys
+// ----------------------------------------------------------------
+
+// defaultFrontMatterOrder lists the keys that lead the front matter, in
+// order; the remaining keys follow alphabetically.
+var defaultFrontMatterOrder = []string{"title", "date", "draft", "tags"}
+
+type FrontMatterKeysRule struct {
+	order []string
+}
+
+// NewFrontMatterKeysRule constructs a rule that puts the keys in order
+// first and sorts the rest alphabetically.
+func NewFrontMatterKeysRule(order []string) Rule {
+	return FrontMatterKeysRule{order: order}
+}
+
+func newFrontMatterKeysRuleFromOptions(o Options) (Rule, error) {
+	return NewFrontMatterKeysRule(o.List("front-matter-order", defaultFrontMatterOrder)), nil
+}
+
+func (FrontMatterKeysRule) Name() string {
+	return "FrontMatterKeys"
+}
+
+// Apply re-emits the front matter through the YAML library, which keeps
+// comments attached to their keys and the style of every scalar. Front
+// matter that does not parse, or is not a mapping, is left untouched for
+// FrontMatterRule to report.
+func (r FrontMatterKeysRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	end := yamlFrontMatterEnd(lines)
+	if end < 0 {
+		return content, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")+"\n"), &doc); err != nil {
+		return content, nil
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return content, nil
+	}
+	m := doc.Content[0]
+
+	rank := make(map[string]int, len(r.order))
+	for i, k := range r.order {
+		if _, ok := rank[k]; !ok {
+			rank[k] = i
+		}
+	}
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		pairs = append(pairs, pair{m.Content[i], m.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, iok := rank[pairs[i].key.Value]
+		rj, jok := rank[pairs[j].key.Value]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		}
+		return pairs[i].key.Value < pairs[j].key.Value
+	})
+	m.Content = m.Content[:0]
+	for _, p := range pairs {
+		m.Content = append(m.Content, p.key, p.value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return content, nil
+	}
+	enc.Close()
+	body := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+
+	out := make([]string, 0, len(lines)-end+len(body)+1)
+	out = append(out, lines[0])
+	out = append(out, body...)
+	out = append(out, lines[end:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+// frontMatterValues decodes the YAML or TOML front matter of a document.
+// It returns nil when there is none or it does not parse.
+func frontMatterValues(lines []string) map[string]any {
+	end := frontMatterEnd(lines)
+	if end < 0 {
+		return nil
+	}
+	src := strings.Join(lines[1:end], "\n") + "\n"
+	values := map[string]any{}
+	var err error
+	if strings.TrimRight(lines[0], " \t") == "+++" {
+		_, err = toml.Decode(src, &values)
+	} else {
+		err = yaml.Unmarshal([]byte(src), &values)
+	}
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// yamlFrontMatterEnd is frontMatterEnd for YAML front matter only, which
+// opens with "---".
+func yamlFrontMatterEnd(lines []string) int {
+	end := frontMatterEnd(lines)
+	if end < 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return -1
+	}
+	return end
+}
+
+// ----------------------------------------------------------------
+// Rule 25: report front matter that does not parse or lacks keys
+// ----------------------------------------------------------------
+
+// yamlErrorLine matches the line number the YAML library puts in its
+// messages, relative to the start of the parsed text.
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+type FrontMatterRule struct {
+	required []string
+}
+
+// NewFrontMatterRule constructs a rule that reports YAML and TOML front
+// matter that fails to parse, and front matter without the keys in
+// required.
+func NewFrontMatterRule(required []string) Rule {
+	return FrontMatterRule{required: required}
+}
+
+func newFrontMatterRuleFromOptions(o Options) (Rule, error) {
+	return NewFrontMatterRule(o.List("front-matter-required", nil)), nil
+}
+
+func (FrontMatterRule) Name() string {
+	return "FrontMatter"
+}
+
+func (FrontMatterRule) Apply(content string) (string, error) {
+	return content, nil
+}
+
+func (r FrontMatterRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	end := frontMatterEnd(lines)
+	if end < 0 {
+		return nil
+	}
+	src := strings.Join(lines[1:end], "\n") + "\n"
+	// the parsers count lines from the one after the opening delimiter
+	diag := func(line int, msg string) Diagnostic {
+		return Diagnostic{Line: line + 1, Rule: r.Name(), Severity: SeverityError, Message: msg}
+	}
+
+	keys := map[string]any{}
+	var diags []Diagnostic
+	if strings.TrimRight(lines[0], " \t") == "+++" {
+		if _, err := toml.Decode(src, &keys); err != nil {
+			var perr toml.ParseError
+			if errors.As(err, &perr) {
+				return []Diagnostic{diag(perr.Position.Line, "invalid TOML front matter: "+perr.Message)}
+			}
+			return []Diagnostic{diag(0, "invalid TOML front matter: "+err.Error())}
+		}
+	} else if err := yaml.Unmarshal([]byte(src), &keys); err != nil {
+		msgs := []string{err.Error()}
+		var terr *yaml.TypeError
+		if errors.As(err, &terr) {
+			msgs = terr.Errors
+		}
+		for _, msg := range msgs {
+			line := 0
+			if m := yamlErrorLine.FindStringSubmatch(strings.TrimSpace(msg)); m != nil {
+				line, _ = strconv.Atoi(m[1])
+				msg = m[2]
+			}
+			diags = append(diags, diag(line, "invalid YAML front matter: "+strings.TrimPrefix(msg, "yaml: ")))
+		}
+		return diags
+	}
+
+	for _, k := range r.required {
+		if _, ok := keys[k]; !ok {
+			diags = append(diags, diag(0, fmt.Sprintf("front matter is missing required key %q", k)))
+		}
+	}
+	return diags
+}
+
+// ----------------------------------------------------------------
+// Rule 26: insert front matter from a template when there is none
+// ----------------------------------------------------------------
+
+// defaultFrontMatterTemplate is used when no template file is configured.
+const defaultFrontMatterTemplate = "title: {{title}}\ndate: {{date}}\n"
+
+type FrontMatterTemplateRule struct {
+	template string
+	path     string
+	now      func() time.Time
+}
+
+// NewFrontMatterTemplateRule constructs a rule that gives documents
+// without front matter a YAML block rendered from template. {{title}} is
+// replaced by the first level-one heading, or the file name, and {{date}}
+// by the file's modification date, or today's.
+func NewFrontMatterTemplateRule(template string) Rule {
+	return FrontMatterTemplateRule{template: template, now: time.Now}
+}
+
+func newFrontMatterTemplateRuleFromOptions(o Options) (Rule, error) {
+	file := o.Get("front-matter-template", "")
+	if file == "" {
+		return NewFrontMatterTemplateRule(defaultFrontMatterTemplate), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("option front-matter-template: %w", err)
+	}
+	return NewFrontMatterTemplateRule(string(data)), nil
+}
+
+func (FrontMatterTemplateRule) Name() string {
+	return "FrontMatterTemplate"
+}
+
+func (r FrontMatterTemplateRule) WithPath(path string) Rule {
+	r.path = path
+	return r
+}
+
+func (r FrontMatterTemplateRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	if metadataEnd(lines) >= 0 {
+		return content, nil
+	}
+
+	title := documentTitle(lines, classifyBlocks(lines))
+	if title == "" && r.path != "" {
+		title = strings.TrimSuffix(filepath.Base(r.path), filepath.Ext(r.path))
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+	date := r.now()
+	if r.path != "" {
+		if fi, err := os.Stat(r.path); err == nil {
+			date = fi.ModTime()
+		}
+	}
+	fm := strings.NewReplacer(
+		"{{title}}", yamlScalar(title),
+		"{{date}}", date.Format("2006-01-02"),
+	).Replace(strings.TrimRight(r.template, "\n"))
+
+	sep := "\n"
+	if strings.TrimSpace(lines[0]) == "" {
+		sep = ""
+	}
+	return "---\n" + fm + "\n---\n" + sep + content, nil
+}
+
+// documentTitle returns the text of the first level-one heading outside of
+// code, or "".
+func documentTitle(lines []string, b *blocks) string {
+	for i, line := range lines {
+		info := b.lines[i]
+		if b.isCode(i) || info.quote > 0 || info.container > 0 || info.kind == lineFrontMatter {
+			continue
+		}
+		if info.kind == lineSetextUnderline && strings.HasPrefix(strings.TrimSpace(line), "=") {
+			return strings.TrimSpace(lines[i-1])
+		}
+		if text, ok := atxH1Text(line); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// atxH1Text returns the text of line if it is a level-one ATX heading.
+func atxH1Text(line string) (string, bool) {
+	level, text, ok := atxHeading(line)
+	return text, ok && level == 1
+}
+
+// yamlScalar renders s as a plain YAML scalar, quoted only when it has to
+// be.
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// isPlainYAMLScalar reports whether s can be written unquoted as a YAML
+// value and read back unchanged, whatever type it resolves to.
+func isPlainYAMLScalar(s string) bool {
+	var doc yaml.Node
+	if yaml.Unmarshal([]byte(s), &doc) != nil || len(doc.Content) != 1 {
+		return false
+	}
+	n := doc.Content[0]
+	return n.Kind == yaml.ScalarNode && n.Style == 0 && n.Value == s
+}
+
+// ----------------------------------------------------------------
+// Rule 28: convert a Pandoc title block to YAML front matter
+// ----------------------------------------------------------------
+
+type TitleBlockToFrontMatterRule struct{}
+
+func NewTitleBlockToFrontMatterRule() Rule { return TitleBlockToFrontMatterRule{} }
+
+func (TitleBlockToFrontMatterRule) Name() string {
+	return "TitleBlockToFrontMatter"
+}
+
+// Apply turns the "% title", "% author" and "% date" lines into title,
+// author and date keys. Several authors, separated by ";" or given on
+// continuation lines, become a list.
+func (TitleBlockToFrontMatterRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	end := titleBlockEnd(lines)
+	if end < 0 {
+		return content, nil
+	}
+
+	// fields[n] holds the parts of the nth "%" line and its continuations
+	var fields [][]string
+	for _, l := range lines[:end+1] {
+		if strings.HasPrefix(l, "%") {
+			fields = append(fields, nil)
+			l = l[1:]
+		}
+		if t := strings.TrimSpace(l); t != "" {
+			fields[len(fields)-1] = append(fields[len(fields)-1], t)
+		}
+	}
+	fm := []string{"---"}
+	for n, key := range []string{"title", "author", "date"} {
+		if n >= len(fields) || len(fields[n]) == 0 {
+			continue
+		}
+		switch v := strings.Join(fields[n], " "); key {
+		case "title":
+			fm = append(fm, "title: "+yamlScalar(v))
+			continue
+		case "date":
+			// a date is left for the YAML parser to read as one
+			if !isPlainYAMLScalar(v) {
+				v = yamlScalar(v)
+			}
+			fm = append(fm, "date: "+v)
+			continue
+		}
+		var authors []string
+		for _, part := range fields[n] {
+			for _, a := range strings.Split(part, ";") {
+				if a = strings.TrimSpace(a); a != "" {
+					authors = append(authors, a)
+				}
+			}
+		}
+		if len(authors) == 1 {
+			fm = append(fm, "author: "+yamlScalar(authors[0]))
+			continue
+		}
+		fm = append(fm, "author:")
+		for _, a := range authors {
+			fm = append(fm, "  - "+yamlScalar(a))
+		}
+	}
+	if len(fm) == 1 {
+		return content, nil
+	}
+	fm = append(fm, "---")
+	return strings.Join(append(fm, lines[end+1:]...), "\n"), nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrontMatterKeysRule(t *testing.T) {
+	rule := NewFrontMatterKeysRule(defaultFrontMatterOrder)
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "configured keys first, then alphabetical",
+			input:    "---\nweight: 3\ntags: [a, b]\nauthor: me\ntitle: Hello\ndate: 2024-01-02\n---\n\n# Body\n",
+			expected: "---\ntitle: Hello\ndate: 2024-01-02\ntags: [a, b]\nauthor: me\nweight: 3\n---\n\n# Body\n",
+		},
+		{
+			name:     "comments and scalar styles kept",
+			input:    "---\nzeta: 'single'   # why\n# the title\ntitle: \"Hello\"\nnotes: |\n  line one\n  line two\n---\nbody",
+			expected: "---\n# the title\ntitle: \"Hello\"\nnotes: |\n  line one\n  line two\nzeta: 'single' # why\n---\nbody",
+		},
+		{
+			name:     "nested values reindented",
+			input:    "---\nparams:\n    b: 1\n    a: 2\ntitle: x\n---",
+			expected: "---\ntitle: x\nparams:\n  b: 1\n  a: 2\n---",
+		},
+		{
+			name:     "invalid front matter left alone",
+			input:    "---\ntitle: [oops\ndraft: true\n---\nbody",
+			expected: "---\ntitle: [oops\ndraft: true\n---\nbody",
+		},
+		{
+			name:     "toml front matter left alone",
+			input:    "+++\ntitle = \"x\"\n+++\nbody",
+			expected: "+++\ntitle = \"x\"\n+++\nbody",
+		},
+		{
+			name:     "no front matter",
+			input:    "b: 1\na: 2\n",
+			expected: "b: 1\na: 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestFrontMatterRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		input    string
+		want     []Diagnostic
+	}{
+		{
+			name:  "valid yaml",
+			input: "---\ntitle: x\n---\nbody",
+		},
+		{
+			name:  "no front matter",
+			input: "# Title\n",
+		},
+		{
+			name:  "yaml error on its absolute line",
+			input: "---\ntitle: x\ntags:\n\t- a\n---\n",
+			want: []Diagnostic{{Line: 4, Rule: "FrontMatter", Severity: SeverityError,
+				Message: "invalid YAML front matter: found character that cannot start any token"}},
+		},
+		{
+			name:  "duplicate yaml key",
+			input: "---\na: 1\na: 2\n---\n",
+			want: []Diagnostic{{Line: 3, Rule: "FrontMatter", Severity: SeverityError,
+				Message: `invalid YAML front matter: mapping key "a" already defined at line 1`}},
+		},
+		{
+			name:  "toml error",
+			input: "+++\ntitle = \"x\"\ndate = \"unclosed\n+++\n",
+			want: []Diagnostic{{Line: 3, Rule: "FrontMatter", Severity: SeverityError,
+				Message: "invalid TOML front matter: strings cannot contain newlines"}},
+		},
+		{
+			name:     "missing required keys",
+			required: []string{"title", "date"},
+			input:    "+++\ntitle = \"x\"\n+++\n",
+			want: []Diagnostic{{Line: 1, Rule: "FrontMatter", Severity: SeverityError,
+				Message: `front matter is missing required key "date"`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewFrontMatterRule(tt.required).(FrontMatterRule)
+			if got := rule.Lint(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if got, _ := rule.Apply(tt.input); got != tt.input {
+				t.Errorf("content changed: %q", got)
+			}
+		})
+	}
+}
+
+func TestFrontMatterTemplateRule(t *testing.T) {
+	dir := t.TempDir()
+	post := filepath.Join(dir, "my-post.md")
+	if err := os.WriteFile(post, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2021, 3, 4, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(post, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "title from heading, date from mtime",
+			path:     post,
+			input:    "# Hello: World #\n\nText\n",
+			expected: "---\ntitle: 'Hello: World'\ndate: 2021-03-04\n---\n\n# Hello: World #\n\nText\n",
+		},
+		{
+			name:     "title from file name",
+			path:     post,
+			input:    "Text\n",
+			expected: "---\ntitle: my-post\ndate: 2021-03-04\n---\n\nText\n",
+		},
+		{
+			name:     "stdin without a path",
+			input:    "Title\n=====\n\n    # code\n",
+			expected: "---\ntitle: Title\ndate: 2020-01-02\n---\n\nTitle\n=====\n\n    # code\n",
+		},
+		{
+			name:     "fallback title",
+			input:    "\n## Section\n",
+			expected: "---\ntitle: Untitled\ndate: 2020-01-02\n---\n\n## Section\n",
+		},
+		{
+			name:     "existing front matter untouched",
+			path:     post,
+			input:    "+++\ntitle = \"x\"\n+++\n# Other\n",
+			expected: "+++\ntitle = \"x\"\n+++\n# Other\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewFrontMatterTemplateRule(defaultFrontMatterTemplate).(FrontMatterTemplateRule)
+			r.now = func() time.Time { return time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC) }
+			rule := r.WithPath(tt.path)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestTitleBlockToFrontMatterRule(t *testing.T) {
+	rule := NewTitleBlockToFrontMatterRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "all fields",
+			input:    "% My: Title\n% Jane Doe\n% 2024-05-01\n\nBody\n",
+			expected: "---\ntitle: 'My: Title'\nauthor: Jane Doe\ndate: 2024-05-01\n---\n\nBody\n",
+		},
+		{
+			name:     "several authors and a continued title",
+			input:    "% A long\n  title\n% Ann; Bob\n  Cy\n\nBody",
+			expected: "---\ntitle: A long title\nauthor:\n  - Ann\n  - Bob\n  - Cy\n---\n\nBody",
+		},
+		{
+			name:     "empty fields left out",
+			input:    "%\n% Ann\n% 2024\nBody",
+			expected: "---\nauthor: Ann\ndate: 2024\n---\nBody",
+		},
+		{
+			name:     "no title block",
+			input:    "Body\n% not a title",
+			expected: "Body\n% not a title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// GitLab Code Quality report (-format gitlab)
+// ----------------------------------------------------------------
+
+// gitlabIssue is one entry of a GitLab Code Quality report.
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// formattingCheck is the check_name of the regions mdfmt would rewrite.
+const formattingCheck = "Formatting"
+
+// writeGitLab writes a Code Quality report on the file at path, whose
+// content in formats to out with the findings diags on out. Issues point
+// at the lines of in, the file as it is in the repository, and there is
+// one for each run of lines formatting would change.
+//
+// GitLab follows an issue from one pipeline to the next by its
+// fingerprint, so fingerprints leave out line numbers, which shift when
+// unrelated lines are edited. They hash the path, the check, the text of
+// the issue's lines and the issue's key, and tell identical issues apart
+// by counting them in the order of the file.
+func writeGitLab(w io.Writer, path, in, out string, diags []Diagnostic) error {
+	name := path
+	if name == "" {
+		name = "<stdin>"
+	}
+	inLines := strings.Split(in, "\n")
+
+	issues := []gitlabIssue{}
+	seen := map[string]int{}
+	add := func(check, severity, description string, line int, parts ...string) {
+		h := sha256.New()
+		for _, p := range append([]string{name, check}, parts...) {
+			fmt.Fprintf(h, "%d:%s\n", len(p), p)
+		}
+		sum := hex.EncodeToString(h.Sum(nil))
+		seen[sum]++
+		if n := seen[sum]; n > 1 {
+			h.Write([]byte(fmt.Sprint(n)))
+			sum = hex.EncodeToString(h.Sum(nil))
+		}
+		issues = append(issues, gitlabIssue{
+			Description: description,
+			CheckName:   check,
+			Fingerprint: sum,
+			Severity:    severity,
+			Location:    gitlabLocation{Path: name, Lines: gitlabLines{Begin: line}},
+		})
+	}
+
+	for _, e := range lineEdits(in, out) {
+		begin := min(e.StartLine+1, len(inLines))
+		description := fmt.Sprintf("mdfmt would reformat lines %d-%d", begin, e.EndLine)
+		switch {
+		case e.EndLine == e.StartLine:
+			description = fmt.Sprintf("mdfmt would insert %d line(s) before line %d", len(e.NewLines), begin)
+		case e.EndLine-e.StartLine == 1:
+			description = fmt.Sprintf("mdfmt would reformat line %d", begin)
+		}
+		add(formattingCheck, "minor", description, begin,
+			strings.Join(inLines[e.StartLine:e.EndLine], "\n"), strings.Join(e.NewLines, "\n"))
+	}
+
+	for _, d := range inputDiagnostics(in, out, diags) {
+		line := d.Line
+		severity := "minor"
+		if d.Severity == SeverityError {
+			severity = "major"
+		}
+		text := ""
+		if line <= len(inLines) && line > 0 {
+			text = strings.TrimSpace(inLines[line-1])
+		}
+		add(d.Rule, severity, d.Message, line, d.Key, text)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+// originLine returns the 1-based line of the input that the output line
+// came from, or for lines formatting inserted the input line before them.
+// Without a map, input and output are the same.
+func originLine(origin LineMap, line int) int {
+	if origin == nil {
+		return line
+	}
+	// origin maps output lines to input lines
+	for i := min(line, len(origin)) - 1; i >= 0; i-- {
+		if origin[i] != Deleted {
+			return origin[i] + 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gitlabReport formats in with the default rules and returns the issues
+// of its Code Quality report.
+func gitlabReport(t *testing.T, path, in string) []gitlabIssue {
+	t.Helper()
+	out, diags, err := formatDocument(in, path, Options{}, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := writeReport(&sb, "gitlab", path, in, out, diags); err != nil {
+		t.Fatal(err)
+	}
+	var issues []gitlabIssue
+	if err := json.Unmarshal([]byte(sb.String()), &issues); err != nil {
+		t.Fatalf("%v:\n%s", err, sb.String())
+	}
+	return issues
+}
+
+func TestGitLabReport(t *testing.T) {
+	in := "# Title\nSee https://a.example now.\n"
+	var got []string
+	for _, is := range gitlabReport(t, "docs/a.md", in) {
+		if is.Location.Path != "docs/a.md" || len(is.Fingerprint) != 64 {
+			t.Errorf("bad issue %+v", is)
+		}
+		got = append(got, fmt.Sprintf("%d: %s %s: %s", is.Location.Lines.Begin, is.Severity, is.CheckName, is.Description))
+	}
+	// the finding is on line 3 of the output, which was line 2 of the file
+	want := []string{
+		"2: minor Formatting: mdfmt would insert 1 line(s) before line 2",
+		"2: minor BareURL: bare URL https://a.example; write it as <https://a.example>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if issues := gitlabReport(t, "docs/a.md", "# Title\n\nFine.\n"); len(issues) != 0 {
+		t.Errorf("clean document: got %+v", issues)
+	}
+}
+
+func TestGitLabFingerprints(t *testing.T) {
+	fingerprints := func(path, in string) map[string]string {
+		m := map[string]string{}
+		for _, is := range gitlabReport(t, path, in) {
+			if _, dup := m[is.Fingerprint]; dup {
+				t.Errorf("fingerprint %s given twice", is.Fingerprint)
+			}
+			m[is.Fingerprint] = is.CheckName
+		}
+		return m
+	}
+	base := "# Title\n\nSee https://a.example now.\n\nAnd https://a.example now.\n\nSee https://a.example now.\n"
+	first := fingerprints("a.md", base)
+	if len(first) != 3 {
+		t.Fatalf("got %d issues, want 3", len(first))
+	}
+
+	// re-runs and edits elsewhere in the file keep them
+	if again := fingerprints("a.md", base); !reflect.DeepEqual(again, first) {
+		t.Errorf("re-run changed fingerprints:\n%v\n%v", first, again)
+	}
+	shifted := fingerprints("a.md", "# Title\n\nA new paragraph\nof two lines.\n\n"+strings.TrimPrefix(base, "# Title\n\n"))
+	if !reflect.DeepEqual(shifted, first) {
+		t.Errorf("shifted lines changed fingerprints:\n%v\n%v", first, shifted)
+	}
+
+	// other files, and edits to the line itself, do not
+	common := func(fp map[string]string) int {
+		n := 0
+		for k := range fp {
+			if _, ok := first[k]; ok {
+				n++
+			}
+		}
+		return n
+	}
+	if n := common(fingerprints("b.md", base)); n != 0 {
+		t.Errorf("another file shares %d fingerprints", n)
+	}
+	if n := common(fingerprints("a.md", strings.Replace(base, "And https", "Also https", 1))); n != 2 {
+		t.Errorf("editing one line kept %d of 3 fingerprints, want 2", n)
+	}
+}
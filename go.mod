@@ -1,3 +1,9 @@
 module github.com/404Simon/mdfmt
 
 go 1.24.3
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/yuin/goldmark v1.8.6
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// gfm parses and renders Markdown the way GitHub does, raw HTML included.
+var gfm = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// classifyWithGoldmark classifies lines with classifyLines and then takes
+// the code blocks, tables, headings, thematic breaks and HTML blocks from
+// goldmark's parse tree instead. Containers, indentation and everything
+// goldmark does not know about, such as definition lists, stay as
+// classifyLines found them.
+func classifyWithGoldmark(lines []string) *blocks {
+	b := classifyLines(lines)
+
+	// parse the body with the metadata blanked out, so offsets still map
+	// to the same lines
+	fm := metadataEnd(lines)
+	body := make([]string, len(lines))
+	copy(body[fm+1:], lines[fm+1:])
+	src := []byte(strings.Join(body, "\n"))
+	starts := make([]int, len(lines))
+	for i, off := 1, 0; i < len(lines); i++ {
+		off += len(body[i-1]) + 1
+		starts[i] = off
+	}
+	lineOf := func(off int) int {
+		return sort.Search(len(starts), func(i int) bool { return starts[i] > off }) - 1
+	}
+
+	kinds := map[int]lineKind{}
+	var fences []fence
+	mark := func(seg text.Segment, kind lineKind) {
+		if i := lineOf(seg.Start); b.lines[i].kind != lineBlank {
+			kinds[i] = kind
+		}
+	}
+	doc := gfm.Parser().Parse(text.NewReader(src))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock:
+			open := lineOf(n.Pos())
+			ch, size, end, _ := openingFence(string(src[n.Pos() : starts[open]+len(lines[open])]))
+			f := fence{
+				open:      open,
+				close:     -1,
+				char:      ch,
+				size:      size,
+				markerEnd: n.Pos() - starts[open] + end,
+				quote:     b.lines[open].quote,
+				container: b.lines[open].container,
+			}
+			if n.Info != nil {
+				f.info = strings.TrimSpace(string(n.Info.Segment.Value(src)))
+			}
+			kinds[open] = lineFenceOpen
+			last := open
+			for k := 0; k < n.Lines().Len(); k++ {
+				last = lineOf(n.Lines().At(k).Start)
+				kinds[last] = lineCode
+			}
+			if c := last + 1; c < len(lines) {
+				_, off := stripQuote(lines[c], f.quote)
+				if isClosingFence(lines[c][off:], ch, size) {
+					f.close = c
+					kinds[c] = lineFenceClose
+				}
+			}
+			fences = append(fences, f)
+		case *ast.CodeBlock:
+			for k := 0; k < n.Lines().Len(); k++ {
+				mark(n.Lines().At(k), lineIndentedCode)
+			}
+		case *ast.HTMLBlock:
+			for k := 0; k < n.Lines().Len(); k++ {
+				mark(n.Lines().At(k), lineHTML)
+			}
+			if n.HasClosure() {
+				mark(n.ClosureLine, lineHTML)
+			}
+		case *ast.ThematicBreak:
+			kinds[lineOf(n.Pos())] = lineThematicBreak
+		case *ast.Heading:
+			if n.Lines().Len() == 0 {
+				break
+			}
+			last := lineOf(n.Lines().At(n.Lines().Len() - 1).Start)
+			if u := last + 1; u < len(lines) && !isATXHeading(lines[lineOf(n.Pos())]) {
+				kinds[u] = lineSetextUnderline
+			}
+		case *east.TableHeader:
+			h := lineOf(n.Pos())
+			kinds[h], kinds[h+1] = lineTable, lineTable
+		case *east.TableRow:
+			kinds[lineOf(n.Pos())] = lineTable
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// goldmark knows nothing of Pandoc definition lists: lines in them
+	// keep their classification, fences included
+	pandoc := make([]bool, len(lines))
+	defCol := 0 // content column of the definition we are in, if any
+	for i := fm + 1; i < len(lines); i++ {
+		info := b.lines[i]
+		if info.kind != lineBlank && info.container < defCol {
+			defCol = 0
+		}
+		if info.definition {
+			defCol = info.container + 4
+		}
+		pandoc[i] = defCol > 0 || info.kind == lineDefinitionTerm
+	}
+	var kept []fence
+	for _, f := range fences {
+		if !pandoc[f.open] {
+			kept = append(kept, f)
+		}
+	}
+	for _, f := range b.fences {
+		if pandoc[f.open] {
+			kept = append(kept, f)
+		}
+	}
+	sort.Slice(kept, func(x, y int) bool { return kept[x].open < kept[y].open })
+
+	for i := fm + 1; i < len(lines); i++ {
+		info := &b.lines[i]
+		info.fence = -1
+		if pandoc[i] {
+			continue
+		}
+		if k, ok := kinds[i]; ok && !(info.item && k == lineIndentedCode) {
+			// code that starts on the line of a list marker stays text:
+			// rules expect code blocks to start in their container
+			info.kind = k
+			continue
+		}
+		switch info.kind {
+		case lineFenceOpen, lineFenceClose, lineCode, lineIndentedCode,
+			lineThematicBreak, lineSetextUnderline, lineTable:
+			// structure only the line heuristics saw
+			info.kind = lineText
+			if strings.TrimSpace(lines[i][info.bodyStart:]) == "" {
+				info.kind = lineBlank
+			}
+		}
+	}
+	for k, f := range kept {
+		end := f.close
+		if end < 0 {
+			end = f.open
+			for end+1 < len(lines) && b.lines[end+1].kind == lineCode {
+				end++
+			}
+		}
+		for i := f.open; i <= end; i++ {
+			b.lines[i].fence = k
+		}
+	}
+	b.fences = kept
+	return b
+}
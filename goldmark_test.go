@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestBlockParsers runs both block parsers over a corpus and checks where
+// they disagree: nowhere on everyday documents, and on the listed lines of
+// the corner cases the line heuristics get wrong.
+func TestBlockParsers(t *testing.T) {
+	defer func(p string) { blockParser = p }(blockParser)
+	classify := func(parser string, lines []string) *blocks {
+		blockParser = parser
+		return classifyBlocks(lines)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		// differ lists the lines on which the goldmark backend differs,
+		// as "line: internal kind -> goldmark kind"
+		differ []string
+	}{
+		{
+			name:  "everyday document",
+			input: "---\ntitle: x\n---\n# Title\n\nText\nmore\n\n- item\n\n  ```go\n  code\n  ```\n\n> quote\n> ```\n> q\n> ```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\nSetext\n---\n\n* * *\n\n    indented\n",
+		},
+		{
+			name:  "unterminated fence and lazy lines",
+			input: "> a\nlazy\n\n~~~\ncode\n```",
+		},
+		{
+			name:  "pandoc definition list",
+			input: "Term\n:   def\n\n    continued\n\n    ```\n    code\n    ```",
+		},
+		{
+			name:   "html block",
+			input:  "<div>\n# not a heading\n</div>\n\nafter",
+			differ: []string{"0: 0 -> 11", "1: 0 -> 11", "2: 0 -> 11"},
+		},
+		{
+			name:   "html comment",
+			input:  "<!--\n```\n-->\n\ntext",
+			differ: []string{"0: 0 -> 11", "1: 2 -> 11", "2: 4 -> 11", "3: 4 -> 1", "4: 4 -> 0"},
+		},
+		{
+			name:   "html block interrupting a paragraph",
+			input:  "para\n<div>\nx",
+			differ: []string{"1: 0 -> 11", "2: 0 -> 11"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.input, "\n")
+			a, b := classify("internal", lines), classify("goldmark", lines)
+			var differ []string
+			for i := range lines {
+				if a.lines[i].kind != b.lines[i].kind {
+					differ = append(differ, fmt.Sprintf("%d: %d -> %d", i, a.lines[i].kind, b.lines[i].kind))
+				}
+			}
+			if strings.Join(differ, "\n") != strings.Join(tt.differ, "\n") {
+				t.Errorf("differences:\n%s\nwant:\n%s", strings.Join(differ, "\n"), strings.Join(tt.differ, "\n"))
+			}
+			if len(tt.differ) == 0 && fmt.Sprint(a.fences) != fmt.Sprint(b.fences) {
+				t.Errorf("fences differ:\n%v\n%v", a.fences, b.fences)
+			}
+		})
+	}
+}
+
+func TestGoldmarkBackendKeepsRulesOut(t *testing.T) {
+	defer func(p string) { blockParser = p }(blockParser)
+	blockParser = "goldmark"
+	input := "<div>\n# Kept as is\ntext\n</div>\n"
+	got, err := NewBlankLineAfterHeadingRule().Apply(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != input {
+		t.Errorf("expected:\n%q\ngot:\n%q", input, got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 54: start ATX headings at the margin of their container
+// ----------------------------------------------------------------
+
+type HeadingIndentRule struct{}
+
+func NewHeadingIndentRule() Rule { return HeadingIndentRule{} }
+
+func (HeadingIndentRule) Name() string {
+	return "HeadingIndent"
+}
+
+// Apply removes the one to three spaces an ATX heading may be indented
+// by. Blockquote markers and the indentation that puts a heading in a
+// list item stay; a heading on the line of its list marker is left to
+// the list rules.
+func (HeadingIndentRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineText || info.item || info.lazy || literalLine(b, i) {
+			continue
+		}
+		body := line[info.bodyStart:]
+		if cols, _ := leadingColumns(body); cols < info.container {
+			continue
+		}
+		inner := containerBody(body, info.container)
+		if !isATXHeading(inner) {
+			continue
+		}
+		_, n := leadingColumns(inner)
+		prefix := line[:len(line)-len(inner)]
+		lines[i] = prefix + inner[n:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
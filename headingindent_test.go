@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestHeadingIndentRule(t *testing.T) {
+	rule := NewHeadingIndentRule()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"no indentation", "# H\n", "# H\n"},
+		{"one space", " # H\n", "# H\n"},
+		{"two spaces", "  ## H\n", "## H\n"},
+		{"three spaces", "   ### H\n", "### H\n"},
+		{"four spaces is code", "    # H\n", "    # H\n"},
+		{"four spaces continue a paragraph", "text\n    # H\n", "text\n    # H\n"},
+		{"tab is code", "\t# H\n", "\t# H\n"},
+		{"in a paragraph", "text\n  # H\ntext\n", "text\n# H\ntext\n"},
+		{"not a heading", "  #hashtag\n", "  #hashtag\n"},
+		{"in a list item", "- item\n\n  # H\n", "- item\n\n  # H\n"},
+		{"one space in a list item", "- item\n\n   # H\n", "- item\n\n  # H\n"},
+		{"three spaces in a list item", "- item\n\n     # H\n", "- item\n\n  # H\n"},
+		{"four spaces in a list item", "- item\n\n      # H\n", "- item\n\n      # H\n"},
+		{"ordered item at column 4", "1.  item\n\n     # H\n", "1.  item\n\n    # H\n"},
+		{"on the list marker", "-  # H\n", "-  # H\n"},
+		{"in a blockquote", ">   # H\n", "> # H\n"},
+		{"in a nested blockquote", "> >  # H\n", "> > # H\n"},
+		{"four spaces in a blockquote", ">     # H\n", ">     # H\n"},
+		{"in code", "```\n  # H\n```\n", "```\n  # H\n```\n"},
+		{"in front matter", "---\n  # c\n---\n", "---\n  # c\n---\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 35: report HTML tags outside an allow-list
+// ----------------------------------------------------------------
+
+// defaultAllowedTags are portable enough to keep in Markdown.
+var defaultAllowedTags = []string{"br", "details", "summary", "img", "sup", "sub", "kbd"}
+
+// htmlTag matches an opening or self-closing tag and captures its name.
+var htmlTag = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)(?:\s[^<>]*)?/?>`)
+
+type InlineHTMLRule struct {
+	allowed map[string]bool // lowercased tag names
+}
+
+// NewInlineHTMLRule constructs a rule that reports HTML tags other than
+// those named in allowed, compared case-insensitively.
+func NewInlineHTMLRule(allowed []string) Rule {
+	r := InlineHTMLRule{allowed: map[string]bool{}}
+	for _, t := range allowed {
+		r.allowed[strings.ToLower(t)] = true
+	}
+	return r
+}
+
+func newInlineHTMLRuleFromOptions(o Options) (Rule, error) {
+	return NewInlineHTMLRule(o.List("allowed-tags", defaultAllowedTags)), nil
+}
+
+func (InlineHTMLRule) Name() string {
+	return "InlineHTML"
+}
+
+func (InlineHTMLRule) Apply(content string) (string, error) {
+	return content, nil
+}
+
+func (r InlineHTMLRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	inComment := false
+	for i, line := range lines {
+		if literalLine(b, i) {
+			inComment = false
+			continue
+		}
+		// anchors HeadingAnchors wrote are wanted
+		if !inComment && isHeadingAnchor(line) {
+			continue
+		}
+		var masked string
+		masked, inComment = maskCommentsAndCode(line, inComment)
+		for _, m := range htmlTag.FindAllStringSubmatchIndex(masked, -1) {
+			name := strings.ToLower(masked[m[2]:m[3]])
+			if r.allowed[name] {
+				continue
+			}
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:m[0]]) + 1,
+				Rule:    r.Name(),
+				Message: fmt.Sprintf("HTML tag <%s> is not allowed", name),
+				Key:     name,
+			})
+		}
+	}
+	return diags
+}
+
+// maskCommentsAndCode blanks out the inline code spans and HTML comments
+// of line, keeping byte offsets, and reports whether a comment is still
+// open at the end of it. inComment says whether one was open before it.
+func maskCommentsAndCode(line string, inComment bool) (string, bool) {
+	buf := []byte(line)
+	blank := func(from, to int) {
+		for k := from; k < to; k++ {
+			buf[k] = ' '
+		}
+	}
+	for _, sp := range codeSpans(line) {
+		blank(sp[0], sp[1])
+	}
+	s := string(buf)
+	for from := 0; ; {
+		if !inComment {
+			k := strings.Index(s[from:], "<!--")
+			if k < 0 {
+				break
+			}
+			from += k
+			inComment = true
+		}
+		k := strings.Index(s[from:], "-->")
+		if k < 0 {
+			blank(from, len(buf))
+			break
+		}
+		blank(from, from+k+3)
+		from += k + 3
+		inComment = false
+	}
+	return string(buf), inComment
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInlineHTMLRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		input   string
+		want    []string
+	}{
+		{
+			name:    "allow-list",
+			allowed: defaultAllowedTags,
+			input:   "Press <kbd>Ctrl</kbd>.<br/>\n\n<div align=\"center\">\n<IMG src=x.png>\n</div>\n\nH<SPAN>2</SPAN>O",
+			want:    []string{"3:1: div", "7:2: span"},
+		},
+		{
+			name:    "code and comments",
+			allowed: nil,
+			input:   "`<b>` and <!-- <i> -->\n\n```html\n<p>\n```\n\n<!--\n<table>\n-->\n\n    <pre>",
+			want:    nil,
+		},
+		{
+			name:    "autolinks are not tags",
+			allowed: nil,
+			input:   "<https://a.io> and <me@a.io>",
+			want:    nil,
+		},
+		{
+			name:    "custom list",
+			allowed: []string{"span"},
+			input:   "a <span>b</span> <br>",
+			want:    []string{"1:18: br"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range NewInlineHTMLRule(tt.allowed).(InlineHTMLRule).Lint(tt.input) {
+				got = append(got, fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Key))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 60: write HTML links and images as Markdown
+// ----------------------------------------------------------------
+
+var (
+	// htmlLinkOrImage matches an anchor on one line whose content is text
+	// or a single image, or an image on its own.
+	htmlLinkOrImage = regexp.MustCompile(`(?i)<a(\s[^<>]*)?>([^<]*|\s*<img(?:\s[^<>]*)?>\s*)</a\s*>|<img(\s[^<>]*)?>`)
+	// htmlAttribute matches one attribute at the start of a tag's
+	// attribute list.
+	htmlAttribute = regexp.MustCompile("^\\s+([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\\s*=\\s*(?:\"([^\"]*)\"|'([^']*)'|([^\\s\"'=<>`]+)))?")
+)
+
+// Attributes the rule can carry over; a tag with any other keeps its
+// HTML, since Markdown has no place for it.
+var (
+	anchorAttributes = map[string]bool{"href": true, "title": true}
+	imageAttributes  = map[string]bool{"src": true, "alt": true, "title": true}
+)
+
+type HTMLLinksRule struct{}
+
+// NewHTMLLinksRule constructs a rule that rewrites <a href> anchors as
+// [text](href "title") and <img src alt> images as ![alt](src "title"),
+// an anchor around an image as [![alt](src)](href). Tags with other
+// attributes, such as width or class, tags in code, comments and HTML
+// blocks, and anchors spanning lines are left alone.
+func NewHTMLLinksRule() Rule {
+	return HTMLLinksRule{}
+}
+
+func (HTMLLinksRule) Name() string {
+	return "HTMLLinks"
+}
+
+func (HTMLLinksRule) Trigger() []string {
+	return []string{"<"}
+}
+
+func (HTMLLinksRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	inHTML := htmlBlockLines(lines, b)
+	inComment := false
+	for i, line := range lines {
+		if literalLine(b, i) {
+			inComment = false
+			continue
+		}
+		var masked string
+		masked, inComment = maskCommentsAndCode(line, inComment)
+		if inHTML[i] {
+			continue
+		}
+		var out strings.Builder
+		last := 0
+		for _, m := range htmlLinkOrImage.FindAllStringIndex(masked, -1) {
+			tag := line[m[0]:m[1]]
+			if tag != masked[m[0]:m[1]] {
+				// part of it is code or a comment
+				continue
+			}
+			md, ok := htmlToMarkdownLink(tag)
+			if !ok {
+				continue
+			}
+			out.WriteString(line[last:m[0]])
+			out.WriteString(md)
+			last = m[1]
+		}
+		out.WriteString(line[last:])
+		lines[i] = out.String()
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// htmlToMarkdownLink returns the Markdown for an anchor or image matched
+// by htmlLinkOrImage, and false if it has no Markdown form.
+func htmlToMarkdownLink(tag string) (string, bool) {
+	m := htmlLinkOrImage.FindStringSubmatch(tag)
+	if m[3] != "" || !strings.HasPrefix(strings.ToLower(tag), "<a") {
+		return markdownImage(m[3])
+	}
+	attrs, ok := parseHTMLAttributes(m[1], anchorAttributes)
+	if !ok {
+		return "", false
+	}
+	href, ok := attrs["href"]
+	if !ok {
+		return "", false
+	}
+	text := m[2]
+	if k := strings.Index(strings.ToLower(text), "<img"); k >= 0 {
+		end := strings.LastIndexByte(text, '>') + 1
+		img, ok := markdownImage(text[k+len("<img") : end-1])
+		if !ok {
+			return "", false
+		}
+		text = text[:k] + img + text[end:]
+	} else if !balancedBrackets(text) {
+		return "", false
+	}
+	dest, ok := markdownDestination(href, attrs["title"])
+	if !ok {
+		return "", false
+	}
+	return "[" + text + "]" + dest, true
+}
+
+// markdownImage returns the Markdown for an image with the given
+// attribute list.
+func markdownImage(attrList string) (string, bool) {
+	attrs, ok := parseHTMLAttributes(attrList, imageAttributes)
+	if !ok {
+		return "", false
+	}
+	src, ok := attrs["src"]
+	if !ok {
+		return "", false
+	}
+	dest, ok := markdownDestination(src, attrs["title"])
+	if !ok {
+		return "", false
+	}
+	// the alt text of a Markdown image is Markdown, the attribute is not
+	alt := strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`).Replace(attrs["alt"])
+	return "![" + alt + "]" + dest, true
+}
+
+// markdownDestination returns "(url "title")" for an attribute's url and
+// title, and false for a url Markdown cannot hold.
+func markdownDestination(url, title string) (string, bool) {
+	if strings.ContainsAny(url, "<>\\\n") {
+		return "", false
+	}
+	if strings.ContainsAny(url, " \t") || strings.Count(url, "(") != strings.Count(url, ")") {
+		url = "<" + url + ">"
+	}
+	if title != "" {
+		title = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(title)
+		return "(" + url + ` "` + title + `")`, true
+	}
+	return "(" + url + ")", true
+}
+
+// parseHTMLAttributes parses the attribute list of a tag, after its name,
+// into lowercased names and their values. It fails if an attribute is
+// not in allowed or is given twice.
+func parseHTMLAttributes(list string, allowed map[string]bool) (map[string]string, bool) {
+	list = strings.TrimRight(list, " \t")
+	if n := len(list); n > 0 && list[n-1] == '/' && (n == 1 || strings.ContainsRune(" \t\"'", rune(list[n-2]))) {
+		list = list[:n-1] // self-closing, not part of an unquoted value
+	}
+	attrs := map[string]string{}
+	for strings.TrimSpace(list) != "" {
+		m := htmlAttribute.FindStringSubmatch(list)
+		if m == nil {
+			return nil, false
+		}
+		name := strings.ToLower(m[1])
+		if _, dup := attrs[name]; dup || !allowed[name] {
+			return nil, false
+		}
+		attrs[name] = m[2] + m[3] + m[4]
+		list = list[len(m[0]):]
+	}
+	return attrs, true
+}
+
+// balancedBrackets reports whether the unescaped square brackets of s
+// pair up, as they must in the text of a link.
+func balancedBrackets(s string) bool {
+	depth := 0
+	for k := 0; k < len(s); k++ {
+		switch s[k] {
+		case '\\':
+			k++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
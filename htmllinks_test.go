@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestHTMLLinksRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "anchors",
+			input:    `See <a href="https://go.dev">Go</a>, <A HREF='/doc' title='The "docs"'>*docs*</A> and <a href=faq.md>FAQ</a>.`,
+			expected: `See [Go](https://go.dev), [*docs*](/doc "The \"docs\"") and [FAQ](faq.md).`,
+		},
+		{
+			name:     "images",
+			input:    `<img src="logo.png" alt="The *logo*"> and <img alt="" src='a b.png' title="A"/> and <img src=x.png />`,
+			expected: `![The \*logo\*](logo.png) and ![](<a b.png> "A") and ![](x.png)`,
+		},
+		{
+			name:     "badge",
+			input:    `Build: <a href="https://ci.example/x"><img src="https://ci.example/x.svg" alt="CI"></a>`,
+			expected: `Build: [![CI](https://ci.example/x.svg)](https://ci.example/x)`,
+		},
+		{
+			name:     "other attributes",
+			input:    "<a href=\"x\" target=\"_blank\">x</a> <img src=\"a.png\" width=\"100\"> <a name=\"top\"></a> <a href=\"y\"><img src=\"b.png\" class=\"c\"></a> <a href=\"z\" href=\"w\">z</a>",
+			expected: "<a href=\"x\" target=\"_blank\">x</a> <img src=\"a.png\" width=\"100\"> <a name=\"top\"></a> <a href=\"y\"><img src=\"b.png\" class=\"c\"></a> <a href=\"z\" href=\"w\">z</a>",
+		},
+		{
+			name:     "no markdown form",
+			input:    `<a href="x">a ] b</a> <a href="x\y">x</a> <a href="x"><b>x</b></a> <img alt="x">`,
+			expected: `<a href="x">a ] b</a> <a href="x\y">x</a> <a href="x"><b>x</b></a> <img alt="x">`,
+		},
+		{
+			name:     "unbalanced parentheses",
+			input:    `<a href="https://en.wikipedia.org/wiki/Go_(">Go</a>`,
+			expected: `[Go](<https://en.wikipedia.org/wiki/Go_(>)`,
+		},
+		{
+			name:     "code, comments and html blocks",
+			input:    "`<a href=\"x\">x</a>` <!-- <img src=\"y\"> -->\n\n```html\n<img src=\"z\">\n```\n\n    <a href=\"x\">x</a>\n\n<p align=\"center\">\n<img src=\"logo.png\">\n</p>\n\n<img src=\"a.png\">",
+			expected: "`<a href=\"x\">x</a>` <!-- <img src=\"y\"> -->\n\n```html\n<img src=\"z\">\n```\n\n    <a href=\"x\">x</a>\n\n<p align=\"center\">\n<img src=\"logo.png\">\n</p>\n\n<img src=\"a.png\">",
+		},
+		{
+			name:     "anchor across lines",
+			input:    "<a href=\"x\">some\ntext</a> <img\nsrc=\"y\">",
+			expected: "<a href=\"x\">some\ntext</a> <img\nsrc=\"y\">",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewHTMLLinksRule()
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q\nbecame:\n%q", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// gitignore-style path patterns
+// ----------------------------------------------------------------
+
+// ignorePattern is one gitignore-style pattern.
+type ignorePattern struct {
+	re *regexp.Regexp
+	// negate re-includes what earlier patterns excluded, as "!keep.md".
+	negate bool
+	// dirOnly patterns, written with a trailing slash, match directories
+	// only.
+	dirOnly bool
+}
+
+// ignoreList is a list of gitignore-style patterns, such as those given
+// with -exclude, matched against slash paths relative to the root they
+// apply to.
+type ignoreList []ignorePattern
+
+// parseIgnorePatterns compiles patterns the way git reads them: a pattern
+// with a slash before its end is anchored to the root, one without
+// matches at any depth, "**" spans directories and a leading "!" negates.
+// Blank patterns and "#" comments are skipped.
+func parseIgnorePatterns(patterns []string) ignoreList {
+	var l ignoreList
+	for _, p := range patterns {
+		p = strings.TrimRight(p, " ")
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		var ip ignorePattern
+		if strings.HasPrefix(p, "!") {
+			ip.negate = true
+			p = p[1:]
+		}
+		p = strings.TrimPrefix(p, `\`) // "\#" and "\!" are literal
+		if strings.HasSuffix(p, "/") {
+			ip.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		if strings.Contains(p, "/") {
+			p = strings.TrimPrefix(p, "/")
+		} else {
+			p = "**/" + p
+		}
+		ip.re = globRegexp(p)
+		l = append(l, ip)
+	}
+	return l
+}
+
+// excludes reports whether the patterns exclude the file, or with dir the
+// directory, at the slash path rel. Whatever is below an excluded
+// directory is excluded too, and later patterns win over earlier ones.
+func (l ignoreList) excludes(rel string, dir bool) bool {
+	rel = strings.Trim(path.Clean(rel), "/")
+	for i := strings.IndexByte(rel, '/'); i >= 0; i = nextSlash(rel, i) {
+		if excluded, _ := l.match(rel[:i], true); excluded {
+			return true
+		}
+	}
+	excluded, _ := l.match(rel, dir)
+	return excluded
+}
+
+// nextSlash returns the index of the slash in s after the one at i, or -1.
+func nextSlash(s string, i int) int {
+	if j := strings.IndexByte(s[i+1:], '/'); j >= 0 {
+		return i + 1 + j
+	}
+	return -1
+}
+
+// match applies the patterns to rel itself, reporting whether they
+// exclude it and whether any of them matched it at all.
+func (l ignoreList) match(rel string, dir bool) (excluded, matched bool) {
+	for _, p := range l {
+		if (!p.dirOnly || dir) && p.re.MatchString(rel) {
+			excluded, matched = !p.negate, true
+		}
+	}
+	return excluded, matched
+}
+
+// ignoreFileName is the name of the files whose gitignore-style patterns
+// name the files and directories below their own directory that mdfmt
+// leaves alone when it walks directories.
+const ignoreFileName = ".mdfmtignore"
+
+// ignoreFiles holds the patterns of the ignore files read so far, by the
+// absolute directory they are in.
+type ignoreFiles map[string]ignoreList
+
+// load reads the ignore file in the absolute directory dir, if any.
+func (f ignoreFiles) load(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f[dir] = parseIgnorePatterns(strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"))
+	return nil
+}
+
+// excludes reports whether the ignore files in the directories from top
+// down to that of the absolute path exclude the file, or with dir the
+// directory, there. As in git, a file deeper down wins over those above
+// it, and its patterns are relative to its own directory.
+func (f ignoreFiles) excludes(top, path string, dir bool) bool {
+	var dirs []string
+	for d := filepath.Dir(path); ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if d == top || filepath.Dir(d) == d {
+			break
+		}
+	}
+	excluded := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		l, ok := f[dirs[i]]
+		if !ok {
+			continue
+		}
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		if e, ok := l.match(filepath.ToSlash(rel), dir); ok {
+			excluded = e
+		}
+	}
+	return excluded
+}
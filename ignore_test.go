@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestIgnoreList(t *testing.T) {
+	l := parseIgnorePatterns([]string{
+		"# generated files",
+		"vendor/**",
+		"**/generated_*.md",
+		"drafts/",
+		"/TODO.md",
+		"*.tmp.md",
+		"!keep.tmp.md",
+		"",
+		"notes/[0-9]*.md",
+	})
+	tests := []struct {
+		path string
+		dir  bool
+		want bool
+	}{
+		{"README.md", false, false},
+		{"vendor/x/README.md", false, true},
+		{"docs/vendor/README.md", false, false},
+		{"generated_api.md", false, true},
+		{"docs/api/generated_api.md", false, true},
+		{"drafts", true, true},
+		{"drafts/post.md", false, true},
+		{"blog/drafts/post.md", false, true},
+		{"drafts", false, false},
+		{"TODO.md", false, true},
+		{"docs/TODO.md", false, false},
+		{"a/b.tmp.md", false, true},
+		{"a/keep.tmp.md", false, false},
+		{"notes/2024-01.md", false, true},
+		{"notes/intro.md", false, false},
+		{"./docs/../vendor/a.md", false, true},
+	}
+	for _, tt := range tests {
+		if got := l.excludes(tt.path, tt.dir); got != tt.want {
+			t.Errorf("excludes(%q, %v) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
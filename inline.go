@@ -0,0 +1,337 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Inline helpers shared by the prose rules
+// ----------------------------------------------------------------
+
+// codeSpans returns the byte ranges [start, end) of the inline code spans
+// in line, backtick delimiters included. A run of backticks without a
+// closing run of the same length is literal text.
+func codeSpans(line string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		n := backtickRun(line, i)
+		closed := false
+		for j := i + n; j < len(line); {
+			if line[j] != '`' {
+				j++
+				continue
+			}
+			m := backtickRun(line, j)
+			if m == n {
+				spans = append(spans, [2]int{i, j + m})
+				i = j + m
+				closed = true
+				break
+			}
+			j += m
+		}
+		if !closed {
+			i += n
+		}
+	}
+	return spans
+}
+
+func backtickRun(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// mapOutsideCode applies fn to the parts of line that are not inline code
+// spans and returns the reassembled line.
+func mapOutsideCode(line string, fn func(string) string) string {
+	return mapOutside(line, codeSpans(line), fn)
+}
+
+// mapOutside applies fn to the parts of line outside of spans, which must
+// be sorted and disjoint, and returns the reassembled line.
+func mapOutside(line string, spans [][2]int, fn func(string) string) string {
+	if len(spans) == 0 {
+		return fn(line)
+	}
+	var sb strings.Builder
+	last := 0
+	for _, sp := range spans {
+		sb.WriteString(fn(line[last:sp[0]]))
+		sb.WriteString(line[sp[0]:sp[1]])
+		last = sp[1]
+	}
+	sb.WriteString(fn(line[last:]))
+	return sb.String()
+}
+
+var (
+	// wikilink matches an Obsidian link or embed: [[Note#Section|alias]],
+	// ![[image.png|300]].
+	wikilink = regexp.MustCompile(`!?\[\[[^\[\]\n]+\]\]`)
+	// blockID matches an Obsidian block ID ending a line.
+	blockID = regexp.MustCompile(`(?:^|\s)(\^[A-Za-z0-9-]+)\s*$`)
+	// obsidianTag matches a tag such as #project/mdfmt; a tag is not all
+	// digits.
+	obsidianTag = regexp.MustCompile(`(?:^|\s)(#[\w/-]*[A-Za-z_/-][\w/-]*)`)
+)
+
+// obsidianSpans returns the byte ranges of the Obsidian links, embeds,
+// tags and block ID in line, in order. Rules must not change them: a
+// link names a note, and a block ID is what links to the line point at.
+func obsidianSpans(line string) [][2]int {
+	var spans [][2]int
+	for _, m := range wikilink.FindAllStringIndex(line, -1) {
+		spans = append(spans, [2]int{m[0], m[1]})
+	}
+	for _, m := range obsidianTag.FindAllStringSubmatchIndex(line, -1) {
+		spans = append(spans, [2]int{m[2], m[3]})
+	}
+	if m := blockID.FindStringSubmatchIndex(line); m != nil {
+		spans = append(spans, [2]int{m[2], m[3]})
+	}
+	return mergeSpans(spans)
+}
+
+var (
+	// bracketedCitation matches a Pandoc citation in brackets, such as
+	// [@smith2020, pp. 30-35], [see @a; @b] or [-@smith2020].
+	bracketedCitation = regexp.MustCompile(`\[(?:[^\[\]@]*[\s;-])?@\w[^\[\]]*\]`)
+	// inTextCitation matches an in-text citation such as @smith2020 and
+	// an optional locator, as in @smith2020 [p. 3].
+	inTextCitation = regexp.MustCompile(`(?:^|[\s(])(-?@\w(?:[\w:.#$%&+?<>~/-]*\w)?)(?:[ \t]*\[[^\[\]]*\])?`)
+)
+
+// citationSpans returns the byte ranges of the Pandoc citations in line,
+// in order. Their keys, locators and separators are not prose.
+func citationSpans(line string) [][2]int {
+	var spans [][2]int
+	for _, m := range bracketedCitation.FindAllStringIndex(line, -1) {
+		spans = append(spans, [2]int{m[0], m[1]})
+	}
+	for _, m := range inTextCitation.FindAllStringSubmatchIndex(line, -1) {
+		spans = append(spans, [2]int{m[2], m[1]})
+	}
+	return mergeSpans(spans)
+}
+
+// attributeSpans returns the byte ranges of the Pandoc attribute blocks in
+// line, such as {#id .class key="value"} after a heading, fence, link or
+// bracketed span, in order.
+func attributeSpans(line string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(line); i++ {
+		if line[i] != '{' {
+			continue
+		}
+		if end, ok := attributeBlock(line, i); ok {
+			spans = append(spans, [2]int{i, end})
+			i = end - 1
+		}
+	}
+	return spans
+}
+
+// attributeBlock parses the attribute block starting with the "{" at
+// line[i] and returns the offset just past its "}". Blocks hold
+// whitespace-separated identifiers (#id), classes (.class), key=value
+// pairs with optionally quoted values, a raw format (=html) or "-".
+func attributeBlock(line string, i int) (int, bool) {
+	name := func(j int) int {
+		for j < len(line) {
+			r, size := utf8.DecodeRuneInString(line[j:])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-:.", r) {
+				break
+			}
+			j += size
+		}
+		return j
+	}
+	j := i + 1
+	n := 0
+	for {
+		for j < len(line) && (line[j] == ' ' || line[j] == '\t') {
+			j++
+		}
+		if j == len(line) {
+			return 0, false
+		}
+		if line[j] == '}' {
+			return j + 1, n > 0
+		}
+		if n > 0 && line[j-1] != ' ' && line[j-1] != '\t' {
+			return 0, false
+		}
+		switch c := line[j]; {
+		case c == '#' || c == '.' || c == '=':
+			k := name(j + 1)
+			if k == j+1 {
+				return 0, false
+			}
+			j = k
+		case c == '-' && j+1 < len(line) && (line[j+1] == ' ' || line[j+1] == '}'):
+			j++
+		default:
+			k := name(j)
+			if k == j || k == len(line) || line[k] != '=' {
+				return 0, false
+			}
+			j = k + 1
+			if j < len(line) && (line[j] == '"' || line[j] == '\'') {
+				q := line[j]
+				for j++; j < len(line) && line[j] != q; j++ {
+					if line[j] == '\\' {
+						j++
+					}
+				}
+				if j >= len(line) {
+					return 0, false
+				}
+				j++
+			} else {
+				for j < len(line) && line[j] != ' ' && line[j] != '\t' && line[j] != '}' {
+					j++
+				}
+			}
+		}
+		n++
+	}
+}
+
+// stripAttributes drops a trailing attribute block from heading text.
+func stripAttributes(text string) string {
+	if k := strings.LastIndexByte(text, '{'); k >= 0 {
+		if end, ok := attributeBlock(text, k); ok && strings.TrimSpace(text[end:]) == "" {
+			return strings.TrimSpace(text[:k])
+		}
+	}
+	return text
+}
+
+// verbatimSpans returns the spans of line that look like prose but must
+// keep every character: Obsidian links, tags and block IDs, citations and
+// attribute blocks.
+func verbatimSpans(line string) [][2]int {
+	spans := append(obsidianSpans(line), citationSpans(line)...)
+	return mergeSpans(append(spans, attributeSpans(line)...))
+}
+
+// protectedSpans returns the inline code spans of line together with the
+// verbatim spans outside of them, sorted and disjoint.
+func protectedSpans(line string) [][2]int {
+	code := codeSpans(line)
+	masked := []byte(line)
+	for _, sp := range code {
+		for k := sp[0]; k < sp[1]; k++ {
+			masked[k] = ' '
+		}
+	}
+	return mergeSpans(append(code, verbatimSpans(string(masked))...))
+}
+
+// nonProseSpans returns the parts of line that prose rules must not touch:
+// its protectedSpans, URLs, link destinations and HTML tags, sorted and
+// disjoint.
+func nonProseSpans(line string) [][2]int {
+	covered := append(protectedSpans(line), bareURLs(line)...)
+	for _, m := range autolink.FindAllStringIndex(line, -1) {
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+	for _, m := range inlineLinkSpan.FindAllStringIndex(line, -1) {
+		dest := m[0] + strings.LastIndex(line[m[0]:m[1]], "](")
+		covered = append(covered, [2]int{dest, m[1]})
+	}
+	for _, m := range htmlTag.FindAllStringIndex(line, -1) {
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+	if loc := linkReferenceDefinition.FindStringIndex(line); loc != nil {
+		covered = append(covered, [2]int{loc[1], len(line)})
+	}
+	return mergeSpans(covered)
+}
+
+// mergeSpans sorts spans and joins the overlapping ones.
+func mergeSpans(spans [][2]int) [][2]int {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	var out [][2]int
+	for _, sp := range spans {
+		if n := len(out); n > 0 && sp[0] < out[n-1][1] {
+			out[n-1][1] = max(out[n-1][1], sp[1])
+			continue
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+var (
+	// bareURL matches a web address; trailing punctuation is trimmed off
+	// separately.
+	bareURL = regexp.MustCompile(`https?://[^\s<>]+`)
+	// inlineLinkSpan matches an inline link or image, destination included.
+	inlineLinkSpan = regexp.MustCompile(`!?\[[^\]]*\]\([^)\s]*(?:\s+"[^"]*")?\)`)
+	autolink       = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*>`)
+	// linkReferenceDefinition matches "[label]: destination".
+	linkReferenceDefinition = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s`)
+)
+
+// bareURLs returns the byte ranges [start, end) of the web addresses in
+// line that are not already links: not inside an inline link, autolink,
+// code span, Obsidian link, citation or link reference definition, and not the
+// value of an HTML attribute. Both the BareURL lint and its fix use it, so
+// they agree on what counts as bare.
+func bareURLs(line string) [][2]int {
+	if linkReferenceDefinition.MatchString(line) {
+		return nil
+	}
+	covered := protectedSpans(line)
+	for _, m := range inlineLinkSpan.FindAllStringIndex(line, -1) {
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+	for _, m := range autolink.FindAllStringIndex(line, -1) {
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+
+	var urls [][2]int
+next:
+	for _, m := range bareURL.FindAllStringIndex(line, -1) {
+		for _, c := range covered {
+			if m[0] < c[1] && c[0] < m[1] {
+				continue next
+			}
+		}
+		// href="...", src='...' and friends
+		if m[0] > 0 && strings.IndexByte(`"'=`, line[m[0]-1]) >= 0 {
+			continue
+		}
+		urls = append(urls, [2]int{m[0], m[0] + len(trimURL(line[m[0]:m[1]]))})
+	}
+	return urls
+}
+
+// trimURL drops the trailing punctuation that ends the sentence around a
+// URL rather than the URL itself, keeping closing parentheses that
+// balance an opening one in the URL.
+func trimURL(u string) string {
+	for len(u) > 0 {
+		switch c := u[len(u)-1]; {
+		case strings.IndexByte(`.,:;!?*_'"`, c) >= 0:
+		case c == ')' && strings.Count(u, "(") < strings.Count(u, ")"):
+		default:
+			return u
+		}
+		u = u[:len(u)-1]
+	}
+	return u
+}
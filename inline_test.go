@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeSpans(t *testing.T) {
+	tests := []struct {
+		input string
+		want  [][2]int
+	}{
+		{"no code", nil},
+		{"a `b` c", [][2]int{{2, 5}}},
+		{"``a ` b`` and `c`", [][2]int{{0, 9}, {14, 17}}},
+		{"unclosed ` tick", nil},
+		{"``` mismatched `` run", nil},
+	}
+	for _, tt := range tests {
+		if got := codeSpans(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("codeSpans(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestObsidianSpans(t *testing.T) {
+	line := "See [[Note|x]] and `[[code]]`, ![[img.png|300]] #tag #123 x^2 ^block-1"
+	var got []string
+	for _, sp := range protectedSpans(line) {
+		got = append(got, line[sp[0]:sp[1]])
+	}
+	want := []string{"[[Note|x]]", "`[[code]]`", "![[img.png|300]]", "#tag", "^block-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestObsidianDocument formats an Obsidian note with the default rules and
+// expects embeds, links, block IDs and tags to survive unchanged.
+func TestObsidianDocument(t *testing.T) {
+	data, err := os.ReadFile("testdata/obsidian.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := string(data)
+	// Obsidian writes callout types in lower case
+	opts := Options{"callout-case": "lower", "terminology-words": "Github:GitHub"}
+	got, _, err := formatDocument(input, "", opts, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the opening quotes outside of links are straightened
+	expected := strings.NewReplacer("“Deep", `"Deep`, "“source", `"source`).Replace(input)
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestCitationSpans(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"As shown [@smith2020, pp. 30-35].", []string{"[@smith2020, pp. 30-35]"}},
+		{"Both [see @a; @b, ch. 2] agree.", []string{"[see @a; @b, ch. 2]"}},
+		{"Smith says [-@smith2020].", []string{"[-@smith2020]"}},
+		{"@smith2020 [p. 3] says so, as does @doe:2021.", []string{"@smith2020 [p. 3]", "@doe:2021"}},
+		{"Mail me@example.com or see https://example.com/@user and [a link](x).", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, sp := range citationSpans(tt.line) {
+			got = append(got, tt.line[sp[0]:sp[1]])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	rule := NewReplacementRule("SmartQuotesToAscii", map[string]string{"“": `"`, "”": `"`})
+	input := "“Quoted” [@a, “Title”, pp. 3–5; @b]"
+	expected := `"Quoted" [@a, “Title”, pp. 3–5; @b]`
+	if got, _ := rule.Apply(input); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestAttributeSpans(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"# Intro {#intro .unnumbered}", []string{"{#intro .unnumbered}"}},
+		{`[text]{.mark data-note="say \"hi\" {ok}"}`, []string{`{.mark data-note="say \"hi\" {ok}"}`}},
+		{"[site](https://x.org){target=_blank} and {-}", []string{"{target=_blank}", "{-}"}},
+		{"{#überblick lang='de'} `x`{=html}", []string{"{#überblick lang='de'}", "{=html}"}},
+		{"{x} {{title}} {#} {a=\"open} ${e^x}$", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, sp := range attributeSpans(tt.line) {
+			got = append(got, tt.line[sp[0]:sp[1]])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestPandocAttributes formats elements carrying attribute blocks and
+// expects the blocks to come through unchanged.
+func TestPandocAttributes(t *testing.T) {
+	input := "# Überblick {#überblick .unnumbered lang=\"de\"}\n\n" +
+		"```py {.numberLines startFrom=\"10\" title=\"„Beispiel“\"}\nx = 1\n```\n\n" +
+		"See (the site)[https://example.org]{target=\"_blank\"} and\n" +
+		"[marked]{.mark data-note=\"“quoted” \\\"escaped\\\"\"} text with “quotes”.\n"
+	expected := "# Überblick {#überblick .unnumbered lang=\"de\"}\n\n" +
+		"```python {.numberLines startFrom=\"10\" title=\"„Beispiel“\"}\nx = 1\n```\n\n" +
+		"See [the site](https://example.org){target=\"_blank\"} and\n" +
+		"[marked]{.mark data-note=\"“quoted” \\\"escaped\\\"\"} text with \"quotes”.\n"
+	got, _, err := formatDocument(input, "", Options{}, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
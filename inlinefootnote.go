@@ -0,0 +1,162 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 36: convert Pandoc inline footnotes to reference footnotes
+// ----------------------------------------------------------------
+
+type InlineFootnoteRule struct{}
+
+// NewInlineFootnoteRule constructs a rule that replaces each ^[text] with
+// a [^n] reference and adds the definition "[^n]: text" after the
+// document's footnotes. New labels continue after the highest numeric
+// label in use.
+func NewInlineFootnoteRule() Rule { return InlineFootnoteRule{} }
+
+func (InlineFootnoteRule) Name() string {
+	return "InlineFootnote"
+}
+
+func (InlineFootnoteRule) Trigger() []string {
+	return []string{"^["}
+}
+
+func (InlineFootnoteRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+
+	used := map[string]bool{}
+	n := 0
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		labels := footnoteRefs(line, b.lines[i])
+		if b.lines[i].footnote {
+			labels = append(labels, footnoteDefinition.FindStringSubmatch(line[b.lines[i].bodyStart:])[1])
+		}
+		for _, l := range labels {
+			used[strings.ToLower(l)] = true
+			if v, err := strconv.Atoi(l); err == nil && isNumericLabel(l) && v > n {
+				n = v
+			}
+		}
+	}
+	nextLabel := func() string {
+		for {
+			n++
+			if l := strconv.Itoa(n); !used[l] {
+				return l
+			}
+		}
+	}
+
+	var defs []string
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		var sb strings.Builder
+		last := 0
+		for _, f := range inlineFootnotes(line) {
+			label := nextLabel()
+			sb.WriteString(line[last:f[0]])
+			sb.WriteString("[^" + label + "]")
+			defs = append(defs, "[^"+label+"]: "+strings.TrimSpace(line[f[0]+2:f[1]-1]))
+			last = f[1]
+		}
+		if last > 0 {
+			sb.WriteString(line[last:])
+			lines[i] = sb.String()
+		}
+	}
+	if len(defs) == 0 {
+		return content, nil
+	}
+
+	// after the last footnote definition, or else at the end
+	if fb := footnoteBlocks(lines, b); len(fb) > 0 {
+		at := fb[len(fb)-1].end + 1
+		if fb[len(fb)-1].end > fb[len(fb)-1].start {
+			defs = append([]string{""}, defs...)
+		}
+		lines = append(lines[:at], append(defs, lines[at:]...)...)
+		return strings.Join(lines, "\n"), nil
+	}
+	trailingNewline := len(lines) > 1 && lines[len(lines)-1] == ""
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, defs...)
+	if trailingNewline {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// inlineFootnotes returns the byte ranges [start, end) of the non-empty
+// inline footnotes ^[...] in line. Brackets inside a footnote must
+// balance; code spans and backslash escapes in it do not count, and a ^[
+// inside a code span starts no footnote.
+func inlineFootnotes(line string) [][2]int {
+	spans := codeSpans(line)
+	// skipCode returns the end of the code span starting at i, or i
+	skipCode := func(i int) int {
+		for _, sp := range spans {
+			if sp[0] == i {
+				return sp[1]
+			}
+		}
+		return i
+	}
+	var found [][2]int
+	for i := 0; i < len(line); i++ {
+		if j := skipCode(i); j > i {
+			i = j - 1
+			continue
+		}
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(line[i:], "^[") {
+			continue
+		}
+		depth := 0
+		end := -1
+	scan:
+		for j := i + 1; j < len(line); j++ {
+			if k := skipCode(j); k > j {
+				j = k - 1
+				continue
+			}
+			switch line[j] {
+			case '\\':
+				j++
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					end = j + 1
+					break scan
+				}
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		if strings.TrimSpace(line[i+2:end-1]) != "" {
+			found = append(found, [2]int{i, end})
+		}
+		i = end - 1
+	}
+	return found
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestInlineFootnoteRule(t *testing.T) {
+	rule := NewInlineFootnoteRule()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "appended at the end",
+			input:    "Text^[A note.] and more^[Another.]\n",
+			expected: "Text[^1] and more[^2]\n\n[^1]: A note.\n[^2]: Another.\n",
+		},
+		{
+			name:     "numbering continues after existing footnotes",
+			input:    "A[^1] B^[new] C[^3]\n\n[^1]: one\n[^3]: three\n\nAfter.\n",
+			expected: "A[^1] B[^4] C[^3]\n\n[^1]: one\n[^3]: three\n[^4]: new\n\nAfter.\n",
+		},
+		{
+			name:     "brackets and code spans inside",
+			input:    "See^[a [link](u) and `]` and \\] too] end",
+			expected: "See[^1] end\n\n[^1]: a [link](u) and `]` and \\] too",
+		},
+		{
+			name:     "after a multi-line definition",
+			input:    "A[^x] B^[b]\n\n[^x]: first\n\n    second\n",
+			expected: "A[^x] B[^1]\n\n[^x]: first\n\n    second\n\n[^1]: b\n",
+		},
+		{
+			name:     "left alone",
+			input:    "`^[code]` x^[unbalanced [ y ^[] \\^[escaped]\n\n```\n^[fenced]\n```\n",
+			expected: "`^[code]` x^[unbalanced [ y ^[] \\^[escaped]\n\n```\n^[fenced]\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 29: report lines longer than a limit, without rewrapping
+// ----------------------------------------------------------------
+
+// lineLengthExclusions are the kinds of lines LineLengthRule can skip.
+var lineLengthExclusions = []string{"urls", "tables", "code", "headings"}
+
+type LineLengthRule struct {
+	max    int
+	ignore map[string]bool // keys from lineLengthExclusions
+}
+
+// NewLineLengthRule constructs a rule that reports lines longer than max
+// runes, except for the kinds of lines named in ignore.
+func NewLineLengthRule(max int, ignore []string) Rule {
+	r := LineLengthRule{max: max, ignore: map[string]bool{}}
+	for _, k := range ignore {
+		r.ignore[k] = true
+	}
+	return r
+}
+
+func newLineLengthRuleFromOptions(o Options) (Rule, error) {
+	max, err := o.Int("max-line-length", 120)
+	if err != nil {
+		return nil, err
+	}
+	ignore := o.List("line-length-ignore", lineLengthExclusions)
+	for _, k := range ignore {
+		if !containsString(lineLengthExclusions, k) {
+			return nil, fmt.Errorf("option line-length-ignore: %q is not one of %s",
+				k, strings.Join(lineLengthExclusions, ", "))
+		}
+	}
+	return NewLineLengthRule(max, ignore), nil
+}
+
+func (LineLengthRule) Name() string {
+	return "LineLength"
+}
+
+func (LineLengthRule) Apply(content string) (string, error) {
+	return content, nil
+}
+
+func (r LineLengthRule) Lint(content string) []Diagnostic {
+	if r.max == 0 {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		n := utf8.RuneCountInString(line)
+		if n <= r.max || r.excluded(lines, b, i) {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Line:    i + 1,
+			Rule:    r.Name(),
+			Message: fmt.Sprintf("line is %d characters long (max %d)", n, r.max),
+		})
+	}
+	return diags
+}
+
+func (r LineLengthRule) excluded(lines []string, b *blocks, i int) bool {
+	info := b.lines[i]
+	switch {
+	case info.kind == lineFrontMatter:
+		return true
+	case b.isCode(i):
+		return r.ignore["code"]
+	case info.kind == lineTable:
+		return r.ignore["tables"]
+	case isATXHeading(lines[i][info.bodyStart:]) ||
+		(i+1 < len(lines) && b.lines[i+1].kind == lineSetextUnderline):
+		return r.ignore["headings"]
+	}
+	return r.ignore["urls"] && longURL(lines[i], r.max)
+}
+
+// longURL reports whether line holds a URL or link destination that runs
+// past column max with no whitespace after it, so that no rewrapping could
+// bring the line under the limit.
+func longURL(line string, max int) bool {
+	if !strings.Contains(line, "://") && !strings.Contains(line, "](") {
+		return false
+	}
+	col := 0
+	for _, c := range line {
+		if col >= max && unicode.IsSpace(c) {
+			return false
+		}
+		col++
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
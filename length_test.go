@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineLengthRule(t *testing.T) {
+	long := strings.Repeat("word ", 5) // 25 runes
+	url := "see https://example.com/a/very/long/path"
+	tests := []struct {
+		name   string
+		ignore []string
+		input  string
+		want   []int // reported lines
+	}{
+		{
+			name:  "prose",
+			input: "short\n" + long + "\n" + strings.Repeat("x", 20),
+			want:  []int{2},
+		},
+		{
+			name:  "runes, not bytes",
+			input: strings.Repeat("漢", 20) + "\n" + strings.Repeat("漢", 21),
+			want:  []int{2},
+		},
+		{
+			name:   "default exclusions",
+			ignore: lineLengthExclusions,
+			input: url + "\n# " + long + "\n" + long + "\n===\n\n| " + long + " |\n| - |\n\n```\n" +
+				long + "\n```\n\n    " + long,
+			want: nil,
+		},
+		{
+			name:  "nothing excluded",
+			input: url + "\n\n# " + long + "\n\n```\n" + long + "\n```",
+			want:  []int{1, 3, 6},
+		},
+		{
+			name:   "url before the limit is no excuse",
+			ignore: lineLengthExclusions,
+			input:  "https://x.io " + long,
+			want:   []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewLineLengthRule(20, tt.ignore).(LineLengthRule)
+			var got []int
+			for _, d := range rule.Lint(tt.input) {
+				got = append(got, d.Line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got lines %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineLengthRuleMessage(t *testing.T) {
+	rule := NewLineLengthRule(3, nil).(LineLengthRule)
+	want := []Diagnostic{{Line: 1, Rule: "LineLength", Message: "line is 4 characters long (max 3)"}}
+	if got := rule.Lint("abcd"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := NewLineLengthRule(0, nil).(LineLengthRule).Lint("abcd"); got != nil {
+		t.Errorf("disabled: got %v", got)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ----------------------------------------------------------------
+// Limits on pathological documents
+// ----------------------------------------------------------------
+
+// limitOptions are the options that set a Formatter's limits rather than
+// configure a rule.
+var limitOptions = []string{"max-input-size", "max-line-size", "rule-timeout"}
+
+const (
+	defaultMaxInputSize = 16 << 20
+	defaultMaxLineSize  = 64 << 10
+	defaultRuleTimeout  = 10 * time.Second
+)
+
+// limits keep a Formatter from spending unbounded time on one document,
+// which would hang the daemon or an editor. Zero values mean no limit.
+type limits struct {
+	// maxInputSize is the size in bytes of the largest document formatted.
+	maxInputSize int
+	// maxLineSize is the length in bytes of the longest line rules see;
+	// longer lines are kept as they are.
+	maxLineSize int
+	// ruleTimeout is how long a single rule may take.
+	ruleTimeout time.Duration
+}
+
+func limitsFromOptions(o Options) (limits, error) {
+	var l limits
+	var err error
+	if l.maxInputSize, err = o.Int("max-input-size", defaultMaxInputSize); err != nil {
+		return l, err
+	}
+	if l.maxLineSize, err = o.Int("max-line-size", defaultMaxLineSize); err != nil {
+		return l, err
+	}
+	if l.ruleTimeout, err = o.Duration("rule-timeout", defaultRuleTimeout); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// checkSize fails for documents larger than maxInputSize.
+func (l limits) checkSize(content string) error {
+	if l.maxInputSize > 0 && len(content) > l.maxInputSize {
+		return fmt.Errorf("document is %d bytes, more than max-input-size (%d); left as is", len(content), l.maxInputSize)
+	}
+	return nil
+}
+
+// The private use characters around the marks of hidden lines.
+const (
+	longLineOpen  = "\ue000"
+	longLineClose = "\ue001"
+)
+
+// longLineMark stands in for a line longer than maxLineSize while the
+// rules run: a private use character, the line's index and another one.
+func longLineMark(i int) string {
+	return longLineOpen + strconv.Itoa(i) + longLineClose
+}
+
+// hideLongLines replaces the lines of content longer than maxLineSize,
+// outside of front matter, with marks that rules leave alone. It returns
+// the lines replaced by index.
+func (l limits) hideLongLines(content string) (string, map[int]string) {
+	if l.maxLineSize <= 0 || len(content) <= l.maxLineSize {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	hidden := map[int]string{}
+	for i := metadataEnd(lines) + 1; i < len(lines); i++ {
+		if len(lines[i]) > l.maxLineSize {
+			hidden[i] = lines[i]
+			lines[i] = longLineMark(i)
+		}
+	}
+	if len(hidden) == 0 {
+		return content, nil
+	}
+	return strings.Join(lines, "\n"), hidden
+}
+
+// restoreLongLines puts the lines hidden by hideLongLines back in place
+// of the lines holding their marks, whatever rules did to those lines.
+func restoreLongLines(content string, hidden map[int]string) (string, error) {
+	if len(hidden) == 0 {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	found := 0
+	for i, line := range lines {
+		k := strings.Index(line, longLineOpen)
+		if k < 0 {
+			continue
+		}
+		end := strings.Index(line[k:], longLineClose)
+		if end < 0 {
+			continue
+		}
+		n, err := strconv.Atoi(line[k+len(longLineOpen) : k+end])
+		if orig, ok := hidden[n]; err == nil && ok {
+			lines[i] = orig
+			found++
+		}
+	}
+	if found != len(hidden) {
+		return "", fmt.Errorf("a rule lost a line longer than max-line-size")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// longLineDiagnostics reports the lines hideLongLines hid.
+func (l limits) longLineDiagnostics(hidden map[int]string) []Diagnostic {
+	var diags []Diagnostic
+	for i, line := range hidden {
+		diags = append(diags, Diagnostic{
+			Line:    i + 1,
+			Rule:    "Limits",
+			Key:     "max-line-size",
+			Message: fmt.Sprintf("line is %d bytes, more than max-line-size (%d); rules left it as is", len(line), l.maxLineSize),
+		})
+	}
+	return diags
+}
+
+// overrunning counts the rules that overran rule-timeout and still run in
+// the background.
+var overrunning atomic.Int64
+
+// errRuleOverrunning is what the daemon and the LSP server answer while a
+// rule that overran rule-timeout still runs. Go cannot stop it, and as
+// they live on, every pathological document would otherwise leave one more
+// busy goroutine behind.
+var errRuleOverrunning = errors.New("a rule that took longer than rule-timeout is still running; try again once it is done")
+
+// checkOverrunning fails while a rule that overran rule-timeout still runs.
+func checkOverrunning() error {
+	if overrunning.Load() > 0 {
+		return errRuleOverrunning
+	}
+	return nil
+}
+
+// applyRule applies r to content, failing when it takes longer than
+// ruleTimeout or ctx is done first. Go cannot stop the rule, so it runs
+// on in the background, counted by overrunning until it is done, but the
+// caller gets its answer in time. Rules only ever see their own copy of
+// the document, so a late result changes nothing.
+func (l limits) applyRule(ctx context.Context, r Rule, content string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if l.ruleTimeout <= 0 && ctx.Done() == nil {
+		return r.Apply(content)
+	}
+	if l.ruleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.ruleTimeout)
+		defer cancel()
+	}
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	// state goes from running to either done or abandoned, whichever
+	// happens first
+	const (
+		running = iota
+		finished
+		abandoned
+	)
+	var state atomic.Int32
+	go func() {
+		out, err := r.Apply(content)
+		if !state.CompareAndSwap(running, finished) {
+			overrunning.Add(-1)
+		}
+		done <- result{out, err}
+	}()
+	select {
+	case res := <-done:
+		return res.out, res.err
+	case <-ctx.Done():
+		if state.CompareAndSwap(running, abandoned) {
+			overrunning.Add(1)
+		}
+		if ctx.Err() == context.DeadlineExceeded && l.ruleTimeout > 0 {
+			return "", fmt.Errorf("took longer than rule-timeout (%s); left as is", l.ruleTimeout)
+		}
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowRule takes its time, as a rule with a pathological regexp would:
+// it runs until release is closed.
+type slowRule struct{ release chan struct{} }
+
+func (slowRule) Name() string { return "Slow" }
+func (r slowRule) Apply(content string) (string, error) {
+	<-r.release
+	return content + "slow", nil
+}
+
+// suffixRule appends "." to every line.
+type suffixRule struct{}
+
+func (suffixRule) Name() string { return "Suffix" }
+func (suffixRule) Apply(content string) (string, error) {
+	return strings.ReplaceAll(content, "\n", ".\n"), nil
+}
+
+func TestLongLinesPassThrough(t *testing.T) {
+	// a million unclosed \( on one line
+	long := strings.Repeat(`\(`, 1<<20)
+	input := "# Title\nSome \\(x\\) math.\n" + long + "\n"
+	opts := Options{"rule-timeout": "5s"}
+	enable := []string{"InlineMathToDollar"}
+
+	start := time.Now()
+	got, diags, err := formatDocument(input, "", opts, enable, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+	want := "# Title\n\nSome $x$ math.\n" + long + "\n"
+	if got != want {
+		t.Errorf("expected the long line to pass through, got %q...", got[:40])
+	}
+	var found bool
+	for _, d := range diags {
+		if d.Rule == "Limits" && d.Line == 4 && strings.Contains(d.Message, "max-line-size") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no diagnostic for the long line in %v", diags)
+	}
+}
+
+func TestRestoreLongLines(t *testing.T) {
+	l := limits{maxLineSize: 10}
+	input := "short\n0123456789abc\nshort"
+	f := &Formatter{rules: []Rule{suffixRule{}}, limits: l}
+	got, err := f.Format(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rule's change to the hidden line is undone with it
+	if want := "short.\n0123456789abc\nshort"; got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+
+	_, hidden := l.hideLongLines(input)
+	if _, err := restoreLongLines("short\nshort", hidden); err == nil {
+		t.Error("no error for a lost line")
+	}
+}
+
+func TestMaxInputSize(t *testing.T) {
+	_, _, err := formatDocument(strings.Repeat("a", 100), "", Options{"max-input-size": "64"}, nil, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "max-input-size") {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := newFormatterFromOptions(Options{"rule-timeout": "soon"}, nil, nil); err == nil {
+		t.Error("no error for a bad rule-timeout")
+	}
+}
+
+func TestRuleTimeout(t *testing.T) {
+	slow := slowRule{release: make(chan struct{})}
+	f := &Formatter{rules: []Rule{slow, suffixRule{}}, limits: limits{ruleTimeout: 50 * time.Millisecond}}
+	start := time.Now()
+	_, err := f.Format("text\n")
+	if err == nil || !strings.Contains(err.Error(), `rule "Slow" failed: took longer than rule-timeout`) {
+		t.Errorf("got error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+	// long-running modes refuse documents while the rule runs on
+	if err := checkOverrunning(); err != errRuleOverrunning {
+		t.Errorf("got %v, want errRuleOverrunning", err)
+	}
+
+	// the late result leaves the rules that run meanwhile and after alone
+	later := &Formatter{rules: []Rule{suffixRule{}}, limits: limits{ruleTimeout: time.Second}}
+	if got, err := later.Format("a\nb\n"); err != nil || got != "a.\nb.\n" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	close(slow.release)
+	for deadline := time.Now().Add(time.Second); checkOverrunning() != nil; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the rule still counts as running")
+		}
+	}
+	if got, err := later.Format("a\nb\n"); err != nil || got != "a.\nb.\n" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if got, err := f.Format("text\n"); err != nil || got != "text.\nslow" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f.limits.ruleTimeout = 0
+	if _, err := f.FormatContext(ctx, "text"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 52: place link reference definitions
+// ----------------------------------------------------------------
+
+// referenceUse matches a full, collapsed or shortcut reference link,
+// "[text][label]", "[label][]" or "[label]", capturing text and label.
+var referenceUse = regexp.MustCompile(`!?\[([^\]]+)\](?:\[([^\]]*)\])?`)
+
+type LinkDefinitionsRule struct {
+	// placement is "end-of-file", "end-of-section", "first-use", or
+	// "preserve" to leave definitions where they are.
+	placement string
+}
+
+// NewLinkDefinitionsRule constructs a rule that moves link reference
+// definitions, [label]: url, to placement: the end of the file, the end of
+// the section that first uses them, ending at the next heading of the same
+// or a higher level, or right after the block that first uses them.
+// Unused definitions go to the end of the file.
+func NewLinkDefinitionsRule(placement string) Rule {
+	return LinkDefinitionsRule{placement: placement}
+}
+
+func newLinkDefinitionsRuleFromOptions(o Options) (Rule, error) {
+	placement, err := o.Choice("definition-placement", "preserve", "preserve", "end-of-file", "end-of-section", "first-use")
+	if err != nil {
+		return nil, err
+	}
+	return NewLinkDefinitionsRule(placement), nil
+}
+
+func (LinkDefinitionsRule) Name() string {
+	return "LinkDefinitions"
+}
+
+// referenceLabel normalizes a link label: labels match regardless of case
+// and of how their whitespace is written.
+func referenceLabel(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// linkDefinitions reports which lines are link reference definitions of
+// their own, outside of containers, and their labels.
+func linkDefinitions(lines []string, b *blocks) map[int]string {
+	defs := map[int]string{}
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineText || info.quote > 0 || info.container > 0 || b.isCode(i) {
+			continue
+		}
+		// a definition cannot interrupt a paragraph
+		if _, ok := defs[i-1]; i > 0 && !ok && b.lines[i-1].kind == lineText && !isATXHeading(lines[i-1]) {
+			continue
+		}
+		loc := linkReferenceDefinition.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		label := line[strings.Index(line, "[")+1 : strings.Index(line, "]:")]
+		if strings.HasPrefix(label, "^") || strings.TrimSpace(line[loc[1]:]) == "" {
+			continue
+		}
+		defs[i] = referenceLabel(label)
+	}
+	return defs
+}
+
+func (r LinkDefinitionsRule) Apply(content string) (string, error) {
+	if r.placement == "preserve" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	defs := linkDefinitions(lines, b)
+	if len(defs) == 0 {
+		return content, nil
+	}
+
+	// the first line using each label
+	firstUse := map[string]int{}
+	for i, line := range lines {
+		if _, ok := defs[i]; ok || literalLine(b, i) {
+			continue
+		}
+		covered := protectedSpans(line)
+		for _, m := range referenceUse.FindAllStringSubmatchIndex(line, -1) {
+			if inSpans(covered, m[0]) || m[1] < len(line) && line[m[1]] == '(' {
+				continue
+			}
+			label := line[m[2]:m[3]]
+			if m[4] >= 0 && m[5] > m[4] {
+				label = line[m[4]:m[5]]
+			}
+			if _, ok := firstUse[referenceLabel(label)]; !ok {
+				firstUse[referenceLabel(label)] = i
+			}
+		}
+	}
+
+	// lastContent is the last line before end that stays in the document
+	lastContent := func(end int) int {
+		for k := end - 1; k >= 0; k-- {
+			if _, ok := defs[k]; !ok && strings.TrimSpace(lines[k]) != "" {
+				return k
+			}
+		}
+		return -1
+	}
+	headings := documentHeadings(lines, b)
+	anchor := func(use int) int {
+		switch r.placement {
+		case "end-of-section":
+			level := 0
+			for _, h := range headings {
+				if h.line <= use {
+					level = h.level
+				}
+			}
+			for _, h := range headings {
+				if h.line > use && (level == 0 || h.level <= level) {
+					return lastContent(h.line)
+				}
+			}
+		case "first-use":
+			return lastContent(topLevelBlockEnd(lines, b, use) + 1)
+		}
+		return len(lines)
+	}
+
+	// the definitions to insert after each anchor line, in document order
+	groups := map[int][]string{}
+	for i := range lines {
+		label, ok := defs[i]
+		if !ok {
+			continue
+		}
+		at := len(lines)
+		if use, ok := firstUse[label]; ok {
+			at = anchor(use)
+		}
+		groups[at] = append(groups[at], strings.TrimRight(lines[i], " \t"))
+	}
+
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+	out := make([]string, 0, len(lines))
+	removed, inserted := false, false
+	for i, l := range lines {
+		if _, ok := defs[i]; ok {
+			removed = true
+			continue
+		}
+		if (removed || inserted) && blank(l) && (len(out) == 0 || blank(out[len(out)-1])) {
+			continue
+		}
+		if inserted && !blank(l) {
+			out = append(out, "")
+		}
+		removed, inserted = false, false
+		out = append(out, l)
+		if g, ok := groups[i]; ok {
+			out = append(out, "")
+			out = append(out, g...)
+			inserted = true
+		}
+	}
+	if g, ok := groups[len(lines)]; ok {
+		trailingNewline := len(lines) > 1 && lines[len(lines)-1] == ""
+		for len(out) > 0 && blank(out[len(out)-1]) {
+			out = out[:len(out)-1]
+		}
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, g...)
+		if trailingNewline {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// topLevelBlockEnd returns the last line of the top-level block line i is
+// in, lists and block quotes included.
+func topLevelBlockEnd(lines []string, b *blocks, i int) int {
+	end := i
+	for k := i + 1; k < len(lines); k++ {
+		info := b.lines[k]
+		if info.kind == lineBlank {
+			continue
+		}
+		gap := k > end+1
+		if gap && info.container == 0 && info.quote == 0 && !b.isCode(k) && info.kind != lineIndentedCode {
+			break
+		}
+		if !gap && info.kind == lineText && info.container == 0 && info.quote == 0 && isATXHeading(lines[k]) {
+			break
+		}
+		end = k
+	}
+	return end
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinkDefinitionsRule(t *testing.T) {
+	input := "# Title\n\nIntro uses [a] and [Go][go].\n\n[b]: https://b.org\n\n## One\n\nUses [b] here.\n\n- item with [c][]\n- more\n\n  continued\n\n### Sub\n\nText.\n\n## Two\n\nText [a].\n\n[a]: https://a.org\n[go]: https://go.dev\n[c]: https://c.org\n[unused]: https://u.org\n"
+	tests := []struct {
+		placement string
+		expected  string
+	}{
+		{
+			placement: "end-of-file",
+			expected:  "# Title\n\nIntro uses [a] and [Go][go].\n\n## One\n\nUses [b] here.\n\n- item with [c][]\n- more\n\n  continued\n\n### Sub\n\nText.\n\n## Two\n\nText [a].\n\n[b]: https://b.org\n[a]: https://a.org\n[go]: https://go.dev\n[c]: https://c.org\n[unused]: https://u.org\n",
+		},
+		{
+			placement: "end-of-section",
+			expected:  "# Title\n\nIntro uses [a] and [Go][go].\n\n## One\n\nUses [b] here.\n\n- item with [c][]\n- more\n\n  continued\n\n### Sub\n\nText.\n\n[b]: https://b.org\n[c]: https://c.org\n\n## Two\n\nText [a].\n\n[a]: https://a.org\n[go]: https://go.dev\n[unused]: https://u.org\n",
+		},
+		{
+			placement: "first-use",
+			expected:  "# Title\n\nIntro uses [a] and [Go][go].\n\n[a]: https://a.org\n[go]: https://go.dev\n\n## One\n\nUses [b] here.\n\n[b]: https://b.org\n\n- item with [c][]\n- more\n\n  continued\n\n[c]: https://c.org\n\n### Sub\n\nText.\n\n## Two\n\nText [a].\n\n[unused]: https://u.org\n",
+		},
+		{
+			placement: "preserve",
+			expected:  input,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.placement, func(t *testing.T) {
+			rule := NewLinkDefinitionsRule(tt.placement)
+			got, err := rule.Apply(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestLinkDefinitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[int]string
+	}{
+		{
+			name:  "definitions",
+			input: "[A  b]: https://a.org\n[c]: <https://c.org> \"title\"",
+			want:  map[int]string{0: "a b", 1: "c"},
+		},
+		{
+			name:  "not definitions",
+			input: "para\n[a]: https://a.org\n\n[^1]: note\n\n> [q]: https://q.org\n\n- [l]: https://l.org\n\n```\n[c]: https://c.org\n```",
+			want:  map[int]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.input, "\n")
+			if got := linkDefinitions(lines, classifyBlocks(lines)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 49: end list items with a period, or without one
+// ----------------------------------------------------------------
+
+var (
+	// taskBox matches the box of a task list item.
+	taskBox = regexp.MustCompile(`^\[[ xX]\](?:\s|$)`)
+	// sentenceBreak matches the end of a sentence followed by another.
+	sentenceBreak = regexp.MustCompile(`[.!?]["'”’)\]]*\s+["'“‘(]?\p{Lu}`)
+	// abbreviationEnd matches a trailing abbreviation such as "e.g." or
+	// "etc.", whose period is not punctuation.
+	abbreviationEnd = regexp.MustCompile(`(?:\b\p{L}\.\p{L}\.|\b(?:etc|Inc|Ltd|Co|vs|approx|al)\.)$`)
+)
+
+type ListPunctuationRule struct {
+	// mode is "strip", "require" or "preserve".
+	mode string
+}
+
+// NewListPunctuationRule constructs a rule that, with mode "strip", drops
+// the period or semicolon ending a list item of one line, and with mode
+// "require" ends every list item with a period. Items ending in a colon,
+// code or a link, items of several sentences and task items are left
+// alone, as is everything with mode "preserve".
+func NewListPunctuationRule(mode string) Rule {
+	return ListPunctuationRule{mode: mode}
+}
+
+func newListPunctuationRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("list-punctuation", "preserve", "strip", "require", "preserve")
+	if err != nil {
+		return nil, err
+	}
+	return NewListPunctuationRule(mode), nil
+}
+
+func (ListPunctuationRule) Name() string {
+	return "ListPunctuation"
+}
+
+func (r ListPunctuationRule) Apply(content string) (string, error) {
+	if r.mode == "preserve" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i := range lines {
+		info := b.lines[i]
+		if !info.item || info.kind != lineText || literalLine(b, i) {
+			continue
+		}
+		// the item's paragraph runs to the next line that is not plain
+		// text continuing it
+		last := i
+		for last+1 < len(lines) {
+			next := b.lines[last+1]
+			if next.kind != lineText || next.item || next.quote != info.quote ||
+				startsBlock(lines[last+1][next.bodyStart:]) {
+				break
+			}
+			last++
+		}
+		_, end, _, _ := listMarker(lines[i][info.bodyStart:])
+		var text []string
+		for k := i; k <= last; k++ {
+			from := b.lines[k].bodyStart
+			if k == i {
+				from += end
+			}
+			text = append(text, strings.TrimSpace(lines[k][from:]))
+		}
+		paragraph := strings.Join(text, " ")
+		if paragraph == "" || taskBox.MatchString(paragraph) || sentenceBreak.MatchString(paragraph) {
+			continue
+		}
+		if r.mode == "strip" && last != i {
+			continue
+		}
+		line := lines[last]
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == line[:b.lines[last].bodyStart] || strings.HasSuffix(trimmed, "\\") {
+			continue
+		}
+		stem := strings.TrimRight(trimmed, ".;")
+		switch {
+		case endsWithCodeOrLink(stem):
+			continue
+		case r.mode == "strip":
+			// only one period or semicolon: "..." is an ellipsis
+			if len(trimmed)-len(stem) != 1 || abbreviationEnd.MatchString(trimmed) {
+				continue
+			}
+		case strings.ContainsAny(trimmed[len(trimmed)-1:], ".!?:;") || strings.HasSuffix(trimmed, "…"):
+			continue
+		}
+		fixed := stem
+		if r.mode == "require" {
+			fixed = trimmed + "."
+		}
+		lines[last] = fixed + line[len(trimmed):]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// endsWithCodeOrLink reports whether s ends with a code span, a link, an
+// autolink or an HTML tag.
+func endsWithCodeOrLink(s string) bool {
+	if strings.HasSuffix(s, "`") || strings.HasSuffix(s, ">") {
+		return true
+	}
+	for _, m := range inlineLinkSpan.FindAllStringIndex(s, -1) {
+		if m[1] == len(s) {
+			return true
+		}
+	}
+	for _, sp := range protectedSpans(s) {
+		if sp[1] == len(s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(s, "]") && strings.Contains(s, "[")
+}
@@ -0,0 +1,107 @@
+package main
+
+import "testing"
+
+func TestListPunctuationRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "strip",
+			mode:     "strip",
+			input:    "- Fast.\n- Safe;\n1. Small.\n> * Quoted.",
+			expected: "- Fast\n- Safe\n1. Small\n> * Quoted",
+		},
+		{
+			name:     "require",
+			mode:     "require",
+			input:    "- Fast\n- Safe!\n- Wrapped over\n  two lines\n  - Nested",
+			expected: "- Fast.\n- Safe!\n- Wrapped over\n  two lines.\n  - Nested.",
+		},
+		{
+			name:     "colons",
+			mode:     "require",
+			input:    "- Options:\n  - a",
+			expected: "- Options:\n  - a.",
+		},
+		{
+			name:     "code and links",
+			mode:     "require",
+			input:    "- Run `make`\n- See [docs](https://x.org)\n- Or <https://x.org>\n- See [docs][ref]",
+			expected: "- Run `make`\n- See [docs](https://x.org)\n- Or <https://x.org>\n- See [docs][ref]",
+		},
+		{
+			name:     "code and links when stripping",
+			mode:     "strip",
+			input:    "- Run `make`.\n- See [docs](https://x.org).",
+			expected: "- Run `make`.\n- See [docs](https://x.org).",
+		},
+		{
+			name:     "several sentences",
+			mode:     "strip",
+			input:    "- This is one. This is two.\n- One! Two",
+			expected: "- This is one. This is two.\n- One! Two",
+		},
+		{
+			name:     "several sentences when requiring",
+			mode:     "require",
+			input:    "- This is one. This is two",
+			expected: "- This is one. This is two",
+		},
+		{
+			name:     "task items",
+			mode:     "require",
+			input:    "- [ ] Write docs\n- [x] Ship it.",
+			expected: "- [ ] Write docs\n- [x] Ship it.",
+		},
+		{
+			name:     "task items when stripping",
+			mode:     "strip",
+			input:    "- [x] Ship it.",
+			expected: "- [x] Ship it.",
+		},
+		{
+			name:     "ellipses and abbreviations",
+			mode:     "strip",
+			input:    "- And so on...\n- Apples, pears, etc.\n- See e.g.",
+			expected: "- And so on...\n- Apples, pears, etc.\n- See e.g.",
+		},
+		{
+			name:     "wrapped items are not stripped",
+			mode:     "strip",
+			input:    "- Wrapped over\n  two lines.",
+			expected: "- Wrapped over\n  two lines.",
+		},
+		{
+			name:     "code blocks and hard breaks",
+			mode:     "require",
+			input:    "```\n- a\n```\n\n- line\\\n  break",
+			expected: "```\n- a\n```\n\n- line\\\n  break.",
+		},
+		{
+			name:     "preserve",
+			mode:     "preserve",
+			input:    "- Fast.\n- Safe",
+			expected: "- Fast.\n- Safe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewListPunctuationRule(tt.mode)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
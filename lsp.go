@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ----------------------------------------------------------------
+// Language Server Protocol over stdio: formatting and diagnostics
+// ----------------------------------------------------------------
+
+// JSON-RPC error codes used by the server.
+const (
+	lspParseError     = -32700
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+	lspInternalError  = -32603
+)
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+// lspServer holds the documents the editor has open, by URI.
+type lspServer struct {
+	cfg  daemonConfig
+	out  io.Writer
+	docs map[string]string
+	// root is the directory of the workspace the client opened, if any.
+	root     string
+	shutdown bool
+}
+
+// runLSP serves the Language Server Protocol on in and out until the
+// client sends exit. It fails when exit comes without a shutdown request
+// first, as the protocol asks.
+func runLSP(in io.Reader, out io.Writer, cfg daemonConfig) error {
+	s := &lspServer{cfg: cfg, out: out, docs: map[string]string{}}
+	r := bufio.NewReader(in)
+	for {
+		data, err := readLSPMessage(r)
+		if err == io.EOF {
+			return fmt.Errorf("lsp: input closed without exit")
+		}
+		if err != nil {
+			return fmt.Errorf("lsp: %w", err)
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.reply(nil, nil, &lspError{lspParseError, err.Error()})
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp: exit without shutdown")
+			}
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rerr)
+		}
+	}
+}
+
+// readLSPMessage reads one message framed by a Content-Length header.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 || length > maxDaemonMessage {
+		return nil, fmt.Errorf("missing or bad Content-Length")
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func (s *lspServer) send(msg lspMessage) {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *lspServer) reply(id *json.RawMessage, result any, rerr *lspError) {
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	if rerr == nil && result == nil {
+		// a successful reply must carry a result, even a null one
+		result = json.RawMessage("null")
+	}
+	s.send(lspMessage{ID: id, Result: result, Error: rerr})
+}
+
+func (s *lspServer) handle(msg lspMessage) (any, *lspError) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &lspError{lspInvalidRequest, "server is shutting down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		var p struct {
+			RootURI          string `json:"rootUri"`
+			RootPath         string `json:"rootPath"`
+			WorkspaceFolders []struct {
+				URI string `json:"uri"`
+			} `json:"workspaceFolders"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		switch {
+		case len(p.WorkspaceFolders) > 0:
+			s.root = uriPath(p.WorkspaceFolders[0].URI)
+		case p.RootURI != "":
+			s.root = uriPath(p.RootURI)
+		default:
+			s.root = p.RootPath
+		}
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":                1, // full documents
+				"documentFormattingProvider":      true,
+				"documentRangeFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "mdfmt", "version": version},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct{ TextDocument lspTextDocument }
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   lspTextDocument
+			ContentChanges []struct{ Text string }
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+	case "textDocument/didClose":
+		var p struct{ TextDocument lspTextDocument }
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": p.TextDocument.URI, "diagnostics": []lspDiagnostic{},
+		})
+	case "textDocument/formatting", "textDocument/rangeFormatting":
+		var p struct {
+			TextDocument lspTextDocument
+			Range        *lspRange
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		text, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, &lspError{lspInvalidParams, "document is not open: " + p.TextDocument.URI}
+		}
+		if err := checkOverrunning(); err != nil {
+			return nil, &lspError{lspInternalError, err.Error()}
+		}
+		fmter, err := s.formatter(p.TextDocument.URI, text)
+		if err != nil {
+			return nil, &lspError{lspInternalError, err.Error()}
+		}
+		// a range formats as -lines does
+		var lines []LineRange
+		if p.Range != nil {
+			lines = []LineRange{lspLines(*p.Range)}
+		}
+		out, _, err := formatWith(fmter, text, s.cfg.verify, lines)
+		if err != nil {
+			return nil, &lspError{lspInternalError, err.Error()}
+		}
+		return textEdits(text, out), nil
+	default:
+		if msg.ID != nil && !strings.HasPrefix(msg.Method, "$/") {
+			return nil, &lspError{lspMethodNotFound, "method not supported: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+// formatter returns the Formatter for text, the open document at uri,
+// with the configuration file nearest above it, or above the workspace
+// root for a document that is not a file.
+func (s *lspServer) formatter(uri, text string) (*Formatter, error) {
+	path := uriPath(uri)
+	where := path
+	if where == "" && s.root != "" {
+		where = filepath.Join(s.root, "untitled.md")
+	}
+	conf := projectConfig
+	if where != "" {
+		// "." is what configDirFor returns when there is none
+		conf = nil
+		if dir := configDirFor(where); dir != "." {
+			var err error
+			if conf, err = loadConfig(dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return conf.documentFormatter(text, path, s.cfg.opts, s.cfg.enable, s.cfg.disable)
+}
+
+// lspLines returns the lines of r, a range that ends before the first
+// character of its last line when it ends at the start of a later one.
+func lspLines(r lspRange) LineRange {
+	end := r.End.Line + 1
+	if r.End.Character == 0 && r.End.Line > r.Start.Line {
+		end--
+	}
+	return LineRange{r.Start.Line + 1, max(end, r.Start.Line+1)}
+}
+
+func (s *lspServer) notify(method string, params any) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.send(lspMessage{Method: method, Params: data})
+}
+
+// publishDiagnostics lints the open document at uri as it is, so that the
+// findings point at the text in the editor.
+func (s *lspServer) publishDiagnostics(uri string) {
+	text := s.docs[uri]
+	lines := strings.Split(text, "\n")
+	diags := []lspDiagnostic{}
+	fmter, err := s.formatter(uri, text)
+	if err != nil {
+		diags = append(diags, lspDiagnostic{Severity: 1, Source: "mdfmt", Message: err.Error()})
+	} else {
+		for _, d := range lintDocument(fmter, text) {
+			diags = append(diags, toLSPDiagnostic(d, lines))
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diags})
+}
+
+func toLSPDiagnostic(d Diagnostic, lines []string) lspDiagnostic {
+	line := d.Line - 1
+	var text string
+	if line >= 0 && line < len(lines) {
+		text = lines[line]
+	}
+	r := lspRange{Start: lspPosition{line, 0}, End: lspPosition{line, utf16Len(text)}}
+	if d.Column > 0 {
+		// Column counts characters, LSP counts UTF-16 code units
+		runes := []rune(text)
+		col := min(d.Column-1, len(runes))
+		r.Start.Character = utf16Len(string(runes[:col]))
+	}
+	severity := 2
+	if d.Severity == SeverityError {
+		severity = 1
+	}
+	code := d.Rule
+	if d.Code != "" {
+		code = d.Code + "/" + d.Rule
+	}
+	return lspDiagnostic{Range: r, Severity: severity, Code: code, Source: "mdfmt", Message: d.Message}
+}
+
+// textEdits returns the edits that turn old into new, one per changed run
+// of lines.
+func textEdits(old, new string) []lspTextEdit {
+	a, b := splitLinesKeepEnds(old), splitLinesKeepEnds(new)
+	edits := []lspTextEdit{}
+	for _, h := range lineDiff(a, b) {
+		end := lspPosition{h.aEnd, 0}
+		if h.aEnd == len(a) && h.aEnd > 0 && !strings.HasSuffix(a[h.aEnd-1], "\n") {
+			// the last line has no newline to end the range after
+			end = lspPosition{h.aEnd - 1, utf16Len(a[h.aEnd-1])}
+		}
+		edits = append(edits, lspTextEdit{
+			Range:   lspRange{Start: lspPosition{h.aStart, 0}, End: end},
+			NewText: strings.Join(b[h.bStart:h.bEnd], ""),
+		})
+	}
+	return edits
+}
+
+// splitLinesKeepEnds splits s after each newline.
+func splitLinesKeepEnds(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// uriPath returns the file path of a file:// URI, or "" for other
+// schemes.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
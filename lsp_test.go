@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lspSession runs the server on the given messages and returns what it
+// wrote, decoded.
+func lspSession(t *testing.T, cfg daemonConfig, msgs ...string) ([]map[string]any, error) {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var out bytes.Buffer
+	err := runLSP(&in, &out, cfg)
+	var got []map[string]any
+	r := bufio.NewReader(&out)
+	for {
+		data, rerr := readLSPMessage(r)
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		var m map[string]any
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	return got, err
+}
+
+func openDoc(text string) string {
+	data, _ := json.Marshal(text)
+	return `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///w/a.md","text":` + string(data) + `}}}`
+}
+
+const lspExit = `{"jsonrpc":"2.0","id":99,"method":"shutdown"}`
+
+func TestLSPInitializeAndShutdown(t *testing.T) {
+	got, err := lspSession(t, daemonConfig{},
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file:///w"}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		lspExit, `{"jsonrpc":"2.0","method":"exit"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d messages: %v", len(got), got)
+	}
+	caps := got[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if caps["documentFormattingProvider"] != true || caps["documentRangeFormattingProvider"] != true {
+		t.Errorf("capabilities %v", caps)
+	}
+	if _, ok := got[1]["result"]; !ok || got[1]["id"] != 99.0 {
+		t.Errorf("shutdown reply %v", got[1])
+	}
+
+	if _, err := lspSession(t, daemonConfig{}, `{"jsonrpc":"2.0","method":"exit"}`); err == nil {
+		t.Error("exit without shutdown succeeded")
+	}
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	got, err := lspSession(t, daemonConfig{}, openDoc("# T\n\n```\nx\n```\n"), lspExit, `{"jsonrpc":"2.0","method":"exit"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0]["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("got %v", got[0])
+	}
+	diags := got[0]["params"].(map[string]any)["diagnostics"].([]any)
+	want := []any{map[string]any{
+		"range":    map[string]any{"start": map[string]any{"line": 2.0, "character": 0.0}, "end": map[string]any{"line": 2.0, "character": 3.0}},
+		"severity": 2.0,
+		"code":     "MD040/FencedCodeLanguage",
+		"source":   "mdfmt",
+		"message":  "fenced code block has no language",
+	}}
+	if !reflect.DeepEqual(diags, want) {
+		t.Errorf("got %v, want %v", diags, want)
+	}
+}
+
+func TestLSPFormatting(t *testing.T) {
+	doc := "# A\ntext\n\n# B\nmore"
+	got, err := lspSession(t, daemonConfig{}, openDoc(doc),
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///w/a.md"},"options":{}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/rangeFormatting","params":{"textDocument":{"uri":"file:///w/a.md"},"range":{"start":{"line":3,"character":0},"end":{"line":4,"character":0}}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/rangeFormatting","params":{"textDocument":{"uri":"file:///w/a.md"},"range":{"start":{"line":3,"character":0},"end":{"line":4,"character":4}}}}`,
+		lspExit, `{"jsonrpc":"2.0","method":"exit"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := func(m map[string]any) string {
+		data, _ := json.Marshal(m["result"])
+		return string(data)
+	}
+	full := `[{"newText":"\n","range":{"end":{"character":0,"line":1},"start":{"character":0,"line":1}}},` +
+		`{"newText":"\nmore\n","range":{"end":{"character":4,"line":4},"start":{"character":0,"line":4}}}]`
+	if e := edits(got[1]); e != full {
+		t.Errorf("formatting:\nexpected:\n%s\ngot:\n%s", full, e)
+	}
+	// a range ending at the start of a line leaves that line out, as it
+	// does the final newline there
+	heading := `[{"newText":"\n","range":{"end":{"character":0,"line":4},"start":{"character":0,"line":4}}}]`
+	if e := edits(got[2]); e != heading {
+		t.Errorf("range formatting:\nexpected:\n%s\ngot:\n%s", heading, e)
+	}
+	part := `[{"newText":"\nmore\n","range":{"end":{"character":4,"line":4},"start":{"character":0,"line":4}}}]`
+	if e := edits(got[3]); e != part {
+		t.Errorf("range formatting:\nexpected:\n%s\ngot:\n%s", part, e)
+	}
+}
+
+func TestLSPConfigPerDocument(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".mdfmt.yaml"), []byte("fence: tilde\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	format := func(id int, uri string) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"textDocument/formatting","params":{"textDocument":{"uri":%q}}}`, id, uri)
+	}
+	open := func(uri string) string {
+		return `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","text":"` + "```go\\nx\\n```\\n" + `"}}}`
+	}
+	inside := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "docs", "a.md"))}).String()
+	got, err := lspSession(t, daemonConfig{},
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"workspaceFolders":[{"uri":"`+(&url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}).String()+`"}]}}`,
+		open(inside), format(2, inside),
+		open("untitled:Untitled-1"), format(3, "untitled:Untitled-1"),
+		open("file:///w/a.md"), format(4, "file:///w/a.md"),
+		lspExit, `{"jsonrpc":"2.0","method":"exit"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := map[float64]string{}
+	for _, m := range got {
+		if id, ok := m["id"].(float64); ok {
+			data, _ := json.Marshal(m["result"])
+			results[id] = string(data)
+		}
+	}
+	tilde := `[{"newText":"~~~go\n","range":{"end":{"character":0,"line":1},"start":{"character":0,"line":0}}},` +
+		`{"newText":"~~~\n","range":{"end":{"character":0,"line":3},"start":{"character":0,"line":2}}}]`
+	for id, want := range map[float64]string{2: tilde, 3: tilde, 4: "[]"} {
+		if results[id] != want {
+			t.Errorf("request %v: expected:\n%s\ngot:\n%s", id, want, results[id])
+		}
+	}
+}
+
+func TestLSPBadRequests(t *testing.T) {
+	got, err := lspSession(t, daemonConfig{},
+		`{not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///w/none.md"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/formatting","params":[1]}`,
+		lspExit, `{"jsonrpc":"2.0","method":"exit"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []float64
+	for _, m := range got[:4] {
+		e, ok := m["error"].(map[string]any)
+		if !ok {
+			t.Fatalf("no error in %v", m)
+		}
+		codes = append(codes, e["code"].(float64))
+	}
+	want := []float64{lspParseError, lspMethodNotFound, lspInvalidParams, lspInvalidParams}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("got codes %v, want %v", codes, want)
+	}
+	if got[0]["id"] != nil {
+		t.Errorf("parse error reply has id %v", got[0]["id"])
+	}
+}
+
+func TestTextEdits(t *testing.T) {
+	apply := func(old string, edits []lspTextEdit) string {
+		lines := splitLinesKeepEnds(old)
+		// apply from the bottom up so earlier positions stay valid
+		for i := len(edits) - 1; i >= 0; i-- {
+			e := edits[i]
+			before := strings.Join(lines[:e.Range.Start.Line], "")
+			var after string
+			if e.Range.End.Line < len(lines) {
+				after = lines[e.Range.End.Line][e.Range.End.Character:] + strings.Join(lines[e.Range.End.Line+1:], "")
+			}
+			lines = splitLinesKeepEnds(before + e.NewText + after)
+		}
+		return strings.Join(lines, "")
+	}
+	for _, c := range [][2]string{
+		{"a\nb\n", "a\nb\n"},
+		{"a\nb", "a\nb\n"},
+		{"", "x\n"},
+		{"a\nx\nb\ny\n", "a\nb\n"},
+		{"a\n\n\n", "a\n"},
+	} {
+		if got := apply(c[0], textEdits(c[0], c[1])); got != c[1] {
+			t.Errorf("%q: expected:\n%q\ngot:\n%q", c[0], c[1], got)
+		}
+	}
+}
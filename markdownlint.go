@@ -0,0 +1,41 @@
+package main
+
+import "strings"
+
+// markdownlintCodes maps built-in rules to the markdownlint rule they
+// correspond to, so that tooling keyed to MD0xx codes keeps working.
+// Several rules may share a code.
+var markdownlintCodes = map[string]string{
+	"ReversedLink":                "MD011",
+	"LineLength":                  "MD013",
+	"BlankLineAfterHeading":       "MD022",
+	"SingleSpaceAfterEnumeration": "MD030",
+	"SingleSpaceAfterListItem":    "MD030",
+	"BlankLinesAroundFences":      "MD031",
+	"InlineHTML":                  "MD033",
+	"BareURL":                     "MD034",
+	"ThematicBreakStyle":          "MD035",
+	"EmphasisAsHeading":           "MD036",
+	"FencedCodeLanguage":          "MD040",
+	"RequiredSections":            "MD043",
+	"IndentedCodeToFenced":        "MD046",
+	"FenceStyle":                  "MD048",
+	"BlankLineBeforeTable":        "MD058",
+}
+
+// ruleCode returns the markdownlint code of the named rule, or "".
+func ruleCode(name string) string {
+	return markdownlintCodes[name]
+}
+
+// rulesForCode returns the built-in rules, in pipeline order, that carry
+// the markdownlint code, compared case-insensitively.
+func rulesForCode(code string) []string {
+	var names []string
+	for _, spec := range builtinRules {
+		if c := ruleCode(spec.name); c != "" && strings.EqualFold(c, code) {
+			names = append(names, spec.name)
+		}
+	}
+	return names
+}
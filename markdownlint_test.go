@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMarkdownlintCodes(t *testing.T) {
+	code := regexp.MustCompile(`^MD\d{3}$`)
+	for rule, c := range markdownlintCodes {
+		if !isBuiltinRule(rule) {
+			t.Errorf("%s: not a built-in rule", rule)
+		}
+		if !code.MatchString(c) {
+			t.Errorf("%s: malformed code %q", rule, c)
+		}
+	}
+
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"MD040", []string{"FencedCodeLanguage"}},
+		{"md022", []string{"BlankLineAfterHeading"}},
+		{"MD030", []string{"SingleSpaceAfterEnumeration", "SingleSpaceAfterListItem"}},
+		{"MD999", nil},
+	}
+	for _, tt := range tests {
+		if got := rulesForCode(tt.code); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownlintCodeInDiagnostics(t *testing.T) {
+	f, err := newFormatterFromOptions(Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := f.Lint("```\ncode\n```\n")
+	if len(diags) != 1 {
+		t.Fatalf("got %v, want one diagnostic", diags)
+	}
+	if got, want := diags[0].String(), "1: warning: MD040/FencedCodeLanguage: fenced code block has no language"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	f, err = newFormatterFromOptions(Options{}, nil, []string{"MD040"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := f.Lint("```\ncode\n```\n"); len(diags) != 0 {
+		t.Errorf("MD040 disabled, got %v", diags)
+	}
+	if _, err := newFormatterFromOptions(Options{}, nil, []string{"MD999"}); err == nil {
+		t.Error("expected an error for an unknown code")
+	}
+}
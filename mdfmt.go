@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"syscall"
 )
 
 // Rule is any transformation over the whole document.
@@ -16,56 +22,420 @@ type Rule interface {
 	Apply(content string) (string, error)
 }
 
-// Formatter applies a sequence of Rules in order.
+// Severity grades a Diagnostic. The zero value is a warning.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem reported by a lint rule.
+type Diagnostic struct {
+	// Line is the 1-based line number the problem was found on.
+	Line int
+	// Column is the 1-based column, in characters, or 0 when the problem
+	// concerns the whole line.
+	Column int
+	// Rule is the Name() of the reporting rule.
+	Rule string
+	// Code is the corresponding markdownlint code, such as "MD040", if
+	// there is one.
+	Code     string
+	Severity Severity
+	Message  string
+	// Key groups findings about the same thing, such as one wordlist term,
+	// in the summary.
+	Key string
+	// Fix, if not nil, is a suggested replacement for the line.
+	Fix *Fix
+}
+
+// Fix is a suggested edit to the line of a Diagnostic.
+type Fix struct {
+	// Start and End are the 1-based byte columns of the replaced text, End
+	// exclusive; they span the whole line.
+	Start, End int
+	// Text is the line as it should read.
+	Text string
+}
+
+// lineFix suggests replacing line[start:end] with text, expressed as a
+// replacement of the whole line.
+func lineFix(line string, start, end int, text string) *Fix {
+	return &Fix{Start: 1, End: len(line) + 1, Text: line[:start] + text + line[end:]}
+}
+
+func (d Diagnostic) String() string {
+	rule := d.Rule
+	if d.Code != "" {
+		rule = d.Code + "/" + rule
+	}
+	if d.Column > 0 {
+		return fmt.Sprintf("%d:%d: %s: %s: %s", d.Line, d.Column, d.Severity, rule, d.Message)
+	}
+	return fmt.Sprintf("%d: %s: %s: %s", d.Line, d.Severity, rule, d.Message)
+}
+
+// summarize counts diags per rule, and per key within a rule, in lines
+// like "Terminology: 3 (Github: 2, web-site: 1)".
+func summarize(diags []Diagnostic) []string {
+	perRule := map[string]int{}
+	perKey := map[string]map[string]int{}
+	for _, d := range diags {
+		perRule[d.Rule]++
+		if d.Key != "" {
+			if perKey[d.Rule] == nil {
+				perKey[d.Rule] = map[string]int{}
+			}
+			perKey[d.Rule][d.Key]++
+		}
+	}
+	rules := make([]string, 0, len(perRule))
+	for r := range perRule {
+		rules = append(rules, r)
+	}
+	sort.Strings(rules)
+	var out []string
+	for _, r := range rules {
+		line := fmt.Sprintf("%s: %d", r, perRule[r])
+		if keys := perKey[r]; len(keys) > 0 {
+			names := make([]string, 0, len(keys))
+			for k := range keys {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			for i, k := range names {
+				names[i] = fmt.Sprintf("%s: %d", k, keys[k])
+			}
+			line += " (" + strings.Join(names, ", ") + ")"
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// Linter is implemented by rules that report problems in addition to, or
+// instead of, rewriting the document.
+type Linter interface {
+	Lint(content string) []Diagnostic
+}
+
+// PathRule is implemented by rules whose result depends on the file being
+// formatted.
+type PathRule interface {
+	// WithPath returns the rule to use for the file at path, which is
+	// empty when the document has no known location.
+	WithPath(path string) Rule
+}
+
+// TriggeredRule is implemented by rules that can only change documents
+// containing certain substrings, so that they need not scan the others.
+type TriggeredRule interface {
+	// Trigger returns substrings at least one of which a document must
+	// contain for Apply to change it. With none, Apply never does.
+	Trigger() []string
+}
+
+// triggerScan remembers which triggers a document contains, so that each
+// is looked for once until a rule changes the document.
+type triggerScan struct {
+	content string
+	found   map[string]bool
+}
+
+// skip reports whether r cannot change content: it is a TriggeredRule and
+// content contains none of its triggers.
+func (s *triggerScan) skip(r Rule, content string) bool {
+	t, ok := r.(TriggeredRule)
+	if !ok {
+		return false
+	}
+	if s.found == nil || s.content != content {
+		s.content, s.found = content, map[string]bool{}
+	}
+	for _, trigger := range t.Trigger() {
+		found, ok := s.found[trigger]
+		if !ok {
+			found = strings.Contains(content, trigger)
+			s.found[trigger] = found
+		}
+		if found {
+			return false
+		}
+	}
+	return true
+}
+
+// Formatter applies a sequence of Rules in order. Its methods never change
+// it, so one Formatter can serve many goroutines, and WithRules and
+// WithoutRule derive variants from it.
 type Formatter struct {
-	rules []Rule
+	rules  []Rule
+	limits limits
+	// trace, if set, is told the content before and after each rule that
+	// runs.
+	trace func(rule, before, after string)
 }
 
 func NewFormatter(rules ...Rule) *Formatter {
 	return &Formatter{rules: rules}
 }
 
+// Rules returns the rules of f in the order they run. The slice is a
+// copy; changing it does not change f.
+func (f *Formatter) Rules() []Rule {
+	return append([]Rule(nil), f.rules...)
+}
+
+// Clone returns a copy of f with a list of rules of its own.
+func (f *Formatter) Clone() *Formatter {
+	return &Formatter{rules: f.Rules(), limits: f.limits, trace: f.trace}
+}
+
+// WithRules returns a copy of f that also runs rules, after its own.
+func (f *Formatter) WithRules(rules ...Rule) *Formatter {
+	c := f.Clone()
+	c.rules = append(c.rules, rules...)
+	return c
+}
+
+// WithoutRule returns a copy of f without the rules whose Name is name,
+// and whether there were any. Without any, the copy has all of f's rules.
+func (f *Formatter) WithoutRule(name string) (*Formatter, bool) {
+	c := &Formatter{limits: f.limits, trace: f.trace}
+	for _, r := range f.rules {
+		if r.Name() != name {
+			c.rules = append(c.rules, r)
+		}
+	}
+	return c, len(c.rules) < len(f.rules)
+}
+
+// ForPath returns a Formatter whose PathRules are bound to path.
+func (f *Formatter) ForPath(path string) *Formatter {
+	rules := make([]Rule, len(f.rules))
+	for i, r := range f.rules {
+		if p, ok := r.(PathRule); ok {
+			r = p.WithPath(path)
+		}
+		rules[i] = r
+	}
+	return &Formatter{rules: rules, limits: f.limits, trace: f.trace}
+}
+
+// WithTrace returns a copy of f that calls trace with the name of each
+// rule it runs and the content before and after, to attribute changes to
+// rules. Rules skipped for want of their triggers are not traced.
+func (f *Formatter) WithTrace(trace func(rule, before, after string)) *Formatter {
+	c := f.Clone()
+	c.trace = trace
+	return c
+}
+
+// RuleError is the error of a Formatter whose rule failed, so that the
+// rule can be told from the message.
+type RuleError struct {
+	// Rule is the Name of the rule.
+	Rule string
+	err  error
+}
+
+func (e *RuleError) Error() string { return e.err.Error() }
+
+func (e *RuleError) Unwrap() error { return e.err }
+
 func (f *Formatter) Format(content string) (string, error) {
+	return f.FormatContext(context.Background(), content)
+}
+
+// FormatContext formats content like Format, giving up when ctx is done.
+// Documents larger than the Formatter's limits allow, and rules that
+// overrun their time, fail the formatting; lines that are too long are
+// kept out of the rules' reach.
+func (f *Formatter) FormatContext(ctx context.Context, content string) (string, error) {
+	if err := f.limits.checkSize(content); err != nil {
+		return "", err
+	}
+	content, hidden := f.limits.hideLongLines(content)
 	var err error
+	var scan triggerScan
 	for _, r := range f.rules {
-		content, err = r.Apply(content)
+		if scan.skip(r, content) {
+			continue
+		}
+		before := content
+		content, err = f.limits.applyRule(ctx, r, content)
 		if err != nil {
-			return "", fmt.Errorf("rule %q failed: %w", r.Name(), err)
+			return "", &RuleError{Rule: r.Name(), err: fmt.Errorf("rule %q failed: %w", r.Name(), err)}
+		}
+		if f.trace != nil {
+			f.trace(r.Name(), before, content)
+		}
+	}
+	return restoreLongLines(content, hidden)
+}
+
+// FormatEdits formats content and returns the changes as edits of its
+// lines rather than the whole result, so that editors can keep the cursor
+// and marks on the lines that stay. The line numbers of all edits refer
+// to content; applied in order they give what Format returns.
+func (f *Formatter) FormatEdits(content string) ([]Edit, error) {
+	out, err := f.Format(content)
+	if err != nil {
+		return nil, err
+	}
+	return lineEdits(content, out), nil
+}
+
+// FormatLines formats content like Format but only makes the changes that
+// touch the given lines, leaving the others as they are. The rules see the
+// whole document, so a change is made in full, lines outside the ranges
+// included, when it touches them at all: realigning a table with one row
+// in range realigns the whole table.
+func (f *Formatter) FormatLines(content string, ranges []LineRange) (string, error) {
+	out, err := f.Format(content)
+	if err != nil {
+		return "", err
+	}
+	return keepLines(content, out, ranges), nil
+}
+
+// FormatLineMap formats content like Format and also returns where each
+// of its lines ended up.
+func (f *Formatter) FormatLineMap(content string) (string, LineMap, error) {
+	out, err := f.Format(content)
+	if err != nil {
+		return "", nil, err
+	}
+	return out, NewLineMap(strings.Count(content, "\n")+1, lineEdits(content, out)), nil
+}
+
+// Lint collects the diagnostics of every rule that implements Linter,
+// ordered by line and tagged with their markdownlint codes.
+func (f *Formatter) Lint(content string) []Diagnostic {
+	if f.limits.checkSize(content) != nil {
+		return nil
+	}
+	content, hidden := f.limits.hideLongLines(content)
+	diags := f.limits.longLineDiagnostics(hidden)
+	for _, r := range f.rules {
+		if l, ok := r.(Linter); ok {
+			for _, d := range l.Lint(content) {
+				if _, ok := hidden[d.Line-1]; ok {
+					continue
+				}
+				if d.Code == "" {
+					d.Code = ruleCode(d.Rule)
+				}
+				diags = append(diags, d)
+			}
 		}
 	}
-	return content, nil
+	sortDiagnostics(diags)
+	return diags
+}
+
+// sortDiagnostics orders diags by position, keeping the order of findings
+// at the same position.
+func sortDiagnostics(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
 }
 
 // ----------------------------------------------------------------
 // Rule 1: ensure exactly one blank line after each ATX heading
 // ----------------------------------------------------------------
 
-type BlankLineAfterHeadingRule struct{}
+type BlankLineAfterHeadingRule struct {
+	// exempt matches lines that may follow a heading directly, such as a
+	// row of badges under a README's title.
+	exempt []*regexp.Regexp
+}
+
+func NewBlankLineAfterHeadingRule(exempt ...*regexp.Regexp) Rule {
+	return BlankLineAfterHeadingRule{exempt: exempt}
+}
 
-func NewBlankLineAfterHeadingRule() Rule { return BlankLineAfterHeadingRule{} }
+func newBlankLineAfterHeadingRuleFromOptions(o Options) (Rule, error) {
+	var exempt []*regexp.Regexp
+	for _, p := range o.List("heading-blank-line-exempt", nil) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("option heading-blank-line-exempt: %w", err)
+		}
+		exempt = append(exempt, re)
+	}
+	return NewBlankLineAfterHeadingRule(exempt...), nil
+}
 
 func (BlankLineAfterHeadingRule) Name() string {
 	return "BlankLineAfterHeading"
 }
 
-func (BlankLineAfterHeadingRule) Apply(content string) (string, error) {
+func (r BlankLineAfterHeadingRule) Apply(content string) (string, error) {
 	var outLines []string
 	lines := strings.Split(content, "\n")
-	for i, line := range lines {
+	// a "#" in front matter starts a YAML comment, and one in code or an
+	// HTML block is literal text
+	b := classifyBlocks(lines)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
 		outLines = append(outLines, line)
-		if isATXHeading(line) {
-			// look ahead: if next line is non‐blank or EOF, insert one blank
-			if i+1 >= len(lines) || strings.TrimSpace(lines[i+1]) != "" {
-				outLines = append(outLines, "")
-			}
+		if b.lines[i].kind != lineText || b.lines[i].quote > 0 || !isHeadingLine(line, b.lines[i]) {
+			continue
+		}
+		// the anchor line HeadingAnchors may put under the heading goes
+		// with it
+		if i+1 < len(lines) && isHeadingAnchor(lines[i+1]) {
+			i++
+			outLines = append(outLines, lines[i])
+		}
+		// look ahead past the blank lines: a run of them becomes one, and
+		// at EOF only the final newline stays
+		next := i + 1
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		switch {
+		case next == len(lines):
+			outLines = append(outLines, "")
+			i = next
+		case next > i+1 || !r.exempted(lines[next]):
+			outLines = append(outLines, "")
+			i = next - 1
 		}
 	}
 	return strings.Join(outLines, "\n"), nil
 }
 
+func (r BlankLineAfterHeadingRule) exempted(line string) bool {
+	for _, re := range r.exempt {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
 func isATXHeading(line string) bool {
-	// trim leading space/tabs
-	t := strings.TrimLeft(line, " \t")
+	// trim up to three columns of indentation; four make indented code
+	cols, n := leadingColumns(line)
+	if cols > 3 {
+		return false
+	}
+	t := line[n:]
 	if !strings.HasPrefix(t, "#") {
 		return false
 	}
@@ -85,6 +455,31 @@ func isATXHeading(line string) bool {
 	return t[count] == ' ' || t[count] == '\t'
 }
 
+// isHeadingLine reports whether line, classified as info, is an ATX
+// heading, counting its indentation from the content column of the list
+// item it is in.
+func isHeadingLine(line string, info lineInfo) bool {
+	return isATXHeading(containerBody(line[info.bodyStart:], info.container))
+}
+
+// containerBody strips the indentation of a list item's content, whose
+// content starts at column container, from the body of a line in it.
+// Bodies indented less, lazy continuations, are returned as they are.
+func containerBody(body string, container int) string {
+	if cols, _ := leadingColumns(body); container == 0 || cols < container {
+		return body
+	}
+	for c := 0; c < container; {
+		if body[0] == '\t' {
+			c += 4 - c%4
+		} else {
+			c++
+		}
+		body = body[1:]
+	}
+	return body
+}
+
 // ----------------------------------------------------------------
 // Rule 2: replace \(...\) with $...$
 // ----------------------------------------------------------------
@@ -113,7 +508,12 @@ func (r InlineMathRule) Apply(content string) (string, error) {
 	//   \\$  → regex engine sees `\$` → emits literal `$`
 	//   $1   → emits group 1
 	//   \\$  → emits literal `$`
-	return r.re.ReplaceAllString(content, "$$$1$"), nil
+	head, body := splitMetadata(content)
+	return head + r.re.ReplaceAllString(body, "$$$1$"), nil
+}
+
+func (InlineMathRule) Trigger() []string {
+	return []string{`\(`}
 }
 
 // ----------------------------------------------------------------
@@ -136,12 +536,33 @@ func (r *ReplacementRule) Name() string {
 	return r.name
 }
 
+func (r *ReplacementRule) Trigger() []string {
+	triggers := make([]string, 0, len(r.replacements))
+	for old := range r.replacements {
+		triggers = append(triggers, old)
+	}
+	return triggers
+}
+
 func (r *ReplacementRule) Apply(content string) (string, error) {
-	// For each unwanted string, replace all its occurrences with the replacement.
-	for old, new := range r.replacements {
-		content = strings.ReplaceAll(content, old, new)
+	// For each unwanted string, replace all its occurrences with the
+	// replacement, leaving the front matter or title block, Obsidian links,
+	// citations, the attributes of Pandoc divs and abbreviation definitions
+	// alone.
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if k := b.lines[i].kind; k == lineFrontMatter || k == lineDivFence || k == lineAbbreviation {
+			continue
+		}
+		lines[i] = mapOutside(line, verbatimSpans(line), func(s string) string {
+			for old, new := range r.replacements {
+				s = strings.ReplaceAll(s, old, new)
+			}
+			return s
+		})
 	}
-	return content, nil
+	return strings.Join(lines, "\n"), nil
 }
 
 // ----------------------------------------------------------------
@@ -161,8 +582,11 @@ func (BlankLineBeforeTableRule) Name() string {
 func (BlankLineBeforeTableRule) Apply(content string) (string, error) {
 	var outLines []string
 	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		if isTableSeparator(line) {
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		// a "---" is only a delimiter row when it completes a table, not
+		// when it closes front matter or underlines a heading
+		if b.lines[i].kind == lineTable && isTableSeparator(line) {
 			// header is the last line in outLines
 			if len(outLines) == 0 {
 				// table at start of doc
@@ -186,9 +610,10 @@ func (BlankLineBeforeTableRule) Apply(content string) (string, error) {
 	return strings.Join(outLines, "\n"), nil
 }
 
+var tableSeparatorRegex = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?[-]+:?[ \t]*(?:\|[ \t]*:?[-]+:?[ \t]*)*\|?[ \t]*$`)
+
 // isTableSeparator detects a Markdown table separator line like "| --- | :---: | ---: |"
 func isTableSeparator(line string) bool {
-	var tableSeparatorRegex = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?[-]+:?[ \t]*(?:\|[ \t]*:?[-]+:?[ \t]*)*\|?[ \t]*$`)
 	return tableSeparatorRegex.MatchString(line)
 }
 
@@ -214,8 +639,9 @@ func (r *SingleSpaceAfterEnumerationRule) Apply(
 	content string,
 ) (string, error) {
 	lines := strings.Split(content, "\n")
+	fm := metadataEnd(lines)
 	for i, line := range lines {
-		if r.re.MatchString(line) {
+		if i > fm && r.re.MatchString(line) {
 			lines[i] = r.re.ReplaceAllString(line, "$1$2 $3")
 		}
 	}
@@ -248,7 +674,13 @@ func (r *SingleSpaceAfterListItemRule) Apply(
 	content string,
 ) (string, error) {
 	lines := strings.Split(content, "\n")
+	fm := metadataEnd(lines)
 	for i, line := range lines {
+		// “* * *” and “- - -” are thematic breaks, not list items, front
+		// matter has YAML sequences and “*[HTML]:” defines an abbreviation
+		if i <= fm || isThematicBreak(line) || abbreviationDefinition.MatchString(line) {
+			continue
+		}
 		if r.re.MatchString(line) {
 			// normalize to “- ” + content
 			lines[i] = r.re.ReplaceAllString(line, "$1- $2")
@@ -259,34 +691,404 @@ func (r *SingleSpaceAfterListItemRule) Apply(
 
 // ----------------------------------------------------------------
 
-func main() {
-	data, err := io.ReadAll(os.Stdin)
+// pathSettings returns the settings for the file at path, which may be
+// empty, with the configuration file of the run.
+func pathSettings(path string, opts Options, enable, disable []string) (configSettings, error) {
+	return projectConfig.pathSettings(path, opts, enable, disable)
+}
+
+// pathSettings returns the settings for the file at path, which may be
+// empty: those of the .editorconfig files above it, unless
+// useEditorConfig is off, then those of c, then opts, enable and disable.
+func (c *config) pathSettings(path string, opts Options, enable, disable []string) (configSettings, error) {
+	s := configSettings{options: Options{}}
+	if useEditorConfig && path != "" {
+		ec, err := editorConfigOptions(path)
+		if err != nil {
+			return s, err
+		}
+		s.options = ec
+	}
+	s = s.merge(c.settingsFor(path))
+	return s.merge(configSettings{options: opts, enable: enable, disable: disable}), nil
+}
+
+// documentFormatter builds the Formatter for content, read from path if
+// it is not empty, with the configuration file of the run.
+func documentFormatter(content, path string, opts Options, enable, disable []string) (*Formatter, error) {
+	return projectConfig.documentFormatter(content, path, opts, enable, disable)
+}
+
+// documentFormatter builds the Formatter for content, read from path if
+// it is not empty: the built-in rules as configured by c.pathSettings,
+// overridden by the document's own front matter settings.
+func (c *config) documentFormatter(content, path string, opts Options, enable, disable []string) (*Formatter, error) {
+	s, err := c.pathSettings(path, opts, enable, disable)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error reading stdin:", err)
+		return nil, err
+	}
+	cfg, _ := parseFileConfig(content)
+	for k, v := range cfg.options {
+		s.options[k] = v
+	}
+	s.enable = append(s.enable, cfg.enable...)
+	s.disable = append(s.disable, cfg.disable...)
+	fmter, err := newFormatterFromSettings(s)
+	if err != nil {
+		return nil, err
+	}
+	return fmter.ForPath(path), nil
+}
+
+// formatDocument formats content with its documentFormatter and returns
+// the result with the lint findings on it. With verify, it fails when a
+// rule changes the rendered HTML without meaning to.
+func formatDocument(content, path string, opts Options, enable, disable []string, verify bool) (string, []Diagnostic, error) {
+	return formatDocumentTraced(content, path, opts, enable, disable, verify, nil, nil)
+}
+
+// formatDocumentTraced is formatDocument with the Formatter's trace, if
+// not nil, set to trace, that with lines, if any, makes only the changes
+// that touch them, as FormatLines does.
+func formatDocumentTraced(content, path string, opts Options, enable, disable []string, verify bool, lines []LineRange, trace func(rule, before, after string)) (string, []Diagnostic, error) {
+	fmter, err := documentFormatter(content, path, opts, enable, disable)
+	if err != nil {
+		return "", nil, err
+	}
+	if trace != nil {
+		fmter = fmter.WithTrace(trace)
+	}
+	return formatWith(fmter, content, verify, lines)
+}
+
+// formatWith formats content with fmter, checked with verify and limited
+// to lines as in formatDocumentTraced, and lints the result.
+func formatWith(fmter *Formatter, content string, verify bool, lines []LineRange) (string, []Diagnostic, error) {
+	format := fmter.Format
+	if verify {
+		format = func(content string) (string, error) { return formatVerified(fmter, content) }
+	}
+	out, err := format(content)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(lines) > 0 {
+		out = keepLines(content, out, lines)
+	}
+	return out, lintDocument(fmter, out), nil
+}
+
+// lintDocument returns the findings of fmter's rules and of the document's
+// own settings on content.
+func lintDocument(fmter *Formatter, content string) []Diagnostic {
+	// rules see LF line breaks, whatever EndOfLine wrote
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	_, diags := parseFileConfig(content)
+	diags = append(diags, fmter.Lint(content)...)
+	sortDiagnostics(diags)
+	return diags
+}
+
+func main() {
+	opts := Options{}
+	var enable, disable ruleNames
+	flag.Var(opts, "set", "set a rule option as `key=value` (repeatable)")
+	flag.Var(&enable, "enable", "enable the opt-in rule `name` (repeatable)")
+	flag.Var(&disable, "disable", "do not run the rule `name` (repeatable)")
+	stdinPath := flag.String("stdin-filepath", "", "`path` of the file read from stdin, for rules, findings, diffs and the configuration file")
+	flag.StringVar(stdinPath, "stdin-filename", "", "alias of -stdin-filepath")
+	flag.StringVar(&blockParser, "parser", blockParser, "block `parser` that decides where rules act: internal or goldmark")
+	verify := flag.Bool("verify", false, "fail when formatting changes the HTML the document renders to")
+	cacheDir := flag.String("cache-dir", defaultCacheDir(), "`directory` that remembers already formatted documents")
+	noCache := flag.Bool("no-cache", false, "neither use nor update the cache")
+	daemon := flag.Bool("daemon", false, "serve formatting requests on -socket instead of formatting stdin")
+	useDaemon := flag.Bool("use-daemon", false, "format through the daemon on -socket, or in-process when none runs")
+	socket := flag.String("socket", defaultSocket(), "unix `socket` of the daemon")
+	format := flag.String("format", "", "lint output `format`: text, github, checkstyle, rdjson, gitlab, json, ndjson or sarif (default github under GitHub Actions, else text)")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile of the formatting to `file`")
+	memProfile := flag.String("memprofile", "", "write a heap profile taken after the formatting to `file`")
+	noEditorConfig := flag.Bool("no-editorconfig", false, "ignore .editorconfig files")
+	showConfig := flag.String("show-config", "", "print the settings that apply to the file at `path` and exit")
+	write := flag.Bool("w", false, "write the formatted documents back to the files given as arguments instead of printing them")
+	backup := flag.Bool("backup", false, "with -w, keep the content of each rewritten file in file.bak")
+	backupSuffix := flag.String("backup-suffix", "", "with -w, keep the content of each rewritten file in the file name plus `suffix`, such as .orig (implies -backup)")
+	refuseSymlinks := flag.Bool("no-symlinks", false, "with -w, leave files that are symbolic links alone instead of writing their targets")
+	check := flag.Bool("check", false, "print the names of documents formatting would change, without the documents, and exit with 1 if there are any (2 on errors)")
+	diff := flag.Bool("d", false, "print the changes formatting makes as a unified diff instead of the formatted documents")
+	exts := flag.String("ext", strings.Join(defaultExtensions, ","), "comma-separated `extensions` of the files formatted in directories and patterns")
+	watchFiles := flag.Bool("watch", false, "with -w, keep running and reformat the files given as arguments whenever they change")
+	watchInterval := flag.Duration("watch-interval", defaultWatchInterval, "how often -watch looks for changed files")
+	verbose := flag.Bool("v", false, "report on stderr how many lines each rule changed")
+	veryVerbose := flag.Bool("vv", false, "like -v, followed by the changes of each rule as a diff")
+	jobs := flag.Int("jobs", runtime.GOMAXPROCS(0), "format up to `n` files at once")
+	var exclude patternFlag
+	flag.Var(&exclude, "exclude", "skip the files and directories matching the gitignore-style `pattern` when walking directories (repeatable)")
+	staged := flag.Bool("staged", false, "format the Markdown files staged in git instead of arguments or stdin; with -w, stage the files it changes again")
+	var lines lineRanges
+	flag.Var(&lines, "lines", "only make the changes that touch the `ranges` of lines, such as 40:80 or 1:10,40:80, of a single document (repeatable)")
+	var listRulesFormat listRulesFlag
+	flag.Var(&listRulesFormat, "list-rules", "print the built-in rules with a description and an example each, as text or with -list-rules=json as JSON, and exit")
+	stats := flag.Bool("stats", false, "print the structure of the files given as arguments, or of stdin, as -format text or json and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		printDefaults(flag.CommandLine)
+	}
+	flag.Parse()
+	useEditorConfig = !*noEditorConfig
+	if !containsString(blockParsers, blockParser) {
+		fmt.Fprintf(os.Stderr, "unknown parser %q (want one of %s)\n", blockParser, strings.Join(blockParsers, ", "))
+		os.Exit(1)
+	}
+	if listRulesFormat != "" {
+		if err := listRules(os.Stdout, string(listRulesFormat)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *stats {
+		if *format == "" {
+			*format = "text"
+		}
+		if err := runStats(os.Stdout, *format, flag.Args(), *stdinPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *format == "" {
+		*format = defaultOutputFormat(os.Getenv)
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, name := range disable {
+		if isRequiredRule(name) {
+			fmt.Fprintf(os.Stderr, "rule %q cannot be disabled\n", name)
+			os.Exit(1)
+		}
+	}
+
+	// stdin from an editor takes the configuration of the file it holds
+	configDir := "."
+	if *stdinPath != "" && flag.NArg() == 0 {
+		configDir = configDirFor(*stdinPath)
+	}
+	var err error
+	if projectConfig, err = loadConfig(configDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *showConfig != "" {
+		s, err := pathSettings(*showConfig, opts, enable, disable)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(s)
+		return
+	}
+
+	if (*cpuProfile != "" || *memProfile != "") && (*daemon || *watchFiles || flag.Arg(0) == "lsp") {
+		fmt.Fprintln(os.Stderr, "-cpuprofile and -memprofile cannot be used with -daemon, -watch or lsp")
 		os.Exit(1)
 	}
 
-	fmter := NewFormatter(
-		NewBlankLineAfterHeadingRule(),
-		NewBlankLineBeforeTableRule(),
-		NewInlineMathReplaceRule(),
-		NewSingleSpaceAfterEnumerationRule(),
-		NewSingleSpaceAfterListItemRule(),
-		NewReplacementRule("SmartQuotesToAscii", map[string]string{
-			"„": `"`,
-			"“": `"`,
-		}),
-	)
+	if flag.Arg(0) == "lsp" {
+		if err := runLSP(os.Stdin, os.Stdout, daemonConfig{opts: opts, enable: enable, disable: disable, verify: *verify}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	out, err := fmter.Format(string(data))
+	if *daemon {
+		l, err := listenDaemon(*socket)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		// remove the socket on the way out
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			l.Close()
+		}()
+		err = serveDaemon(l, daemonConfig{opts: opts, enable: enable, disable: disable, verify: *verify})
+		l.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	run := runOptions{
+		opts: opts, enable: enable, disable: disable, verify: *verify, format: *format,
+		useDaemon: *useDaemon, socket: *socket, write: *write, refuseSymlinks: *refuseSymlinks,
+		check: *check, diff: *diff, jobs: *jobs, lines: lines,
+	}
+	switch {
+	case *veryVerbose:
+		run.verbose = 2
+	case *verbose:
+		run.verbose = 1
+	}
+	if !*noCache {
+		run.cacheDir = *cacheDir
+	}
+	if *backupSuffix != "" {
+		run.backup = *backupSuffix
+	} else if *backup {
+		run.backup = ".bak"
+	}
+
+	if *watchFiles && !*write {
+		fmt.Fprintln(os.Stderr, "-watch only applies with -w")
+		os.Exit(1)
+	}
+	// an array is only closed at the end
+	if *watchFiles && (*format == "json" || *format == "sarif") {
+		fmt.Fprintf(os.Stderr, "-watch and -format %s cannot be used together, use ndjson\n", *format)
+		os.Exit(1)
+	}
+	if len(lines) > 0 && *watchFiles {
+		fmt.Fprintln(os.Stderr, "-lines and -watch cannot be used together")
+		os.Exit(1)
+	}
+	if *watchInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "-watch-interval must be positive")
+		os.Exit(1)
+	}
+	if *jobs < 1 {
+		fmt.Fprintln(os.Stderr, "-jobs must be at least 1")
+		os.Exit(1)
+	}
+	if run.backup != "" && !*write {
+		fmt.Fprintln(os.Stderr, "-backup only applies with -w")
+		os.Exit(1)
+	}
+	if *refuseSymlinks && !*write {
+		fmt.Fprintln(os.Stderr, "-no-symlinks only applies with -w")
+		os.Exit(1)
+	}
+	if *diff && reportOnStdout(*format) {
+		fmt.Fprintf(os.Stderr, "-d and -format %s both write to stdout\n", *format)
+		os.Exit(1)
+	}
+	if *write && *check {
+		fmt.Fprintln(os.Stderr, "-w and -check cannot be used together")
+		os.Exit(1)
+	}
+	if *write && flag.NArg() == 0 && !*staged {
+		fmt.Fprintln(os.Stderr, "-w needs files to write to as arguments, or -staged")
+		os.Exit(1)
+	}
+	if *staged {
+		if flag.NArg() > 0 || *stdinPath != "" || *watchFiles {
+			fmt.Fprintln(os.Stderr, "-staged takes neither file arguments, -stdin-filepath nor -watch")
+			os.Exit(1)
+		}
+		if reportOnStdout(*format) && !reportsResults(*format) {
+			fmt.Fprintf(os.Stderr, "-format %s reports on one file at a time\n", *format)
+			os.Exit(1)
+		}
+		finder := fileFinder{extensions: strings.Split(*exts, ","), exclude: parseIgnorePatterns(exclude)}
+		files, err := finder.findStaged(".")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(run.exitStatus(0, err))
+		}
+		if len(lines) > 0 && len(files.files) > 1 {
+			fmt.Fprintf(os.Stderr, "-lines applies to a single document, not %d files\n", len(files.files))
+			os.Exit(1)
+		}
+		for _, path := range files.files {
+			if _, ok := files.partial[path]; ok {
+				fmt.Fprintf(os.Stderr, "warning: %s has unstaged changes; formatting the working tree version\n", path)
+			}
+		}
+		// the profiles cover formatting, not finding the files
+		if err := startProfiling(*cpuProfile, *memProfile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		changed, err := run.formatFiles(os.Stdout, os.Stderr, files.files)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if *write {
+			if serr := files.restage(os.Stderr); serr != nil {
+				fmt.Fprintln(os.Stderr, serr)
+				if err == nil {
+					err = serr
+				}
+			}
+		}
+		exit(run.exitStatus(changed, err))
+	}
+	if flag.NArg() > 0 {
+		if *stdinPath != "" {
+			fmt.Fprintln(os.Stderr, "-stdin-filepath only applies to stdin, not to file arguments")
+			os.Exit(1)
+		}
+		// one report per file would not make one valid document
+		if reportOnStdout(*format) && !reportsResults(*format) && flag.NArg() > 1 {
+			fmt.Fprintf(os.Stderr, "-format %s reports on one file at a time\n", *format)
+			os.Exit(1)
+		}
+		finder := fileFinder{extensions: strings.Split(*exts, ","), exclude: parseIgnorePatterns(exclude)}
+		files, walked, err := finder.find(flag.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(run.exitStatus(0, err))
+		}
+		finder.warnExcluded(os.Stderr, flag.Args())
+		// line numbers belong to one document
+		if len(lines) > 0 && len(files) > 1 {
+			fmt.Fprintf(os.Stderr, "-lines applies to a single document, not %d files\n", len(files))
+			os.Exit(1)
+		}
+		// the profiles cover formatting, not finding the files
+		if err := startProfiling(*cpuProfile, *memProfile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		changed, err := run.formatFiles(os.Stdout, os.Stderr, files)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if walked {
+			fmt.Fprintln(os.Stderr, run.summary(len(files), changed))
+		}
+		if *watchFiles {
+			// until interrupted
+			newWatcher(run, finder, flag.Args()).watch(os.Stdout, os.Stderr, *watchInterval, nil)
+		}
+		exit(run.exitStatus(changed, err))
+	}
+
+	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
+		fmt.Fprintln(os.Stderr, "error reading stdin:", err)
+		os.Exit(run.exitStatus(0, err))
+	}
+
+	if err := startProfiling(*cpuProfile, *memProfile); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	// ensure trailing newline
-	if !strings.HasSuffix(out, "\n") {
-		out += "\n"
+	changed, err := run.formatStdin(os.Stdout, os.Stderr, *stdinPath, string(data))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	n := 0
+	if changed {
+		n = 1
 	}
-	fmt.Print(out)
+	exit(run.exitStatus(n, err))
 }
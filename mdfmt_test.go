@@ -1,6 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -32,6 +39,41 @@ func TestBlankLineAfterHeadingRule(t *testing.T) {
 			input:    "# Heading 1\n\nText",
 			expected: "# Heading 1\n\nText",
 		},
+		{
+			name:     "collapses blank lines after heading",
+			input:    "# Heading 1\n\n\n\nText\n## Heading 2\n \n\t\nText2",
+			expected: "# Heading 1\n\nText\n## Heading 2\n\nText2",
+		},
+		{
+			name:     "blank lines after heading at end of file",
+			input:    "Text\n# Heading 1\n\n\n",
+			expected: "Text\n# Heading 1\n",
+		},
+		{
+			name:     "blank lines in code are kept",
+			input:    "```\n# not a heading\n\n\nx\n```",
+			expected: "```\n# not a heading\n\n\nx\n```",
+		},
+		{
+			name:     "comment in front matter is not a heading",
+			input:    "---\n# comment\ntitle: x\n---\n# Heading",
+			expected: "---\n# comment\ntitle: x\n---\n# Heading\n",
+		},
+		{
+			name:     "heading indented three spaces",
+			input:    "   # Heading\nText",
+			expected: "   # Heading\n\nText",
+		},
+		{
+			name:     "indented four spaces is code",
+			input:    "Text\n\n    # not a heading\n    x",
+			expected: "Text\n\n    # not a heading\n    x",
+		},
+		{
+			name:     "heading in list item content at column four",
+			input:    "1.  item\n\n    # Heading\n    text",
+			expected: "1.  item\n\n    # Heading\n\n    text",
+		},
 	}
 
 	for _, tt := range tests {
@@ -40,6 +82,9 @@ func TestBlankLineAfterHeadingRule(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
 			if got != tt.expected {
 				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
 			}
@@ -47,6 +92,46 @@ func TestBlankLineAfterHeadingRule(t *testing.T) {
 	}
 }
 
+func TestBlankLineAfterHeadingExempt(t *testing.T) {
+	rule, err := newBlankLineAfterHeadingRuleFromOptions(Options{"heading-blank-line-exempt": `^\[!\[`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "# project\n[![ci](ci.svg)](ci) [![go](go.svg)](go)\n\n## Usage\nText"
+	expected := "# project\n[![ci](ci.svg)](ci) [![go](go.svg)](go)\n\n## Usage\n\nText"
+	got, err := rule.Apply(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+
+	if _, err := newBlankLineAfterHeadingRuleFromOptions(Options{"heading-blank-line-exempt": "("}); err == nil {
+		t.Error("no error for an invalid pattern")
+	}
+}
+
+func TestIsATXHeading(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"# h", true},
+		{"   # h", true},
+		{"    # h", false},
+		{"\t# h", false},
+		{" \t# h", false},
+		{"#h", false},
+		{"####### h", false},
+	}
+	for _, tt := range tests {
+		if got := isATXHeading(tt.line); got != tt.want {
+			t.Errorf("isATXHeading(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
 func TestInlineMathRule(t *testing.T) {
 	rule := NewInlineMathReplaceRule()
 
@@ -122,6 +207,11 @@ func TestReplacementRule(t *testing.T) {
 			input:    `„Hello“ and "hi"`,
 			expected: `"Hello" and "hi"`,
 		},
+		{
+			name:     "title block untouched",
+			input:    "% „Title“\n% Author\n\n„Body“",
+			expected: "% „Title“\n% Author\n\n\"Body\"",
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +254,11 @@ func TestBlankLineBeforeTableRule_Apply(t *testing.T) {
 			input: "Line1\nLine2\n",
 			want:  "Line1\nLine2\n",
 		},
+		{
+			name:  "front matter and setext heading",
+			input: "---\ntitle: x\n---\nHeading\n---\n",
+			want:  "---\ntitle: x\n---\nHeading\n---\n",
+		},
 	}
 
 	for _, tc := range tests {
@@ -224,6 +319,21 @@ func TestSingleSpaceAfterListItemRule(t *testing.T) {
 			input: "foo*  bar",
 			want:  "foo*  bar",
 		},
+		{
+			name:  "spaced dash thematic break",
+			input: "- - -",
+			want:  "- - -",
+		},
+		{
+			name:  "spaced star thematic break",
+			input: "* * *",
+			want:  "* * *",
+		},
+		{
+			name:  "abbreviation definition",
+			input: "*[HTML]: HyperText Markup Language\n*[W3C]:  World Wide Web Consortium",
+			want:  "*[HTML]: HyperText Markup Language\n*[W3C]:  World Wide Web Consortium",
+		},
 	}
 
 	for _, tc := range cases {
@@ -238,3 +348,265 @@ func TestSingleSpaceAfterListItemRule(t *testing.T) {
 		})
 	}
 }
+
+// triggerCorpus are documents of the kind most files are: no inline
+// math, smart quotes, entities, HTML or inline footnotes.
+var triggerCorpus = []string{
+	"# Title\n\nSome text with *emphasis* and a [link](https://example.com).\n\n- one\n- two\n",
+	"Intro\n\n```go\nfunc main() {}\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n",
+	"---\ntitle: x\n---\n\n## Notes\n\n1. first\n2. second\n\n> quoted text\n",
+}
+
+func TestTriggeredRulesSkipOnlyNoOps(t *testing.T) {
+	docs := append([]string(nil), triggerCorpus...)
+	paths, _ := filepath.Glob("testdata/*.md")
+	for _, p := range append(paths, "README.md") {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, string(data))
+	}
+	optionSets := []Options{{}, {"ellipsis": "unicode"}, {"ellipsis": "ascii"}}
+
+	for _, spec := range builtinRules {
+		for _, opts := range optionSets {
+			r, err := spec.build(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, ok := r.(TriggeredRule)
+			if !ok {
+				continue
+			}
+			skipped := 0
+			for _, doc := range docs {
+				var scan triggerScan
+				if !scan.skip(r, doc) {
+					continue
+				}
+				skipped++
+				if got, _ := r.Apply(doc); got != doc {
+					t.Errorf("%s (%v) skipped without any of %q but changes:\n%q", spec.name, opts, tr.Trigger(), doc)
+				}
+			}
+			if skipped == 0 && len(tr.Trigger()) > 0 {
+				t.Errorf("%s (%v): no document without its triggers", spec.name, opts)
+			}
+		}
+	}
+}
+
+// countingRule counts its Apply calls and appends "!" to the document.
+type countingRule struct {
+	trigger []string
+	calls   *int
+}
+
+func (countingRule) Name() string        { return "Counting" }
+func (r countingRule) Trigger() []string { return r.trigger }
+func (r countingRule) Apply(content string) (string, error) {
+	*r.calls++
+	return content + "!", nil
+}
+
+func TestFormatSkipsUntriggeredRules(t *testing.T) {
+	var bang, math int
+	f := NewFormatter(
+		countingRule{trigger: []string{"\\("}, calls: &math},
+		countingRule{trigger: []string{"?", "!"}, calls: &bang},
+	)
+	got, err := f.Format("text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second rule is triggered by what the first one wrote
+	if got != "text" || math != 0 || bang != 0 {
+		t.Errorf("got %q, calls %d and %d", got, math, bang)
+	}
+	if got, _ = f.Format("a \\(x\\)"); got != "a \\(x\\)!!" || math != 1 || bang != 1 {
+		t.Errorf("got %q, calls %d and %d", got, math, bang)
+	}
+}
+
+func BenchmarkFormatWithoutTriggers(b *testing.B) {
+	enable := []string{"InlineMathToDollar", "StripHTMLComments", "HTMLLinks", "InlineFootnote", "Mojibake", "DecodeEntities"}
+	f, err := newFormatterFromOptions(Options{"ellipsis": "unicode"}, enable, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	doc := strings.Repeat(strings.Join(triggerCorpus, "\n"), 50)
+
+	b.Run("skip", func(b *testing.B) {
+		for range b.N {
+			if _, err := f.Format(doc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("run-all", func(b *testing.B) {
+		for range b.N {
+			content := doc
+			for _, r := range f.rules {
+				content, _ = r.Apply(content)
+			}
+		}
+	})
+}
+
+func TestFormatterRuleManagement(t *testing.T) {
+	names := func(f *Formatter) string {
+		var n []string
+		for _, r := range f.Rules() {
+			n = append(n, r.Name())
+		}
+		return strings.Join(n, ",")
+	}
+	base := NewFormatter(NewBlankLineAfterHeadingRule(), NewInlineMathReplaceRule(), NewBlankLineAfterHeadingRule())
+
+	rules := base.Rules()
+	rules[0] = NewInlineMathReplaceRule()
+	if got := names(base); got != "BlankLineAfterHeading,InlineMathToDollar,BlankLineAfterHeading" {
+		t.Errorf("changing Rules() changed the formatter: %s", got)
+	}
+
+	without, ok := base.WithoutRule("BlankLineAfterHeading")
+	if !ok || names(without) != "InlineMathToDollar" {
+		t.Errorf("WithoutRule: got %s, %t", names(without), ok)
+	}
+	if same, ok := base.WithoutRule("Bogus"); ok || names(same) != names(base) {
+		t.Errorf("WithoutRule of an unknown rule: got %s, %t", names(same), ok)
+	}
+
+	with := without.WithRules(countingRule{calls: new(int), trigger: []string{"x"}})
+	if names(with) != "InlineMathToDollar,Counting" || names(without) != "InlineMathToDollar" {
+		t.Errorf("WithRules: got %s, original now %s", names(with), names(without))
+	}
+	if got, _ := with.Format(`\(x\)`); got != "$x$!" {
+		t.Errorf("got %q", got)
+	}
+
+	clone := base.Clone()
+	if names(clone) != names(base) || &clone.rules[0] == &base.rules[0] {
+		t.Error("Clone shares its rules with the original")
+	}
+}
+
+func TestRuleFlags(t *testing.T) {
+	var enable, disable ruleNames
+	for _, name := range []string{"todotask", "MD040"} {
+		if err := enable.Set(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := disable.Set("inlineMathToDollar"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (ruleNames{"TodoTask", "FencedCodeLanguage"}); !reflect.DeepEqual(enable, want) {
+		t.Errorf("got %q, want %q", enable, want)
+	}
+	err := disable.Set("InlineMath")
+	if err == nil || !strings.Contains(err.Error(), `unknown rule "InlineMath" (rules are UnterminatedFence, `) {
+		t.Errorf("got error %v", err)
+	}
+
+	names := func(f *Formatter) []string {
+		var n []string
+		for _, r := range f.Rules() {
+			n = append(n, r.Name())
+		}
+		return n
+	}
+	def, err := newFormatterFromOptions(Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := newFormatterFromOptions(Options{}, nil, disable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, n := range names(def) {
+		if n != "InlineMathToDollar" {
+			want = append(want, n)
+		}
+	}
+	if got := names(f); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	input := "# Math\n\nSee \\(x^2\\).\n"
+	for _, tt := range []struct {
+		disable ruleNames
+		want    string
+	}{
+		{nil, "# Math\n\nSee $x^2$.\n"},
+		{disable, input},
+	} {
+		got, _, err := formatDocument(input, "", Options{}, nil, tt.disable, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("disable %q: expected:\n%q\ngot:\n%q", tt.disable, tt.want, got)
+		}
+	}
+}
+
+func TestPrintDefaults(t *testing.T) {
+	fs := flag.NewFlagSet("mdfmt", flag.ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	path := fs.String("stdin-filepath", "", "`path` of the file read from stdin")
+	fs.StringVar(path, "stdin-filename", "", "alias of -stdin-filepath")
+	fs.Int("jobs", 4, "format up to `n` files at once")
+	if err := fs.Parse([]string{"-stdin-filename", "a.md", "-jobs", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if *path != "a.md" {
+		t.Errorf("-stdin-filename set %q", *path)
+	}
+
+	printDefaults(fs)
+	got := buf.String()
+	want := "  -jobs n\n    \tformat up to n files at once (default 4)\n" +
+		"  -stdin-filepath path\n    \tpath of the file read from stdin\n"
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFormatterConcurrentUse(t *testing.T) {
+	// every rule at work on its own example, all in one document
+	var docs []string
+	for _, spec := range builtinRules {
+		docs = append(docs, spec.example)
+	}
+	docs = append(docs, strings.Join(docs, "\n\n"))
+	f, err := newFormatterFromOptions(Options{"quotes": "en", "dashes": "smart", "emoji": "unicode"}, builtinRuleNames(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]string, len(docs))
+	for i, doc := range docs {
+		if want[i], err = f.Format(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, doc := range docs {
+				got, err := f.Format(doc)
+				if err != nil || got != want[i] {
+					t.Errorf("document %d: got %q, %v", i, got, err)
+				}
+				f.Lint(got)
+			}
+		}()
+	}
+	wg.Wait()
+}
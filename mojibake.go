@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 59: repair UTF-8 text that was decoded as Windows-1252
+// ----------------------------------------------------------------
+
+// cp1252High are the characters Windows-1252 has at 0x80-0x9F, where
+// Latin-1 has control characters. The five unassigned bytes keep their
+// Latin-1 meaning, as most decoders do.
+var cp1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// mojibakeTargets are the characters whose misdecoded forms the rule
+// repairs: Latin-1 letters and symbols, and the typographic punctuation
+// of Windows-1252.
+var mojibakeTargets = func() []rune {
+	var rs []rune
+	for r := rune(0xa0); r <= 0xff; r++ {
+		rs = append(rs, r)
+	}
+	for _, r := range cp1252High {
+		if r > 0xff {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}()
+
+// mojibake maps the text a character turns into when its UTF-8 encoding
+// is read as Windows-1252 back to the character, as "â€™" to "’".
+var mojibake = func() map[string]string {
+	m := map[string]string{}
+	for _, r := range mojibakeTargets {
+		var sb strings.Builder
+		for _, c := range []byte(string(r)) {
+			if c >= 0x80 && c < 0xa0 {
+				sb.WriteRune(cp1252High[c-0x80])
+			} else {
+				sb.WriteRune(rune(c))
+			}
+		}
+		m[sb.String()] = string(r)
+	}
+	return m
+}()
+
+// mojibakeReplacer repairs all of mojibake, longest sequences first.
+var mojibakeReplacer = func() *strings.Replacer {
+	seqs := make([]string, 0, len(mojibake))
+	for s := range mojibake {
+		seqs = append(seqs, s)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		if len(seqs[i]) != len(seqs[j]) {
+			return len(seqs[i]) > len(seqs[j])
+		}
+		return seqs[i] < seqs[j]
+	})
+	pairs := make([]string, 0, 2*len(seqs))
+	for _, s := range seqs {
+		pairs = append(pairs, s, mojibake[s])
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// mojibakeLeads are the first characters of the sequences of mojibake.
+var mojibakeLeads = func() []string {
+	seen := map[string]bool{}
+	var leads []string
+	for s := range mojibake {
+		_, size := utf8.DecodeRuneInString(s)
+		if !seen[s[:size]] {
+			seen[s[:size]] = true
+			leads = append(leads, s[:size])
+		}
+	}
+	sort.Strings(leads)
+	return leads
+}()
+
+type MojibakeRule struct {
+	// fix repairs the text instead of only reporting it.
+	fix bool
+}
+
+// NewMojibakeRule constructs a rule that reports text in prose that was
+// UTF-8 read as Windows-1252, such as "â€œ" for "“" or "Ã¤" for "ä", and
+// with fix writes the intended characters. Code is left alone.
+func NewMojibakeRule(fix bool) Rule {
+	return MojibakeRule{fix: fix}
+}
+
+func newMojibakeRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("mojibake", "fix", "fix", "lint")
+	if err != nil {
+		return nil, err
+	}
+	return NewMojibakeRule(mode == "fix"), nil
+}
+
+func (MojibakeRule) Name() string {
+	return "Mojibake"
+}
+
+// Trigger returns the characters misdecoded sequences start with.
+func (r MojibakeRule) Trigger() []string {
+	if !r.fix {
+		return nil
+	}
+	return mojibakeLeads
+}
+
+func (r MojibakeRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		lines[i] = mapOutside(line, nonProseSpans(line), mojibakeReplacer.Replace)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Lint reports each misdecoded sequence, keyed by the sequence so that
+// the summary counts them apart.
+func (r MojibakeRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		covered := nonProseSpans(line)
+		for k := 0; k < len(line); {
+			seq := longestMojibake(line[k:])
+			if seq == "" || inSpans(covered, k) {
+				_, size := utf8.DecodeRuneInString(line[k:])
+				k += size
+				continue
+			}
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:k]) + 1,
+				Rule:    r.Name(),
+				Key:     seq,
+				Message: fmt.Sprintf("%q looks like %q decoded with the wrong encoding", seq, mojibake[seq]),
+				Fix:     lineFix(line, k, k+len(seq), mojibake[seq]),
+			})
+			k += len(seq)
+		}
+	}
+	return diags
+}
+
+// longestMojibake returns the longest misdecoded sequence s starts with,
+// or "".
+func longestMojibake(s string) string {
+	// sequences are two or three characters of up to three bytes each
+	for n := 3; n >= 2; n-- {
+		end, count := 0, 0
+		for end < len(s) && count < n {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+			count++
+		}
+		if count == n {
+			if _, ok := mojibake[s[:end]]; ok {
+				return s[:end]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMojibakeRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "punctuation",
+			input:    "Itâ€™s a â€œtestâ€\u009d â€” reallyâ€¦ â€¢ 5 â‚¬",
+			expected: "It’s a “test” — really… • 5 €",
+		},
+		{
+			name:     "latin letters",
+			input:    "CafÃ© in MÃ¼nchen, Ã…se and Ã‰mile, 20Â°C, Â© 2024, aÂ\u00a0b",
+			expected: "Café in München, Åse and Émile, 20°C, © 2024, a\u00a0b",
+		},
+		{
+			name:     "correct text",
+			input:    "Café “São Paulo” — Ågård, Ãx",
+			expected: "Café “São Paulo” — Ågård, Ãx",
+		},
+		{
+			name:     "code",
+			input:    "`Ã©` and [Ã©](http://x/Ã©)\n\n```\nâ€™\n```\n\n    Ã©",
+			expected: "`Ã©` and [é](http://x/Ã©)\n\n```\nâ€™\n```\n\n    Ã©",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewMojibakeRule(true)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q\nbecame:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestMojibakeLint(t *testing.T) {
+	input := "Donâ€™t, wonâ€™t, cafÃ©\n`Ã©`\n"
+	rule := NewMojibakeRule(false)
+	if got, _ := rule.Apply(input); got != input {
+		t.Errorf("lint mode changed the text:\n%q", got)
+	}
+	diags := rule.(Linter).Lint(input)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`1:4: warning: Mojibake: "â€™" looks like "’" decoded with the wrong encoding`,
+		`1:13: warning: Mojibake: "â€™" looks like "’" decoded with the wrong encoding`,
+		`1:22: warning: Mojibake: "Ã©" looks like "é" decoded with the wrong encoding`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	summary := []string{"Mojibake: 3 (Ã©: 1, â€™: 2)"}
+	if s := summarize(diags); !reflect.DeepEqual(s, summary) {
+		t.Errorf("got summary %q, want %q", s, summary)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options holds rule settings as plain strings keyed by option name, as
+// given on the command line with -set key=value.
+type Options map[string]string
+
+// String implements flag.Value.
+func (o Options) String() string {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + o[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value, accepting a single key=value pair.
+func (o Options) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("option %q is not of the form key=value", s)
+	}
+	o[key] = strings.TrimSpace(value)
+	return nil
+}
+
+// Get returns the value of key, or def when it is unset.
+func (o Options) Get(key, def string) string {
+	if v, ok := o[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Bool parses key as a boolean, returning def when it is unset.
+func (o Options) Bool(key string, def bool) (bool, error) {
+	v, ok := o[key]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("option %s: %q is not a boolean", key, v)
+	}
+	return b, nil
+}
+
+// Int parses key as a non-negative integer, returning def when it is
+// unset.
+func (o Options) Int(key string, def int) (int, error) {
+	v, ok := o[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("option %s: %q is not a non-negative integer", key, v)
+	}
+	return n, nil
+}
+
+// Duration parses key as a duration such as "5s", returning def when it
+// is unset.
+func (o Options) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := o[key]
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("option %s: %q is not a non-negative duration", key, v)
+	}
+	return d, nil
+}
+
+// Choice returns the value of key, which must be one of allowed, or def
+// when it is unset.
+func (o Options) Choice(key, def string, allowed ...string) (string, error) {
+	v, ok := o[key]
+	if !ok {
+		return def, nil
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("option %s: %q is not one of %s",
+		key, v, strings.Join(allowed, ", "))
+}
+
+// List parses key as a comma-separated list, returning def when it is
+// unset. Empty elements are dropped.
+func (o Options) List(key string, def []string) []string {
+	v, ok := o[key]
+	if !ok {
+		return def
+	}
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// ruleNames is a repeatable flag collecting rule names. Names are checked
+// as they are given, so a misspelt one stops mdfmt before any formatting,
+// and kept as the rules spell them.
+type ruleNames []string
+
+func (n *ruleNames) String() string { return strings.Join(*n, ",") }
+
+func (n *ruleNames) Set(s string) error {
+	names, err := resolveRuleNames([]string{s})
+	if err != nil {
+		return fmt.Errorf("%w (rules are %s)", err, strings.Join(builtinRuleNames(), ", "))
+	}
+	*n = append(*n, names...)
+	return nil
+}
+
+// lineRanges is a repeatable flag collecting comma-separated line ranges,
+// such as 40:80, or single lines.
+type lineRanges []LineRange
+
+func (l *lineRanges) String() string {
+	parts := make([]string, len(*l))
+	for i, r := range *l {
+		parts[i] = fmt.Sprintf("%d:%d", r.Start, r.End)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *lineRanges) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, ":")
+		if !isRange {
+			to = from
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(from))
+		end, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || start < 1 || end < start {
+			return fmt.Errorf("%q is not a line range, such as 40:80, or a line", part)
+		}
+		*l = append(*l, LineRange{start, end})
+	}
+	return nil
+}
+
+// hiddenFlags are aliases that the command line accepts but -help leaves
+// out, to keep to one name for each setting.
+var hiddenFlags = map[string]bool{"stdin-filename": true}
+
+// printDefaults prints the flags of fs as fs.PrintDefaults does, without
+// hiddenFlags.
+func printDefaults(fs *flag.FlagSet) {
+	shown := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	shown.SetOutput(fs.Output())
+	fs.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			shown.Var(f.Value, f.Name, f.Usage)
+			shown.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	shown.PrintDefaults()
+}
+
+// Map parses key as comma-separated from:to pairs that are merged over
+// def. The result is a fresh map; def is not modified.
+func (o Options) Map(key string, def map[string]string) (map[string]string, error) {
+	m := make(map[string]string, len(def))
+	for k, v := range def {
+		m[k] = v
+	}
+	v, ok := o[key]
+	if !ok || v == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		from, to, ok := strings.Cut(pair, ":")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" {
+			return nil, fmt.Errorf("option %s: %q is not of the form from:to", key, pair)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
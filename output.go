@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// outputFormats are the values of the -format flag.
+var outputFormats = []string{"text", "github", "checkstyle", "rdjson", "gitlab", "json", "ndjson", "sarif"}
+
+// reportOnStdout reports whether format is a machine-readable report that
+// is written to stdout in place of the formatted document.
+func reportOnStdout(format string) bool {
+	return format == "checkstyle" || format == "rdjson" || format == "gitlab" || reportsResults(format)
+}
+
+// defaultOutputFormat picks the format when -format is not given: GitHub
+// workflow commands inside GitHub Actions, plain text elsewhere.
+func defaultOutputFormat(getenv func(string) string) string {
+	if getenv("GITHUB_ACTIONS") == "true" {
+		return "github"
+	}
+	return "text"
+}
+
+// writeReport reports on the file at path, whose content in formats to
+// out with the findings diags on out, in the given format. Findings are
+// reported at the lines of in, the file as it is.
+func writeReport(w io.Writer, format, path, in, out string, diags []Diagnostic) error {
+	if format == "gitlab" {
+		return writeGitLab(w, path, in, out, diags)
+	}
+	return writeDiagnostics(w, format, path, inputDiagnostics(in, out, diags))
+}
+
+// inputDiagnostics returns diags, found on out, at the lines of in that
+// they came from, as originLine maps them. Columns and fixes carry over
+// only to lines that formatting leaves as they are.
+func inputDiagnostics(in, out string, diags []Diagnostic) []Diagnostic {
+	if len(diags) == 0 {
+		return diags
+	}
+	// findings are on out with LF line breaks
+	inLF, outLF := strings.ReplaceAll(in, "\r\n", "\n"), strings.ReplaceAll(out, "\r\n", "\n")
+	if inLF == outLF {
+		return diags
+	}
+	origin := NewLineMap(strings.Count(outLF, "\n")+1, lineEdits(outLF, inLF))
+	inLines, outLines := strings.Split(inLF, "\n"), strings.Split(outLF, "\n")
+	mapped := make([]Diagnostic, len(diags))
+	for i, d := range diags {
+		line := originLine(origin, d.Line)
+		if d.Line < 1 || d.Line > len(outLines) || line > len(inLines) || outLines[d.Line-1] != inLines[line-1] {
+			d.Column, d.Fix = 0, nil
+		}
+		d.Line = line
+		mapped[i] = d
+	}
+	return mapped
+}
+
+// writeDiagnostics reports diags found in the file at path, which is empty
+// for stdin without -stdin-filepath, in the given format.
+func writeDiagnostics(w io.Writer, format, path string, diags []Diagnostic) error {
+	switch format {
+	case "text":
+		name := path
+		if name == "" {
+			name = "<stdin>"
+		}
+		for _, d := range diags {
+			fmt.Fprintf(w, "%s:%s\n", name, d)
+		}
+		for _, line := range summarize(diags) {
+			fmt.Fprintf(w, "summary: %s\n", line)
+		}
+	case "github":
+		for _, d := range diags {
+			props := []string{}
+			if path != "" {
+				props = append(props, "file="+escapeGitHubProperty(path))
+			}
+			props = append(props, fmt.Sprintf("line=%d", d.Line))
+			if d.Column > 0 {
+				props = append(props, fmt.Sprintf("col=%d", d.Column))
+			}
+			props = append(props, "title="+escapeGitHubProperty(d.Rule))
+			fmt.Fprintf(w, "::%s %s::%s\n", d.Severity, strings.Join(props, ","), escapeGitHubData(d.Message))
+		}
+		if len(diags) > 0 {
+			msg := fmt.Sprintf("%d finding(s): %s", len(diags), strings.Join(summarize(diags), "; "))
+			fmt.Fprintf(w, "::notice title=mdfmt::%s\n", escapeGitHubData(msg))
+		}
+	case "checkstyle":
+		return writeCheckstyle(w, path, diags)
+	case "rdjson":
+		return writeRDJSON(w, path, diags)
+	case "gitlab":
+		// without the document, at the lines of diags alone
+		return writeGitLab(w, path, "", "", diags)
+	default:
+		return checkOutputFormat(format)
+	}
+	return nil
+}
+
+func checkOutputFormat(format string) error {
+	if !containsString(outputFormats, format) {
+		return fmt.Errorf("unknown output format %q (want one of %s)", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+// writeGitHubChanges reports the rules that would change the file at path
+// for -check as GitHub error annotations, each at the first line it
+// changes.
+func writeGitHubChanges(w io.Writer, path string, changes []ruleChange) error {
+	for _, c := range changes {
+		props := []string{}
+		if path != "" {
+			props = append(props, "file="+escapeGitHubProperty(path))
+		}
+		props = append(props, fmt.Sprintf("line=%d", c.line), "title="+escapeGitHubProperty(c.rule))
+		msg := fmt.Sprintf("%s would change %d line(s) from here", c.rule, c.lines)
+		if desc := ruleDescription(c.rule); desc != "" {
+			msg += ": it " + desc
+		}
+		if _, err := fmt.Fprintf(w, "::error %s::%s\n", strings.Join(props, ","), escapeGitHubData(msg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// githubAnnotationLimit is how many error annotations, and how many
+// warning annotations, GitHub shows for a step; it drops the rest.
+const githubAnnotationLimit = 10
+
+// annotationLimit passes what is written to it on to w line by line,
+// except for the error and warning commands past githubAnnotationLimit,
+// which it counts instead, so that close can say how many were left out.
+type annotationLimit struct {
+	w       io.Writer
+	counts  map[string]int
+	dropped int
+	line    []byte
+}
+
+func newAnnotationLimit(w io.Writer) *annotationLimit {
+	return &annotationLimit{w: w, counts: map[string]int{}}
+}
+
+func (a *annotationLimit) Write(p []byte) (int, error) {
+	a.line = append(a.line, p...)
+	for {
+		i := bytes.IndexByte(a.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := a.writeLine(a.line[:i+1]); err != nil {
+			return len(p), err
+		}
+		a.line = a.line[i+1:]
+	}
+}
+
+func (a *annotationLimit) writeLine(line []byte) error {
+	for _, kind := range []string{"error", "warning"} {
+		if bytes.HasPrefix(line, []byte("::"+kind+" ")) || bytes.HasPrefix(line, []byte("::"+kind+"::")) {
+			a.counts[kind]++
+			if a.counts[kind] > githubAnnotationLimit {
+				a.dropped++
+				return nil
+			}
+		}
+	}
+	_, err := a.w.Write(line)
+	return err
+}
+
+// close passes on an unfinished last line and, when annotations were
+// dropped, ends with a notice saying how many.
+func (a *annotationLimit) close() error {
+	if len(a.line) > 0 {
+		if a.dropped > 0 {
+			// the notice goes on a line of its own
+			a.line = append(a.line, '\n')
+		}
+		if err := a.writeLine(a.line); err != nil {
+			return err
+		}
+		a.line = nil
+	}
+	if a.dropped == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%d more annotation(s) not shown: GitHub shows %d errors and %d warnings per step; run mdfmt -check locally for all of them",
+		a.dropped, githubAnnotationLimit, githubAnnotationLimit)
+	_, err := fmt.Fprintf(a.w, "::notice title=mdfmt::%s\n", escapeGitHubData(msg))
+	return err
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command,
+// which additionally may not contain the separators ":" and ",".
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// writeCheckstyle writes diags as a Checkstyle XML report, with a file
+// element only when there are findings.
+func writeCheckstyle(w io.Writer, path string, diags []Diagnostic) error {
+	report := checkstyleReport{Version: "4.3"}
+	if len(diags) > 0 {
+		if path == "" {
+			path = "<stdin>"
+		}
+		f := checkstyleFile{Name: path}
+		for _, d := range diags {
+			f.Errors = append(f.Errors, checkstyleError{
+				Line:     d.Line,
+				Column:   d.Column,
+				Severity: d.Severity.String(),
+				Message:  d.Message,
+				Source:   d.Rule,
+			})
+		}
+		report.Files = append(report.Files, f)
+	}
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, out)
+	return err
+}
+
+// The rdjson types follow reviewdog's DiagnosticResult schema.
+type (
+	rdjsonResult struct {
+		Source      rdjsonSource       `json:"source"`
+		Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+	}
+	rdjsonSource struct {
+		Name string `json:"name"`
+	}
+	rdjsonDiagnostic struct {
+		Message     string             `json:"message"`
+		Location    rdjsonLocation     `json:"location"`
+		Severity    string             `json:"severity"`
+		Code        rdjsonCode         `json:"code"`
+		Suggestions []rdjsonSuggestion `json:"suggestions,omitempty"`
+	}
+	rdjsonLocation struct {
+		Path  string      `json:"path,omitempty"`
+		Range rdjsonRange `json:"range"`
+	}
+	rdjsonRange struct {
+		Start rdjsonPosition  `json:"start"`
+		End   *rdjsonPosition `json:"end,omitempty"`
+	}
+	rdjsonPosition struct {
+		Line   int `json:"line"`
+		Column int `json:"column,omitempty"`
+	}
+	rdjsonCode struct {
+		Value string `json:"value"`
+	}
+	rdjsonSuggestion struct {
+		Range rdjsonRange `json:"range"`
+		Text  string      `json:"text"`
+	}
+)
+
+// writeRDJSON writes diags as a reviewdog rdjson result, with the
+// suggested fixes as suggestions.
+func writeRDJSON(w io.Writer, path string, diags []Diagnostic) error {
+	result := rdjsonResult{Source: rdjsonSource{Name: "mdfmt"}, Diagnostics: []rdjsonDiagnostic{}}
+	for _, d := range diags {
+		rd := rdjsonDiagnostic{
+			Message: d.Message,
+			Location: rdjsonLocation{
+				Path:  path,
+				Range: rdjsonRange{Start: rdjsonPosition{Line: d.Line, Column: d.Column}},
+			},
+			Severity: strings.ToUpper(d.Severity.String()),
+			Code:     rdjsonCode{Value: d.Rule},
+		}
+		if d.Fix != nil {
+			rd.Suggestions = []rdjsonSuggestion{{
+				Range: rdjsonRange{
+					Start: rdjsonPosition{Line: d.Line, Column: d.Fix.Start},
+					End:   &rdjsonPosition{Line: d.Line, Column: d.Fix.End},
+				},
+				Text: d.Fix.Text,
+			}}
+		}
+		result.Diagnostics = append(result.Diagnostics, rd)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	diags := []Diagnostic{
+		{Line: 3, Rule: "FrontMatter", Severity: SeverityError, Message: "bad: 100%\nsecond line"},
+		{Line: 12, Column: 4, Rule: "BlankLineAfterHeading", Code: "MD022", Message: "missing blank line"},
+	}
+	tests := []struct {
+		name     string
+		format   string
+		path     string
+		expected string
+	}{
+		{
+			name:   "text",
+			format: "text",
+			expected: "<stdin>:3: error: FrontMatter: bad: 100%\nsecond line\n" +
+				"<stdin>:12:4: warning: MD022/BlankLineAfterHeading: missing blank line\n" +
+				"summary: BlankLineAfterHeading: 1\n" +
+				"summary: FrontMatter: 1\n",
+		},
+		{
+			name:   "github",
+			format: "github",
+			path:   "docs/a,b.md",
+			expected: "::error file=docs/a%2Cb.md,line=3,title=FrontMatter::bad: 100%25%0Asecond line\n" +
+				"::warning file=docs/a%2Cb.md,line=12,col=4,title=BlankLineAfterHeading::missing blank line\n" +
+				"::notice title=mdfmt::2 finding(s): BlankLineAfterHeading: 1; FrontMatter: 1\n",
+		},
+		{
+			name:   "github without a path",
+			format: "github",
+			expected: "::error line=3,title=FrontMatter::bad: 100%25%0Asecond line\n" +
+				"::warning line=12,col=4,title=BlankLineAfterHeading::missing blank line\n" +
+				"::notice title=mdfmt::2 finding(s): BlankLineAfterHeading: 1; FrontMatter: 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := writeDiagnostics(&sb, tt.format, tt.path, diags); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sb.String(); got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+
+	if err := writeDiagnostics(&strings.Builder{}, "xml", "", diags); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestDefaultOutputFormat(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+	if got := defaultOutputFormat(getenv); got != "text" {
+		t.Errorf("got %q, want text", got)
+	}
+	env["GITHUB_ACTIONS"] = "true"
+	if got := defaultOutputFormat(getenv); got != "github" {
+		t.Errorf("got %q, want github", got)
+	}
+}
+
+func TestWriteCheckstyle(t *testing.T) {
+	diags := []Diagnostic{
+		{Line: 2, Column: 5, Rule: "InlineHTML", Message: `HTML tag <div class="x"> & more`},
+		{Line: 7, Rule: "FrontMatter", Severity: SeverityError, Message: "bad"},
+	}
+	var sb strings.Builder
+	if err := writeDiagnostics(&sb, "checkstyle", "docs/a.md", diags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report checkstyleReport
+	if err := xml.Unmarshal([]byte(sb.String()), &report); err != nil {
+		t.Fatalf("not well-formed: %v\n%s", err, sb.String())
+	}
+	want := []checkstyleError{
+		{Line: 2, Column: 5, Severity: "warning", Message: `HTML tag <div class="x"> & more`, Source: "InlineHTML"},
+		{Line: 7, Severity: "error", Message: "bad", Source: "FrontMatter"},
+	}
+	if len(report.Files) != 1 || report.Files[0].Name != "docs/a.md" ||
+		!reflect.DeepEqual(report.Files[0].Errors, want) {
+		t.Errorf("got %+v", report)
+	}
+
+	sb.Reset()
+	if err := writeDiagnostics(&sb, "checkstyle", "docs/a.md", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := xml.Header + `<checkstyle version="4.3"></checkstyle>` + "\n"
+	if sb.String() != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, sb.String())
+	}
+}
+
+func TestWriteRDJSON(t *testing.T) {
+	diags := []Diagnostic{
+		{Line: 2, Column: 5, Rule: "BareURL", Message: "bare URL",
+			Fix: &Fix{Start: 1, End: 17, Text: "see <https://a.io>"}},
+		{Line: 7, Rule: "FrontMatter", Severity: SeverityError, Message: "bad"},
+	}
+	var sb strings.Builder
+	if err := writeDiagnostics(&sb, "rdjson", "docs/a.md", diags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got rdjsonResult
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, sb.String())
+	}
+	want := rdjsonResult{
+		Source: rdjsonSource{Name: "mdfmt"},
+		Diagnostics: []rdjsonDiagnostic{
+			{
+				Message: "bare URL",
+				Location: rdjsonLocation{
+					Path:  "docs/a.md",
+					Range: rdjsonRange{Start: rdjsonPosition{Line: 2, Column: 5}},
+				},
+				Severity: "WARNING",
+				Code:     rdjsonCode{Value: "BareURL"},
+				Suggestions: []rdjsonSuggestion{{
+					Range: rdjsonRange{
+						Start: rdjsonPosition{Line: 2, Column: 1},
+						End:   &rdjsonPosition{Line: 2, Column: 17},
+					},
+					Text: "see <https://a.io>",
+				}},
+			},
+			{
+				Message: "bad",
+				Location: rdjsonLocation{
+					Path:  "docs/a.md",
+					Range: rdjsonRange{Start: rdjsonPosition{Line: 7}},
+				},
+				Severity: "ERROR",
+				Code:     rdjsonCode{Value: "FrontMatter"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReportInputLines(t *testing.T) {
+	// formatting puts a blank line after the heading, so the URL found on
+	// line 3 of the output is on line 2 of the file
+	in := "# Title\nSee https://a.example\n"
+	run := runOptions{format: "rdjson"}
+	var stdout, stderr strings.Builder
+	if _, err := run.formatStdin(&stdout, &stderr, "docs/a.md", in); err != nil {
+		t.Fatal(err)
+	}
+	var got rdjsonResult
+	if err := json.Unmarshal([]byte(stdout.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(got.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", got.Diagnostics)
+	}
+	d := got.Diagnostics[0]
+	if start := d.Location.Range.Start; start != (rdjsonPosition{Line: 2, Column: 5}) {
+		t.Errorf("diagnostic at %+v, want line 2, column 5", start)
+	}
+	for _, s := range d.Suggestions {
+		if s.Range.Start.Line != 2 || s.Range.End.Line != 2 {
+			t.Errorf("suggestion at %+v, want line 2", s.Range)
+		}
+	}
+
+	// lines formatting changes keep neither column nor fix
+	diags := []Diagnostic{
+		{Line: 2, Column: 3, Rule: "A", Fix: &Fix{Start: 1, End: 2, Text: "x"}},
+		{Line: 4, Column: 3, Rule: "B", Fix: &Fix{Start: 1, End: 2, Text: "y"}},
+	}
+	want := []Diagnostic{
+		{Line: 1, Rule: "A"},
+		{Line: 2, Column: 3, Rule: "B", Fix: &Fix{Start: 1, End: 2, Text: "y"}},
+	}
+	if got := inputDiagnostics("a\r\nb\r\n", "a\n\nc\nb\n", diags); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGitHubCheckAnnotations(t *testing.T) {
+	in := "# Title\ntext\n| a | b |\n|---|---|\n| 1 | 2 |\n\nSee https://a.example\n"
+	run := runOptions{format: "github", check: true}
+	var stdout, stderr strings.Builder
+	changed, err := run.formatStdin(&stdout, &stderr, "docs/a,b.md", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected the document to need formatting")
+	}
+	expected := "docs/a,b.md is not formatted\n" +
+		"::error file=docs/a%2Cb.md,line=2,title=BlankLineAfterHeading::BlankLineAfterHeading would change 1 line(s) from here: it puts a blank line after headings\n" +
+		"::error file=docs/a%2Cb.md,line=3,title=BlankLineBeforeTable::BlankLineBeforeTable would change 1 line(s) from here: it puts a blank line before tables\n" +
+		"::warning file=docs/a%2Cb.md,line=7,col=5,title=BareURL::bare URL https://a.example; write it as <https://a.example>\n" +
+		"::notice title=mdfmt::1 finding(s): BareURL: 1\n"
+	if got := stderr.String(); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing on stdout, got %q", stdout.String())
+	}
+
+	// the fence, and the finding on it, are on line 3 of the file,
+	// though on line 5 once formatted
+	stderr.Reset()
+	if _, err := run.formatStdin(&stdout, &stderr, "a.md", "# T\nx\n```\ny\n```\n"); err != nil {
+		t.Fatal(err)
+	}
+	expected = "a.md is not formatted\n" +
+		"::error file=a.md,line=2,title=BlankLineAfterHeading::BlankLineAfterHeading would change 1 line(s) from here: it puts a blank line after headings\n" +
+		"::error file=a.md,line=3,title=BlankLinesAroundFences::BlankLinesAroundFences would change 1 line(s) from here: it puts a blank line before and after fenced code blocks\n" +
+		"::warning file=a.md,line=3,title=FencedCodeLanguage::fenced code block has no language\n" +
+		"::notice title=mdfmt::1 finding(s): FencedCodeLanguage: 1\n"
+	if got := stderr.String(); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestAnnotationLimit(t *testing.T) {
+	var sb strings.Builder
+	limit := newAnnotationLimit(&sb)
+	var expected strings.Builder
+	for i := 1; i <= githubAnnotationLimit+2; i++ {
+		line := fmt.Sprintf("::error line=%d::e%d\n", i, i)
+		// split across writes
+		limit.Write([]byte(line[:5]))
+		limit.Write([]byte(line[5:] + "plain\n"))
+		if i <= githubAnnotationLimit {
+			expected.WriteString(line)
+		}
+		expected.WriteString("plain\n")
+	}
+	limit.Write([]byte("::warning::w\n::notice::n"))
+	if err := limit.close(); err != nil {
+		t.Fatal(err)
+	}
+	expected.WriteString("::warning::w\n::notice::n\n" +
+		"::notice title=mdfmt::2 more annotation(s) not shown: GitHub shows 10 errors and 10 warnings per step; run mdfmt -check locally for all of them\n")
+	if sb.String() != expected.String() {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected.String(), sb.String())
+	}
+}
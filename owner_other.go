@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// ----------------------------------------------------------------
+// Keeping the owner of rewritten files (elsewhere)
+// ----------------------------------------------------------------
+
+// chownLike does nothing where files have no unix owner.
+func chownLike(f *os.File, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// ----------------------------------------------------------------
+// Keeping the owner of rewritten files (unix)
+// ----------------------------------------------------------------
+
+// chownLike gives f the owner and group of info when mdfmt runs as root,
+// which alone may give files away.
+func chownLike(f *os.File, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || os.Geteuid() != 0 {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
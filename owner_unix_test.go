@@ -0,0 +1,33 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFileAtomicOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("only root can give files away")
+	}
+	const old, formatted = "# A\ntext", "# A\n\ntext\n"
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": old})
+	path := filepath.Join(dir, "a.md")
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, old, formatted, "", false); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := info.Sys().(*syscall.Stat_t); st.Uid != 1234 || st.Gid != 5678 {
+		t.Errorf("owner changed to %d:%d", st.Uid, st.Gid)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// stopProfiling writes out the profiles started by startProfiling. It is
+// safe to call when none were started, and more than once.
+var stopProfiling = func() error { return nil }
+
+// startProfiling starts a CPU profile written to cpuFile and arranges for
+// a heap profile to be written to memFile when stopProfiling is called.
+// Either file may be empty to skip that profile.
+func startProfiling(cpuFile, memFile string) error {
+	var cpu *os.File
+	if cpuFile != "" {
+		f, err := os.Create(cpuFile)
+		if err != nil {
+			return fmt.Errorf("cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("cpu profile: %w", err)
+		}
+		cpu = f
+	}
+	stopProfiling = func() error {
+		stopProfiling = func() error { return nil }
+		var errs []error
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("cpu profile: %w", err))
+			}
+		}
+		if memFile != "" {
+			if err := writeHeapProfile(memFile); err != nil {
+				errs = append(errs, fmt.Errorf("memory profile: %w", err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func writeHeapProfile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	runtime.GC() // up-to-date statistics
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exit writes out any profiles before ending the process with code.
+func exit(code int) {
+	if err := stopProfiling(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	os.Exit(code)
+}
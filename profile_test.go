@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiling(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.out"), filepath.Join(dir, "mem.out")
+	if err := startProfiling(cpu, mem); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := formatDocument("# T\ntext\n", "", Options{}, nil, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopProfiling(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopProfiling(); err != nil {
+		t.Errorf("second stop: %v", err)
+	}
+	for _, name := range []string{cpu, mem} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// pprof profiles are gzipped protocol buffers
+		if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+			t.Errorf("%s is not a gzipped profile", filepath.Base(name))
+		}
+	}
+
+	if err := startProfiling(filepath.Join(dir, "missing", "cpu.out"), ""); err == nil {
+		t.Error("no error for an uncreatable profile")
+	}
+}
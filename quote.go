@@ -0,0 +1,132 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 14: normalize blockquote markers to "> " at every level
+// ----------------------------------------------------------------
+
+type BlockquoteMarkerRule struct{}
+
+func NewBlockquoteMarkerRule() Rule { return BlockquoteMarkerRule{} }
+
+func (BlockquoteMarkerRule) Name() string {
+	return "BlockquoteMarker"
+}
+
+func (BlockquoteMarkerRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.quote == 0 {
+			continue
+		}
+		// whatever precedes the first marker belongs to the container
+		prefix := line[:strings.IndexByte(line, '>')]
+		body := line[info.bodyStart:]
+		markers := strings.Repeat("> ", info.quote)
+		switch {
+		case b.isCode(i):
+			// code keeps every byte after the markers the parser strips
+			if body == "" {
+				markers = strings.TrimRight(markers, " ")
+			}
+		case strings.TrimSpace(body) == "":
+			markers, body = strings.TrimRight(markers, " "), ""
+		case info.container == 0 && !info.item && info.indent < 4:
+			body = strings.TrimLeft(body, " \t")
+		}
+		lines[i] = prefix + markers + body
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 15: exactly one blank line around blockquotes
+// ----------------------------------------------------------------
+
+type BlankLinesAroundBlockquotesRule struct{}
+
+func NewBlankLinesAroundBlockquotesRule() Rule { return BlankLinesAroundBlockquotesRule{} }
+
+func (BlankLinesAroundBlockquotesRule) Name() string {
+	return "BlankLinesAroundBlockquotes"
+}
+
+func (BlankLinesAroundBlockquotesRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	gaps := newGapEditor(lines, b)
+	// only unquoted blank lines separate a blockquote from its surroundings
+	separator := func(i int) bool {
+		return b.lines[i].kind == lineBlank && b.lines[i].quote == 0
+	}
+	for i := 0; i < len(lines); i++ {
+		if b.lines[i].quote == 0 {
+			continue
+		}
+		start, end := i, i
+		for end+1 < len(lines) && (b.lines[end+1].quote > 0 || b.lines[end+1].lazy) {
+			end++
+		}
+		i = end
+		indent, _ := leadingColumns(lines[start])
+
+		p := start - 1
+		for p >= 0 && separator(p) {
+			p--
+		}
+		if p >= 0 {
+			gaps.ensureOne(p, start, "")
+		}
+
+		n := end + 1
+		for n < len(lines) && separator(n) {
+			n++
+		}
+		if n == len(lines) {
+			continue
+		}
+		// a following sibling list item means the quote ends its item
+		if col, _ := leadingColumns(lines[n]); b.lines[n].item && col < indent {
+			continue
+		}
+		gaps.ensureOne(end, n, "")
+	}
+	return strings.Join(gaps.apply(), "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 16: make lazy blockquote continuation lines explicit
+// ----------------------------------------------------------------
+
+type ExplicitBlockquoteContinuationRule struct{}
+
+func NewExplicitBlockquoteContinuationRule() Rule {
+	return ExplicitBlockquoteContinuationRule{}
+}
+
+func (ExplicitBlockquoteContinuationRule) Name() string {
+	return "ExplicitBlockquoteContinuation"
+}
+
+func (ExplicitBlockquoteContinuationRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	depth := 0   // quote depth of the paragraph being continued
+	prefix := "" // container prefix before its first marker
+	for i, line := range lines {
+		info := b.lines[i]
+		if !info.lazy {
+			depth, prefix = info.quote, ""
+			if depth > 0 {
+				prefix = line[:strings.IndexByte(line, '>')]
+			}
+			continue
+		}
+		body := strings.TrimLeft(line[info.bodyStart:], " \t")
+		lines[i] = prefix + strings.Repeat("> ", depth) + body
+	}
+	return strings.Join(lines, "\n"), nil
+}
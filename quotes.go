@@ -0,0 +1,238 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 42: write quotation marks in the style of a locale
+// ----------------------------------------------------------------
+
+// quoteMarks are the opening and closing marks of one nesting level.
+type quoteMarks struct{ open, close string }
+
+// quoteStyles lists the primary and secondary marks of each locale.
+var quoteStyles = map[string][2]quoteMarks{
+	"ascii": {{`"`, `"`}, {`'`, `'`}},
+	"en":    {{"“", "”"}, {"‘", "’"}},
+	"de":    {{"„", "“"}, {"‚", "‘"}},
+	"fr":    {{"«", "»"}, {"“", "”"}},
+}
+
+// mathSpan matches inline and display math written with dollars.
+var mathSpan = regexp.MustCompile(`\$\$[^$]+\$\$|\$[^$\s](?:[^$]*[^$\s])?\$`)
+
+type QuoteStyleRule struct {
+	// style is a key of quoteStyles, or "" to leave quotes alone.
+	style string
+	// space is written inside French guillemets.
+	space rune
+}
+
+// NewQuoteStyleRule constructs a rule that rewrites the paired quotation
+// marks of prose in style, one of "ascii", "en", "de" and "fr", using the
+// secondary marks for quotations inside quotations. French guillemets get
+// space inside them. An empty style turns the rule off.
+func NewQuoteStyleRule(style string, space rune) Rule {
+	return QuoteStyleRule{style: style, space: space}
+}
+
+func newQuoteStyleRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("quotes", "", "", "ascii", "en", "de", "fr")
+	if err != nil {
+		return nil, err
+	}
+	space, err := o.Choice("french-space", "nbsp", "nbsp", "narrow")
+	if err != nil {
+		return nil, err
+	}
+	r := '\u00a0'
+	if space == "narrow" {
+		r = '\u202f'
+	}
+	return NewQuoteStyleRule(style, r), nil
+}
+
+func (QuoteStyleRule) Name() string {
+	return "QuoteStyle"
+}
+
+// quoteMark is a quotation mark found in prose.
+type quoteMark struct {
+	line, start, end int // byte range of the mark in its line
+	double           bool
+	opening          bool
+	guillemet        bool
+	// level is the nesting depth of the pair, from 1, once matched.
+	level int
+}
+
+const (
+	doubleQuotes = `"“”„«»‟`
+	singleQuotes = `'‘’‚`
+)
+
+func (r QuoteStyleRule) Apply(content string) (string, error) {
+	if r.style == "" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var marks []quoteMark
+	flush := func() {
+		r.rewrite(lines, pairQuotes(marks))
+		marks = marks[:0]
+	}
+	for i, line := range lines {
+		// quotations do not run past their paragraph
+		if literalLine(b, i) || b.lines[i].kind == lineBlank || isATXHeading(line) {
+			flush()
+			if literalLine(b, i) || b.lines[i].kind == lineBlank {
+				continue
+			}
+		}
+		covered := mergeSpans(append(nonProseSpans(line), mathSpans(line)...))
+		last := 0
+		for _, sp := range append(covered, [2]int{len(line), len(line)}) {
+			marks = append(marks, findQuotes(line, i, last, sp[0])...)
+			last = sp[1]
+		}
+		if isATXHeading(line) {
+			flush()
+		}
+	}
+	flush()
+	return strings.Join(lines, "\n"), nil
+}
+
+func mathSpans(line string) [][2]int {
+	var spans [][2]int
+	for _, m := range mathSpan.FindAllStringIndex(line, -1) {
+		spans = append(spans, [2]int{m[0], m[1]})
+	}
+	return spans
+}
+
+// findQuotes returns the quotation marks in line[from:to], telling opening
+// from closing ones by what is around them.
+func findQuotes(line string, i, from, to int) []quoteMark {
+	var marks []quoteMark
+	for k := from; k < to; {
+		c, size := utf8.DecodeRuneInString(line[k:])
+		double := strings.ContainsRune(doubleQuotes, c)
+		if !double && !strings.ContainsRune(singleQuotes, c) {
+			k += size
+			continue
+		}
+		prev, _ := utf8.DecodeLastRuneInString(line[:k])
+		next, _ := utf8.DecodeRuneInString(line[k+size:])
+		before := k == 0 || unicode.IsSpace(prev) || strings.ContainsRune("([{—–-/"+doubleQuotes+singleQuotes, prev)
+		after := k+size == len(line) || unicode.IsSpace(next) || unicode.IsPunct(next) && !strings.ContainsRune("([{", next)
+		m := quoteMark{line: i, start: k, end: k + size, double: double, guillemet: c == '«' || c == '»'}
+		switch {
+		case c == '„' || c == '‚' || c == '«':
+			m.opening = true
+		case c == '»':
+		case before && !after:
+			m.opening = true
+		case after && !before:
+		case unicode.IsLetter(prev) && unicode.IsLetter(next):
+			// an apostrophe, as in "don't"
+			k += size
+			continue
+		default:
+			// spaced out on both sides: decided when pairing
+			m.opening = unicode.IsSpace(prev) && !unicode.IsSpace(next)
+		}
+		marks = append(marks, m)
+		k += size
+	}
+	return marks
+}
+
+// pairQuotes matches opening and closing marks and returns the matched
+// ones with their nesting level. Single marks only count as quotes inside
+// a quotation; elsewhere they are apostrophes.
+func pairQuotes(marks []quoteMark) []quoteMark {
+	type open struct {
+		index  int
+		double bool
+	}
+	var stack []open
+	var paired []quoteMark
+	for k, m := range marks {
+		if m.opening && (m.double || len(stack) > 0) {
+			stack = append(stack, open{k, m.double})
+			continue
+		}
+		if m.opening {
+			continue
+		}
+		// the innermost open quotation of the same kind closes
+		for s := len(stack) - 1; s >= 0; s-- {
+			if stack[s].double != m.double {
+				continue
+			}
+			o := marks[stack[s].index]
+			o.level, m.level = s+1, s+1
+			paired = append(paired, o, m)
+			stack = stack[:s]
+			break
+		}
+	}
+	return paired
+}
+
+// rewrite replaces the paired marks with those of the style, right to left
+// within each line so offsets stay valid.
+func (r QuoteStyleRule) rewrite(lines []string, marks []quoteMark) {
+	sort.Slice(marks, func(a, b int) bool {
+		if marks[a].line != marks[b].line {
+			return marks[a].line < marks[b].line
+		}
+		return marks[a].start > marks[b].start
+	})
+	style := quoteStyles[r.style]
+	for _, m := range marks {
+		set := style[(m.level+1)%2]
+		line := lines[m.line]
+		start, end := m.start, m.end
+		var text string
+		guillemets := set.open == "«"
+		if m.opening {
+			text = set.open
+			// the space inside guillemets goes with them
+			if m.guillemet || guillemets {
+				for end < len(line) {
+					c, size := utf8.DecodeRuneInString(line[end:])
+					if !isSpace(c) {
+						break
+					}
+					end += size
+				}
+			}
+			if guillemets {
+				text += string(r.space)
+			}
+		} else {
+			text = set.close
+			if m.guillemet || guillemets {
+				for start > 0 {
+					c, size := utf8.DecodeLastRuneInString(line[:start])
+					if !isSpace(c) {
+						break
+					}
+					start -= size
+				}
+			}
+			if guillemets {
+				text = string(r.space) + text
+			}
+		}
+		lines[m.line] = line[:start] + text + line[end:]
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteStyleRule(t *testing.T) {
+	// "~" stands for the no-break space in the expectations
+	input := "He said \"it's 'fine' now\" and “left”.\n\n" +
+		"« Bonjour » dit-il, `\"x\"`, $a\"b$ and [l](http://x/\"y\").\n\n" +
+		"A 5\" screen, \"open\nacross\" lines.\n\n" +
+		"```\n\"code\"\n```\n"
+	tests := []struct {
+		style    string
+		expected string
+	}{
+		{
+			style: "de",
+			expected: "He said „it's ‚fine‘ now“ and „left“.\n\n" +
+				"„Bonjour“ dit-il, `\"x\"`, $a\"b$ and [l](http://x/\"y\").\n\n" +
+				"A 5\" screen, „open\nacross“ lines.\n\n" +
+				"```\n\"code\"\n```\n",
+		},
+		{
+			style: "fr",
+			expected: "He said «~it's “fine” now~» and «~left~».\n\n" +
+				"«~Bonjour~» dit-il, `\"x\"`, $a\"b$ and [l](http://x/\"y\").\n\n" +
+				"A 5\" screen, «~open\nacross~» lines.\n\n" +
+				"```\n\"code\"\n```\n",
+		},
+		{
+			style: "en",
+			expected: "He said “it's ‘fine’ now” and “left”.\n\n" +
+				"“Bonjour” dit-il, `\"x\"`, $a\"b$ and [l](http://x/\"y\").\n\n" +
+				"A 5\" screen, “open\nacross” lines.\n\n" +
+				"```\n\"code\"\n```\n",
+		},
+		{
+			style: "ascii",
+			expected: "He said \"it's 'fine' now\" and \"left\".\n\n" +
+				"\"Bonjour\" dit-il, `\"x\"`, $a\"b$ and [l](http://x/\"y\").\n\n" +
+				"A 5\" screen, \"open\nacross\" lines.\n\n" +
+				"```\n\"code\"\n```\n",
+		},
+		{
+			style:    "",
+			expected: input,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			rule := NewQuoteStyleRule(tt.style, '\u00a0')
+			expected := strings.ReplaceAll(tt.expected, "~", "\u00a0")
+			got, err := rule.Apply(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+			}
+			if again, _ := rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
+
+func TestQuoteStyleNesting(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "double inside double",
+			input:    `She wrote "he said "no" twice" here.`,
+			expected: "She wrote „he said ‚no‘ twice“ here.",
+		},
+		{
+			name:     "third level is primary again",
+			input:    `"a 'b "c" b' a"`,
+			expected: "„a ‚b „c“ b‘ a“",
+		},
+		{
+			name:     "apostrophes outside quotes",
+			input:    "the students' books, 'tis and rock 'n' roll",
+			expected: "the students' books, 'tis and rock 'n' roll",
+		},
+		{
+			name:     "unmatched quotes",
+			input:    "He said \"hello\n\nand left.",
+			expected: "He said \"hello\n\nand left.",
+		},
+		{
+			name:     "headings end quotations",
+			input:    "# \"Title\n\ntext\"",
+			expected: "# \"Title\n\ntext\"",
+		},
+		{
+			name:     "front matter",
+			input:    "---\ntitle: \"x\"\n---\n\"y\"",
+			expected: "---\ntitle: \"x\"\n---\n„y“",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewQuoteStyleRule("de", '\u00a0').Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
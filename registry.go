@@ -0,0 +1,721 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ruleSpec describes a built-in rule and how to build it from Options.
+type ruleSpec struct {
+	name string
+	// optIn rules only run when enabled explicitly.
+	optIn bool
+	// required rules always run: the rest of the pipeline depends on them.
+	required bool
+	// rendering rules change how the document renders on purpose, so
+	// -verify does not hold them to the HTML of their input.
+	rendering bool
+	// options lists the option keys the rule reads.
+	options []string
+	// description says in a line what the rule does, and example is a
+	// small document it changes or reports on when run with
+	// exampleOptions, for -list-rules.
+	description    string
+	example        string
+	exampleOptions Options
+	build          func(Options) (Rule, error)
+}
+
+// builtinRules lists every built-in rule in pipeline order.
+var builtinRules = []ruleSpec{
+	{
+		name:        "UnterminatedFence",
+		description: "reports code fences that are never closed, or closes them with unterminated-fence=fix",
+		example:     "```go\nfmt.Println()\n",
+		required:    true,
+		options:     []string{"unterminated-fence"},
+		build:       newUnterminatedFenceRuleFromOptions,
+	},
+	{
+		name:           "ExpandTabs",
+		description:    "replaces the tabs indenting lines with spaces, with tabs=expand",
+		example:        "- one\n\t- two\n",
+		exampleOptions: Options{"tabs": "expand"},
+		options:        []string{"tabs", "tab-width"},
+		build:          newExpandTabsRuleFromOptions,
+	},
+	{
+		name:        "TitleBlockToFrontMatter",
+		description: "turns a Pandoc title block of % lines into YAML front matter",
+		example:     "% Title\n% Author\n\nText\n",
+		optIn:       true,
+		build:       func(Options) (Rule, error) { return NewTitleBlockToFrontMatterRule(), nil },
+	},
+	{
+		name:        "FrontMatterTemplate",
+		description: "gives documents without front matter a block from front-matter-template",
+		example:     "# Notes\n",
+		optIn:       true,
+		options:     []string{"front-matter-template"},
+		build:       newFrontMatterTemplateRuleFromOptions,
+	},
+	{
+		name:        "FrontMatter",
+		description: "reports front matter that does not parse or lacks the front-matter-required keys",
+		example:     "---\ntitle: [oops\n---\n",
+		options:     []string{"front-matter-required"},
+		build:       newFrontMatterRuleFromOptions,
+	},
+	{
+		name:        "FrontMatterKeys",
+		description: "sorts the keys of the front matter, the front-matter-order ones first",
+		example:     "---\ntags: [go]\nauthor: Ann\ntitle: Notes\n---\n",
+		optIn:       true,
+		options:     []string{"front-matter-order"},
+		build:       newFrontMatterKeysRuleFromOptions,
+	},
+	{
+		name:        "TitleHeading",
+		description: "keeps the front matter title and the leading level 1 heading in agreement",
+		example:     "---\ntitle: Notes\n---\n\n# Other\n",
+		rendering:   true,
+		options:     []string{"title-policy", "title-match"},
+		build:       newTitleHeadingRuleFromOptions,
+	},
+	{
+		name:        "StripHTMLComments",
+		description: "removes HTML comments, except mdfmt directives",
+		example:     "Text<!-- draft --> here\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"html-comment-keep"},
+		build:       newStripHTMLCommentsRuleFromOptions,
+	},
+	{
+		name:        "HTMLLinks",
+		description: "rewrites plain <a> and <img> tags as Markdown links and images",
+		example:     "See <a href=\"https://go.dev\">Go</a>.\n",
+		optIn:       true,
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewHTMLLinksRule(), nil },
+	},
+	{
+		name:        "BlockquoteMarker",
+		description: "writes blockquote markers as \"> \"",
+		example:     ">Quote\n>>Nested\n",
+		build:       func(Options) (Rule, error) { return NewBlockquoteMarkerRule(), nil },
+	},
+	{
+		name:        "Callout",
+		description: "writes callout types such as [!NOTE] in one case and reports unknown ones",
+		example:     "> [!note]\n> Text\n",
+		rendering:   true,
+		options:     []string{"callout-case", "callout-types"},
+		build:       newCalloutRuleFromOptions,
+	},
+	{
+		name:        "ExplicitBlockquoteContinuation",
+		description: "adds the > markers that lazy continuation lines of a blockquote leave out",
+		example:     "> One\ntwo\n",
+		optIn:       true,
+		build:       func(Options) (Rule, error) { return NewExplicitBlockquoteContinuationRule(), nil },
+	},
+	{
+		name:        "BlankLinesAroundBlockquotes",
+		description: "puts a blank line before and after blockquotes",
+		example:     "Text\n> Quote\n",
+		build:       func(Options) (Rule, error) { return NewBlankLinesAroundBlockquotesRule(), nil },
+	},
+	{
+		name:        "IndentedCodeToFenced",
+		description: "rewrites indented code blocks as fenced ones",
+		example:     "Text\n\n    code\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"indented-code-language"},
+		build: func(o Options) (Rule, error) {
+			return NewIndentedCodeToFencedRule(o.Get("indented-code-language", "text")), nil
+		},
+	},
+	{
+		name:        "BlankLinesAroundThematicBreaks",
+		description: "puts a blank line before and after thematic breaks",
+		example:     "One\n***\nTwo\n",
+		build:       func(Options) (Rule, error) { return NewBlankLinesAroundThematicBreaksRule(), nil },
+	},
+	{
+		name:        "ThematicBreakStyle",
+		description: "writes every thematic break as ---",
+		example:     "One\n\n***\n\nTwo\n",
+		options:     []string{"thematic-break"},
+		build:       newThematicBreakStyleRuleFromOptions,
+	},
+	{
+		// before the blank line rules, which then space the headings it
+		// makes
+		name:        "EmphasisAsHeading",
+		description: "reports paragraphs of a single bold phrase used as a heading, or makes them headings",
+		example:     "**Usage**\n\nText\n",
+		rendering:   true,
+		options:     []string{"emphasis-heading", "emphasis-heading-level", "emphasis-heading-max-length"},
+		build:       newEmphasisAsHeadingRuleFromOptions,
+	},
+	{
+		name:        "EmptyHeading",
+		description: "reports headings without text, or deletes them with empty-heading=fix",
+		example:     "##\n\nText\n",
+		rendering:   true,
+		options:     []string{"empty-heading"},
+		build:       newEmptyHeadingRuleFromOptions,
+	},
+	{
+		name:        "HeadingAnchors",
+		description: "puts an <a id> anchor line next to each heading",
+		example:     "# Getting started\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"heading-anchors", "slug-style"},
+		build:       newHeadingAnchorsRuleFromOptions,
+	},
+	{
+		name:        "BlankLineAfterHeading",
+		description: "puts a blank line after headings",
+		example:     "# Title\nText\n",
+		options:     []string{"heading-blank-line-exempt"},
+		build:       newBlankLineAfterHeadingRuleFromOptions,
+	},
+	{
+		name:        "BlankLineBeforeTable",
+		description: "puts a blank line before tables",
+		example:     "Text\n| a | b |\n| - | - |\n",
+		build:       func(Options) (Rule, error) { return NewBlankLineBeforeTableRule(), nil },
+	},
+	{
+		name:        "DefinitionList",
+		description: "writes the markers of Pandoc definition lists as \":   \"",
+		example:     "Term\n:  Definition\n",
+		optIn:       true,
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewDefinitionListRule(), nil },
+	},
+	{
+		name:        "InlineMathToDollar",
+		description: "rewrites \\(...\\) inline math as $...$",
+		example:     "See \\(x^2\\).\n",
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewInlineMathReplaceRule(), nil },
+	},
+	{
+		name:        "SingleSpaceAfterEnumeration",
+		description: "puts one space after the markers of ordered list items",
+		example:     "1.   One\n2.  Two\n",
+		build:       func(Options) (Rule, error) { return NewSingleSpaceAfterEnumerationRule(), nil },
+	},
+	{
+		name:        "SingleSpaceAfterListItem",
+		description: "puts one space after the markers of bullet list items",
+		example:     "-   One\n*  Two\n",
+		build:       func(Options) (Rule, error) { return NewSingleSpaceAfterListItemRule(), nil },
+	},
+	{
+		name:        "HeadingIndent",
+		description: "removes the indentation before headings",
+		example:     "  # Title\n",
+		build:       func(Options) (Rule, error) { return NewHeadingIndentRule(), nil },
+	},
+	{
+		name:        "TodoTask",
+		description: "turns TODO lines and list items into unchecked task items",
+		example:     "- TODO: write docs\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"todo-markers", "todo-marker"},
+		build:       newTodoTaskRuleFromOptions,
+	},
+	{
+		name:        "SortList",
+		description: "sorts the lists between <!-- mdfmt:sort --> and <!-- mdfmt:endsort -->",
+		example:     "<!-- mdfmt:sort -->\n- pear\n- apple\n<!-- mdfmt:endsort -->\n",
+		rendering:   true,
+		options:     []string{"sort-numeric"},
+		build:       newSortListRuleFromOptions,
+	},
+	{
+		name:        "Mojibake",
+		description: "repairs UTF-8 text that was read as Windows-1252",
+		example:     "Itâ€™s here\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"mojibake"},
+		build:       newMojibakeRuleFromOptions,
+	},
+	{
+		name:        "DecodeEntities",
+		description: "replaces HTML entities in prose with the characters they stand for",
+		example:     "&copy; 2024 &mdash; Ann\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"entity-keep"},
+		build:       newDecodeEntitiesRuleFromOptions,
+	},
+	{
+		name:           "ListPunctuation",
+		description:    "drops or adds the period ending list items, with list-punctuation",
+		example:        "- One.\n- Two.\n",
+		exampleOptions: Options{"list-punctuation": "strip"},
+		rendering:      true,
+		options:        []string{"list-punctuation"},
+		build:          newListPunctuationRuleFromOptions,
+	},
+	{
+		name:        "Capitalize",
+		description: "uppercases the first letter of list items and table header cells",
+		example:     "- first item\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"capitalize-exempt"},
+		build:       newCapitalizeRuleFromOptions,
+	},
+	{
+		name:        "SmartQuotesToAscii",
+		description: "replaces typographic quotation marks with ASCII ones",
+		example:     "„Hallo“\n",
+		rendering:   true,
+		options:     []string{"replacements"},
+		build: func(o Options) (Rule, error) {
+			replacements, err := o.Map("replacements", map[string]string{
+				"„": `"`,
+				"“": `"`,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return NewReplacementRule("SmartQuotesToAscii", replacements), nil
+		},
+	},
+	{
+		name:           "QuoteStyle",
+		description:    "writes paired quotation marks in the quotes style",
+		example:        "She said \"hi\".\n",
+		exampleOptions: Options{"quotes": "en"},
+		rendering:      true,
+		options:        []string{"quotes", "french-space"},
+		build:          newQuoteStyleRuleFromOptions,
+	},
+	{
+		name:           "DashStyle",
+		description:    "writes dashes as en and em dashes or as ASCII, with dashes",
+		example:        "Wait -- really.\n",
+		exampleOptions: Options{"dashes": "smart"},
+		rendering:      true,
+		options:        []string{"dashes", "dash-ranges"},
+		build:          newDashStyleRuleFromOptions,
+	},
+	{
+		name:           "Ellipsis",
+		description:    "writes ellipses as ... or as …, with ellipsis",
+		example:        "Wait...\n",
+		exampleOptions: Options{"ellipsis": "unicode"},
+		rendering:      true,
+		options:        []string{"ellipsis"},
+		build:          newEllipsisRuleFromOptions,
+	},
+	{
+		name:           "Apostrophe",
+		description:    "writes apostrophes as ' or as ’, with apostrophe",
+		example:        "It’s done\n",
+		exampleOptions: Options{"apostrophe": "ascii"},
+		rendering:      true,
+		options:        []string{"apostrophe"},
+		build:          newApostropheRuleFromOptions,
+	},
+	{
+		name:           "Emoji",
+		description:    "writes emoji as shortcodes or as Unicode, with emoji, and reports unknown shortcodes",
+		example:        "Done :tada:\n",
+		exampleOptions: Options{"emoji": "unicode"},
+		rendering:      true,
+		options:        []string{"emoji"},
+		build:          newEmojiRuleFromOptions,
+	},
+	{
+		name:        "CJKSpacing",
+		description: "puts a space between CJK characters and Latin letters or digits",
+		example:     "使用Go编写\n",
+		optIn:       true,
+		rendering:   true,
+		options:     []string{"cjk-spacing"},
+		build:       newCJKSpacingRuleFromOptions,
+	},
+	{
+		name:           "FrenchSpacing",
+		description:    "puts the no-break space French typography asks for before : ; ! ? and inside guillemets",
+		example:        "Bonjour !\n",
+		exampleOptions: Options{"lang": "fr"},
+		optIn:          true,
+		rendering:      true,
+		options:        []string{"lang", "french-space"},
+		build:          newFrenchSpacingRuleFromOptions,
+	},
+	{
+		name:        "FenceStyle",
+		description: "writes code fences with backticks, or tildes with fence=tilde",
+		example:     "~~~go\nx := 1\n~~~\n",
+		options:     []string{"fence"},
+		build:       newFenceStyleRuleFromOptions,
+	},
+	{
+		name:        "FenceInfoString",
+		description: "trims fence info strings and lowercases and resolves their language",
+		example:     "```  Go\nx := 1\n```\n",
+		rendering:   true,
+		options:     []string{"code-language-aliases"},
+		build:       newFenceInfoStringRuleFromOptions,
+	},
+	{
+		name:        "TrimFencedCode",
+		description: "removes blank lines before closing code fences",
+		example:     "```go\nx := 1\n\n```\n",
+		rendering:   true,
+		options:     []string{"code-trim-leading"},
+		build:       newTrimFencedCodeRuleFromOptions,
+	},
+	{
+		name:        "BlankLinesAroundFences",
+		description: "puts a blank line before and after fenced code blocks",
+		example:     "Text\n```go\nx := 1\n```\n",
+		build:       func(Options) (Rule, error) { return NewBlankLinesAroundFencesRule(), nil },
+	},
+	{
+		name:        "BlankLinesAroundDivs",
+		description: "puts a blank line before and after Pandoc fenced divs",
+		example:     "Text\n::: note\nHi\n:::\n",
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewBlankLinesAroundDivsRule(), nil },
+	},
+	{
+		name:        "InlineFootnote",
+		description: "turns ^[inline footnotes] into numbered references and definitions",
+		example:     "Text.^[A note.]\n",
+		optIn:       true,
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewInlineFootnoteRule(), nil },
+	},
+	{
+		name:        "FootnoteNumbering",
+		description: "numbers footnotes in the order they are first referenced",
+		example:     "A[^2] b[^1].\n\n[^1]: One.\n[^2]: Two.\n",
+		build:       func(Options) (Rule, error) { return NewFootnoteNumberingRule(), nil },
+	},
+	{
+		name:           "LinkDefinitions",
+		description:    "moves link reference definitions to definition-placement",
+		example:        "[Go][go]\n\n[go]: https://go.dev\n\nMore text\n",
+		exampleOptions: Options{"definition-placement": "end-of-file"},
+		rendering:      true,
+		options:        []string{"definition-placement"},
+		build:          newLinkDefinitionsRuleFromOptions,
+	},
+	{
+		name:        "Abbreviations",
+		description: "moves abbreviation definitions to the end of the document",
+		example:     "*[HTML]: HyperText Markup Language\n\nHTML is fun.\n",
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewAbbreviationsRule(), nil },
+	},
+	{
+		name:        "FootnoteReferences",
+		description: "reports footnote references without a definition and definitions never referenced",
+		example:     "Text[^1].\n\n[^2]: Unused.\n",
+		build:       func(Options) (Rule, error) { return NewFootnoteReferencesRule(), nil },
+	},
+	{
+		name:        "InlineHTML",
+		description: "reports HTML tags other than the allowed-tags",
+		example:     "Some <b>bold</b> text\n",
+		options:     []string{"allowed-tags"},
+		build:       newInlineHTMLRuleFromOptions,
+	},
+	{
+		name:        "ReversedLink",
+		description: "repairs links written (text)[url]",
+		example:     "(Go)[https://go.dev]\n",
+		rendering:   true,
+		options:     []string{"reversed-link"},
+		build:       newReversedLinkRuleFromOptions,
+	},
+	{
+		name:        "BareURL",
+		description: "reports bare URLs, or wraps them in angle brackets with bare-url=fix",
+		example:     "See https://go.dev\n",
+		rendering:   true,
+		options:     []string{"bare-url"},
+		build:       newBareURLRuleFromOptions,
+	},
+	{
+		name:           "Terminology",
+		description:    "reports or replaces the terms of terminology-words",
+		example:        "Written in golang\n",
+		exampleOptions: Options{"terminology-words": "golang:Go"},
+		rendering:      true,
+		options:        []string{"terminology", "terminology-file", "terminology-words"},
+		build:          newTerminologyRuleFromOptions,
+	},
+	{
+		name:        "RepeatedWords",
+		description: "reports words repeated right after themselves, or deletes the repetition",
+		example:     "It is the the end\n",
+		rendering:   true,
+		options:     []string{"repeated-words", "repeated-words-allow"},
+		build:       newRepeatedWordsRuleFromOptions,
+	},
+	{
+		name:           "RequiredSections",
+		description:    "reports the required-sections no heading of the document matches",
+		example:        "# Tool\n\n## Usage\n",
+		exampleOptions: Options{"required-sections": "Usage,License"},
+		options:        []string{"required-sections", "required-sections-level", "required-sections-paths"},
+		build:          newRequiredSectionsRuleFromOptions,
+	},
+	{
+		name:        "KeepAChangelog",
+		description: "holds changelogs to the Keep a Changelog format",
+		example:     "# Changelog\n\n## [1.0.0] - 2024/05/01\n\n[1.0.0]: https://example.com\n",
+		optIn:       true,
+		rendering:   true,
+		build:       func(Options) (Rule, error) { return NewKeepAChangelogRule(), nil },
+	},
+	{
+		name:           "LineLength",
+		description:    "reports lines longer than max-line-length",
+		example:        "A line that goes on and on.\n",
+		exampleOptions: Options{"max-line-length": "20"},
+		options:        []string{"max-line-length", "line-length-ignore"},
+		build:          newLineLengthRuleFromOptions,
+	},
+	{
+		name:        "FencedCodeLanguage",
+		description: "reports code fences without a language, or adds one",
+		example:     "```\nx := 1\n```\n",
+		rendering:   true,
+		options:     []string{"code-language", "code-language-default", "code-language-guess"},
+		build:       newFencedCodeLanguageRuleFromOptions,
+	},
+	{
+		name:           "TrailingWhitespace",
+		description:    "removes spaces at the end of lines, with trailing-whitespace=trim",
+		example:        "Text   \n",
+		exampleOptions: Options{"trailing-whitespace": "trim"},
+		options:        []string{"trailing-whitespace"},
+		build:          newTrailingWhitespaceRuleFromOptions,
+	},
+	{
+		name:        "FinalNewline",
+		description: "ends documents with exactly one line break",
+		example:     "Text",
+		options:     []string{"final-newline"},
+		build:       newFinalNewlineRuleFromOptions,
+	},
+	{
+		name:           "EndOfLine",
+		description:    "writes line breaks as end-of-line says",
+		example:        "One\r\nTwo\n",
+		exampleOptions: Options{"end-of-line": "lf"},
+		options:        []string{"end-of-line"},
+		build:          newEndOfLineRuleFromOptions,
+	},
+}
+
+// newFormatterFromOptions builds the Formatter for the default built-in
+// rules plus the opt-in rules named in enable, minus those named in
+// disable, rejecting option keys that no rule understands.
+func newFormatterFromOptions(opts Options, enable, disable []string) (*Formatter, error) {
+	names, err := resolveRuleNames(enable)
+	if err != nil {
+		return nil, err
+	}
+	enabled := map[string]bool{}
+	for _, name := range names {
+		enabled[name] = true
+	}
+	if names, err = resolveRuleNames(disable); err != nil {
+		return nil, err
+	}
+	disabled := map[string]bool{}
+	for _, name := range names {
+		if isRequiredRule(name) {
+			return nil, fmt.Errorf("rule %q cannot be disabled", name)
+		}
+		disabled[name] = true
+	}
+
+	var unknown []string
+	for key := range opts {
+		if !isKnownOption(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown option(s): %s", strings.Join(unknown, ", "))
+	}
+
+	rules := make([]Rule, 0, len(builtinRules))
+	for _, spec := range builtinRules {
+		if (spec.optIn && !enabled[spec.name]) || disabled[spec.name] {
+			continue
+		}
+		r, err := spec.build(opts)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", spec.name, err)
+		}
+		rules = append(rules, r)
+	}
+	l, err := limitsFromOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Formatter{rules: rules, limits: l}, nil
+}
+
+// newFormatterFromSettings builds the Formatter for settings. Without a
+// rules list it is newFormatterFromOptions's; with one, the rules listed
+// run in their order, after the required rules and before any others
+// enabled, and the rest of the built-in rules do not run.
+func newFormatterFromSettings(s configSettings) (*Formatter, error) {
+	if s.rules == nil {
+		return newFormatterFromOptions(s.options, s.enable, s.disable)
+	}
+	order, err := resolveRuleNames(s.rules)
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := resolveRuleNames(s.enable)
+	if err != nil {
+		return nil, err
+	}
+	rank := map[string]int{}
+	for i, name := range order {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i + 1
+		}
+	}
+	disable := s.disable
+	for _, spec := range builtinRules {
+		switch _, listed := rank[spec.name]; {
+		case spec.required:
+			rank[spec.name] = 0
+		case !listed && !containsString(enabled, spec.name):
+			disable = append(disable, spec.name)
+		}
+	}
+	f, err := newFormatterFromOptions(s.options, append(order, enabled...), disable)
+	if err != nil {
+		return nil, err
+	}
+	// rules enabled besides the list keep their pipeline order after it
+	position := func(r Rule) int {
+		if n, ok := rank[r.Name()]; ok {
+			return n
+		}
+		return len(order) + 1
+	}
+	sort.SliceStable(f.rules, func(i, j int) bool { return position(f.rules[i]) < position(f.rules[j]) })
+	return f, nil
+}
+
+// resolveRuleNames checks that names are built-in rules, in any case,
+// returning them as the rules spell them and expanding markdownlint codes
+// such as MD040 to the rules that carry them.
+func resolveRuleNames(names []string) ([]string, error) {
+	var out []string
+	for _, name := range names {
+		switch {
+		case builtinRuleName(name) != "":
+			out = append(out, builtinRuleName(name))
+		case len(rulesForCode(name)) > 0:
+			out = append(out, rulesForCode(name)...)
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return out, nil
+}
+
+// builtinRuleNames returns the names of the built-in rules in pipeline
+// order.
+func builtinRuleNames() []string {
+	names := make([]string, len(builtinRules))
+	for i, spec := range builtinRules {
+		names[i] = spec.name
+	}
+	return names
+}
+
+// builtinRuleName returns the name of the built-in rule called name, with
+// the case ignored, or "".
+func builtinRuleName(name string) string {
+	for _, spec := range builtinRules {
+		if strings.EqualFold(spec.name, name) {
+			return spec.name
+		}
+	}
+	return ""
+}
+
+// ruleDescription returns the description of the built-in rule name, ""
+// for other rules.
+func ruleDescription(name string) string {
+	for _, spec := range builtinRules {
+		if spec.name == name {
+			return spec.description
+		}
+	}
+	return ""
+}
+
+func isBuiltinRule(name string) bool {
+	for _, spec := range builtinRules {
+		if spec.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isRequiredRule(name string) bool {
+	for _, spec := range builtinRules {
+		if spec.name == name {
+			return spec.required
+		}
+	}
+	return false
+}
+
+// changesRendering reports whether the named rule changes how the
+// document renders on purpose.
+func changesRendering(name string) bool {
+	for _, spec := range builtinRules {
+		if spec.name == name {
+			return spec.rendering
+		}
+	}
+	return false
+}
+
+// isKnownOption reports whether some built-in rule, or the Formatter's
+// limits, read key.
+func isKnownOption(key string) bool {
+	if containsString(limitOptions, key) {
+		return true
+	}
+	for _, spec := range builtinRules {
+		for _, k := range spec.options {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 48: report words written twice in a row
+// ----------------------------------------------------------------
+
+// proseWord matches a word, apostrophes and hyphens included.
+var proseWord = regexp.MustCompile(`\p{L}+(?:['’-]\p{L}+)*`)
+
+// defaultRepeatedWordsAllowed are words that are often correctly repeated.
+var defaultRepeatedWordsAllowed = []string{"had", "that"}
+
+type RepeatedWordsRule struct {
+	// fix deletes the repetition instead of only reporting it.
+	fix   bool
+	allow map[string]bool
+}
+
+// NewRepeatedWordsRule constructs a rule that reports a word repeated
+// right after itself, regardless of case, across a space or the end of a
+// line within a paragraph, except the words in allow. With fix it
+// deletes the second occurrence.
+func NewRepeatedWordsRule(fix bool, allow ...string) Rule {
+	r := RepeatedWordsRule{fix: fix, allow: map[string]bool{}}
+	for _, w := range allow {
+		r.allow[strings.ToLower(w)] = true
+	}
+	return r
+}
+
+func newRepeatedWordsRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("repeated-words", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	return NewRepeatedWordsRule(mode == "fix", o.List("repeated-words-allow", defaultRepeatedWordsAllowed)...), nil
+}
+
+func (RepeatedWordsRule) Name() string {
+	return "RepeatedWords"
+}
+
+// repetition is the second occurrence of a repeated word and the text to
+// delete with it.
+type repetition struct {
+	word       string
+	line       int
+	start, end int // the word
+	cut        [2]int
+}
+
+// repetitions finds the repeated words of a document.
+func (r RepeatedWordsRule) repetitions(lines []string) []repetition {
+	b := classifyBlocks(lines)
+	type word struct {
+		text      string
+		line, end int
+	}
+	var found []repetition
+	var prev *word
+	for i, line := range lines {
+		switch {
+		case literalLine(b, i), b.lines[i].kind != lineText, isATXHeading(line):
+			// paragraphs end here; tables repeat cells on purpose
+			prev = nil
+			continue
+		}
+		if prev != nil && prev.end != len(strings.TrimRight(lines[prev.line], " \t")) {
+			prev = nil
+		}
+		covered := nonProseSpans(line)
+		for _, m := range proseWord.FindAllStringIndex(line, -1) {
+			if inSpans(covered, m[0]) {
+				prev = nil
+				continue
+			}
+			w := &word{strings.ToLower(line[m[0]:m[1]]), i, m[1]}
+			if prev != nil && prev.text == w.text && !r.allow[w.text] {
+				rep := repetition{word: line[m[0]:m[1]], line: i, start: m[0], end: m[1]}
+				switch {
+				case prev.line == i && line[prev.end:m[0]] == " ":
+					rep.cut = [2]int{prev.end, m[1]}
+				case prev.line != i && strings.TrimSpace(line[:m[0]]) == strings.TrimSpace(line[:b.lines[i].bodyStart]):
+					// the second word starts the line
+					end := m[1]
+					for end < len(line) && line[end] == ' ' {
+						end++
+					}
+					rep.cut = [2]int{m[0], end}
+					if strings.TrimSpace(line[:m[0]]+line[end:]) == strings.TrimSpace(line[:b.lines[i].bodyStart]) {
+						// deleting it would end the paragraph
+						rep.cut = [2]int{m[0], m[0]}
+					}
+				default:
+					rep.line = -1
+				}
+				if rep.line >= 0 {
+					found = append(found, rep)
+				}
+			}
+			prev = w
+		}
+	}
+	return found
+}
+
+func (r RepeatedWordsRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	reps := r.repetitions(lines)
+	sort.SliceStable(reps, func(a, b int) bool { return reps[a].start > reps[b].start })
+	for _, rep := range reps {
+		line := lines[rep.line]
+		lines[rep.line] = line[:rep.cut[0]] + line[rep.cut[1]:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r RepeatedWordsRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	var diags []Diagnostic
+	for _, rep := range r.repetitions(lines) {
+		line := lines[rep.line]
+		d := Diagnostic{
+			Line:    rep.line + 1,
+			Column:  utf8.RuneCountInString(line[:rep.start]) + 1,
+			Rule:    r.Name(),
+			Key:     strings.ToLower(rep.word),
+			Message: fmt.Sprintf("repeated word %q", rep.word),
+		}
+		if rep.cut[0] < rep.cut[1] {
+			d.Fix = lineFix(line, rep.cut[0], rep.cut[1], "")
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRepeatedWordsLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "same line",
+			input: "This is is the the case, The the end.",
+			want: []string{
+				`1:9: warning: RepeatedWords: repeated word "is"`,
+				`1:16: warning: RepeatedWords: repeated word "the"`,
+				`1:30: warning: RepeatedWords: repeated word "the"`,
+			},
+		},
+		{
+			name:  "across a line break",
+			input: "> It was the\n> the best.\n\n- one of the\n  the items",
+			want: []string{
+				`2:3: warning: RepeatedWords: repeated word "the"`,
+				`5:3: warning: RepeatedWords: repeated word "the"`,
+			},
+		},
+		{
+			name:  "allowed",
+			input: "He had had enough, and that that was it.",
+		},
+		{
+			name:  "new sentences and paragraphs",
+			input: "Go. Go now, or\n\nor not. It was so\nso-so, then: then",
+		},
+		{
+			name:  "code and tables",
+			input: "Run `go` go and `x x`.\n\n```\nthe the\n```\n\n| a | a |\n| - | - |\n| b | b |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range NewRepeatedWordsRule(false, defaultRepeatedWordsAllowed...).(Linter).Lint(tt.input) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepeatedWordsFix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "same line",
+			input:    "This is is the the the case.",
+			expected: "This is the case.",
+		},
+		{
+			name:     "across a line break",
+			input:    "It was the\nthe best of\nof times.",
+			expected: "It was the\nbest of\ntimes.",
+		},
+		{
+			name:     "a line of its own",
+			input:    "It was the\nthe\nbest.",
+			expected: "It was the\nthe\nbest.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRepeatedWordsRule(true).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+	// lint only by default
+	if got, _ := NewRepeatedWordsRule(false).Apply("the the"); got != "the the" {
+		t.Errorf("lint mode changed the document: %q", got)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// JSON results (-format json, -format ndjson)
+// ----------------------------------------------------------------
+
+// FileResult is what -format json and ndjson report on a document: json
+// prints a JSON array of them, one for each file in the order of the
+// arguments, and ndjson one per line. Fields are only ever added.
+type FileResult struct {
+	// Path is the file, "" for stdin without -stdin-filepath.
+	Path string `json:"path"`
+	// NeedsFormatting is whether formatting changes the document.
+	NeedsFormatting bool `json:"needs_formatting"`
+	// Rules are the names of the rules that change the document, in the
+	// order they run.
+	Rules       []string         `json:"rules"`
+	Diagnostics []JSONDiagnostic `json:"diagnostics"`
+	// Error is why the document could not be read or formatted; the
+	// other fields are empty then.
+	Error *FileError `json:"error,omitempty"`
+}
+
+// JSONDiagnostic is a Diagnostic in a FileResult.
+type JSONDiagnostic struct {
+	Line int `json:"line"`
+	// Column is 0 when the finding concerns the whole line.
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule"`
+	Code     string `json:"code,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// FileError is the error of a FileResult.
+type FileError struct {
+	// Rule is the Name of the rule that failed, if a rule did.
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// reportsResults reports whether format reports the rules that change
+// each document, as FileResults or SARIF results, which, unlike the other
+// reports on stdout, can cover several files.
+func reportsResults(format string) bool {
+	return format == "json" || format == "ndjson" || format == "sarif"
+}
+
+// reportArray returns the array that puts the results of all documents
+// together in format, nil for ndjson, whose results stand alone.
+func reportArray(w io.Writer, format string) (*jsonArray, error) {
+	switch format {
+	case "json":
+		return &jsonArray{w: w, tail: "\n"}, nil
+	case "sarif":
+		return sarifArray(w)
+	}
+	return nil, nil
+}
+
+// ruleChange is a rule changing a document: how many lines it changed,
+// the first of them at line, counted from 1, of the original document.
+type ruleChange struct {
+	rule        string
+	line, lines int
+}
+
+// traceChanges returns a Formatter trace that appends to *changes the
+// rules that change content as it is formatted. The lines a rule changes
+// are mapped back from the document the rule sees to content, as the
+// findings of writeGitLab are.
+func traceChanges(content string, changes *[]ruleChange) func(rule, before, after string) {
+	return func(rule, before, after string) {
+		if before == after {
+			return
+		}
+		edits := lineEdits(before, after)
+		line := min(edits[0].StartLine+1, strings.Count(before, "\n")+1)
+		if before != content {
+			line = originLine(NewLineMap(strings.Count(before, "\n")+1, lineEdits(before, content)), line)
+		}
+		*changes = append(*changes, ruleChange{rule: rule, line: line, lines: changedLines(before, after)})
+	}
+}
+
+// joinTraces returns a Formatter trace that calls each of traces that is
+// not nil, or nil when none is.
+func joinTraces(traces ...func(rule, before, after string)) func(rule, before, after string) {
+	var set []func(rule, before, after string)
+	for _, t := range traces {
+		if t != nil {
+			set = append(set, t)
+		}
+	}
+	switch len(set) {
+	case 0:
+		return nil
+	case 1:
+		return set[0]
+	}
+	return func(rule, before, after string) {
+		for _, t := range set {
+			t(rule, before, after)
+		}
+	}
+}
+
+// changedRules returns the names of the rules of changes, each once.
+func changedRules(changes []ruleChange) []string {
+	var rules []string
+	for _, c := range changes {
+		if !containsString(rules, c.rule) {
+			rules = append(rules, c.rule)
+		}
+	}
+	return rules
+}
+
+// newFileResult returns the result for the document at path that
+// formatting changed, by the given rules, to out with the findings diags,
+// or that failed with err.
+func newFileResult(path, in, out string, rules []string, diags []Diagnostic, err error) FileResult {
+	res := FileResult{Path: path, Rules: []string{}, Diagnostics: []JSONDiagnostic{}}
+	if err != nil {
+		res.Error = &FileError{Message: err.Error()}
+		var rerr *RuleError
+		if errors.As(err, &rerr) {
+			res.Error.Rule = rerr.Rule
+		}
+		return res
+	}
+	res.NeedsFormatting = in != out
+	if rules != nil {
+		res.Rules = rules
+	}
+	for _, d := range diags {
+		res.Diagnostics = append(res.Diagnostics, JSONDiagnostic{
+			Line: d.Line, Column: d.Column, Rule: d.Rule, Code: d.Code,
+			Severity: d.Severity.String(), Message: d.Message,
+		})
+	}
+	return res
+}
+
+// writeFileResult writes res in format: a line of its own for ndjson, an
+// indented element of the array reportArray puts together for json.
+func writeFileResult(w io.Writer, format string, res FileResult) error {
+	var data []byte
+	var err error
+	if format == "ndjson" {
+		data, err = json.Marshal(res)
+	} else {
+		data, err = json.MarshalIndent(res, "  ", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		data = append([]byte("  "), data...)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// jsonArray joins the elements the documents of a run report into one
+// JSON array, written between head and tail.
+type jsonArray struct {
+	w          io.Writer
+	head, tail string
+	// indent is that of the closing bracket.
+	indent string
+	n      int
+}
+
+// add writes elems, one or more elements separated by commas and ending
+// in a newline, as a document reports them; empty ones are skipped.
+func (a *jsonArray) add(elems []byte) error {
+	if len(elems) == 0 {
+		return nil
+	}
+	sep := ",\n"
+	if a.n == 0 {
+		sep = a.head + "[\n"
+	}
+	a.n++
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	_, err := a.w.Write(elems[:len(elems)-1])
+	return err
+}
+
+// close ends the array.
+func (a *jsonArray) close() error {
+	end := "\n" + a.indent + "]" + a.tail
+	if a.n == 0 {
+		end = a.head + "[]" + a.tail
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
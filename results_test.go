@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// failingRule fails on every document.
+type failingRule struct{}
+
+func (failingRule) Name() string { return "Failing" }
+func (failingRule) Apply(string) (string, error) {
+	return "", errors.New("boom")
+}
+
+func TestFormatFilesResults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.md": "# Clean\n",
+		"dirty.md": "# Dirty\ntext\n",
+		"large.md": "# Larger than the limit\n",
+	})
+	clean, dirty := filepath.Join(dir, "clean.md"), filepath.Join(dir, "dirty.md")
+	large, missing := filepath.Join(dir, "large.md"), filepath.Join(dir, "missing.md")
+	paths := []string{clean, dirty, large, missing}
+
+	for _, format := range []string{"json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			run := runOptions{format: format, check: true, jobs: 2}
+			// only large.md is larger
+			run.opts = Options{"max-input-size": "20"}
+			var stdout, stderr strings.Builder
+			changed, err := run.formatFiles(&stdout, &stderr, paths)
+			if err == nil {
+				t.Fatal("expected an error for large.md and missing.md")
+			}
+			if changed != 1 {
+				t.Errorf("expected 1 changed file, got %d", changed)
+			}
+
+			var results []FileResult
+			if format == "json" {
+				if err := json.Unmarshal([]byte(stdout.String()), &results); err != nil {
+					t.Fatalf("not a JSON array: %v\n%s", err, stdout.String())
+				}
+			} else {
+				sc := bufio.NewScanner(strings.NewReader(stdout.String()))
+				for sc.Scan() {
+					var res FileResult
+					if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+						t.Fatalf("line %q: %v", sc.Text(), err)
+					}
+					results = append(results, res)
+				}
+			}
+			if len(results) != len(paths) {
+				t.Fatalf("expected %d results, got %d:\n%s", len(paths), len(results), stdout.String())
+			}
+			for i, res := range results {
+				if res.Path != paths[i] {
+					t.Errorf("result %d: expected path %q, got %q", i, paths[i], res.Path)
+				}
+			}
+
+			if res := results[0]; res.NeedsFormatting || len(res.Rules) != 0 || res.Error != nil {
+				t.Errorf("clean.md: expected a clean result, got %+v", res)
+			}
+			if res := results[1]; !res.NeedsFormatting || !reflect.DeepEqual(res.Rules, []string{"BlankLineAfterHeading"}) || res.Error != nil {
+				t.Errorf("dirty.md: expected it to need BlankLineAfterHeading, got %+v", res)
+			}
+			if res := results[2]; res.Error == nil || res.Error.Rule != "" || !strings.Contains(res.Error.Message, "max-input-size") {
+				t.Errorf("large.md: expected the max-input-size error, got %+v", res)
+			}
+			if res := results[3]; res.Error == nil || res.NeedsFormatting {
+				t.Errorf("missing.md: expected a read error, got %+v", res)
+			}
+		})
+	}
+}
+
+func TestFormatStdinResults(t *testing.T) {
+	run := runOptions{format: "json"}
+	var stdout, stderr strings.Builder
+	if _, err := run.formatStdin(&stdout, &stderr, "", "# A\n\nSee https://a.example\n"); err != nil {
+		t.Fatal(err)
+	}
+	var results []FileResult
+	if err := json.Unmarshal([]byte(stdout.String()), &results); err != nil {
+		t.Fatalf("not a JSON array: %v\n%s", err, stdout.String())
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	res := results[0]
+	if res.Path != "" || res.NeedsFormatting || len(res.Diagnostics) != 1 || res.Diagnostics[0].Rule != "BareURL" {
+		t.Errorf("expected a BareURL finding alone, got %+v", res)
+	}
+}
+
+func TestResultsInputLines(t *testing.T) {
+	// the fence is on line 3 of the document, on line 5 once formatted
+	in := "# T\nx\n```\ny\n```\n"
+	for _, tt := range []struct {
+		write bool
+		line  int
+	}{
+		{false, 3},
+		// the findings are on the file as written back
+		{true, 5},
+	} {
+		path := filepath.Join(t.TempDir(), "a.md")
+		if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run := runOptions{format: "json", write: tt.write}
+		var stdout, stderr strings.Builder
+		if _, err := run.formatFiles(&stdout, &stderr, []string{path}); err != nil {
+			t.Fatal(err)
+		}
+		var results []FileResult
+		if err := json.Unmarshal([]byte(stdout.String()), &results); err != nil {
+			t.Fatalf("not a JSON array: %v\n%s", err, stdout.String())
+		}
+		if len(results) != 1 || len(results[0].Diagnostics) != 1 {
+			t.Fatalf("expected one finding, got %+v", results)
+		}
+		if d := results[0].Diagnostics[0]; d.Rule != "FencedCodeLanguage" || d.Line != tt.line {
+			t.Errorf("write %t: expected FencedCodeLanguage on line %d, got %+v", tt.write, tt.line, d)
+		}
+	}
+}
+
+func TestFileResultRuleError(t *testing.T) {
+	_, err := NewFormatter(suffixRule{}, failingRule{}).Format("text\n")
+	if err == nil {
+		t.Fatal("expected Failing to fail")
+	}
+	res := newFileResult("a.md", "text\n", "", nil, nil, err)
+	expected := &FileError{Rule: "Failing", Message: `rule "Failing" failed: boom`}
+	if !reflect.DeepEqual(res.Error, expected) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, res.Error)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back FileResult
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, res) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", res, back)
+	}
+}
+
+func TestJSONArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		elems    []string
+		expected string
+	}{
+		{"none", nil, "[]\n"},
+		{"skipped", []string{""}, "[]\n"},
+		{"one", []string{"  1\n"}, "[\n  1\n]\n"},
+		{"several", []string{"  1\n", "", "  2\n"}, "[\n  1,\n  2\n]\n"},
+		{"several at once", []string{"  1,\n  2\n", "  3\n"}, "[\n  1,\n  2,\n  3\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			arr, err := reportArray(&sb, "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range tt.elems {
+				if err := arr.add([]byte(e)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := arr.close(); err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, sb.String())
+			}
+		})
+	}
+}
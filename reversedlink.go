@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 34: fix links written as (text)[url]
+// ----------------------------------------------------------------
+
+var (
+	// reversedLink matches "(text)[destination]" with plain text in the
+	// parentheses.
+	reversedLink = regexp.MustCompile(`\(([^()\[\]]*[^()\[\]\s][^()\[\]]*)\)\[([^\]\s]+)\]`)
+	// linkLike matches destinations that read as a URL or relative path:
+	// a scheme, a path separator, an anchor or a file extension.
+	linkLike = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9+.-]*:|#)|/|\.[a-zA-Z][a-zA-Z0-9]*(?:#.*)?$`)
+)
+
+type ReversedLinkRule struct {
+	// fix rewrites reversed links instead of only reporting them.
+	fix bool
+}
+
+func NewReversedLinkRule(fix bool) Rule { return ReversedLinkRule{fix: fix} }
+
+func newReversedLinkRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("reversed-link", "fix", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	return NewReversedLinkRule(mode == "fix"), nil
+}
+
+func (ReversedLinkRule) Name() string {
+	return "ReversedLink"
+}
+
+// reversedLinks returns the submatch indices of the reversed links in
+// line, leaving out code spans, Obsidian links, citations, footnote
+// references and destinations of real links.
+func reversedLinks(line string) [][]int {
+	if linkReferenceDefinition.MatchString(line) {
+		return nil
+	}
+	spans := protectedSpans(line)
+	var found [][]int
+next:
+	for _, m := range reversedLink.FindAllStringSubmatchIndex(line, -1) {
+		for _, sp := range spans {
+			if m[0] < sp[1] && sp[0] < m[1] {
+				continue next
+			}
+		}
+		dest := line[m[4]:m[5]]
+		// "[a](b)[c]" is a link followed by a bracket
+		if m[0] > 0 && line[m[0]-1] == ']' {
+			continue
+		}
+		if strings.HasPrefix(dest, "^") || !linkLike.MatchString(dest) {
+			continue
+		}
+		found = append(found, m)
+	}
+	return found
+}
+
+func (r ReversedLinkRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		found := reversedLinks(line)
+		for k := len(found) - 1; k >= 0; k-- {
+			m := found[k]
+			line = line[:m[0]] + fixedLink(line, m) + line[m[1]:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r ReversedLinkRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		for _, m := range reversedLinks(line) {
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:m[0]]) + 1,
+				Rule:    r.Name(),
+				Message: fmt.Sprintf("reversed link syntax %s; write %s", line[m[0]:m[1]], fixedLink(line, m)),
+				Fix:     lineFix(line, m[0], m[1], fixedLink(line, m)),
+			})
+		}
+	}
+	return diags
+}
+
+// fixedLink writes the reversed link at submatch indices m the right way
+// round.
+func fixedLink(line string, m []int) string {
+	return "[" + line[m[2]:m[3]] + "](" + line[m[4]:m[5]] + ")"
+}
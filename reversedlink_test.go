@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReversedLinkRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "url",
+			input:    "Please (click here)[https://example.com] now.",
+			expected: "Please [click here](https://example.com) now.",
+		},
+		{
+			name:     "relative paths and anchors",
+			input:    "(the guide)[docs/guide.md], (notes)[notes.md#setup], (top)[#intro]",
+			expected: "[the guide](docs/guide.md), [notes](notes.md#setup), [top](#intro)",
+		},
+		{
+			name:     "image",
+			input:    "!(logo)[img/logo.png]",
+			expected: "![logo](img/logo.png)",
+		},
+		{
+			name:     "prose and reference labels",
+			input:    "(see [docs]) and (a note)[docs] and (v)[1.2] and (x)[^1]",
+			expected: "(see [docs]) and (a note)[docs] and (v)[1.2] and (x)[^1]",
+		},
+		{
+			name:     "link followed by a bracket",
+			input:    "[a](b)[c/d]",
+			expected: "[a](b)[c/d]",
+		},
+		{
+			name:     "code",
+			input:    "`(x)[https://a.io]`\n\n```\n(x)[https://a.io]\n```",
+			expected: "`(x)[https://a.io]`\n\n```\n(x)[https://a.io]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewReversedLinkRule(true).Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReversedLinkRuleLint(t *testing.T) {
+	rule := NewReversedLinkRule(false).(ReversedLinkRule)
+	input := "ünï (here)[https://a.io]"
+	if got, _ := rule.Apply(input); got != input {
+		t.Errorf("lint mode changed the document: %q", got)
+	}
+	want := []Diagnostic{{
+		Line:    1,
+		Column:  5,
+		Rule:    "ReversedLink",
+		Message: "reversed link syntax (here)[https://a.io]; write [here](https://a.io)",
+		Fix:     &Fix{Start: 1, End: 27, Text: "ünï [here](https://a.io)"},
+	}}
+	if got := rule.Lint(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------
+// Listing the built-in rules (-list-rules)
+// ----------------------------------------------------------------
+
+// ruleListFormats are the formats -list-rules prints in.
+var ruleListFormats = []string{"text", "json"}
+
+// listRulesFlag is -list-rules, which takes a format but works as a
+// boolean flag too: -list-rules prints text, -list-rules=json JSON.
+type listRulesFlag string
+
+func (f *listRulesFlag) String() string { return string(*f) }
+
+func (f *listRulesFlag) IsBoolFlag() bool { return true }
+
+func (f *listRulesFlag) Set(s string) error {
+	switch s {
+	case "true":
+		s = "text"
+	case "false":
+		s = ""
+	}
+	if s != "" && !containsString(ruleListFormats, s) {
+		return fmt.Errorf("unknown format %q (want one of %s)", s, strings.Join(ruleListFormats, ", "))
+	}
+	*f = listRulesFlag(s)
+	return nil
+}
+
+// ruleInfo describes a built-in rule for -list-rules.
+type ruleInfo struct {
+	Name        string `json:"name"`
+	Code        string `json:"code,omitempty"`
+	Description string `json:"description"`
+	// Default rules run unless disabled, the others when enabled;
+	// required ones always run.
+	Default  bool        `json:"default"`
+	Required bool        `json:"required,omitempty"`
+	Options  []string    `json:"options,omitempty"`
+	Example  ruleExample `json:"example"`
+}
+
+// ruleExample is what a rule makes of its example document.
+type ruleExample struct {
+	Options     Options  `json:"options,omitempty"`
+	Before      string   `json:"before"`
+	After       string   `json:"after"`
+	Diagnostics []string `json:"diagnostics,omitempty"`
+}
+
+// describeRules returns the built-in rules, sorted by name regardless of
+// case, each with its example run through it alone.
+func describeRules() ([]ruleInfo, error) {
+	infos := make([]ruleInfo, 0, len(builtinRules))
+	for _, spec := range builtinRules {
+		opts := spec.exampleOptions
+		if opts == nil {
+			opts = Options{}
+		}
+		r, err := spec.build(opts)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", spec.name, err)
+		}
+		// the example is dated as of a fixed day rather than today
+		if t, ok := r.(FrontMatterTemplateRule); ok {
+			t.now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+			r = t
+		}
+		ex := ruleExample{Options: spec.exampleOptions, Before: spec.example}
+		if ex.After, err = r.Apply(spec.example); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", spec.name, err)
+		}
+		if l, ok := r.(Linter); ok {
+			for _, d := range l.Lint(spec.example) {
+				ex.Diagnostics = append(ex.Diagnostics, d.String())
+			}
+		}
+		infos = append(infos, ruleInfo{
+			Name:        spec.name,
+			Code:        ruleCode(spec.name),
+			Description: spec.description,
+			Default:     !spec.optIn,
+			Required:    spec.required,
+			Options:     spec.options,
+			Example:     ex,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return strings.ToLower(infos[i].Name) < strings.ToLower(infos[j].Name) })
+	return infos, nil
+}
+
+// listRules prints the built-in rules in format. The text format gives
+// each rule a line of its own, "Name (status): description", with its
+// example indented below; documents are quoted so that whitespace shows.
+func listRules(w io.Writer, format string) error {
+	infos, err := describeRules()
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	var b strings.Builder
+	for _, info := range infos {
+		status := "opt-in"
+		switch {
+		case info.Required:
+			status = "required"
+		case info.Default:
+			status = "default"
+		}
+		if info.Code != "" {
+			status += ", " + info.Code
+		}
+		fmt.Fprintf(&b, "%s (%s): %s\n", info.Name, status, info.Description)
+		if len(info.Example.Options) > 0 {
+			keys := make([]string, 0, len(info.Example.Options))
+			for k := range info.Example.Options {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "    with:    -set %s=%s\n", k, info.Example.Options[k])
+			}
+		}
+		fmt.Fprintf(&b, "    before:  %q\n", info.Example.Before)
+		if info.Example.After != info.Example.Before {
+			fmt.Fprintf(&b, "    after:   %q\n", info.Example.After)
+		}
+		for _, d := range info.Example.Diagnostics {
+			fmt.Fprintf(&b, "    reports: %s\n", d)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
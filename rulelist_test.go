@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDescribeRules(t *testing.T) {
+	infos, err := describeRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != len(builtinRules) {
+		t.Fatalf("got %d rules, want %d", len(infos), len(builtinRules))
+	}
+	if !sort.SliceIsSorted(infos, func(i, j int) bool {
+		return strings.ToLower(infos[i].Name) < strings.ToLower(infos[j].Name)
+	}) {
+		t.Error("rules are not in alphabetical order")
+	}
+	for _, info := range infos {
+		if info.Description == "" || info.Example.Before == "" {
+			t.Errorf("%s: no description or example", info.Name)
+		}
+		// an example shows the rule at work
+		if info.Example.After == info.Example.Before && len(info.Example.Diagnostics) == 0 {
+			t.Errorf("%s: the example %q is neither changed nor reported", info.Name, info.Example.Before)
+		}
+		for key := range info.Example.Options {
+			if !containsString(info.Options, key) && !isKnownOption(key) {
+				t.Errorf("%s: unknown example option %q", info.Name, key)
+			}
+		}
+	}
+}
+
+func TestListRules(t *testing.T) {
+	var text bytes.Buffer
+	if err := listRules(&text, "text"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"BareURL (default, MD034): reports bare URLs, or wraps them in angle brackets with bare-url=fix\n" +
+			"    before:  \"See https://go.dev\\n\"\n" +
+			"    reports: 1:5: warning: BareURL: bare URL https://go.dev; write it as <https://go.dev>\n",
+		"TrailingWhitespace (default): removes spaces at the end of lines, with trailing-whitespace=trim\n" +
+			"    with:    -set trailing-whitespace=trim\n" +
+			"    before:  \"Text   \\n\"\n" +
+			"    after:   \"Text\\n\"\n",
+		"UnterminatedFence (required): ",
+		"TodoTask (opt-in): ",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("missing:\n%s", want)
+		}
+	}
+	var again bytes.Buffer
+	listRules(&again, "text")
+	if again.String() != text.String() {
+		t.Error("the list differs between runs")
+	}
+
+	var out bytes.Buffer
+	if err := listRules(&out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var infos []ruleInfo
+	if err := json.Unmarshal(out.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != len(builtinRules) || infos[0].Name != "Abbreviations" || !infos[0].Default {
+		t.Errorf("got %+v", infos[0])
+	}
+}
+
+func TestListRulesFlag(t *testing.T) {
+	for in, want := range map[string]string{"true": "text", "json": "json", "text": "text", "false": ""} {
+		var f listRulesFlag
+		if err := f.Set(in); err != nil || string(f) != want {
+			t.Errorf("%s: got %q, %v", in, f, err)
+		}
+	}
+	var f listRulesFlag
+	if err := f.Set("xml"); err == nil {
+		t.Error("accepted xml")
+	}
+}
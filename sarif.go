@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// SARIF 2.1.0 logs (-format sarif)
+// ----------------------------------------------------------------
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLog is a SARIF log of a single run of mdfmt.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifRule is a reportingDescriptor.
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifRules describes the built-in rules for the log's driver.
+func sarifRules() []sarifRule {
+	rules := make([]sarifRule, 0, len(builtinRules))
+	for _, spec := range builtinRules {
+		rules = append(rules, sarifRule{ID: spec.name, ShortDescription: sarifMessage{spec.description}})
+	}
+	return rules
+}
+
+// sarifArray returns the array that puts the results of all documents
+// together into one log: the log is written around it, with its results
+// left out.
+func sarifArray(w io.Writer) (*jsonArray, error) {
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "mdfmt",
+				InformationURI: "https://github.com/404Simon/mdfmt",
+				Rules:          sarifRules(),
+			}},
+			Results: []sarifResult{},
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	const results = `"results": `
+	i := strings.LastIndex(string(data), results+"[]")
+	if i < 0 {
+		return nil, fmt.Errorf("sarif: no results in the log")
+	}
+	i += len(results)
+	return &jsonArray{w: w, head: string(data[:i]), tail: string(data[i+2:]) + "\n", indent: "      "}, nil
+}
+
+// sarifResults returns the results for the document at path, in before
+// formatting: one for each rule that changes it, at the first line it
+// changes, and one for each finding on out, the formatted document, at
+// the line of in it came from.
+func sarifResults(path, in, out string, changes []ruleChange, diags []Diagnostic) []sarifResult {
+	var locations func(line, column int) []sarifLocation
+	if path == "" {
+		// stdin has no URI
+		locations = func(int, int) []sarifLocation { return nil }
+	} else {
+		uri := filepath.ToSlash(path)
+		locations = func(line, column int) []sarifLocation {
+			return []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region:           sarifRegion{StartLine: line, StartColumn: column},
+			}}}
+		}
+	}
+
+	results := []sarifResult{}
+	for _, c := range changes {
+		results = append(results, sarifResult{
+			RuleID:    c.rule,
+			Level:     "warning",
+			Message:   sarifMessage{fmt.Sprintf("%s would change %d line(s) from here", c.rule, c.lines)},
+			Locations: locations(c.line, 0),
+		})
+	}
+
+	for _, d := range inputDiagnostics(in, out, diags) {
+		results = append(results, sarifResult{
+			RuleID:    d.Rule,
+			Level:     d.Severity.String(),
+			Message:   sarifMessage{d.Message},
+			Locations: locations(d.Line, d.Column),
+		})
+	}
+	return results
+}
+
+// writeSARIFResults writes results as elements of the array sarifArray
+// puts together, separated by commas.
+func writeSARIFResults(w io.Writer, results []sarifResult) error {
+	elems := make([]string, 0, len(results))
+	for _, res := range results {
+		data, err := json.MarshalIndent(res, "        ", "  ")
+		if err != nil {
+			return err
+		}
+		elems = append(elems, "        "+string(data))
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, strings.Join(elems, ",\n")+"\n")
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkSARIF checks data against what the SARIF 2.1.0 schema requires of
+// the parts of a log mdfmt writes, and returns the log.
+func checkSARIF(t *testing.T, data string) sarifLog {
+	t.Helper()
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		t.Fatalf("not JSON: %v\n%s", err, data)
+	}
+	if raw["version"] != "2.1.0" {
+		t.Errorf("version: expected 2.1.0, got %v", raw["version"])
+	}
+	runs, ok := raw["runs"].([]any)
+	if !ok {
+		t.Fatalf("runs: expected an array, got %T", raw["runs"])
+	}
+	for _, run := range runs {
+		run := run.(map[string]any)
+		driver, ok := run["tool"].(map[string]any)["driver"].(map[string]any)
+		if !ok {
+			t.Fatal("run: expected tool.driver")
+		}
+		if name, _ := driver["name"].(string); name == "" {
+			t.Error("driver: expected a name")
+		}
+		for _, r := range driver["rules"].([]any) {
+			r := r.(map[string]any)
+			if id, _ := r["id"].(string); id == "" {
+				t.Errorf("reportingDescriptor: expected an id, got %v", r)
+			}
+			if text, _ := r["shortDescription"].(map[string]any)["text"].(string); text == "" {
+				t.Errorf("reportingDescriptor %v: expected shortDescription.text", r["id"])
+			}
+		}
+		results, ok := run["results"].([]any)
+		if !ok {
+			t.Fatalf("run: expected results to be an array, got %T", run["results"])
+		}
+		for _, res := range results {
+			res := res.(map[string]any)
+			if text, _ := res["message"].(map[string]any)["text"].(string); text == "" {
+				t.Errorf("result: expected message.text, got %v", res)
+			}
+			if !containsString([]string{"none", "note", "warning", "error"}, res["level"].(string)) {
+				t.Errorf("result: level %v is not one of none, note, warning, error", res["level"])
+			}
+			locs, _ := res["locations"].([]any)
+			for _, loc := range locs {
+				phys := loc.(map[string]any)["physicalLocation"].(map[string]any)
+				if uri, _ := phys["artifactLocation"].(map[string]any)["uri"].(string); uri == "" {
+					t.Errorf("physicalLocation: expected artifactLocation.uri, got %v", phys)
+				}
+				if line, _ := phys["region"].(map[string]any)["startLine"].(float64); line < 1 {
+					t.Errorf("region: startLine %v is less than 1", line)
+				}
+			}
+		}
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(data), &log); err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+func TestFormatFilesSARIF(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.md": "# Clean\n",
+		"dirty.md": "# Dirty\ntext\n\nSee https://a.example\n",
+		"large.md": "# Large\n\n" + strings.Repeat("text ", 40) + "\n",
+	})
+	clean, dirty, large := filepath.Join(dir, "clean.md"), filepath.Join(dir, "dirty.md"), filepath.Join(dir, "large.md")
+
+	run := runOptions{format: "sarif", check: true, jobs: 2, opts: Options{"max-input-size": "100"}}
+	var stdout, stderr strings.Builder
+	changed, err := run.formatFiles(&stdout, &stderr, []string{clean, dirty, large})
+	if err == nil {
+		t.Error("expected an error for large.md")
+	}
+	if changed != 1 {
+		t.Errorf("expected 1 changed file, got %d", changed)
+	}
+	log := checkSARIF(t, stdout.String())
+	if len(log.Runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(log.Runs))
+	}
+	if rules := log.Runs[0].Tool.Driver.Rules; len(rules) != len(builtinRules) {
+		t.Errorf("expected %d rules, got %d", len(builtinRules), len(rules))
+	}
+
+	uri := filepath.ToSlash(dirty)
+	expected := []sarifResult{
+		{
+			RuleID: "BlankLineAfterHeading", Level: "warning",
+			Message:   sarifMessage{"BlankLineAfterHeading would change 1 line(s) from here"},
+			Locations: []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{uri}, sarifRegion{StartLine: 2}}}},
+		},
+		{
+			RuleID: "BareURL", Level: "warning",
+			Message:   sarifMessage{"bare URL https://a.example; write it as <https://a.example>"},
+			Locations: []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{uri}, sarifRegion{StartLine: 4, StartColumn: 5}}}},
+		},
+	}
+	if got := log.Runs[0].Results; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, got)
+	}
+}
+
+func TestFormatStdinSARIF(t *testing.T) {
+	run := runOptions{format: "sarif"}
+	var stdout, stderr strings.Builder
+	if _, err := run.formatStdin(&stdout, &stderr, "", "# A\n"); err != nil {
+		t.Fatal(err)
+	}
+	log := checkSARIF(t, stdout.String())
+	if n := len(log.Runs[0].Results); n != 0 {
+		t.Errorf("expected no results, got %d", n)
+	}
+}
+
+func TestTraceChanges(t *testing.T) {
+	content := "a\nb\nc\n"
+	var changes []ruleChange
+	trace := traceChanges(content, &changes)
+	trace("Unchanged", content, content)
+	// a line inserted at the top, then the third line of content changed
+	trace("Insert", content, "x\na\nb\nc\n")
+	trace("Upper", "x\na\nb\nc\n", "x\na\nb\nC\n")
+	// a line added at the end
+	trace("Append", "x\na\nb\nC\n", "x\na\nb\nC\nd\n")
+
+	expected := []ruleChange{
+		{rule: "Insert", line: 1, lines: 1},
+		{rule: "Upper", line: 3, lines: 1},
+		{rule: "Append", line: 4, lines: 1},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, changes)
+	}
+	if got := changedRules(append(changes, changes[0])); !reflect.DeepEqual(got, []string{"Insert", "Upper", "Append"}) {
+		t.Errorf("expected each rule once, got %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 33: report required sections a document lacks
+// ----------------------------------------------------------------
+
+// requiredSection is one entry of the required-sections option: heading
+// text compared case-insensitively, or a regular expression written
+// between slashes.
+type requiredSection struct {
+	text string
+	re   *regexp.Regexp
+}
+
+func (s requiredSection) matches(heading string) bool {
+	if s.re != nil {
+		return s.re.MatchString(heading)
+	}
+	return strings.EqualFold(strings.Join(strings.Fields(heading), " "), s.text)
+}
+
+type RequiredSectionsRule struct {
+	sections []requiredSection
+	// level restricts matching headings to one level; 0 accepts any.
+	level int
+	// paths are the globs a document must match for the rule to apply,
+	// or nil for every document.
+	paths []*regexp.Regexp
+	path  string
+}
+
+// NewRequiredSectionsRule constructs a rule that reports each entry of
+// sections no heading of the document matches. Entries of the form
+// /regexp/ are matched as case-insensitive regular expressions. When
+// paths is not empty, only documents whose path matches one of the globs
+// there are checked; "**" in a glob spans directories.
+func NewRequiredSectionsRule(sections []string, level int, paths []string) (Rule, error) {
+	r := RequiredSectionsRule{level: level}
+	for _, s := range sections {
+		if len(s) > 1 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+			re, err := regexp.Compile("(?i)" + s[1:len(s)-1])
+			if err != nil {
+				return nil, fmt.Errorf("section %s: %w", s, err)
+			}
+			r.sections = append(r.sections, requiredSection{text: s, re: re})
+			continue
+		}
+		r.sections = append(r.sections, requiredSection{text: strings.Join(strings.Fields(s), " ")})
+	}
+	for _, p := range paths {
+		r.paths = append(r.paths, globRegexp(p))
+	}
+	return r, nil
+}
+
+func newRequiredSectionsRuleFromOptions(o Options) (Rule, error) {
+	lvl, err := o.Choice("required-sections-level", "any", "any", "1", "2", "3", "4", "5", "6")
+	if err != nil {
+		return nil, err
+	}
+	level := 0
+	if lvl != "any" {
+		level = int(lvl[0] - '0')
+	}
+	return NewRequiredSectionsRule(o.List("required-sections", nil), level,
+		o.List("required-sections-paths", nil))
+}
+
+func (RequiredSectionsRule) Name() string {
+	return "RequiredSections"
+}
+
+func (r RequiredSectionsRule) WithPath(path string) Rule {
+	r.path = path
+	return r
+}
+
+func (RequiredSectionsRule) Apply(content string) (string, error) {
+	return content, nil
+}
+
+// applies reports whether the document is in scope of the path globs. A
+// document without a path is only in scope when there are none.
+func (r RequiredSectionsRule) applies() bool {
+	if len(r.paths) == 0 {
+		return true
+	}
+	if r.path == "" {
+		return false
+	}
+	p := filepath.ToSlash(filepath.Clean(r.path))
+	for _, re := range r.paths {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RequiredSectionsRule) Lint(content string) []Diagnostic {
+	if len(r.sections) == 0 || !r.applies() {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	headings := documentHeadings(lines, classifyBlocks(lines))
+	var diags []Diagnostic
+	for _, s := range r.sections {
+		found := false
+		for _, h := range headings {
+			if (r.level == 0 || h.level == r.level) && s.matches(plainText(h.text)) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		what := fmt.Sprintf("section %q", s.text)
+		if s.re != nil {
+			what = "section matching " + s.text
+		}
+		if r.level > 0 {
+			what = fmt.Sprintf("level %d %s", r.level, what)
+		}
+		diags = append(diags, Diagnostic{
+			Line:    1,
+			Rule:    r.Name(),
+			Message: "missing required " + what,
+			Key:     s.text,
+		})
+	}
+	return diags
+}
+
+// heading is an ATX or setext heading outside of block quotes.
+type heading struct {
+	line  int
+	level int
+	text  string
+}
+
+// documentHeadings returns the headings of a document in order.
+func documentHeadings(lines []string, b *blocks) []heading {
+	var hs []heading
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind != lineText || info.quote > 0 {
+			continue
+		}
+		if i+1 < len(lines) && b.lines[i+1].kind == lineSetextUnderline {
+			level := 2
+			if strings.HasPrefix(strings.TrimSpace(lines[i+1]), "=") {
+				level = 1
+			}
+			hs = append(hs, heading{i, level, stripAttributes(strings.TrimSpace(line))})
+			continue
+		}
+		if level, text, ok := atxHeading(line); ok {
+			hs = append(hs, heading{i, level, text})
+		}
+	}
+	return hs
+}
+
+// atxHeading returns the level and text of an ATX heading line, without
+// closing #s and attributes.
+func atxHeading(line string) (level int, text string, ok bool) {
+	if !isATXHeading(line) {
+		return 0, "", false
+	}
+	body := strings.TrimSpace(line)
+	level = len(body) - len(strings.TrimLeft(body, "#"))
+	// drop an optional closing sequence of #s
+	text = strings.TrimSpace(body[level:])
+	if t := strings.TrimRight(text, "#"); t == "" || strings.HasSuffix(t, " ") {
+		text = strings.TrimSpace(t)
+	}
+	// and Pandoc attributes, as in "# Intro {#intro}"
+	return level, stripAttributes(text), true
+}
+
+// globRegexp translates a path glob into an anchored regular expression.
+// "*", "?" and character classes such as "[a-z]" or "[!0-9]" stay within
+// one path segment, "**/" matches any number of leading directories,
+// including none, and a trailing "**" anything.
+func globRegexp(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[' && strings.IndexByte(glob[i:], ']') > 1:
+			end := i + strings.IndexByte(glob[i:], ']')
+			class := strings.ReplaceAll(glob[i+1:end], `\`, `\\`)
+			if class[0] == '!' {
+				class = "^/" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i = end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		// a malformed class matches nothing
+		return regexp.MustCompile(`[^\s\S]`)
+	}
+	return re
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequiredSectionsRule(t *testing.T) {
+	doc := "# Tool\n\nIntro\n\n## Installation\n\nRun it.\n\nLicense\n-------\n\nMIT\n\n> ## Usage\n"
+	tests := []struct {
+		name     string
+		doc      string
+		sections []string
+		level    int
+		paths    []string
+		path     string
+		missing  []string
+	}{
+		{
+			name:     "atx and setext headings",
+			sections: []string{"installation", "LICENSE"},
+		},
+		{
+			name:     "headings with attributes",
+			doc:      "# Tool\n\n## Installation {#install}\n\nRun it.\n\nLicense {.unnumbered}\n-------\n\nMIT\n",
+			sections: []string{"Installation", "License", "Usage"},
+			missing:  []string{`missing required section "Usage"`},
+		},
+		{
+			name:     "missing sections",
+			sections: []string{"Installation", "Usage", "Contributing"},
+			missing:  []string{`missing required section "Usage"`, `missing required section "Contributing"`},
+		},
+		{
+			name:     "regexp",
+			sections: []string{"/^licen[cs]e$/", "/^install/", "/^changes|changelog$/"},
+			missing:  []string{"missing required section matching /^changes|changelog$/"},
+		},
+		{
+			name:     "level",
+			sections: []string{"Installation", "License", "Tool"},
+			level:    2,
+			missing:  []string{`missing required level 2 section "Tool"`},
+		},
+		{
+			name:     "path in scope",
+			sections: []string{"Usage"},
+			paths:    []string{"**/README.md"},
+			path:     "./pkg/a/README.md",
+			missing:  []string{`missing required section "Usage"`},
+		},
+		{
+			name:     "top-level path in scope",
+			sections: []string{"Usage"},
+			paths:    []string{"**/README.md"},
+			path:     "README.md",
+			missing:  []string{`missing required section "Usage"`},
+		},
+		{
+			name:     "path out of scope",
+			sections: []string{"Usage"},
+			paths:    []string{"**/README.md", "docs/*.md"},
+			path:     "docs/sub/guide.md",
+		},
+		{
+			name:     "unknown path with scope",
+			sections: []string{"Usage"},
+			paths:    []string{"**/README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRequiredSectionsRule(tt.sections, tt.level, tt.paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.doc == "" {
+				tt.doc = doc
+			}
+			var got []string
+			for _, d := range r.(PathRule).WithPath(tt.path).(Linter).Lint(tt.doc) {
+				got = append(got, d.Message)
+			}
+			if !reflect.DeepEqual(got, tt.missing) {
+				t.Errorf("got %q, want %q", got, tt.missing)
+			}
+		})
+	}
+}
+
+func TestRequiredSectionsRuleBadRegexp(t *testing.T) {
+	if _, err := NewRequiredSectionsRule([]string{"/(/"}, 0, nil); err == nil {
+		t.Error("expected an error for an invalid regexp")
+	}
+}
+
+func TestRequiredSectionsFormatDocument(t *testing.T) {
+	opts := Options{"required-sections": "License", "required-sections-paths": "**/README.md"}
+	for path, want := range map[string]int{"pkg/README.md": 1, "pkg/NOTES.md": 0} {
+		_, diags, err := formatDocument("# Pkg\n", path, opts, nil, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diags) != want {
+			t.Errorf("%s: got %v, want %d diagnostics", path, diags, want)
+		}
+	}
+}
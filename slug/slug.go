@@ -0,0 +1,113 @@
+// Package slug turns heading text into the anchors GitHub and GitLab give
+// headings, so that rules creating or checking links to headings agree
+// with the platforms that render them.
+package slug
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GitHub returns the anchor GitHub generates for a heading with the given
+// text, as github-slugger does: the text in lower case without
+// punctuation, symbols and emoji, with each space replaced by a hyphen.
+// Runs of hyphens are kept, so "A & B" becomes "a--b".
+func GitHub(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			sb.WriteByte('-')
+		case r == '-' || word(r):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// GitLab returns the anchor GitLab generates for a heading with the given
+// text: like GitHub, but with runs of hyphens squeezed into one and
+// "anchor-" put before anchors made of digits only, which would read as
+// issue references.
+func GitLab(text string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ' || r == '-':
+			if !hyphen {
+				sb.WriteByte('-')
+			}
+			hyphen = true
+		case word(r):
+			sb.WriteRune(r)
+			hyphen = false
+		}
+	}
+	s := sb.String()
+	if digits.MatchString(s) {
+		s = "anchor-" + s
+	}
+	return s
+}
+
+var digits = regexp.MustCompile(`^[0-9]+$`)
+
+// word reports whether r is a word character: a letter, a mark, a
+// decimal digit or connector punctuation such as "_".
+func word(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.Nd, unicode.Pc)
+}
+
+// Styles maps the names of the slug styles to their functions.
+var Styles = map[string]func(string) string{
+	"github": GitHub,
+	"gitlab": GitLab,
+}
+
+// ByName returns the slug function of the named style.
+func ByName(style string) (func(string) string, error) {
+	if f, ok := Styles[style]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown slug style %q (want github or gitlab)", style)
+}
+
+// Deduplicator hands out the anchors of the headings of one document in
+// order, appending "-1", "-2" and so on to anchors already taken, as both
+// GitHub and GitLab do.
+type Deduplicator struct {
+	slug func(string) string
+	seen map[string]int
+}
+
+// NewDeduplicator returns a Deduplicator that makes anchors with slug,
+// such as GitHub or GitLab.
+func NewDeduplicator(slug func(string) string) *Deduplicator {
+	return &Deduplicator{slug: slug, seen: map[string]int{}}
+}
+
+// Slug returns the anchor of the next heading, which has the given text.
+func (d *Deduplicator) Slug(text string) string {
+	base := d.slug(text)
+	s := base
+	for {
+		if _, taken := d.seen[s]; !taken {
+			break
+		}
+		d.seen[base]++
+		s = base + "-" + strconv.Itoa(d.seen[base])
+	}
+	d.seen[s] = 0
+	return s
+}
+
+// Reserve marks anchor as taken, as by an explicit {#id} on a heading.
+func (d *Deduplicator) Reserve(anchor string) {
+	if _, ok := d.seen[anchor]; !ok {
+		d.seen[anchor] = 0
+	}
+}
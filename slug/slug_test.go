@@ -0,0 +1,99 @@
+package slug
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		text   string
+		github string
+		gitlab string
+	}{
+		{"Getting Started", "getting-started", "getting-started"},
+		{"Hello 👋 World", "hello--world", "hello-world"},
+		{"🚀 Launch", "-launch", "-launch"},
+		{"Use `go test`", "use-go-test", "use-go-test"},
+		{"Q&A", "qa", "qa"},
+		{"Salt & Pepper", "salt--pepper", "salt-pepper"},
+		{"C++ / C#", "c--c", "c-c"},
+		{"1. Introduction", "1-introduction", "1-introduction"},
+		{"2024", "2024", "anchor-2024"},
+		{"v1.2.3 -- notes", "v123----notes", "v123-notes"},
+		{"snake_case Name", "snake_case-name", "snake_case-name"},
+		{"Привет, мир!", "привет-мир", "привет-мир"},
+		{"日本語の見出し", "日本語の見出し", "日本語の見出し"},
+		{"Émile Zola's “Œuvre”", "émile-zolas-œuvre", "émile-zolas-œuvre"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := GitHub(tt.text); got != tt.github {
+			t.Errorf("GitHub(%q) = %q, want %q", tt.text, got, tt.github)
+		}
+		if got := GitLab(tt.text); got != tt.gitlab {
+			t.Errorf("GitLab(%q) = %q, want %q", tt.text, got, tt.gitlab)
+		}
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		reserved []string
+		headings []string
+		want     []string
+	}{
+		{
+			name:     "consecutive duplicates",
+			style:    "github",
+			headings: []string{"Usage", "Usage", "Usage"},
+			want:     []string{"usage", "usage-1", "usage-2"},
+		},
+		{
+			name:     "suffix taken by another heading",
+			style:    "github",
+			headings: []string{"Intro", "Intro 1", "Intro", "Intro"},
+			want:     []string{"intro", "intro-1", "intro-2", "intro-3"},
+		},
+		{
+			name:     "different texts, same anchor",
+			style:    "gitlab",
+			headings: []string{"A & B", "A-B", "a b"},
+			want:     []string{"a-b", "a-b-1", "a-b-2"},
+		},
+		{
+			name:     "reserved ids",
+			style:    "github",
+			reserved: []string{"faq"},
+			headings: []string{"FAQ", "FAQ"},
+			want:     []string{"faq-1", "faq-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ByName(tt.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := NewDeduplicator(f)
+			for _, id := range tt.reserved {
+				d.Reserve(id)
+			}
+			var got []string
+			for _, h := range tt.headings {
+				got = append(got, d.Slug(h))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	if _, err := ByName("bitbucket"); err == nil {
+		t.Error("no error for an unknown style")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 37: sort list items between mdfmt:sort markers
+// ----------------------------------------------------------------
+
+type SortListRule struct {
+	// numeric compares runs of digits by value, so "item 9" sorts before
+	// "item 10".
+	numeric bool
+}
+
+// NewSortListRule constructs a rule that sorts the top-level items of the
+// list between <!-- mdfmt:sort --> and <!-- mdfmt:endsort -->
+// case-insensitively, each item moving with its continuation lines and
+// sub-lists. "<!-- mdfmt:sort numeric -->" sorts one list numerically.
+func NewSortListRule(numeric bool) Rule { return SortListRule{numeric: numeric} }
+
+func newSortListRuleFromOptions(o Options) (Rule, error) {
+	numeric, err := o.Bool("sort-numeric", false)
+	if err != nil {
+		return nil, err
+	}
+	return NewSortListRule(numeric), nil
+}
+
+func (SortListRule) Name() string {
+	return "SortList"
+}
+
+// sortDirective returns the words of an mdfmt:NAME comment directive on
+// line, after the name.
+func sortDirective(line, name string) ([]string, bool) {
+	t := strings.TrimSpace(line)
+	if !strings.HasPrefix(t, "<!--") || !strings.HasSuffix(t, "-->") {
+		return nil, false
+	}
+	fields := strings.Fields(t[4 : len(t)-3])
+	if len(fields) == 0 || fields[0] != "mdfmt:"+name {
+		return nil, false
+	}
+	return fields[1:], true
+}
+
+func (r SortListRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	directive := func(i int, name string) ([]string, bool) {
+		if b.isCode(i) || b.lines[i].kind == lineFrontMatter || b.lines[i].quote > 0 {
+			return nil, false
+		}
+		return sortDirective(lines[i], name)
+	}
+	changed := false
+	for i := 0; i < len(lines); i++ {
+		args, ok := directive(i, "sort")
+		if !ok {
+			continue
+		}
+		end := -1
+		for j := i + 1; j < len(lines); j++ {
+			if _, ok := directive(j, "endsort"); ok {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			break
+		}
+		numeric := r.numeric || containsString(args, "numeric")
+		// sorting only moves lines within the region, so the
+		// classification of the rest stays valid
+		if sortRegion(lines, b, i+1, end, numeric) {
+			changed = true
+		}
+		i = end
+	}
+	if !changed {
+		return content, nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// sortRegion sorts the list that starts on the first non-blank line of
+// lines[from:to] in place, leaving what follows the list alone.
+func sortRegion(lines []string, b *blocks, from, to int, numeric bool) bool {
+	first := from
+	for first < to && b.lines[first].kind == lineBlank {
+		first++
+	}
+	if first == to || !b.lines[first].item {
+		return false
+	}
+	_, _, top, _ := listMarker(lines[first])
+
+	// items as [start, end) line ranges without trailing blank lines
+	var items [][2]int
+	start, last := first, first
+scan:
+	for j := first + 1; j < to; j++ {
+		info := b.lines[j]
+		switch {
+		case info.kind == lineBlank:
+			continue
+		case info.item && info.indent < top:
+			items = append(items, [2]int{start, last + 1})
+			start = j
+		case info.container >= top || last == j-1:
+			// nested content, or a lazy continuation line
+		default:
+			break scan
+		}
+		last = j
+	}
+	items = append(items, [2]int{start, last + 1})
+	if len(items) < 2 {
+		return false
+	}
+
+	type item struct {
+		lines []string
+		key   string
+	}
+	sorted := make([]item, len(items))
+	for k, it := range items {
+		sorted[k] = item{
+			lines: append([]string(nil), lines[it[0]:it[1]]...),
+			key:   itemSortKey(lines[it[0]]),
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if numeric {
+			return naturalLess(sorted[i].key, sorted[j].key)
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	// ordered lists keep counting from their first number
+	number, ordered := itemNumber(lines[first])
+	for _, it := range items {
+		if _, ok := itemNumber(lines[it[0]]); !ok {
+			ordered = false
+		}
+	}
+
+	out := make([]string, 0, last+1-first)
+	for k, it := range sorted {
+		if ordered {
+			it.lines[0] = renumberItem(it.lines[0], number+k)
+		}
+		out = append(out, it.lines...)
+		if k+1 < len(items) {
+			// the blank lines between items stay where they were
+			out = append(out, lines[items[k][1]:items[k+1][0]]...)
+		}
+	}
+	changed := false
+	for k, l := range out {
+		if lines[first+k] != l {
+			lines[first+k] = l
+			changed = true
+		}
+	}
+	return changed
+}
+
+// itemSortKey is the lowercased text of a list item's first line, without
+// its marker and task box.
+func itemSortKey(line string) string {
+	_, end, _, _ := listMarker(line)
+	text := strings.TrimSpace(line[end:])
+	for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+		text = strings.TrimPrefix(text, box)
+	}
+	return strings.ToLower(text)
+}
+
+// itemNumber returns the number of an ordered list item.
+func itemNumber(line string) (int, bool) {
+	marker, _, _, ok := listMarker(line)
+	if !ok || marker[0] < '0' || marker[0] > '9' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(marker[:len(marker)-1])
+	return n, err == nil
+}
+
+// renumberItem replaces the number of an ordered list item.
+func renumberItem(line string, n int) string {
+	marker, end, _, _ := listMarker(line)
+	start := end - len(marker)
+	return line[:start] + strconv.Itoa(n) + marker[len(marker)-1:] + line[end:]
+}
+
+// naturalLess compares a and b with runs of digits compared by value.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return ra < rb
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return a == "" && b != ""
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
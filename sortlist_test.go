@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestSortListRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		numeric  bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "bullets case-insensitively",
+			input:    "<!-- mdfmt:sort -->\n- banana\n- Apple\n- cherry\n<!-- mdfmt:endsort -->\n",
+			expected: "<!-- mdfmt:sort -->\n- Apple\n- banana\n- cherry\n<!-- mdfmt:endsort -->\n",
+		},
+		{
+			name:     "items keep sub-lists and continuations",
+			input:    "<!-- mdfmt:sort -->\n\n- zeta\n  more zeta\n  - sub z\n\n- alpha\n\n  para\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort -->\n\n- alpha\n\n  para\n\n- zeta\n  more zeta\n  - sub z\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "ordered lists are renumbered",
+			input:    "<!-- mdfmt:sort -->\n3. c\n4. a\n5. b\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort -->\n3. a\n4. b\n5. c\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "numeric",
+			numeric:  true,
+			input:    "<!-- mdfmt:sort -->\n- item 10\n- item 9\n- [x] item 1\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort -->\n- [x] item 1\n- item 9\n- item 10\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "numeric per marker",
+			input:    "<!-- mdfmt:sort numeric -->\n- v10\n- v2\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort numeric -->\n- v2\n- v10\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "stable for equal keys",
+			input:    "<!-- mdfmt:sort -->\n- b\n- A 2\n- a 2\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort -->\n- A 2\n- a 2\n- b\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "text after the list stays",
+			input:    "<!-- mdfmt:sort -->\n- b\n- a\n\nNot an item.\n<!-- mdfmt:endsort -->",
+			expected: "<!-- mdfmt:sort -->\n- a\n- b\n\nNot an item.\n<!-- mdfmt:endsort -->",
+		},
+		{
+			name:     "outside markers and in code",
+			input:    "- b\n- a\n\n```\n<!-- mdfmt:sort -->\n- b\n- a\n<!-- mdfmt:endsort -->\n```\n\n<!-- mdfmt:sort -->\n- b\n- a",
+			expected: "- b\n- a\n\n```\n<!-- mdfmt:sort -->\n- b\n- a\n<!-- mdfmt:endsort -->\n```\n\n<!-- mdfmt:sort -->\n- b\n- a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewSortListRule(tt.numeric)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Formatting the files staged in git (-staged)
+// ----------------------------------------------------------------
+
+// stagedFiles are the Markdown files added, copied, modified or renamed
+// in the index of a git repository, for a pre-commit hook to format.
+type stagedFiles struct {
+	// top is the root of the work tree.
+	top string
+	// files are the paths of the files, relative to the current
+	// directory where they are below it and absolute elsewhere.
+	files []string
+	// names are the paths of the files in the repository, by path.
+	names map[string]string
+	// partial holds the content of the files whose working tree version
+	// differs from the index, as it was before formatting, by path.
+	partial map[string][]byte
+}
+
+// git runs git with args in dir and returns what it prints on stdout.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// gitPaths splits the NUL-separated paths git prints with -z.
+func gitPaths(out string) []string {
+	return strings.FieldsFunc(out, func(r rune) bool { return r == 0 })
+}
+
+// findStaged returns the files staged in the git repository dir is in
+// that f would find walking the repository: Markdown by their extension
+// and neither excluded by -exclude, relative to the root of the work
+// tree, nor by the .mdfmtignore files.
+func (f fileFinder) findStaged(dir string) (*stagedFiles, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("-staged needs a git repository: %w", err)
+	}
+	s := &stagedFiles{top: strings.TrimSpace(out), names: map[string]string{}, partial: map[string][]byte{}}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// git reports the top without symbolic links
+	if real, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = real
+	}
+
+	staged, err := git(s.top, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	unstaged, err := git(s.top, "diff", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	differs := map[string]bool{}
+	for _, p := range gitPaths(unstaged) {
+		differs[p] = true
+	}
+
+	// as a walk of the repository would exclude them
+	ignores := ignoreFiles{}
+	if err := ignores.load(s.top); err != nil {
+		return nil, err
+	}
+	excluded := func(name string) bool {
+		parts := strings.Split(name, "/")
+		dir := s.top
+		for i, part := range parts[:len(parts)-1] {
+			dir = filepath.Join(dir, part)
+			if strings.HasPrefix(part, ".") || f.exclude.excludes(strings.Join(parts[:i+1], "/"), true) || ignores.excludes(s.top, dir, true) {
+				return true
+			}
+			if _, ok := ignores[dir]; !ok {
+				if err := ignores.load(dir); err != nil {
+					return true
+				}
+			}
+		}
+		return f.exclude.excludes(name, false) || ignores.excludes(s.top, filepath.Join(s.top, filepath.FromSlash(name)), false)
+	}
+
+	for _, p := range gitPaths(staged) {
+		if !f.markdown(p) || excluded(p) {
+			continue
+		}
+		path := filepath.Join(s.top, filepath.FromSlash(p))
+		if rel, err := filepath.Rel(cwd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			path = rel
+		}
+		s.names[path] = p
+		if differs[p] {
+			// nil for a file deleted since, which formatting reports
+			data, _ := os.ReadFile(path)
+			s.partial[path] = data
+		}
+		s.files = append(s.files, path)
+	}
+	return s, nil
+}
+
+// restage stages again the files that formatting changed, which the
+// commit would otherwise leave out. Partially staged files are not staged,
+// as that would stage the changes left out of the commit as well; the
+// ones formatting changed are reported on stderr instead, and restage
+// fails, so that a hook stops the commit.
+func (s *stagedFiles) restage(stderr io.Writer) error {
+	out, err := git(s.top, "diff", "--name-only", "-z")
+	if err != nil {
+		return err
+	}
+	differs := map[string]bool{}
+	for _, p := range gitPaths(out) {
+		differs[p] = true
+	}
+
+	var add []string
+	left := 0
+	for _, path := range s.files {
+		if old, ok := s.partial[path]; ok {
+			if data, err := os.ReadFile(path); err == nil && !bytes.Equal(data, old) {
+				fmt.Fprintf(stderr, "%s is partially staged and was reformatted; stage the changes to commit\n", path)
+				left++
+			}
+			continue
+		}
+		if differs[s.names[path]] {
+			add = append(add, s.names[path])
+		}
+	}
+	if len(add) > 0 {
+		if _, err := git(s.top, append([]string{"add", "--"}, add...)...); err != nil {
+			return err
+		}
+	}
+	if left > 0 {
+		return fmt.Errorf("%d partially staged file(s) reformatted but not staged", left)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gitRepo returns a new git repository holding files, all staged.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	// without symbolic links, as git reports it
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "mdfmt@example.com"},
+		{"config", "user.name", "mdfmt"},
+		{"add", "."},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStaged(t *testing.T) {
+	dir := gitRepo(t, map[string]string{
+		"a.md":            "# A\ntext\n",
+		"clean.md":        "# Clean\n\ntext\n",
+		"partial.md":      "# Partial\ntext\n",
+		"notes.txt":       "# Not Markdown\ntext\n",
+		".github/pr.md":   "# Hidden\ntext\n",
+		"vendor/v.md":     "# Vendored\ntext\n",
+		".mdfmtignore":    "vendor/\n",
+		"docs/nested.md":  "# Nested\ntext\n",
+		"docs/skipped.md": "# Skipped\ntext\n",
+	})
+	// changes left out of the index
+	writeFiles(t, dir, map[string]string{
+		"partial.md":   "# Partial\ntext\n\nunstaged\n",
+		"untracked.md": "# Untracked\ntext\n",
+	})
+
+	finder := fileFinder{extensions: defaultExtensions, exclude: parseIgnorePatterns([]string{"docs/skipped.md"})}
+	staged, err := finder.findStaged(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, path := range staged.files {
+		names = append(names, staged.names[path])
+	}
+	if want := []string{"a.md", "clean.md", "docs/nested.md", "partial.md"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("staged: got %q, want %q", names, want)
+	}
+	if _, ok := staged.partial[filepath.Join(dir, "partial.md")]; !ok || len(staged.partial) != 1 {
+		t.Errorf("partially staged: got %v", staged.partial)
+	}
+
+	run := runOptions{format: "text", write: true}
+	var stdout, stderr strings.Builder
+	changed, err := run.formatFiles(&stdout, &stderr, staged.files)
+	if err != nil || changed != 3 {
+		t.Fatalf("formatting: %d changed, %v", changed, err)
+	}
+	if err := staged.restage(&stderr); err == nil {
+		t.Error("expected restage to fail for the reformatted partial.md")
+	}
+	if want := filepath.Join(dir, "partial.md") + " is partially staged and was reformatted; stage the changes to commit\n"; stderr.String() != want {
+		t.Errorf("stderr: expected:\n%q\ngot:\n%q", want, stderr.String())
+	}
+
+	// the reformatted files are staged again, except for partial.md
+	out, err := git(dir, "diff", "--name-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "partial.md\n" {
+		t.Errorf("differing from the index: got %q, want only partial.md", out)
+	}
+	index, err := git(dir, "show", ":a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != "# A\n\ntext\n" {
+		t.Errorf("a.md in the index: got %q", index)
+	}
+	for name, want := range map[string]string{
+		"partial.md":      "# Partial\n\ntext\n\nunstaged\n",
+		"untracked.md":    "# Untracked\ntext\n",
+		"notes.txt":       "# Not Markdown\ntext\n",
+		".github/pr.md":   "# Hidden\ntext\n",
+		"vendor/v.md":     "# Vendored\ntext\n",
+		"docs/skipped.md": "# Skipped\ntext\n",
+	} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("%s: got %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestStagedOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	_, err := fileFinder{extensions: defaultExtensions}.findStaged(dir)
+	if err == nil || !strings.HasPrefix(err.Error(), "-staged needs a git repository") {
+		t.Errorf("expected a clear error, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// ----------------------------------------------------------------
+// Document statistics (-stats)
+// ----------------------------------------------------------------
+
+// documentStats counts the structure of a document as the rules see it.
+type documentStats struct {
+	Path string `json:"path,omitempty"`
+	// Headings counts the headings of each level, h1 first.
+	Headings [6]int `json:"headings"`
+	// Words counts the words of prose, leaving out code, URLs and HTML.
+	Words int `json:"words"`
+	// CodeBlocks counts fenced code blocks by language, "" for those
+	// without.
+	CodeBlocks    map[string]int `json:"code_blocks"`
+	Tables        int            `json:"tables"`
+	Images        int            `json:"images"`
+	InternalLinks int            `json:"internal_links"`
+	ExternalLinks int            `json:"external_links"`
+	Tasks         int            `json:"tasks"`
+	TasksDone     int            `json:"tasks_done"`
+}
+
+var (
+	// imageDestination matches an inline image and captures its
+	// destination.
+	imageDestination = regexp.MustCompile(`!\[[^\]]*\]\(<?([^)\s>]*)>?(?:\s+"[^"]*")?\)`)
+	// linkDestination matches an inline link and captures its
+	// destination.
+	linkDestination = regexp.MustCompile(`\[[^\]]*\]\(<?([^)\s>]*)>?(?:\s+"[^"]*")?\)`)
+	// htmlLinkTarget matches the href of an anchor or the src of an image.
+	htmlLinkTarget = regexp.MustCompile(`(?i)<(a|img)\s[^<>]*?\b(?:href|src)\s*=\s*["']?([^"'\s>]*)`)
+	// externalURL matches a destination with a scheme, or a
+	// protocol-relative one.
+	externalURL = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9+.-]*:|//)`)
+)
+
+// collectStats counts the structure of content, without running any rule.
+func collectStats(content string) documentStats {
+	st := documentStats{CodeBlocks: map[string]int{}}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	b := classifyBlocks(lines)
+	inHTML := htmlBlockLines(lines, b)
+
+	defs := linkDefinitions(lines, b)
+	targets := map[string]string{} // link destinations by label
+	for i, label := range defs {
+		if _, ok := targets[label]; !ok {
+			rest := lines[i][linkReferenceDefinition.FindStringIndex(lines[i])[1]:]
+			targets[label] = strings.Trim(strings.Fields(rest)[0], "<>")
+		}
+	}
+	link := func(dest string, image bool) {
+		switch {
+		case image:
+			st.Images++
+		case externalURL.MatchString(dest):
+			st.ExternalLinks++
+		default:
+			st.InternalLinks++
+		}
+	}
+
+	for _, f := range b.fences {
+		lang := ""
+		if fields := strings.Fields(strings.Trim(f.info, "{}")); len(fields) > 0 {
+			lang = strings.TrimPrefix(fields[0], ".")
+		}
+		st.CodeBlocks[lang]++
+	}
+
+	for i, line := range lines {
+		info := b.lines[i]
+		switch info.kind {
+		case lineTable:
+			if i == 0 || b.lines[i-1].kind != lineTable {
+				st.Tables++
+			}
+		case lineSetextUnderline:
+			if strings.Contains(line, "=") {
+				st.Headings[0]++
+			} else {
+				st.Headings[1]++
+			}
+			continue
+		}
+		if literalLine(b, i) {
+			continue
+		}
+		for _, m := range htmlLinkTarget.FindAllStringSubmatch(maskCode(line), -1) {
+			link(m[2], strings.EqualFold(m[1], "img"))
+		}
+		if _, ok := defs[i]; ok || inHTML[i] {
+			continue
+		}
+
+		body := containerBody(line[info.bodyStart:], info.container)
+		if isHeadingLine(line, info) {
+			marker := strings.TrimLeft(body, " ")
+			st.Headings[len(marker)-len(strings.TrimLeft(marker, "#"))-1]++
+		}
+		box := -1 // offset of a task item's checkbox
+		if _, end, _, ok := listMarker(body); ok && info.item {
+			rest := strings.TrimLeft(body[end:], " \t")
+			switch {
+			case strings.HasPrefix(rest, "[ ] "):
+				st.Tasks++
+			case strings.HasPrefix(rest, "[x] "), strings.HasPrefix(rest, "[X] "):
+				st.Tasks++
+				st.TasksDone++
+			default:
+				rest = ""
+			}
+			if rest != "" {
+				box = len(line) - len(rest)
+			}
+		}
+
+		// images first, so that a badge is an image in a link
+		masked := maskCode(line)
+		for _, re := range []*regexp.Regexp{imageDestination, linkDestination} {
+			for _, m := range re.FindAllStringSubmatchIndex(masked, -1) {
+				link(masked[m[2]:m[3]], re == imageDestination)
+				masked = masked[:m[0]] + strings.Repeat(" ", m[1]-m[0]) + masked[m[1]:]
+			}
+		}
+		for _, m := range referenceUse.FindAllStringSubmatch(masked, -1) {
+			label := m[2]
+			if label == "" {
+				label = m[1]
+			}
+			if dest, ok := targets[referenceLabel(label)]; ok {
+				link(dest, strings.HasPrefix(m[0], "!"))
+			}
+		}
+		for range autolink.FindAllString(masked, -1) {
+			st.ExternalLinks++
+		}
+		st.ExternalLinks += len(bareURLs(line))
+
+		words := []byte(line)
+		if box >= 0 {
+			copy(words[box:], "   ")
+		}
+		for _, sp := range nonProseSpans(line) {
+			for k := sp[0]; k < sp[1]; k++ {
+				words[k] = ' '
+			}
+		}
+		for _, w := range strings.Fields(string(words)) {
+			if strings.IndexFunc(w, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+				st.Words++
+			}
+		}
+	}
+	return st
+}
+
+// maskCode blanks out the code spans and other protectedSpans of line,
+// keeping byte offsets.
+func maskCode(line string) string {
+	buf := []byte(line)
+	for _, sp := range protectedSpans(line) {
+		for k := sp[0]; k < sp[1]; k++ {
+			buf[k] = ' '
+		}
+	}
+	return string(buf)
+}
+
+// add adds the counts of t to st.
+func (st *documentStats) add(t documentStats) {
+	for i, n := range t.Headings {
+		st.Headings[i] += n
+	}
+	st.Words += t.Words
+	for lang, n := range t.CodeBlocks {
+		st.CodeBlocks[lang] += n
+	}
+	st.Tables += t.Tables
+	st.Images += t.Images
+	st.InternalLinks += t.InternalLinks
+	st.ExternalLinks += t.ExternalLinks
+	st.Tasks += t.Tasks
+	st.TasksDone += t.TasksDone
+}
+
+// statsReport is what -stats prints: the counts of each file and their
+// sum.
+type statsReport struct {
+	Files []documentStats `json:"files"`
+	Total documentStats   `json:"total"`
+}
+
+// statsFormats are the formats -stats writes in.
+var statsFormats = []string{"text", "json"}
+
+// runStats reads the files at paths, or stdin as stdinPath when there are
+// none, and writes their statistics to w in format.
+func runStats(w io.Writer, format string, paths []string, stdinPath string) error {
+	if !containsString(statsFormats, format) {
+		return fmt.Errorf("unknown stats format %q (want one of %s)", format, strings.Join(statsFormats, ", "))
+	}
+	report := statsReport{Files: []documentStats{}, Total: documentStats{CodeBlocks: map[string]int{}}}
+	read := func(path string) ([]byte, error) { return os.ReadFile(path) }
+	if len(paths) == 0 {
+		paths = []string{stdinPath}
+		read = func(string) ([]byte, error) { return io.ReadAll(os.Stdin) }
+	}
+	for _, path := range paths {
+		data, err := read(path)
+		if err != nil {
+			return err
+		}
+		st := collectStats(string(data))
+		st.Path = path
+		report.Files = append(report.Files, st)
+		report.Total.add(st)
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	var buf strings.Builder
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	for _, st := range report.Files {
+		writeStats(tw, st.Path, st)
+	}
+	if len(report.Files) > 1 {
+		writeStats(tw, fmt.Sprintf("total (%d files)", len(report.Files)), report.Total)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// rows without details end in padding
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		out.WriteString(strings.TrimRight(line, " \n"))
+		if strings.HasSuffix(line, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeStats writes the counts of st as a table headed by title.
+func writeStats(w io.Writer, title string, st documentStats) {
+	if title == "" {
+		title = "<stdin>"
+	}
+	fmt.Fprintln(w, title)
+	var levels []string
+	headings := 0
+	for i, n := range st.Headings {
+		if n > 0 {
+			levels = append(levels, fmt.Sprintf("h%d: %d", i+1, n))
+		}
+		headings += n
+	}
+	fmt.Fprintf(w, "  headings\t%d\t%s\n", headings, strings.Join(levels, ", "))
+	fmt.Fprintf(w, "  words\t%d\t\n", st.Words)
+	langs := make([]string, 0, len(st.CodeBlocks))
+	blocks := 0
+	for lang, n := range st.CodeBlocks {
+		if lang == "" {
+			lang = "no language"
+		}
+		langs = append(langs, fmt.Sprintf("%s: %d", lang, n))
+		blocks += n
+	}
+	sort.Strings(langs)
+	fmt.Fprintf(w, "  code blocks\t%d\t%s\n", blocks, strings.Join(langs, ", "))
+	fmt.Fprintf(w, "  tables\t%d\t\n", st.Tables)
+	fmt.Fprintf(w, "  images\t%d\t\n", st.Images)
+	fmt.Fprintf(w, "  links\t%d\tinternal: %d, external: %d\n", st.InternalLinks+st.ExternalLinks, st.InternalLinks, st.ExternalLinks)
+	tasks := ""
+	if st.Tasks > 0 {
+		tasks = fmt.Sprintf("%d done (%d%%)", st.TasksDone, 100*st.TasksDone/st.Tasks)
+	}
+	fmt.Fprintf(w, "  tasks\t%d\t%s\n", st.Tasks, tasks)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const statsDocument = `---
+title: x
+---
+
+# Title
+
+Intro with a [link](other.md), a [site](https://go.dev), <https://x.io> and
+https://bare.example plus ` + "`code [x](y)`" + ` words.
+
+[![CI](https://ci/x.svg)](https://ci/x) ![logo](logo.png) [ref][r] [Ref]
+
+<a href="#top">up</a> <img src="a.png">
+
+Setext
+------
+
+## Tasks
+
+- [ ] one
+- [x] two
+
+| a | b |
+|---|---|
+| 1 | 2 |
+
+` + "```go\n// [not](a link)\n# not a heading\n```\n\n~~~\nplain\n~~~\n" + `
+<!-- mdfmt-disable -->
+
+[r]: https://ref.example
+[ref]: ./local.md
+`
+
+func TestCollectStats(t *testing.T) {
+	want := documentStats{
+		Headings:      [6]int{1, 2},
+		Words:         25,
+		CodeBlocks:    map[string]int{"go": 1, "": 1},
+		Tables:        1,
+		Images:        3,
+		InternalLinks: 3,
+		ExternalLinks: 5,
+		Tasks:         2,
+		TasksDone:     1,
+	}
+	if got := collectStats(statsDocument); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := collectStats(strings.ReplaceAll(statsDocument, "\n", "\r\n")); !reflect.DeepEqual(got, want) {
+		t.Errorf("CRLF: got %+v, want %+v", got, want)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")
+	if err := os.WriteFile(a, []byte("# A\n\n- [x] done\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("## B\n\n```sh\nls\n```\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := runStats(&out, "json", []string{a, b}, ""); err != nil {
+		t.Fatal(err)
+	}
+	var report statsReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 2 || report.Files[1].Path != b {
+		t.Fatalf("files: got %+v", report.Files)
+	}
+	total := documentStats{Headings: [6]int{1, 1}, Words: 3, CodeBlocks: map[string]int{"sh": 1}, Tasks: 1, TasksDone: 1}
+	if !reflect.DeepEqual(report.Total, total) {
+		t.Errorf("total: got %+v, want %+v", report.Total, total)
+	}
+
+	out.Reset()
+	if err := runStats(&out, "text", []string{a}, ""); err != nil {
+		t.Fatal(err)
+	}
+	want := a + `
+  headings     1  h1: 1
+  words        2
+  code blocks  0
+  tables       0
+  images       0
+  links        0  internal: 0, external: 0
+  tasks        1  1 done (100%)
+`
+	if out.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+
+	if err := runStats(&out, "checkstyle", []string{a}, ""); err == nil {
+		t.Error("no error for a format -stats does not write")
+	}
+}
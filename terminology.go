@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 32: report or replace discouraged terms from a wordlist
+// ----------------------------------------------------------------
+
+// term is one wordlist entry.
+type term struct {
+	from, to string
+	message  string
+	// fold is set for all-lowercase terms, which match in any case.
+	fold bool
+}
+
+type TerminologyRule struct {
+	terms []term
+	fix   bool
+}
+
+// NewTerminologyRule constructs a rule for a wordlist given as lines of
+// "term => preferred", each optionally followed by "| message". Blank
+// lines and lines starting with "#" are skipped.
+func NewTerminologyRule(wordlist string, fix bool) (Rule, error) {
+	r := TerminologyRule{fix: fix}
+	for n, line := range strings.Split(wordlist, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from, rest, ok := strings.Cut(line, "=>")
+		to, msg, _ := strings.Cut(rest, "|")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("wordlist line %d: %q is not of the form term => preferred", n+1, line)
+		}
+		r.terms = append(r.terms, term{
+			from:    from,
+			to:      to,
+			message: strings.TrimSpace(msg),
+			fold:    strings.ToLower(from) == from,
+		})
+	}
+	return r, nil
+}
+
+func newTerminologyRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("terminology", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	var wordlist []string
+	if file := o.Get("terminology-file", ""); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("option terminology-file: %w", err)
+		}
+		wordlist = append(wordlist, string(data))
+	}
+	words, err := o.Map("terminology-words", nil)
+	if err != nil {
+		return nil, err
+	}
+	for from, to := range words {
+		wordlist = append(wordlist, from+" => "+to)
+	}
+	return NewTerminologyRule(strings.Join(wordlist, "\n"), mode == "fix")
+}
+
+func (TerminologyRule) Name() string {
+	return "Terminology"
+}
+
+// termMatch is an occurrence of a wordlist term in a line.
+type termMatch struct {
+	start, end int // byte range
+	term       term
+}
+
+// termMatches finds the wordlist terms in the prose of line, leaving out
+// its nonProseSpans. Both the lint and the fix use it.
+func (r TerminologyRule) termMatches(line string) []termMatch {
+	covered := nonProseSpans(line)
+
+	var found []termMatch
+	lower := strings.ToLower(line)
+	for _, t := range r.terms {
+		haystack, needle := line, t.from
+		if t.fold && len(lower) == len(line) {
+			haystack = lower
+		}
+	search:
+		for from := 0; ; {
+			k := strings.Index(haystack[from:], needle)
+			if k < 0 {
+				break
+			}
+			start, end := from+k, from+k+len(needle)
+			from = end
+			if !wordBoundary(line, start, end) {
+				continue
+			}
+			for _, c := range covered {
+				if start < c[1] && c[0] < end {
+					continue search
+				}
+			}
+			found = append(found, termMatch{start, end, t})
+		}
+	}
+	return found
+}
+
+// wordBoundary reports whether line[start:end] is not part of a longer word.
+func wordBoundary(line string, start, end int) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	if r, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWord(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWord(r) {
+		return false
+	}
+	return true
+}
+
+// replacement returns the preferred spelling for matched, keeping a
+// leading capital when the term matches in any case.
+func (t term) replacement(matched string) string {
+	first, _ := utf8.DecodeRuneInString(matched)
+	if t.fold && unicode.IsUpper(first) {
+		r, n := utf8.DecodeRuneInString(t.to)
+		return string(unicode.ToUpper(r)) + t.to[n:]
+	}
+	return t.to
+}
+
+func (r TerminologyRule) Apply(content string) (string, error) {
+	if !r.fix || len(r.terms) == 0 {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		matches := r.termMatches(line)
+		// replace right to left so earlier offsets stay valid, skipping
+		// matches that overlap one already replaced
+		sort.Slice(matches, func(a, b int) bool { return matches[a].start < matches[b].start })
+		last := len(line) + 1
+		for k := len(matches) - 1; k >= 0; k-- {
+			m := matches[k]
+			if m.end > last {
+				continue
+			}
+			line = line[:m.start] + m.term.replacement(line[m.start:m.end]) + line[m.end:]
+			last = m.start
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r TerminologyRule) Lint(content string) []Diagnostic {
+	if len(r.terms) == 0 {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		for _, m := range r.termMatches(line) {
+			matched := line[m.start:m.end]
+			msg := fmt.Sprintf("use %q instead of %q", m.term.replacement(matched), matched)
+			if m.term.message != "" {
+				msg += ": " + m.term.message
+			}
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:m.start]) + 1,
+				Rule:    r.Name(),
+				Key:     m.term.from,
+				Message: msg,
+				Fix:     lineFix(line, m.start, m.end, m.term.replacement(matched)),
+			})
+		}
+	}
+	return diags
+}
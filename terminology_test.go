@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testWordlist = `# preferred spellings
+web-site => website
+Github => GitHub | the product name
+e-mail => email
+`
+
+func TestTerminologyRule(t *testing.T) {
+	rule, err := NewTerminologyRule(testWordlist, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "replacements",
+			input:    "Our web-site is on Github.",
+			expected: "Our website is on GitHub.",
+		},
+		{
+			name:     "lowercase terms match in any case",
+			input:    "Web-site and E-MAIL",
+			expected: "Website and Email",
+		},
+		{
+			name:     "mixed-case terms match exactly",
+			input:    "github and GitHub",
+			expected: "github and GitHub",
+		},
+		{
+			name:     "word boundaries",
+			input:    "web-sites and myweb-site",
+			expected: "web-sites and myweb-site",
+		},
+		{
+			name:     "code, links and front matter skipped",
+			input:    "---\nsite: web-site\n---\n`web-site` [web-site](https://web-site.io) https://github.com/Github\n\n    web-site\n\n[ref]: https://x.io/web-site",
+			expected: "---\nsite: web-site\n---\n`web-site` [website](https://web-site.io) https://github.com/Github\n\n    web-site\n\n[ref]: https://x.io/web-site",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTerminologyRuleLint(t *testing.T) {
+	r, err := NewTerminologyRule(testWordlist, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := r.(TerminologyRule)
+	input := "A web-site.\nSee Github, Web-site."
+	if got, _ := rule.Apply(input); got != input {
+		t.Errorf("lint mode changed the document: %q", got)
+	}
+	want := []Diagnostic{
+		{Line: 1, Column: 3, Rule: "Terminology", Key: "web-site", Message: `use "website" instead of "web-site"`,
+			Fix: &Fix{Start: 1, End: 12, Text: "A website."}},
+		{Line: 2, Column: 13, Rule: "Terminology", Key: "web-site", Message: `use "Website" instead of "Web-site"`,
+			Fix: &Fix{Start: 1, End: 22, Text: "See Github, Website."}},
+		{Line: 2, Column: 5, Rule: "Terminology", Key: "Github", Message: `use "GitHub" instead of "Github": the product name`,
+			Fix: &Fix{Start: 1, End: 22, Text: "See GitHub, Web-site."}},
+	}
+	got := rule.Lint(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	summary := []string{"Terminology: 3 (Github: 1, web-site: 2)"}
+	if s := summarize(got); !reflect.DeepEqual(s, summary) {
+		t.Errorf("summary: got %q, want %q", s, summary)
+	}
+}
+
+func TestTerminologyWordlistErrors(t *testing.T) {
+	if _, err := NewTerminologyRule("web-site website", false); err == nil {
+		t.Error("expected an error for a line without =>")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 27: keep the leading H1 and the front matter title in sync
+// ----------------------------------------------------------------
+
+var (
+	inlineImage = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	inlineLink  = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	// emphasis markers; underscores only at the edges of words
+	emphasis = regexp.MustCompile("\\*+|~~|`+|(?:^|\\b)_+\\B|\\B_+(?:\\b|$)")
+)
+
+type TitleHeadingRule struct {
+	// policy is "front-matter", "h1" or "both-match".
+	policy string
+	loose  bool
+}
+
+// NewTitleHeadingRule constructs a rule that enforces policy between the
+// front matter title and the leading H1. loose comparisons ignore case
+// and differences in whitespace.
+func NewTitleHeadingRule(policy string, loose bool) Rule {
+	return TitleHeadingRule{policy: policy, loose: loose}
+}
+
+func newTitleHeadingRuleFromOptions(o Options) (Rule, error) {
+	policy, err := o.Choice("title-policy", "both-match", "front-matter", "h1", "both-match")
+	if err != nil {
+		return nil, err
+	}
+	match, err := o.Choice("title-match", "exact", "exact", "loose")
+	if err != nil {
+		return nil, err
+	}
+	return NewTitleHeadingRule(policy, match == "loose"), nil
+}
+
+func (TitleHeadingRule) Name() string {
+	return "TitleHeading"
+}
+
+// titleHeading is the H1 that opens the body of a document, spanning
+// lines start..end inclusive.
+type titleHeading struct {
+	start, end int
+	text       string
+}
+
+// leadingHeading finds the H1 on the first non-blank line after the front
+// matter, if there is one.
+func leadingHeading(lines []string, b *blocks) (titleHeading, bool) {
+	i := metadataEnd(lines) + 1
+	for i < len(lines) && b.lines[i].kind == lineBlank {
+		i++
+	}
+	if i == len(lines) || b.lines[i].kind != lineText || b.lines[i].quote > 0 {
+		return titleHeading{}, false
+	}
+	if i+1 < len(lines) && b.lines[i+1].kind == lineSetextUnderline &&
+		strings.HasPrefix(strings.TrimSpace(lines[i+1]), "=") {
+		return titleHeading{i, i + 1, strings.TrimSpace(lines[i])}, true
+	}
+	if text, ok := atxH1Text(lines[i]); ok {
+		return titleHeading{i, i, text}, true
+	}
+	return titleHeading{}, false
+}
+
+// plainText strips the inline formatting from a heading.
+func plainText(s string) string {
+	s = inlineImage.ReplaceAllString(s, "$1")
+	s = inlineLink.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(emphasis.ReplaceAllString(s, ""))
+}
+
+func (r TitleHeadingRule) same(title, heading string) bool {
+	heading = plainText(heading)
+	if !r.loose {
+		return strings.TrimSpace(title) == heading
+	}
+	return strings.EqualFold(strings.Join(strings.Fields(title), " "),
+		strings.Join(strings.Fields(heading), " "))
+}
+
+// frontMatterTitle returns the front matter title, if the document has one.
+func frontMatterTitle(lines []string) (string, bool) {
+	v, ok := frontMatterValues(lines)["title"]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+func (r TitleHeadingRule) Apply(content string) (string, error) {
+	if r.policy == "both-match" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	title, ok := frontMatterTitle(lines)
+	if !ok {
+		return content, nil
+	}
+	h, found := leadingHeading(lines, classifyBlocks(lines))
+	switch {
+	case r.policy == "front-matter" && found && r.same(title, h.text):
+		// drop the heading and the blank lines after it
+		end := h.end + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) == "" && end < len(lines)-1 {
+			end++
+		}
+		lines = append(lines[:h.start], lines[end:]...)
+	case r.policy == "h1" && !found:
+		fm := frontMatterEnd(lines)
+		rest := lines[fm+1:]
+		for len(rest) > 1 && strings.TrimSpace(rest[0]) == "" {
+			rest = rest[1:]
+		}
+		out := append([]string{}, lines[:fm+1]...)
+		out = append(out, "", "# "+title)
+		if len(rest) > 1 || rest[0] != "" {
+			out = append(out, "")
+		}
+		lines = append(out, rest...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r TitleHeadingRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	title, ok := frontMatterTitle(lines)
+	if !ok {
+		return nil
+	}
+	h, found := leadingHeading(lines, classifyBlocks(lines))
+	if !found || r.same(title, h.text) {
+		return nil
+	}
+	return []Diagnostic{{
+		Line:    h.start + 1,
+		Rule:    r.Name(),
+		Message: fmt.Sprintf("heading %q does not match the front matter title %q", h.text, title),
+	}}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTitleHeadingRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		loose    bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "front-matter drops a matching H1",
+			policy:   "front-matter",
+			input:    "---\ntitle: Hello\n---\n\n# Hello\n\nText\n",
+			expected: "---\ntitle: Hello\n---\n\nText\n",
+		},
+		{
+			name:     "front-matter compares plain text",
+			policy:   "front-matter",
+			input:    "---\ntitle: Hello World\n---\n# **Hello** [World](/w)\n",
+			expected: "---\ntitle: Hello World\n---\n",
+		},
+		{
+			name:     "front-matter keeps a different H1",
+			policy:   "front-matter",
+			input:    "---\ntitle: Hello\n---\n\n# Goodbye\n",
+			expected: "---\ntitle: Hello\n---\n\n# Goodbye\n",
+		},
+		{
+			name:     "loose match",
+			policy:   "front-matter",
+			loose:    true,
+			input:    "+++\ntitle = \"Hello  world\"\n+++\nHello World\n===\n\nText",
+			expected: "+++\ntitle = \"Hello  world\"\n+++\nText",
+		},
+		{
+			name:     "h1 inserted from the title",
+			policy:   "h1",
+			input:    "---\ntitle: Hello\n---\nText\n",
+			expected: "---\ntitle: Hello\n---\n\n# Hello\n\nText\n",
+		},
+		{
+			name:     "h1 into an empty body",
+			policy:   "h1",
+			input:    "---\ntitle: Hello\n---\n",
+			expected: "---\ntitle: Hello\n---\n\n# Hello\n",
+		},
+		{
+			name:     "h1 already present",
+			policy:   "h1",
+			input:    "---\ntitle: Hello\n---\n# Other\n",
+			expected: "---\ntitle: Hello\n---\n# Other\n",
+		},
+		{
+			name:     "both-match never rewrites",
+			policy:   "both-match",
+			input:    "---\ntitle: Hello\n---\n# Hello\n",
+			expected: "---\ntitle: Hello\n---\n# Hello\n",
+		},
+		{
+			name:     "no front matter title",
+			policy:   "h1",
+			input:    "---\ndraft: true\n---\nText",
+			expected: "---\ndraft: true\n---\nText",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewTitleHeadingRule(tt.policy, tt.loose)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+			again, _ := rule.Apply(got)
+			if again != got {
+				t.Errorf("not idempotent:\n%q\nthen:\n%q", got, again)
+			}
+		})
+	}
+}
+
+func TestTitleHeadingRuleLint(t *testing.T) {
+	rule := NewTitleHeadingRule("both-match", false).(TitleHeadingRule)
+	if got := rule.Lint("---\ntitle: Hello\n---\n# _Hello_\n"); got != nil {
+		t.Errorf("matching title: got %v", got)
+	}
+	want := []Diagnostic{{
+		Line:    5,
+		Rule:    "TitleHeading",
+		Message: `heading "hello" does not match the front matter title "Hello"`,
+	}}
+	if got := rule.Lint("---\ntitle: Hello\n---\n\n# hello\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Rule 38: turn TODO and FIXME notes into task list items
+// ----------------------------------------------------------------
+
+var defaultTodoMarkers = []string{"TODO", "FIXME", "HACK"}
+
+type TodoTaskRule struct {
+	// marker matches a note: one of the markers, an optional colon and the
+	// text after them.
+	marker *regexp.Regexp
+	strip  bool
+}
+
+// NewTodoTaskRule constructs a rule that turns paragraphs of one line and
+// list items starting with one of markers into unchecked task items. With
+// strip, the marker and its colon are dropped from the text.
+func NewTodoTaskRule(markers []string, strip bool) Rule {
+	quoted := make([]string, len(markers))
+	for i, m := range markers {
+		quoted[i] = regexp.QuoteMeta(m)
+	}
+	return TodoTaskRule{
+		marker: regexp.MustCompile(`^(?:` + strings.Join(quoted, "|") + `)(?::|\s|$)\s*`),
+		strip:  strip,
+	}
+}
+
+func newTodoTaskRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("todo-marker", "keep", "keep", "strip")
+	if err != nil {
+		return nil, err
+	}
+	return NewTodoTaskRule(o.List("todo-markers", defaultTodoMarkers), mode == "strip"), nil
+}
+
+func (TodoTaskRule) Name() string {
+	return "TodoTask"
+}
+
+func (r TodoTaskRule) Apply(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	blankOrNone := func(i int) bool {
+		return i < 0 || i >= len(lines) || b.lines[i].kind == lineBlank || b.lines[i].kind == lineFrontMatter
+	}
+	inComment := false
+	for i, line := range lines {
+		info := b.lines[i]
+		if literalLine(b, i) {
+			continue
+		}
+		masked, open := maskCommentsAndCode(line, inComment)
+		inComment = open
+		if info.kind != lineText || info.quote > 0 || isATXHeading(line) ||
+			(i+1 < len(lines) && b.lines[i+1].kind == lineSetextUnderline) {
+			continue
+		}
+
+		// where the note would start, and what goes in front of the box
+		var at int
+		var prefix string
+		switch {
+		case info.item:
+			_, end, _, _ := listMarker(line)
+			at = end + len(line[end:]) - len(strings.TrimLeft(line[end:], " \t"))
+			prefix = line[:at]
+		case info.container == 0 && info.indent < 4 && blankOrNone(i-1) && blankOrNone(i+1):
+			at = len(line) - len(strings.TrimLeft(line, " \t"))
+			prefix = line[:at] + "- "
+		default:
+			continue
+		}
+		m := r.marker.FindString(masked[at:])
+		if m == "" {
+			continue
+		}
+		text := line[at:]
+		if r.strip && len(m) < len(text) {
+			text = text[len(m):]
+		}
+		lines[i] = prefix + "[ ] " + text
+	}
+	return strings.Join(lines, "\n"), nil
+}
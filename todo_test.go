@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestTodoTaskRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		strip    bool
+		input    string
+		expected string
+	}{
+		{
+			name:     "paragraphs and items",
+			input:    "TODO: write the migration guide\n\nFIXME handle errors\n\n- HACK: works for now\n1. TODO later\n",
+			expected: "- [ ] TODO: write the migration guide\n\n- [ ] FIXME handle errors\n\n- [ ] HACK: works for now\n1. [ ] TODO later\n",
+		},
+		{
+			name:     "stripped markers",
+			strip:    true,
+			input:    "TODO: write the guide\n\n- FIXME handle errors\n\nTODO\n",
+			expected: "- [ ] write the guide\n\n- [ ] handle errors\n\n- [ ] TODO\n",
+		},
+		{
+			name:     "idempotent",
+			input:    "- [ ] TODO: done once\n",
+			expected: "- [ ] TODO: done once\n",
+		},
+		{
+			name:     "left alone",
+			input:    "# TODO: heading\n\nTODO: a longer\nparagraph\n\nTODOS are fine\n\n<!--\nTODO: hidden\n-->\n\n`TODO` in code\n\n```\nTODO: code\n```\n\n    TODO: indented\n\n> TODO: quoted\n\nTODO: setext\n---\n",
+			expected: "# TODO: heading\n\nTODO: a longer\nparagraph\n\nTODOS are fine\n\n<!--\nTODO: hidden\n-->\n\n`TODO` in code\n\n```\nTODO: code\n```\n\n    TODO: indented\n\n> TODO: quoted\n\nTODO: setext\n---\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewTodoTaskRule(defaultTodoMarkers, tt.strip)
+			got, err := rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
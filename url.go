@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------------
+// Rule 30: report bare URLs, or turn them into autolinks
+// ----------------------------------------------------------------
+
+type BareURLRule struct {
+	// fix wraps bare URLs in <...> instead of only reporting them.
+	fix bool
+}
+
+func NewBareURLRule(fix bool) Rule { return BareURLRule{fix: fix} }
+
+func newBareURLRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("bare-url", "lint", "lint", "fix")
+	if err != nil {
+		return nil, err
+	}
+	return NewBareURLRule(mode == "fix"), nil
+}
+
+func (BareURLRule) Name() string {
+	return "BareURL"
+}
+
+func (r BareURLRule) Apply(content string) (string, error) {
+	if !r.fix {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		urls := bareURLs(line)
+		for k := len(urls) - 1; k >= 0; k-- {
+			u := urls[k]
+			line = line[:u[0]] + "<" + line[u[0]:u[1]] + ">" + line[u[1]:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r BareURLRule) Lint(content string) []Diagnostic {
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	var diags []Diagnostic
+	for i, line := range lines {
+		if literalLine(b, i) {
+			continue
+		}
+		for _, u := range bareURLs(line) {
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(line[:u[0]]) + 1,
+				Rule:    r.Name(),
+				Message: fmt.Sprintf("bare URL %s; write it as <%s>", line[u[0]:u[1]], line[u[0]:u[1]]),
+				Fix:     lineFix(line, u[0], u[1], "<"+line[u[0]:u[1]]+">"),
+			})
+		}
+	}
+	return diags
+}
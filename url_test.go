@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBareURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"plain", "see https://example.com/x for more", []string{"https://example.com/x"}},
+		{"sentence punctuation", "Go to http://a.io/b.", []string{"http://a.io/b"}},
+		{"balanced parens", "(see https://en.wikipedia.org/wiki/Go_(language))", []string{"https://en.wikipedia.org/wiki/Go_(language)"}},
+		{"autolink", "<https://a.io>", nil},
+		{"inline link", "[https://a.io](https://a.io) and ![img](http://b.io/i.png)", nil},
+		{"code span", "`curl https://a.io`", nil},
+		{"html attribute", `<a href="https://a.io">text</a>`, nil},
+		{"reference definition", "[ref]: https://a.io", nil},
+		{"several", "https://a.io and <https://b.io> and https://c.io", []string{"https://a.io", "https://c.io"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, u := range bareURLs(tt.line) {
+				got = append(got, tt.line[u[0]:u[1]])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBareURLRule(t *testing.T) {
+	input := "Visit https://a.io/x.\n\n```\nhttps://in.code\n```\n\n    https://indented.code\n\n> ünï https://b.io"
+
+	fixed, err := NewBareURLRule(true).Apply(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Visit <https://a.io/x>.\n\n```\nhttps://in.code\n```\n\n    https://indented.code\n\n> ünï <https://b.io>"
+	if fixed != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, fixed)
+	}
+
+	lint := NewBareURLRule(false).(BareURLRule)
+	if got, _ := lint.Apply(input); got != input {
+		t.Errorf("lint mode changed the document: %q", got)
+	}
+	want := []Diagnostic{
+		{Line: 1, Column: 7, Rule: "BareURL", Message: "bare URL https://a.io/x; write it as <https://a.io/x>",
+			Fix: &Fix{Start: 1, End: 22, Text: "Visit <https://a.io/x>."}},
+		{Line: 9, Column: 7, Rule: "BareURL", Message: "bare URL https://b.io; write it as <https://b.io>",
+			Fix: &Fix{Start: 1, End: 21, Text: "> ünï <https://b.io>"}},
+	}
+	if got := lint.Lint(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := lint.Lint(fixed); got != nil {
+		t.Errorf("fixed document still reported: %v", got)
+	}
+}
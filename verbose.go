@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// ----------------------------------------------------------------
+// Attributing changes to rules (-v, -vv)
+// ----------------------------------------------------------------
+
+// ruleChanges returns a Formatter trace that reports on w each rule that
+// changes the document at path, "" for stdin, as "Rule: 3 lines changed",
+// preceded by the path if any. With diff the changes follow as a unified
+// diff.
+func ruleChanges(w io.Writer, path string, diff bool) func(rule, before, after string) {
+	prefix := ""
+	if path != "" {
+		prefix = path + ": "
+	}
+	return func(rule, before, after string) {
+		if before == after {
+			return
+		}
+		n := changedLines(before, after)
+		noun := "lines"
+		if n == 1 {
+			noun = "line"
+		}
+		fmt.Fprintf(w, "%s%s: %d %s changed\n", prefix, rule, n, noun)
+		if diff {
+			io.WriteString(w, unifiedDiff("before "+rule, "after "+rule, before, after))
+		}
+	}
+}
+
+// changedLines counts the lines in which before and after differ: for
+// each run of changed lines, the larger of the number of lines removed
+// and added.
+func changedLines(before, after string) int {
+	n := 0
+	for _, e := range lineEdits(before, after) {
+		n += max(e.EndLine-e.StartLine, len(e.NewLines))
+	}
+	return n
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestChangedLines(t *testing.T) {
+	tests := []struct {
+		before, after string
+		want          int
+	}{
+		{"a\nb\n", "a\nb\n", 0},
+		{"a\nb\n", "a\n\nb\n", 1},
+		{"a\nb\nc\n", "a\nB\nC\n", 2},
+		{"a\nb\nc\nd\n", "x\nb\nc\n", 2},
+	}
+	for _, tt := range tests {
+		if got := changedLines(tt.before, tt.after); got != tt.want {
+			t.Errorf("%q -> %q: got %d, want %d", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	input := "# Title\ntext\n| a |\n|---|\n*  item\n*  item\n"
+	tests := []struct {
+		name    string
+		verbose int
+		path    string
+		stderr  string
+	}{
+		{
+			name:    "rules",
+			verbose: 1,
+			stderr: "BlankLineAfterHeading: 1 line changed\n" +
+				"BlankLineBeforeTable: 1 line changed\n" +
+				"SingleSpaceAfterListItem: 2 lines changed\n",
+		},
+		{
+			name:    "with path",
+			verbose: 1,
+			path:    "doc.md",
+			stderr: "doc.md: BlankLineAfterHeading: 1 line changed\n" +
+				"doc.md: BlankLineBeforeTable: 1 line changed\n" +
+				"doc.md: SingleSpaceAfterListItem: 2 lines changed\n",
+		},
+		{
+			name: "quiet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			// the cache would skip the formatting -v reports on
+			run := runOptions{format: "text", verbose: tt.verbose, cacheDir: t.TempDir()}
+			if _, err := run.formatAndReport(&stdout, &stderr, tt.path, input); err != nil {
+				t.Fatal(err)
+			}
+			if got := stderr.String(); got != tt.stderr {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.stderr, got)
+			}
+			if want := "# Title\n\ntext\n\n| a |\n|---|\n- item\n- item\n"; stdout.String() != want {
+				t.Errorf("expected:\n%q\ngot:\n%q", want, stdout.String())
+			}
+		})
+	}
+
+	var stderr strings.Builder
+	run := runOptions{format: "text", verbose: 2}
+	if _, err := run.formatAndReport(&strings.Builder{}, &stderr, "", "# Title\ntext\n"); err != nil {
+		t.Fatal(err)
+	}
+	want := "BlankLineAfterHeading: 1 line changed\n" +
+		"--- before BlankLineAfterHeading\n+++ after BlankLineAfterHeading\n@@ -1,2 +1,3 @@\n # Title\n+\n text\n"
+	if stderr.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, stderr.String())
+	}
+}
+
+func TestFormatterTrace(t *testing.T) {
+	var traced []string
+	f := NewFormatter(NewBlankLineAfterHeadingRule(), NewInlineMathReplaceRule(), NewBlankLineBeforeTableRule())
+	traceless := f.Clone()
+	f = f.WithTrace(func(rule, before, after string) {
+		traced = append(traced, rule+"="+strconv.FormatBool(before != after))
+	})
+	got, err := f.Format("# A\ntext\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "# A\n\ntext\n" {
+		t.Errorf("got %q", got)
+	}
+	// InlineMathToDollar finds nothing to trigger it and does not run
+	if want := "BlankLineAfterHeading=true,BlankLineBeforeTable=false"; strings.Join(traced, ",") != want {
+		t.Errorf("got %q, want %q", traced, want)
+	}
+	traced = nil
+	traceless.Format("# A\ntext\n")
+	if traced != nil {
+		t.Errorf("the original is traced: %q", traced)
+	}
+}
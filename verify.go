@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// renderHTML renders the body of content, without its front matter or
+// title block, which goldmark would take for prose. Raw HTML is kept, so
+// that -verify also notices changes to comments and inline HTML.
+func renderHTML(content string) (string, error) {
+	_, body := splitMetadata(content)
+	var buf bytes.Buffer
+	if err := gfm.Convert([]byte(body), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// formatVerified formats content like f.Format, checking after each rule
+// that the rendered HTML did not change. Rules that change the rendering
+// on purpose are not checked.
+func formatVerified(f *Formatter, content string) (string, error) {
+	if err := f.limits.checkSize(content); err != nil {
+		return "", err
+	}
+	want, err := renderHTML(content)
+	if err != nil {
+		return "", err
+	}
+	content, hidden := f.limits.hideLongLines(content)
+	var scan triggerScan
+	for _, r := range f.rules {
+		if scan.skip(r, content) {
+			continue
+		}
+		out, err := f.limits.applyRule(context.Background(), r, content)
+		if err != nil {
+			return "", &RuleError{Rule: r.Name(), err: fmt.Errorf("rule %q failed: %w", r.Name(), err)}
+		}
+		if f.trace != nil {
+			f.trace(r.Name(), content, out)
+		}
+		if out == content {
+			continue
+		}
+		content = out
+		got, err := renderHTML(content)
+		if err != nil {
+			return "", err
+		}
+		if got != want && !changesRendering(r.Name()) {
+			return "", &RuleError{Rule: r.Name(), err: fmt.Errorf("rule %q changed the rendered HTML:\n%s", r.Name(), htmlDiff(want, got))}
+		}
+		want = got
+	}
+	return restoreLongLines(content, hidden)
+}
+
+// htmlDiff shows the first region in which the HTML lines of a and b
+// differ, with a line of context on either side.
+func htmlDiff(a, b string) string {
+	al := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	bl := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	start := 0
+	for start < len(al) && start < len(bl) && al[start] == bl[start] {
+		start++
+	}
+	endA, endB := len(al), len(bl)
+	for endA > start && endB > start && al[endA-1] == bl[endB-1] {
+		endA--
+		endB--
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		fmt.Fprintf(&sb, "  %s\n", al[start-1])
+	}
+	for _, l := range al[start:endA] {
+		fmt.Fprintf(&sb, "- %s\n", l)
+	}
+	for _, l := range bl[start:endB] {
+		fmt.Fprintf(&sb, "+ %s\n", l)
+	}
+	if endA < len(al) {
+		fmt.Fprintf(&sb, "  %s\n", al[endA])
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatVerified(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		input   string
+		wantErr string
+	}{
+		{
+			name:  "layout only",
+			rules: []Rule{NewBlankLineAfterHeadingRule(), NewThematicBreakStyleRule("---")},
+			input: "# Title\ntext\n\n***\n",
+		},
+		{
+			name:  "front matter is not rendered",
+			rules: []Rule{NewBlankLineAfterHeadingRule()},
+			input: "---\ntitle: x\n---\n# Title\ntext\n",
+		},
+		{
+			name:  "rules that change rendering on purpose",
+			rules: []Rule{NewInlineMathReplaceRule(), NewFencedCodeLanguageRule(true, "text", false)},
+			input: "Text \\(x\\)\n\n```\ncode\n```\n",
+		},
+		{
+			name:    "merged lists",
+			rules:   []Rule{NewSingleSpaceAfterListItemRule()},
+			input:   "- a\n\n* b\n",
+			wantErr: `rule "SingleSpaceAfterListItem" changed the rendered HTML`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFormatter(tt.rules...)
+			got, err := formatVerified(f, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want, _ := f.Format(tt.input); got != want {
+				t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+			}
+		})
+	}
+}
+
+func TestHTMLDiff(t *testing.T) {
+	a := "<h1>T</h1>\n<p>a</p>\n<p>b</p>\n<p>c</p>\n"
+	b := "<h1>T</h1>\n<p>a</p>\n<p>B</p>\n<p>c</p>\n"
+	expected := "  <p>a</p>\n- <p>b</p>\n+ <p>B</p>\n  <p>c</p>"
+	if got := htmlDiff(a, b); got != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------------------
+// Finding the Markdown files named by command-line arguments
+// ----------------------------------------------------------------
+
+// defaultExtensions are the extensions of the files mdfmt formats in the
+// directories it walks.
+var defaultExtensions = []string{"md", "markdown"}
+
+// fileFinder expands the command-line arguments into the files to format.
+type fileFinder struct {
+	// extensions are the extensions, without the dot, of the files found
+	// in directories and by patterns.
+	extensions []string
+	// exclude are the -exclude patterns, relative to the directory walked.
+	exclude ignoreList
+}
+
+// patternFlag collects the values of a repeatable flag such as -exclude.
+type patternFlag []string
+
+func (p *patternFlag) String() string { return strings.Join(*p, ",") }
+
+func (p *patternFlag) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// isPattern reports whether arg is a glob pattern rather than a path.
+func isPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// find returns the files named by args, in order and each once, and
+// whether any argument was a directory or pattern. A file named itself is
+// formatted whatever its extension. A directory, or a path ending in
+// "/...", stands for the Markdown files below it; a pattern such as
+// "docs/**/*.md" for the Markdown files it matches. Hidden directories
+// and symbolic links to directories are not entered.
+func (f fileFinder) find(args []string) (files []string, walked bool, err error) {
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	for _, arg := range args {
+		root, pattern := arg, ""
+		switch {
+		case strings.HasSuffix(arg, "/..."):
+			root = strings.TrimSuffix(arg, "/...")
+			if root == "" {
+				root = "/"
+			}
+		case isPattern(arg):
+			root, pattern = patternRoot(filepath.ToSlash(arg))
+		default:
+			info, err := os.Stat(arg)
+			if err != nil || !info.IsDir() {
+				// reading it reports the error
+				add(arg)
+				continue
+			}
+		}
+		walked = true
+		found, err := f.walk(root, pattern)
+		if err != nil {
+			return nil, walked, err
+		}
+		if pattern != "" && len(found) == 0 {
+			return nil, walked, fmt.Errorf("no Markdown files match %s", arg)
+		}
+		for _, p := range found {
+			add(p)
+		}
+	}
+	return files, walked, nil
+}
+
+// warnExcluded warns on w about the files named explicitly in args that
+// -exclude, relative to the current directory, or an ignore file would
+// leave out of a walk. They are formatted all the same.
+func (f fileFinder) warnExcluded(w io.Writer, args []string) {
+	for _, arg := range args {
+		if strings.HasSuffix(arg, "/...") || isPattern(arg) {
+			continue
+		}
+		if info, err := os.Stat(arg); err != nil || info.IsDir() {
+			continue
+		}
+		if by := f.excludedBy(arg); by != "" {
+			fmt.Fprintf(w, "warning: %s is excluded by %s; formatting it as it was named explicitly\n", arg, by)
+		}
+	}
+}
+
+// excludedBy returns what excludes the file at path from a walk, -exclude
+// or the ignore file name, or "" when nothing does. Only the ignore files
+// from the current directory down to that of path apply, or with path
+// outside the current directory the one next to it.
+func (f fileFinder) excludedBy(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	top := filepath.Dir(abs)
+	rel := filepath.Base(abs)
+	if cwd, err := os.Getwd(); err == nil {
+		if r, err := filepath.Rel(cwd, abs); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			top, rel = cwd, r
+		}
+	}
+	if f.exclude.excludes(filepath.ToSlash(rel), false) {
+		return "-exclude"
+	}
+	ignores := ignoreFiles{}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if err := ignores.load(dir); err != nil {
+			return ""
+		}
+		if dir == top || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	// a walk never enters an excluded directory
+	for p, dir := abs, false; p != top && filepath.Dir(p) != p; p, dir = filepath.Dir(p), true {
+		if ignores.excludes(top, p, dir) {
+			return ignoreFileName
+		}
+	}
+	return ""
+}
+
+// patternRoot splits a slash pattern into the directory before its first
+// component with a wildcard and the rest.
+func patternRoot(pattern string) (root, rest string) {
+	parts := strings.Split(pattern, "/")
+	i := 0
+	for i < len(parts)-1 && !isPattern(parts[i]) {
+		i++
+	}
+	root = filepath.FromSlash(strings.Join(parts[:i], "/"))
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+	return root, strings.Join(parts[i:], "/")
+}
+
+// walk returns the Markdown files below root, in lexical order, that the
+// slash pattern, relative to root, matches; all of them without one. The
+// .mdfmtignore files in root, below it and in the directories between the
+// current directory and root exclude files as -exclude does, so that
+// excluded files and directories are never read.
+func (f fileFinder) walk(root, pattern string) ([]string, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		re = globRegexp(pattern)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// the ignore files of the project apply to a walk of a subdirectory
+	top := absRoot
+	ignores := ignoreFiles{}
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, absRoot); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			top = cwd
+		}
+	}
+	for dir := filepath.Dir(absRoot); top != absRoot; dir = filepath.Dir(dir) {
+		if err := ignores.load(dir); err != nil {
+			return nil, err
+		}
+		if dir == top {
+			break
+		}
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		abs := filepath.Join(absRoot, rel)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || f.exclude.excludes(rel, true) || ignores.excludes(top, abs, true)) {
+				return filepath.SkipDir
+			}
+			return ignores.load(abs)
+		}
+		// symbolic links to directories are left alone, against cycles
+		if d.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		if !f.markdown(d.Name()) || f.exclude.excludes(rel, false) || ignores.excludes(top, abs, false) || (re != nil && !re.MatchString(rel)) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// markdown reports whether the file name has one of the extensions.
+func (f fileFinder) markdown(name string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	for _, e := range f.extensions {
+		if strings.EqualFold(ext, strings.TrimPrefix(e, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileFinder(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"README.md":               "",
+		"notes.txt":               "",
+		"docs/index.md":           "",
+		"docs/guide.markdown":     "",
+		"docs/page.mdx":           "",
+		"docs/api/ref.md":         "",
+		"docs/api/deep/more.MD":   "",
+		"docs/api/logo.png":       "",
+		"docs/.drafts/draft.md":   "",
+		"docs/vendor/vendor.md":   "",
+		".github/template.md":     "",
+		"other/docs/unrelated.md": "",
+	})
+	if err := os.Symlink(filepath.Join(dir, "docs"), filepath.Join(dir, "docs", "api", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "README.md"), filepath.Join(dir, "docs", "readme-link.md")); err != nil {
+		t.Fatal(err)
+	}
+	// paths are relative to dir in the test
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name    string
+		finder  fileFinder
+		args    []string
+		want    []string
+		walked  bool
+		wantErr bool
+	}{
+		{
+			name: "files as given",
+			args: []string{"notes.txt", "missing.md", "notes.txt"},
+			want: []string{"notes.txt", "missing.md"},
+		},
+		{
+			name:   "directory",
+			args:   []string{"docs"},
+			want:   []string{"docs/api/deep/more.MD", "docs/api/ref.md", "docs/guide.markdown", "docs/index.md", "docs/readme-link.md", "docs/vendor/vendor.md"},
+			walked: true,
+		},
+		{
+			name:   "dots",
+			finder: fileFinder{extensions: []string{"mdx"}},
+			args:   []string{"./docs/..."},
+			want:   []string{"docs/page.mdx"},
+			walked: true,
+		},
+		{
+			name:   "pattern",
+			args:   []string{"docs/**/*.md"},
+			want:   []string{"docs/api/ref.md", "docs/index.md", "docs/readme-link.md", "docs/vendor/vendor.md"},
+			walked: true,
+		},
+		{
+			name:   "pattern for other files",
+			args:   []string{"docs/api/*"},
+			want:   []string{"docs/api/ref.md"},
+			walked: true,
+		},
+		{
+			name:   "excluded",
+			finder: fileFinder{exclude: parseIgnorePatterns([]string{"vendor/", "deep"})},
+			args:   []string{"docs"},
+			want:   []string{"docs/api/ref.md", "docs/guide.markdown", "docs/index.md", "docs/readme-link.md"},
+			walked: true,
+		},
+		{
+			name:    "pattern without matches",
+			args:    []string{"docs/*.txt"},
+			walked:  true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.finder.extensions == nil {
+				tt.finder.extensions = defaultExtensions
+			}
+			got, walked, err := tt.finder.find(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+			var want []string
+			for _, p := range tt.want {
+				want = append(want, filepath.FromSlash(p))
+			}
+			if !reflect.DeepEqual(got, want) || walked != tt.walked {
+				t.Errorf("got %q, %t; want %q, %t", got, walked, want, tt.walked)
+			}
+		})
+	}
+}
+
+func TestIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".mdfmtignore":                "# vendored\nLICENSE.md\nbuild/\ngenerated/**/*.md\n!generated/keep/important.md\n",
+		"README.md":                   "",
+		"LICENSE.md":                  "",
+		"build/out.md":                "",
+		"generated/a/b.md":            "",
+		"generated/keep/important.md": "",
+		"docs/.mdfmtignore":           "api/\n*.gen.md\n/top.md\n",
+		"docs/index.md":               "",
+		"docs/top.md":                 "",
+		"docs/LICENSE.md":             "",
+		"docs/build/x.md":             "",
+		"docs/api/ref.md":             "",
+		"docs/y.gen.md":               "",
+		"docs/sub/.mdfmtignore":       "!x.gen.md\n",
+		"docs/sub/top.md":             "",
+		"docs/sub/x.gen.md":           "",
+		"docs/sub/z.gen.md":           "",
+	})
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	finder := fileFinder{extensions: defaultExtensions}
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"."},
+			want: []string{"README.md", "docs/index.md", "docs/sub/top.md", "docs/sub/x.gen.md", "generated/keep/important.md"},
+		},
+		{
+			// the ignore files above docs still apply
+			args: []string{"docs"},
+			want: []string{"docs/index.md", "docs/sub/top.md", "docs/sub/x.gen.md"},
+		},
+		{
+			args: []string{"docs/sub/..."},
+			want: []string{"docs/sub/top.md", "docs/sub/x.gen.md"},
+		},
+		{
+			args: []string{"**/*.gen.md"},
+			want: []string{"docs/sub/x.gen.md"},
+		},
+		{
+			// files named themselves are formatted all the same
+			args: []string{"LICENSE.md", "docs/api/ref.md"},
+			want: []string{"LICENSE.md", "docs/api/ref.md"},
+		},
+	} {
+		got, _, err := finder.find(tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, p := range tt.want {
+			want = append(want, filepath.FromSlash(p))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %q, want %q", tt.args, got, want)
+		}
+	}
+}
+
+func TestFormatWalkedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"docs/a.md":     "# A\ntext\n",
+		"docs/sub/b.md": "# B\n\ntext\n",
+		"docs/c.txt":    "# C\ntext\n",
+	})
+	files, _, err := fileFinder{extensions: defaultExtensions}.find([]string{filepath.Join(dir, "docs")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := runOptions{format: "text", write: true}
+	var stdout, stderr strings.Builder
+	changed, err := run.formatFiles(&stdout, &stderr, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := run.summary(len(files), changed); got != "2 file(s) visited, 1 changed" {
+		t.Errorf("summary: got %q", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "a.md")); string(data) != "# A\n\ntext\n" {
+		t.Errorf("a.md: got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "c.txt")); string(data) != "# C\ntext\n" {
+		t.Errorf("c.txt was formatted: %q", data)
+	}
+}
+
+func TestWarnExcluded(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".mdfmtignore":        "build/\n",
+		"docs/a.md":           "",
+		"docs/b.md":           "",
+		"build/out.md":        "",
+		"vendor/.mdfmtignore": "*.md\n",
+		"vendor/lib.md":       "",
+	})
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	finder := fileFinder{extensions: defaultExtensions, exclude: parseIgnorePatterns([]string{"docs/a.md"})}
+	args := []string{"docs/a.md", "docs/b.md", "build/out.md", "vendor/lib.md", "docs", "missing.md"}
+	files, _, err := finder.find(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// named explicitly, excluded files are formatted all the same
+	for _, p := range []string{"docs/a.md", "build/out.md", "vendor/lib.md"} {
+		if !slices.Contains(files, filepath.FromSlash(p)) {
+			t.Errorf("%s not found in %q", p, files)
+		}
+	}
+	var stderr strings.Builder
+	finder.warnExcluded(&stderr, args)
+	want := "warning: docs/a.md is excluded by -exclude; formatting it as it was named explicitly\n" +
+		"warning: build/out.md is excluded by .mdfmtignore; formatting it as it was named explicitly\n" +
+		"warning: vendor/lib.md is excluded by .mdfmtignore; formatting it as it was named explicitly\n"
+	if stderr.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stderr.String(), want)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ----------------------------------------------------------------
+// Reformatting files as they change (-watch)
+// ----------------------------------------------------------------
+
+// defaultWatchInterval is how often -watch looks for changed files.
+const defaultWatchInterval = 500 * time.Millisecond
+
+// fileStamp is what tells a changed file from an unchanged one.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// watcher reformats the files its arguments stand for as they change. It
+// polls: each scan expands the arguments anew, as a run would, so new
+// files are picked up, files in deleted directories dropped and the
+// ignore files respected, and compares the files with the scan before.
+type watcher struct {
+	run    runOptions
+	finder fileFinder
+	args   []string
+	// seen holds the stamps of the files at the last scan, nil before
+	// the first.
+	seen map[string]fileStamp
+	// pending are the files that changed at the last scan. They are
+	// formatted once a scan finds them unchanged, so that a burst of
+	// writes, such as an editor saving twice, is formatted once.
+	pending map[string]bool
+}
+
+func newWatcher(run runOptions, finder fileFinder, args []string) *watcher {
+	return &watcher{run: run, finder: finder, args: args, pending: map[string]bool{}}
+}
+
+// scan returns the stamps of the files the arguments stand for now.
+func (w *watcher) scan() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	// a pattern matching nothing for now is no reason to stop watching
+	files, _, _ := w.finder.find(w.args)
+	for _, path := range files {
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			stamps[path] = fileStamp{fi.ModTime(), fi.Size()}
+		}
+	}
+	return stamps
+}
+
+// poll scans the files and formats those that have settled since they
+// changed, reporting each one formatting changed on stderr. The files
+// mdfmt itself rewrites are stamped afresh, so the rewrite does not count
+// as a change.
+func (w *watcher) poll(stdout, stderr io.Writer) {
+	stamps := w.scan()
+	if w.seen == nil {
+		w.seen = stamps
+		return
+	}
+	var ready []string
+	for path, st := range stamps {
+		old, ok := w.seen[path]
+		switch {
+		case !ok || old != st:
+			w.pending[path] = true
+		case w.pending[path]:
+			ready = append(ready, path)
+			delete(w.pending, path)
+		}
+	}
+	for path := range w.pending {
+		if _, ok := stamps[path]; !ok {
+			delete(w.pending, path)
+		}
+	}
+	w.seen = stamps
+	if len(ready) == 0 {
+		return
+	}
+	sort.Strings(ready)
+	for _, path := range ready {
+		changed, failed := w.run.formatFile(stdout, stderr, path)
+		if changed && !failed {
+			fmt.Fprintf(stderr, "%s reformatted\n", path)
+		}
+		if fi, err := os.Stat(path); err == nil {
+			w.seen[path] = fileStamp{fi.ModTime(), fi.Size()}
+		}
+	}
+}
+
+// watch polls every interval until stop is closed.
+func (w *watcher) watch(stdout, stderr io.Writer, interval time.Duration, stop <-chan struct{}) {
+	w.poll(stdout, stderr)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			w.poll(stdout, stderr)
+		}
+	}
+}
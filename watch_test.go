@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".mdfmtignore":  "vendor/\n",
+		"clean.md":      "# Clean\n\ntext\n",
+		"vendor/x.md":   "# X\ntext\n",
+		"docs/intro.md": "# Intro\n\ntext\n",
+	})
+	run := runOptions{format: "text", write: true}
+	w := newWatcher(run, fileFinder{extensions: defaultExtensions}, []string{dir})
+	var stderr strings.Builder
+	poll := func() string {
+		stderr.Reset()
+		var stdout strings.Builder
+		w.poll(&stdout, &stderr)
+		if stdout.Len() > 0 {
+			t.Errorf("printed %q", stdout.String())
+		}
+		return stderr.String()
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	reformatted := func(name string) string {
+		return filepath.Join(dir, name) + " reformatted\n"
+	}
+
+	// the first scan only takes note of the files
+	if got := poll(); got != "" {
+		t.Errorf("first scan: %q", got)
+	}
+
+	// a burst of writes is formatted once it settles, and once only
+	write("clean.md", "# Clean\ntext\n")
+	write("clean.md", "# Clean\ntext\nmore\n")
+	if got := poll(); got != "" {
+		t.Errorf("formatted before settling: %q", got)
+	}
+	if got := poll(); got != reformatted("clean.md") {
+		t.Errorf("got %q", got)
+	}
+	if got := read("clean.md"); got != "# Clean\n\ntext\nmore\n" {
+		t.Errorf("expected:\n%q\ngot:\n%q", "# Clean\n\ntext\nmore\n", got)
+	}
+	// the rewrite is no change of its own
+	for range 3 {
+		if got := poll(); got != "" {
+			t.Errorf("formatted again: %q", got)
+		}
+	}
+
+	// new files and directories are picked up; ignored ones are not
+	if err := os.MkdirAll(filepath.Join(dir, "new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write("new/page.md", "# Page\ntext\n")
+	write("vendor/y.md", "# Y\ntext\n")
+	poll()
+	if got := poll(); got != reformatted("new/page.md") {
+		t.Errorf("got %q", got)
+	}
+	if got := read("vendor/y.md"); got != "# Y\ntext\n" {
+		t.Errorf("ignored file formatted: %q", got)
+	}
+
+	// a file already formatted is left alone, and deleted directories
+	// drop out
+	write("docs/intro.md", "# Intro\n\nnew text\n")
+	if err := os.RemoveAll(filepath.Join(dir, "new")); err != nil {
+		t.Fatal(err)
+	}
+	poll()
+	if got := poll(); got != "" {
+		t.Errorf("got %q", got)
+	}
+	if _, ok := w.seen[filepath.Join(dir, "new", "page.md")]; ok {
+		t.Error("deleted file still watched")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "# A\n\ntext\n"})
+	w := newWatcher(runOptions{format: "text", write: true}, fileFinder{extensions: defaultExtensions}, []string{dir})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.watch(io.Discard, io.Discard, 5*time.Millisecond, stop)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A\ntext\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(filepath.Join(dir, "a.md"))
+		if string(data) == "# A\n\ntext\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not reformatted: %q", data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	<-done
+}
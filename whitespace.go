@@ -0,0 +1,215 @@
+package main
+
+import "strings"
+
+// ----------------------------------------------------------------
+// Rule 55: expand tabs in indentation into spaces
+// ----------------------------------------------------------------
+
+type ExpandTabsRule struct {
+	expand bool
+	// width is the tab width inside fenced code blocks. Markdown itself
+	// always has tab stops every four columns.
+	width int
+}
+
+// NewExpandTabsRule constructs a rule that, with expand, replaces the
+// tabs in the indentation of each line by spaces: up to the next multiple
+// of four columns in Markdown, which renders the same, and of width in
+// fenced code.
+func NewExpandTabsRule(expand bool, width int) Rule {
+	return ExpandTabsRule{expand: expand, width: width}
+}
+
+func newExpandTabsRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("tabs", "keep", "keep", "expand")
+	if err != nil {
+		return nil, err
+	}
+	width, err := o.Int("tab-width", 4)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 {
+		width = 4
+	}
+	return NewExpandTabsRule(mode == "expand", width), nil
+}
+
+func (ExpandTabsRule) Name() string {
+	return "ExpandTabs"
+}
+
+func (r ExpandTabsRule) Apply(content string) (string, error) {
+	if !r.expand || !strings.Contains(content, "\t") {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		info := b.lines[i]
+		if info.kind == lineFrontMatter {
+			continue
+		}
+		width := 4
+		if info.kind == lineCode && info.fence >= 0 {
+			width = r.width
+		}
+		// the tabs in the blockquote markers too
+		prefix := expandIndentation(line[:info.bodyStart], 0, 4)
+		body := line[info.bodyStart:]
+		if _, end, _, ok := listMarker(body); ok && info.item {
+			// and between a list marker and the item's text
+			prefix += expandIndentation(body[:end], len(prefix), 4)
+			body = body[end:]
+		}
+		lines[i] = prefix + expandIndentation(body, len(prefix), width)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// expandIndentation replaces the tabs in the leading whitespace and
+// blockquote markers of s, which starts at column col, by spaces up to
+// the next multiple of width columns.
+func expandIndentation(s string, col, width int) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\t':
+			n := width - col%width
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+		case ' ', '>':
+			sb.WriteByte(s[i])
+			col++
+		default:
+			return sb.String() + s[i:]
+		}
+	}
+	return sb.String()
+}
+
+// ----------------------------------------------------------------
+// Rule 56: trim trailing whitespace
+// ----------------------------------------------------------------
+
+type TrailingWhitespaceRule struct {
+	trim bool
+}
+
+// NewTrailingWhitespaceRule constructs a rule that, with trim, deletes
+// the spaces and tabs at the end of lines, except in code blocks and the
+// two or more spaces of a hard line break.
+func NewTrailingWhitespaceRule(trim bool) Rule {
+	return TrailingWhitespaceRule{trim: trim}
+}
+
+func newTrailingWhitespaceRuleFromOptions(o Options) (Rule, error) {
+	mode, err := o.Choice("trailing-whitespace", "keep", "keep", "trim")
+	if err != nil {
+		return nil, err
+	}
+	return NewTrailingWhitespaceRule(mode == "trim"), nil
+}
+
+func (TrailingWhitespaceRule) Name() string {
+	return "TrailingWhitespace"
+}
+
+func (r TrailingWhitespaceRule) Apply(content string) (string, error) {
+	if !r.trim {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	b := classifyBlocks(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == line || b.isCode(i) {
+			continue
+		}
+		// a hard break, when the paragraph goes on
+		if strings.HasSuffix(line, "  ") && b.lines[i].kind == lineText && i+1 < len(lines) &&
+			b.lines[i+1].kind == lineText && !isHeadingLine(line, b.lines[i]) && !isHeadingLine(lines[i+1], b.lines[i+1]) {
+			continue
+		}
+		lines[i] = trimmed
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ----------------------------------------------------------------
+// Rule 57: end the document with a newline, or without one
+// ----------------------------------------------------------------
+
+type FinalNewlineRule struct {
+	newline bool
+}
+
+// NewFinalNewlineRule constructs a rule that ends the document with a
+// line break when newline is set, and strips the line breaks at its end
+// otherwise.
+func NewFinalNewlineRule(newline bool) Rule {
+	return FinalNewlineRule{newline: newline}
+}
+
+func newFinalNewlineRuleFromOptions(o Options) (Rule, error) {
+	newline, err := o.Bool("final-newline", true)
+	if err != nil {
+		return nil, err
+	}
+	return NewFinalNewlineRule(newline), nil
+}
+
+func (FinalNewlineRule) Name() string {
+	return "FinalNewline"
+}
+
+func (r FinalNewlineRule) Apply(content string) (string, error) {
+	if !r.newline {
+		return strings.TrimRight(content, "\r\n"), nil
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content, nil
+}
+
+// ----------------------------------------------------------------
+// Rule 58: write line breaks as LF or as CRLF
+// ----------------------------------------------------------------
+
+type EndOfLineRule struct {
+	// eol is "\n" or "\r\n", or empty to leave the line breaks alone.
+	eol string
+}
+
+// NewEndOfLineRule constructs a rule that writes every line break as eol.
+func NewEndOfLineRule(eol string) Rule {
+	return EndOfLineRule{eol: eol}
+}
+
+func newEndOfLineRuleFromOptions(o Options) (Rule, error) {
+	style, err := o.Choice("end-of-line", "", "", "lf", "crlf")
+	if err != nil {
+		return nil, err
+	}
+	return NewEndOfLineRule(map[string]string{"lf": "\n", "crlf": "\r\n"}[style]), nil
+}
+
+func (EndOfLineRule) Name() string {
+	return "EndOfLine"
+}
+
+func (r EndOfLineRule) Apply(content string) (string, error) {
+	if r.eol == "" {
+		return content, nil
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if r.eol != "\n" {
+		content = strings.ReplaceAll(content, "\n", r.eol)
+	}
+	return content, nil
+}
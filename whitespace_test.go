@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestWhitespaceRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		input    string
+		expected string
+	}{
+		{
+			name:     "tabs kept",
+			rule:     NewExpandTabsRule(false, 4),
+			input:    "-\titem\n",
+			expected: "-\titem\n",
+		},
+		{
+			name:     "tabs expanded to Markdown tab stops",
+			rule:     NewExpandTabsRule(true, 2),
+			input:    "-\titem\n\n\tcode\n>\tquoted\n\n- a\n\n\t\tmore\n",
+			expected: "-   item\n\n    code\n>   quoted\n\n- a\n\n        more\n",
+		},
+		{
+			name:     "tab width in fenced code",
+			rule:     NewExpandTabsRule(true, 2),
+			input:    "```go\n\tx := 1\n\t\ty\tz\n```\n",
+			expected: "```go\n  x := 1\n    y\tz\n```\n",
+		},
+		{
+			name:     "trailing whitespace kept",
+			rule:     NewTrailingWhitespaceRule(false),
+			input:    "text  \n",
+			expected: "text  \n",
+		},
+		{
+			name:     "trailing whitespace trimmed",
+			rule:     NewTrailingWhitespaceRule(true),
+			input:    "# H \nend of paragraph  \n\n> quoted\t\n> \n- item \n",
+			expected: "# H\nend of paragraph\n\n> quoted\n>\n- item\n",
+		},
+		{
+			name:     "hard breaks and code keep theirs",
+			rule:     NewTrailingWhitespaceRule(true),
+			input:    "one  \ntwo \n\n```\ncode  \n```\n\n    indented  \n",
+			expected: "one  \ntwo\n\n```\ncode  \n```\n\n    indented  \n",
+		},
+		{
+			name:     "final newline added",
+			rule:     NewFinalNewlineRule(true),
+			input:    "text",
+			expected: "text\n",
+		},
+		{
+			name:     "final newlines removed",
+			rule:     NewFinalNewlineRule(false),
+			input:    "text\r\n\n",
+			expected: "text",
+		},
+		{
+			name:     "line breaks kept",
+			rule:     NewEndOfLineRule(""),
+			input:    "a\r\nb\n",
+			expected: "a\r\nb\n",
+		},
+		{
+			name:     "line breaks as CRLF",
+			rule:     NewEndOfLineRule("\r\n"),
+			input:    "a\r\nb\n",
+			expected: "a\r\nb\r\n",
+		},
+		{
+			name:     "line breaks as LF",
+			rule:     NewEndOfLineRule("\n"),
+			input:    "a\r\nb\n",
+			expected: "a\nb\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if again, _ := tt.rule.Apply(got); again != got {
+				t.Errorf("not idempotent:\n%q", again)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}